@@ -0,0 +1,141 @@
+package gslk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AlternateSeparator starts the conditions in the name of an alternate file
+// or directory, such as .gitconfig##os.darwin or .ssh/config##host.work.
+// Among the alternates of a name, only the one best matching the machine is
+// linked, under the name without the conditions; the others are ignored.
+//
+// Conditions are separated by commas and must all hold:
+//
+//	os.<GOOS>     runtime.GOOS, e.g. os.linux or os.darwin
+//	arch.<GOARCH> runtime.GOARCH, e.g. arch.arm64
+//	host.<name>   The host name, or its first label (hostname.<name> works too)
+//	default       Always holds; chosen when no other alternate matches
+//
+// A host condition outweighs an architecture one, which outweighs an OS one,
+// so file##host.work beats file##os.linux on the machine called work. Of
+// alternates matching equally well the first by name wins.
+const AlternateSeparator = "##"
+
+// alternateWeights rank the conditions of alternates; the alternate with the
+// highest sum of weights is chosen.
+var alternateWeights = map[string]int{"default": 0, "os": 1, "arch": 2, "host": 4, "hostname": 4}
+
+// splitAlternate returns the base name and the conditions of an alternate
+// name, or false if name is not an alternate.
+func splitAlternate(name string) (base, conditions string, ok bool) {
+	base, conditions, ok = strings.Cut(name, AlternateSeparator)
+	return base, conditions, ok && base != ""
+}
+
+// stripAlternates removes the conditions from every alternate component of
+// relPath.
+func stripAlternates(relPath string) string {
+	if !strings.Contains(relPath, AlternateSeparator) {
+		return relPath
+	}
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i, part := range parts {
+		if base, _, ok := splitAlternate(part); ok {
+			parts[i] = base
+		}
+	}
+	return filepath.Join(parts...)
+}
+
+// alternateScore returns how well conditions match the machine, or false if
+// one of them does not hold.
+func alternateScore(conditions, hostname string) (int, bool, error) {
+	score := 0
+	for _, condition := range strings.Split(conditions, ",") {
+		key, value, _ := strings.Cut(condition, ".")
+		weight, known := alternateWeights[key]
+		if !known || (key == "default") != (value == "") {
+			return 0, false, fmt.Errorf("unknown alternate condition %q", condition)
+		}
+
+		var holds bool
+		switch key {
+		case "default":
+			holds = true
+		case "os":
+			holds = strings.EqualFold(value, runtime.GOOS)
+		case "arch":
+			holds = strings.EqualFold(value, runtime.GOARCH)
+		case "host", "hostname":
+			short, _, _ := strings.Cut(hostname, ".")
+			holds = strings.EqualFold(value, hostname) || strings.EqualFold(value, short)
+		}
+		if !holds {
+			return 0, false, nil
+		}
+		score += weight
+	}
+	return score, true, nil
+}
+
+// chooseAlternates returns the alternates of the package directory dir that
+// match the machine best, one per base name, by their names in dir.
+func (l *Linker) chooseAlternates(dir string) (map[string]bool, error) {
+	var names []string
+	err := orOS(l.FS).WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		names = append(names, d.Name())
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alternates in %s: %w", dir, err)
+	}
+
+	hostname, _ := os.Hostname()
+	type choice struct {
+		name  string
+		score int
+	}
+	best := make(map[string]choice) // By base name
+	plain := make(map[string]bool)
+	for _, name := range names {
+		base, conditions, ok := splitAlternate(name)
+		if !ok {
+			plain[name] = true
+			continue
+		}
+		score, holds, err := alternateScore(conditions, hostname)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, name), err)
+		}
+		if b, seen := best[base]; holds && (!seen || score > b.score) {
+			best[base] = choice{name, score}
+		} else if !seen {
+			best[base] = choice{score: -1} // Known, but nothing matches yet
+		}
+	}
+
+	chosen := make(map[string]bool, len(best))
+	for base, b := range best {
+		if plain[base] {
+			return nil, fmt.Errorf("%w: both %s and alternates of it exist in %s; rename it to %s%sdefault", ErrConflict, base, dir, base, AlternateSeparator)
+		}
+		if b.name != "" {
+			chosen[b.name] = true
+		}
+	}
+	return chosen, nil
+}
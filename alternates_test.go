@@ -0,0 +1,85 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkAlternates(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"os##os.plan9":                             "plan9",
+		"os##os." + runtime.GOOS:                   "this os",
+		"os##default":                              "default",
+		"host##os." + runtime.GOOS:                 "os",
+		"host##host." + hostname:                   "host",
+		"both##os." + runtime.GOOS + ",arch.plan9": "no",
+		"both##default":                            "default",
+		"none##os.plan9":                           "plan9",
+		"conf.tmpl##arch." + runtime.GOARCH:        "{{ .Package }}",
+		"dir##os." + runtime.GOOS + "/file":        "in dir",
+		"dir##default/file":                        "default dir",
+		"plain":                                    "plain",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Mapper: Mapper{TemplateSuffix: DefaultTemplateSuffix}}
+	result, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	for name, want := range map[string]string{
+		"os":       "this os",
+		"host":     "host",
+		"both":     "default",
+		"conf":     "pkg",
+		"dir/file": "in dir",
+		"plain":    "plain",
+	} {
+		content, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(name)))
+		require.NoError(t, err, name)
+		assert.Equal(t, want, string(content), name)
+	}
+	assert.NoFileExists(t, filepath.Join(targetDir, "none"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "os##default"))
+	assert.Contains(t, result.Ignored, filepath.Join(pkgPath, "os##os.plan9"))
+	assert.Contains(t, result.Ignored, filepath.Join(pkgPath, "dir##default"))
+
+	_, err = linker.Unlink([]string{"pkg"})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(targetDir, "os"))
+}
+
+func TestLinkAlternatesInvalid(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"unknown condition": {"file##class.work": ""},
+		"default value":     {"file##default.x": ""},
+		"plain and alternate": {
+			"file":           "",
+			"file##os.plan9": "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sourceDir, targetDir, cleanup := setupTestDirs(t)
+			defer cleanup()
+			createDummyPackage(t, filepath.Join(sourceDir, "pkg"), files)
+
+			linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+			_, err := linker.PlanLink([]string{"pkg"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStripAlternates(t *testing.T) {
+	assert.Equal(t, filepath.Join("dir", "file"), stripAlternates(filepath.Join("dir##os.linux", "file##default")))
+	assert.Equal(t, "##odd", stripAlternates("##odd"))
+}
@@ -0,0 +1,90 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// DesiredFileName is the name of the file declaring the packages to link on
+// a machine, read by gslk apply next to the configuration file.
+const DesiredFileName = "gslkfile"
+
+// LoadDesired reads the packages listed in the desired-state file at path,
+// a TOML file with a packages array:
+//
+//	packages = ["git", "vim", "zsh"]
+func LoadDesired(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, ok := doc["packages"]; !ok {
+		return nil, fmt.Errorf("%s does not declare packages", path)
+	}
+	packages, err := tomlStringList(doc, "packages")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return packages, nil
+}
+
+// Apply reconciles the target with packageNames, the complete list of
+// packages that should be linked: packages with files deployed that are not
+// in the list are unlinked, and the listed packages are relinked, linking
+// those missing and fixing what drifted. Groups stand for their members and
+// the dependencies of listed packages are wanted too. An empty list unlinks
+// every package.
+//
+// The returned Result is never nil and covers both steps.
+func (l *Linker) Apply(packageNames []string) (*Result, error) {
+	result := &Result{}
+
+	// Dependencies are wanted too, or the next run would unlink them
+	packages, err := l.lookupPackages(packageNames)
+	if err != nil {
+		return result, err
+	}
+	if packages, err = l.withDependencies(packages); err != nil {
+		return result, err
+	}
+	desired := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		if pkg.Subpath != "" {
+			return result, fmt.Errorf("apply takes whole packages, not %s/%s", pkg.Name, filepath.ToSlash(pkg.Subpath))
+		}
+		desired = append(desired, pkg.Name)
+	}
+
+	statuses, err := l.Status()
+	if err != nil {
+		return result, err
+	}
+	var stale []string
+	for _, status := range statuses {
+		if status.Status != StatusUnlinked && !slices.Contains(desired, status.Package.Name) {
+			stale = append(stale, status.Package.Name)
+		}
+	}
+
+	if len(stale) > 0 {
+		l.printf("Unlinking packages %v, which are no longer wanted\n", stale)
+		unlinked, err := l.Unlink(stale)
+		result.Merge(unlinked)
+		if err != nil {
+			return result, fmt.Errorf("failed to unlink packages no longer wanted: %w", err)
+		}
+	}
+	if len(desired) == 0 {
+		return result, nil
+	}
+
+	relinked, err := l.Relink(desired)
+	result.Merge(relinked)
+	return result, err
+}
@@ -0,0 +1,89 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDesired(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DesiredFileName)
+
+	require.NoError(t, os.WriteFile(path, []byte("# this machine\npackages = [\"git\", \"vim\"]\n"), 0644))
+	packages, err := LoadDesired(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"git", "vim"}, packages)
+
+	require.NoError(t, os.WriteFile(path, []byte("packages = []\n"), 0644))
+	packages, err = LoadDesired(path)
+	require.NoError(t, err)
+	assert.Empty(t, packages)
+
+	require.NoError(t, os.WriteFile(path, []byte("pakages = [\"git\"]\n"), 0644))
+	_, err = LoadDesired(path)
+	assert.ErrorContains(t, err, "does not declare packages")
+
+	_, err = LoadDesired(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestApply(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": "g"})
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{".vimrc": "v"})
+	createDummyPackage(t, filepath.Join(sourceDir, "zsh"), map[string]string{
+		".zshrc":             "z",
+		".gslk-package.toml": "depends = [\"shell\"]\n",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "shell"), map[string]string{".profile": "p"})
+
+	linker := New(sourceDir, targetDir)
+	linked := func(name string) bool {
+		_, err := os.Lstat(filepath.Join(targetDir, name))
+		return err == nil
+	}
+
+	_, err := linker.Apply([]string{"git", "zsh"})
+	require.NoError(t, err)
+	assert.True(t, linked(".gitconfig"))
+	assert.True(t, linked(".zshrc"))
+	assert.True(t, linked(".profile"), "dependencies are linked")
+	assert.False(t, linked(".vimrc"))
+
+	t.Run("packages removed from the list are unlinked", func(t *testing.T) {
+		result, err := linker.Apply([]string{"vim", "zsh"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(targetDir, ".gitconfig")}, result.Unlinked)
+		assert.False(t, linked(".gitconfig"))
+		assert.True(t, linked(".vimrc"))
+		assert.True(t, linked(".profile"), "dependencies of wanted packages stay")
+	})
+
+	t.Run("drift is fixed", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(targetDir, ".vimrc")))
+		result, err := linker.Apply([]string{"vim", "zsh"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(targetDir, ".vimrc")}, result.Linked)
+		assert.Empty(t, result.Unlinked)
+	})
+
+	t.Run("an empty list unlinks everything", func(t *testing.T) {
+		_, err := linker.Apply(nil)
+		require.NoError(t, err)
+		for _, name := range []string{".vimrc", ".zshrc", ".profile"} {
+			assert.False(t, linked(name), name)
+		}
+	})
+
+	t.Run("unknown packages fail before anything changes", func(t *testing.T) {
+		_, err := linker.Apply([]string{"vim", "emcas"})
+		assert.ErrorContains(t, err, "package 'emcas' not found")
+		assert.False(t, linked(".vimrc"))
+	})
+}
@@ -0,0 +1,389 @@
+package gslk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveFormats maps the file name suffixes of supported archives to their format.
+var archiveFormats = []struct{ suffix, format string }{
+	{".tar.gz", "tgz"},
+	{".tgz", "tgz"},
+	{".tar", "tar"},
+	{".zip", "zip"},
+}
+
+// IsArchiveSource reports whether spec names a tarball or zip archive, by
+// local path or HTTPS URL, rather than a source directory. A "#dir" suffix
+// selects a directory inside the archive as the source directory.
+func IsArchiveSource(spec string) bool {
+	return archiveFormat(spec) != ""
+}
+
+// IsRemoteSource reports whether spec is a URL rather than a local path.
+func IsRemoteSource(spec string) bool {
+	return strings.HasPrefix(spec, "https://") || strings.HasPrefix(spec, "http://")
+}
+
+// archiveFormat returns the format of the archive spec names, or "" if it
+// does not name an archive.
+func archiveFormat(spec string) string {
+	location, _, _ := strings.Cut(spec, "#")
+	if IsRemoteSource(location) {
+		u, err := url.Parse(location)
+		if err != nil {
+			return ""
+		}
+		location = u.Path
+	}
+	for _, f := range archiveFormats {
+		if strings.HasSuffix(strings.ToLower(location), f.suffix) {
+			return f.format
+		}
+	}
+	return ""
+}
+
+// DefaultSourceCacheDir returns where archive sources are extracted:
+// $XDG_CACHE_HOME/gslk/sources or its platform equivalent.
+func DefaultSourceCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gslk", "sources"), nil
+}
+
+// archiveSumFileName is the file in an extracted archive's directory that
+// holds the checksum of the archive.
+const archiveSumFileName = ".gslk-archive-sum"
+
+// SourceCache extracts archive sources so that packages can be linked from
+// them. Each archive location has its own directory, extracted anew when the
+// content of the archive changed, so links into it stay valid across updates.
+type SourceCache struct {
+	Dir    string       // Directory archives are extracted into; defaults to DefaultSourceCacheDir
+	Client *http.Client // Client downloading remote archives; defaults to http.DefaultClient
+}
+
+// Resolve returns the source directory spec stands for: spec itself if it
+// is not an archive, otherwise the directory the archive, read from its path
+// or downloaded over HTTPS, was extracted to, or the directory named by its
+// "#dir" suffix inside it.
+func (c *SourceCache) Resolve(spec string) (string, error) {
+	format := archiveFormat(spec)
+	if format == "" {
+		return spec, nil
+	}
+	location, subdir, _ := strings.Cut(spec, "#")
+
+	data, err := c.read(location)
+	if err != nil {
+		return "", err
+	}
+	cacheDir := c.Dir
+	if cacheDir == "" {
+		if cacheDir, err = DefaultSourceCacheDir(); err != nil {
+			return "", fmt.Errorf("failed to locate the source cache: %w", err)
+		}
+	}
+
+	key := location
+	if !IsRemoteSource(location) {
+		if key, err = filepath.Abs(location); err != nil {
+			return "", fmt.Errorf("failed to get absolute path for %s: %w", location, err)
+		}
+	}
+	name := sha256.Sum256([]byte(key))
+	dir := filepath.Join(cacheDir, hex.EncodeToString(name[:8]))
+	sum := sha256.Sum256(data)
+	if extracted, err := os.ReadFile(filepath.Join(dir, archiveSumFileName)); err != nil || string(extracted) != hex.EncodeToString(sum[:]) {
+		if err := extractArchive(data, format, hex.EncodeToString(sum[:]), cacheDir, dir); err != nil {
+			return "", fmt.Errorf("failed to extract %s: %w", location, err)
+		}
+	}
+
+	if subdir != "" {
+		if !filepath.IsLocal(subdir) {
+			return "", fmt.Errorf("invalid directory %q in archive source %s", subdir, spec)
+		}
+		dir = filepath.Join(dir, subdir)
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return "", fmt.Errorf("archive %s has no directory %s", location, subdir)
+		}
+	}
+	return dir, nil
+}
+
+// read returns the content of the archive at location.
+func (c *SourceCache) read(location string) ([]byte, error) {
+	if !IsRemoteSource(location) {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", location, err)
+		}
+		return data, nil
+	}
+	if !strings.HasPrefix(location, "https://") {
+		return nil, fmt.Errorf("refusing to download %s: archive sources must use HTTPS", location)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	return data, nil
+}
+
+// extractArchive extracts data, an archive in format with checksum sum, to
+// dir, replacing what it held. It is extracted to a temporary directory in
+// cacheDir first, so that dir only ever holds a complete archive.
+func extractArchive(data []byte, format, sum, cacheDir, dir string) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(cacheDir, ".extract-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	// Entries are written through root, so they cannot leave tmp by way of
+	// links extracted before them
+	root, err := os.OpenRoot(tmp)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	if format == "zip" {
+		err = extractZip(data, root)
+	} else {
+		err = extractTar(data, format == "tgz", root)
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkArchiveLinks(tmp); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, archiveSumFileName), []byte(sum), 0644); err != nil {
+		return err
+	}
+
+	// Swap the directories, keeping the previous extraction until the new one is in place
+	old := dir + ".old"
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	if err := os.Rename(dir, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.Rename(old, dir)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// extractTar extracts the tar archive in data, gzip-compressed if gzipped, to root.
+func extractTar(data []byte, gzipped bool, root *os.Root) error {
+	var r io.Reader = bytes.NewReader(data)
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = extractEntry(root, hdr.Name, os.ModeDir|hdr.FileInfo().Mode().Perm(), nil, "")
+		case tar.TypeReg:
+			err = extractEntry(root, hdr.Name, hdr.FileInfo().Mode().Perm(), tr, "")
+		case tar.TypeSymlink:
+			err = extractEntry(root, hdr.Name, os.ModeSymlink, nil, hdr.Linkname)
+		case tar.TypeXGlobalHeader:
+			// Metadata such as the commit of a GitHub tarball
+		default:
+			err = fmt.Errorf("%s: unsupported entry type %q", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts the zip archive in data to root.
+func extractZip(data []byte, root *os.Root) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		mode := f.Mode()
+		if mode.IsDir() || mode.IsRegular() {
+			if err := extractZipFile(f, root); err != nil {
+				return err
+			}
+			continue
+		}
+		if mode&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s: unsupported entry type %v", f.Name, mode.Type())
+		}
+		// A symbolic link holds its destination as content
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		dest, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := extractEntry(root, f.Name, os.ModeSymlink, nil, string(dest)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile extracts the file or directory f of a zip archive to root.
+func extractZipFile(f *zip.File, root *os.Root) error {
+	if f.Mode().IsDir() {
+		return extractEntry(root, f.Name, os.ModeDir|f.Mode().Perm(), nil, "")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return extractEntry(root, f.Name, f.Mode().Perm(), rc, "")
+}
+
+// extractEntry creates the archive entry name below root: a directory, a
+// regular file with the content of r, or a symbolic link to linkDest. Names
+// and link destinations leading outside root are refused, as are links
+// created below another link.
+func extractEntry(root *os.Root, name string, mode os.FileMode, r io.Reader, linkDest string) error {
+	rel := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(rel) {
+		return fmt.Errorf("%s: path leads outside the archive", name)
+	}
+
+	if mode.IsDir() {
+		return mkdirAllIn(root, rel, mode.Perm()|0700)
+	}
+	if err := mkdirAllIn(root, filepath.Dir(rel), 0755); err != nil {
+		return err
+	}
+
+	if mode&os.ModeSymlink != 0 {
+		dest := filepath.FromSlash(linkDest)
+		if filepath.IsAbs(dest) || strings.HasPrefix(linkDest, "/") || !filepath.IsLocal(filepath.Join(filepath.Dir(rel), dest)) {
+			return fmt.Errorf("%s: link to %s leads outside the archive", name, linkDest)
+		}
+		// os.Root creates no links before Go 1.25, so the link is created by
+		// path, which must then not pass through another link
+		for dir := filepath.Dir(rel); dir != "."; dir = filepath.Dir(dir) {
+			fi, err := root.Lstat(dir)
+			if err != nil {
+				return err
+			}
+			if fi.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("%s: path leads through link %s, possibly outside the archive", name, filepath.ToSlash(dir))
+			}
+		}
+		return os.Symlink(linkDest, filepath.Join(root.Name(), rel))
+	}
+
+	f, err := root.OpenFile(rel, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mkdirAllIn creates the directory rel below root with perm, along with any
+// missing parents.
+func mkdirAllIn(root *os.Root, rel string, perm os.FileMode) error {
+	if rel == "." {
+		return nil
+	}
+	if err := mkdirAllIn(root, filepath.Dir(rel), perm); err != nil {
+		return err
+	}
+	if err := root.Mkdir(rel, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// checkArchiveLinks makes sure every link extracted to dir resolves to a path
+// inside it: each link destination is only checked as text when extracted,
+// and may still lead outside through other links. Links to nothing are left
+// alone.
+func checkArchiveLinks(dir string) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		dest, err := filepath.EvalSymlinks(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.ToSlash(rel), err)
+		}
+		if dest != realDir && !isSubPath(realDir, dest) {
+			return fmt.Errorf("%s: link leads outside the archive", filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
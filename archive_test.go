@@ -0,0 +1,160 @@
+package gslk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarball returns a gzipped tar archive of files; values starting with "->"
+// are symbolic links to the rest of the value.
+func tarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	// Entries in name order, so directories and links precede what is below them
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		content := files[name]
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(content))}
+		if dest, ok := strings.CutPrefix(content, "->"); ok {
+			hdr = &tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: dest}
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if hdr.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestIsArchiveSource(t *testing.T) {
+	for spec, want := range map[string]bool{
+		"/home/me/dotfiles":                              false,
+		"dotfiles.tar.gz":                                true,
+		"bundle.TGZ":                                     true,
+		"bundle.tar#dotfiles-main":                       true,
+		"https://example.com/dotfiles.zip":               true,
+		"https://example.com/dotfiles.zip?token=x#inner": true,
+		"https://example.com/dotfiles":                   false,
+	} {
+		assert.Equal(t, want, IsArchiveSource(spec), spec)
+	}
+}
+
+func TestSourceCacheTarball(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "dotfiles.tar.gz")
+	require.NoError(t, os.WriteFile(archive, tarball(t, map[string]string{
+		"dotfiles-main/zsh/.zshrc":    "zsh",
+		"dotfiles-main/zsh/.zprofile": "->.zshrc",
+	}), 0644))
+
+	cache := &SourceCache{Dir: filepath.Join(tempDir, "cache")}
+	dir, err := cache.Resolve(archive + "#dotfiles-main")
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "zsh", ".zprofile"))
+	require.NoError(t, err)
+	assert.Equal(t, "zsh", string(data))
+
+	// An unchanged archive is not extracted again
+	again, err := cache.Resolve(archive + "#dotfiles-main")
+	require.NoError(t, err)
+	assert.Equal(t, dir, again)
+
+	// Packages are linked from the extracted directory
+	targetDir := filepath.Join(tempDir, "target")
+	require.NoError(t, os.Mkdir(targetDir, 0755))
+	linker := &Linker{SourceDir: dir, TargetDir: targetDir}
+	_, err = linker.Link([]string{"zsh"})
+	require.NoError(t, err)
+	dest, err := os.Readlink(filepath.Join(targetDir, ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "zsh", ".zshrc"), dest)
+
+	// A changed archive replaces the extracted files, so links stay valid
+	require.NoError(t, os.WriteFile(archive, tarball(t, map[string]string{
+		"dotfiles-main/zsh/.zshrc": "zsh 2",
+	}), 0644))
+	again, err = cache.Resolve(archive + "#dotfiles-main")
+	require.NoError(t, err)
+	assert.Equal(t, dir, again)
+	data, err = os.ReadFile(filepath.Join(targetDir, ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "zsh 2", string(data))
+	_, err = os.Lstat(filepath.Join(dir, "zsh", ".zprofile"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = cache.Resolve(archive + "#missing")
+	assert.Error(t, err)
+}
+
+func TestSourceCacheRejectsEscapes(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, files := range map[string]map[string]string{
+		"parent.tar.gz":   {"../evil": "x"},
+		"absolute.tar.gz": {"zsh/.zshrc": "->/etc/passwd"},
+		"link.tar.gz":     {"zsh/.zshrc": "->../../outside"},
+		// Each link stays inside as text, but the next entry goes through it
+		"chain.tar.gz": {"d/l": "->..", "d/l/l2": "->..", "d/l/l2/escaped.txt": "x"},
+		// As text a/z stays in a/b/c, but y leads back to the top first
+		"resolved.tar.gz": {"a/b/c/y": "->../../..", "a/z": "->b/c/y/../secret"},
+	} {
+		archive := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(archive, tarball(t, files), 0644))
+		cache := &SourceCache{Dir: filepath.Join(tempDir, "cache")}
+		require.NoError(t, os.MkdirAll(cache.Dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(cache.Dir, "secret"), nil, 0600))
+		_, err := cache.Resolve(archive)
+		assert.ErrorContains(t, err, "outside the archive", name)
+	}
+	_, err := os.Stat(filepath.Join(tempDir, "evil"))
+	assert.True(t, os.IsNotExist(err))
+	assert.NoFileExists(t, filepath.Join(tempDir, "cache", "escaped.txt"))
+}
+
+func TestSourceCacheDownload(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("vim/.vimrc")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("vim"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dotfiles.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	cache := &SourceCache{Dir: t.TempDir(), Client: server.Client()}
+	dir, err := cache.Resolve(server.URL + "/dotfiles.zip")
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "vim", ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "vim", string(data))
+
+	_, err = cache.Resolve(server.URL + "/missing.zip")
+	assert.ErrorContains(t, err, "404")
+
+	_, err = cache.Resolve("http://example.com/dotfiles.zip")
+	assert.ErrorContains(t, err, "HTTPS")
+}
@@ -0,0 +1,141 @@
+package gslk
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The benchmarks work on synthetic trees of 1k, 10k and 100k files; -short
+// skips the largest. Run them with
+//
+//	go test -run '^$' -bench . -benchtime 3x
+//
+// Each reports files/s, the rate README.md sets a target for.
+
+// generateTree creates the package pkgPath with files files to link, 100 to a
+// directory two levels deep. Every directory also holds a backup file and an
+// editor swap file its .gslk-ignore excludes. It returns how many paths the
+// package links: the files and the directories holding them.
+func generateTree(tb testing.TB, pkgPath string, files int) int {
+	tb.Helper()
+	require.NoError(tb, os.MkdirAll(pkgPath, 0755))
+	require.NoError(tb, os.WriteFile(filepath.Join(pkgPath, IgnoreFileName), []byte("*.bak\n.*.swp\n.config/*/cache\n"), 0644))
+
+	dirs := make(map[string]bool)
+	for i := range files {
+		top := fmt.Sprintf("d%02d", i/10000)
+		dir := filepath.Join(pkgPath, top, fmt.Sprintf("d%03d", i/100%100))
+		if i%100 == 0 {
+			require.NoError(tb, os.MkdirAll(dir, 0755))
+			require.NoError(tb, os.WriteFile(filepath.Join(dir, "notes.bak"), nil, 0644))
+			require.NoError(tb, os.WriteFile(filepath.Join(dir, ".notes.swp"), nil, 0644))
+			dirs[top], dirs[dir] = true, true
+		}
+		name := fmt.Sprintf("file%02d.conf", i%100)
+		if i%10 == 0 {
+			name = "." + name
+		}
+		require.NoError(tb, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	return files + len(dirs)
+}
+
+// benchTrees runs bench on a freshly generated tree of every size, with a
+// Linker whose source directory is relative to the working directory, as it
+// is when given on the command line. paths is what generateTree returned.
+func benchTrees(b *testing.B, bench func(b *testing.B, linker *Linker, files, paths int)) {
+	for _, files := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("files=%d", files), func(b *testing.B) {
+			if testing.Short() && files > 10000 {
+				b.Skip("skipping the largest tree in short mode")
+			}
+			dir := b.TempDir()
+			paths := generateTree(b, filepath.Join(dir, "source", "big"), files)
+			require.NoError(b, os.Mkdir(filepath.Join(dir, "target"), 0755))
+			b.Chdir(dir)
+
+			linker := &Linker{SourceDir: "source", TargetDir: filepath.Join(dir, "target"), Logger: log.New(io.Discard, "", 0)}
+			bench(b, linker, files, paths)
+			b.ReportMetric(float64(files)*float64(b.N)/b.Elapsed().Seconds(), "files/s")
+		})
+	}
+}
+
+// BenchmarkPlanLink plans linking a package into an empty target.
+func BenchmarkPlanLink(b *testing.B) {
+	benchTrees(b, func(b *testing.B, linker *Linker, files, paths int) {
+		for b.Loop() {
+			plan, err := linker.PlanLink([]string{"big"})
+			require.NoError(b, err)
+			require.Len(b, plan.Operations, paths)
+		}
+	})
+}
+
+// BenchmarkPlanLinked plans linking a package that is already linked, which
+// finds nothing to do.
+func BenchmarkPlanLinked(b *testing.B) {
+	benchTrees(b, func(b *testing.B, linker *Linker, files, paths int) {
+		_, err := linker.Link([]string{"big"})
+		require.NoError(b, err)
+
+		for b.Loop() {
+			plan, err := linker.PlanLink([]string{"big"})
+			require.NoError(b, err)
+			require.Len(b, plan.Operations, paths)
+		}
+	})
+}
+
+func BenchmarkLink(b *testing.B) {
+	benchTrees(b, func(b *testing.B, linker *Linker, files, paths int) {
+		for b.Loop() {
+			result, err := linker.Link([]string{"big"})
+			require.NoError(b, err)
+			require.Len(b, result.Linked, files)
+
+			b.StopTimer()
+			_, err = linker.Unlink([]string{"big"})
+			require.NoError(b, err)
+			b.StartTimer()
+		}
+	})
+}
+
+func BenchmarkUnlink(b *testing.B) {
+	benchTrees(b, func(b *testing.B, linker *Linker, files, paths int) {
+		for b.Loop() {
+			b.StopTimer()
+			_, err := linker.Link([]string{"big"})
+			require.NoError(b, err)
+			b.StartTimer()
+
+			result, err := linker.Unlink([]string{"big"})
+			require.NoError(b, err)
+			require.Len(b, result.Unlinked, files)
+		}
+	})
+}
+
+func BenchmarkIgnoreRulesMatch(b *testing.B) {
+	rules := NewIgnoreRules([]string{".git", "README.md", "*.bak", ".*.swp", ".config/*/cache", "node_modules"}).WithInclude([]string{"d*"})
+	paths := make([]string, 100000)
+	for i := range paths {
+		paths[i] = filepath.Join(fmt.Sprintf("d%02d", i/10000), fmt.Sprintf("d%03d", i/100%100), fmt.Sprintf("file%02d.conf", i%100))
+	}
+
+	for b.Loop() {
+		for _, path := range paths {
+			if rules.Match(path) || !rules.Includes(path) {
+				b.Fatalf("%s is not linked", path)
+			}
+		}
+	}
+	b.ReportMetric(float64(len(paths))*float64(b.N)/b.Elapsed().Seconds(), "files/s")
+}
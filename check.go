@@ -0,0 +1,143 @@
+package gslk
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CheckProblemKind categorises a file that is not deployed as its package
+// says.
+type CheckProblemKind string
+
+const (
+	ProblemMissing   CheckProblemKind = "missing"    // Nothing exists at the target path
+	ProblemWrongLink CheckProblemKind = "wrong-link" // A symlink at the target path points somewhere else
+	ProblemOccupied  CheckProblemKind = "occupied"   // A file or directory gslk did not deploy is in the way
+	ProblemModified  CheckProblemKind = "modified"   // A deployed copy was edited in the target since
+	ProblemOutdated  CheckProblemKind = "outdated"   // The package file changed since it was deployed
+)
+
+// CheckProblem is a file of a checked package that is not deployed as it
+// should be.
+type CheckProblem struct {
+	Kind    CheckProblemKind
+	Package string
+	Target  string
+}
+
+// Strict reports whether the problem only fails strict checks: the file is
+// deployed, but no longer matches its package.
+func (p CheckProblem) Strict() bool {
+	return p.Kind == ProblemModified || p.Kind == ProblemOutdated
+}
+
+// CheckResult is the outcome of Check.
+type CheckResult struct {
+	Packages []string // Packages checked, in the order given, with their dependencies
+	Files    int      // Files they deploy, after ignore rules
+	Problems []CheckProblem
+}
+
+// Healthy reports whether every file is deployed. If strict is set, deployed
+// copies must also still match their packages.
+func (r *CheckResult) Healthy(strict bool) bool {
+	return r.Failing(strict) == nil
+}
+
+// Failing returns the problems failing the check, or the strict check if
+// strict is set.
+func (r *CheckResult) Failing(strict bool) []CheckProblem {
+	var failing []CheckProblem
+	for _, p := range r.Problems {
+		if strict || !p.Strict() {
+			failing = append(failing, p)
+		}
+	}
+	return failing
+}
+
+// FailingPackages returns the packages with problems failing the check, in
+// the order they were checked.
+func (r *CheckResult) FailingPackages(strict bool) []string {
+	var names []string
+	for _, p := range r.Failing(strict) {
+		if !slices.Contains(names, p.Package) {
+			names = append(names, p.Package)
+		}
+	}
+	slices.SortStableFunc(names, func(a, b string) int {
+		return slices.Index(r.Packages, a) - slices.Index(r.Packages, b)
+	})
+	return names
+}
+
+// Check reports whether the files of packageNames and the packages they
+// depend on are all deployed in the target directory, and deployed copies,
+// rendered templates and decrypted secrets still match their packages. It is
+// meant for health checks and CI assertions; nothing is modified.
+func (l *Linker) Check(packageNames []string) (*CheckResult, error) {
+	packages, err := l.lookupPackages(packageNames)
+	if err != nil {
+		return nil, err
+	}
+	if packages, err = l.withDependencies(packages); err != nil {
+		return nil, err
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	result := &CheckResult{}
+	var deployed []string // Packages whose copies are checked against their checksums
+	for _, pkg := range packages {
+		result.Packages = append(result.Packages, pkg.Name)
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			result.Files++
+			c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+
+			problem := CheckProblem{Package: pkg.Name, Target: path.targetPath}
+			switch c.State {
+			case TargetLinked:
+				return nil
+			case TargetDeployed:
+				deployed = append(deployed, pkg.Name)
+				return nil
+			case TargetMissing:
+				problem.Kind = ProblemMissing
+			case TargetForeignLink:
+				problem.Kind = ProblemWrongLink
+			case TargetModified:
+				problem.Kind = ProblemModified
+			default:
+				problem.Kind = ProblemOccupied
+			}
+			result.Problems = append(result.Problems, problem)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+	}
+
+	if len(deployed) > 0 {
+		verifications, err := l.Verify(slices.Compact(deployed))
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range verifications {
+			if v.TargetStatus == ChecksumOK && v.SourceStatus != ChecksumOK && v.SourceStatus != ChecksumUnchecked {
+				result.Problems = append(result.Problems, CheckProblem{Kind: ProblemOutdated, Package: v.Package, Target: v.Target})
+			}
+		}
+	}
+	return result, nil
+}
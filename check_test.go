@@ -0,0 +1,70 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"linked":  "linked",
+		"missing": "missing",
+		"wrong":   "wrong",
+		"in-way":  "in-way",
+		"copied":  "copied",
+		"edited":  "edited",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`copy = ["copied", "edited"]`), 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "other"), map[string]string{"other": "other"})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"pkg", "other"})
+	require.NoError(t, err)
+
+	result, err := linker.Check([]string{"pkg", "other"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pkg", "other"}, result.Packages)
+	assert.Equal(t, 7, result.Files)
+	assert.Empty(t, result.Problems)
+	assert.True(t, result.Healthy(true))
+
+	require.NoError(t, os.Remove(filepath.Join(targetDir, "missing")))
+	require.NoError(t, os.Remove(filepath.Join(targetDir, "wrong")))
+	require.NoError(t, os.Symlink(filepath.Join(sourceDir, "other", "other"), filepath.Join(targetDir, "wrong")))
+	require.NoError(t, os.Remove(filepath.Join(targetDir, "in-way")))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "in-way"), []byte("mine"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "copied"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "edited"), []byte("user"), 0644))
+
+	result, err = linker.Check([]string{"pkg", "other"})
+	require.NoError(t, err)
+	problems := make(map[string]CheckProblemKind)
+	for _, p := range result.Problems {
+		assert.Equal(t, "pkg", p.Package)
+		problems[filepath.Base(p.Target)] = p.Kind
+	}
+	assert.Equal(t, map[string]CheckProblemKind{
+		"missing": ProblemMissing,
+		"wrong":   ProblemWrongLink,
+		"in-way":  ProblemOccupied,
+		"copied":  ProblemOutdated,
+		"edited":  ProblemModified,
+	}, problems)
+	assert.False(t, result.Healthy(false))
+	assert.Len(t, result.Failing(false), 3, "only strict checks fail on copies that no longer match")
+	assert.Len(t, result.Failing(true), 5)
+	assert.Equal(t, []string{"pkg"}, result.FailingPackages(true))
+
+	_, err = linker.Check([]string{"nonexistent"})
+	assert.Error(t, err)
+}
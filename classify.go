@@ -0,0 +1,193 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TargetState describes what currently occupies a target path, relative to the
+// package file that is supposed to be placed there.
+type TargetState int
+
+const (
+	TargetMissing     TargetState = iota // Nothing exists at the target path
+	TargetLinked                         // A symlink pointing at the expected source file, or a bind mount of the expected source directory
+	TargetDeployed                       // An unmodified copy or rendered file recorded in state for the package
+	TargetModified                       // A copy or rendered file recorded for the package, edited since it was deployed
+	TargetForeignLink                    // A symlink pointing anywhere else
+	TargetFile                           // A file not deployed by gslk for the package
+	TargetDirectory                      // A directory
+)
+
+// String returns a short human-readable name for the state.
+func (s TargetState) String() string {
+	switch s {
+	case TargetMissing:
+		return "missing"
+	case TargetLinked:
+		return "linked"
+	case TargetDeployed:
+		return "deployed"
+	case TargetModified:
+		return "modified"
+	case TargetForeignLink:
+		return "foreign link"
+	case TargetFile:
+		return "file"
+	case TargetDirectory:
+		return "directory"
+	default:
+		return fmt.Sprintf("TargetState(%d)", int(s))
+	}
+}
+
+// Classification is the result of inspecting a single target path.
+type Classification struct {
+	State TargetState
+	Info  os.FileInfo // Lstat result for the target, nil if missing
+	Entry StateEntry  // State manifest entry, set for TargetDeployed and TargetModified, and for directories recorded as bind mounts
+}
+
+// Classifier inspects target paths to tell files gslk manages apart from
+// anything else occupying them. It never modifies the filesystem.
+type Classifier struct {
+	// State is the manifest used to recognise deployed copies. If nil, copies
+	// are never recognised and classify as TargetFile.
+	State *State
+
+	// FS is the filesystem target paths are inspected on; if nil, the local filesystem is used
+	FS FS
+
+	// listings holds the lower-cased names in each target directory listed so
+	// far, by directory; nil for directories that could not be listed. It is
+	// only set on classifiers taking one snapshot, such as for a plan, since
+	// the listings are never refreshed. See missing.
+	listings map[string]map[string]bool
+}
+
+// newSnapshotClassifier returns a Classifier that lists every target directory
+// once and then only inspects the paths found in it. That turns an Lstat per
+// file into a directory read per directory where the target is mostly empty,
+// as when a package is linked for the first time, but changes made after a
+// directory was listed go unnoticed.
+func newSnapshotClassifier(state *State, fsys FS) *Classifier {
+	return &Classifier{State: state, FS: fsys, listings: make(map[string]map[string]bool)}
+}
+
+// missing reports whether the listing of the directory of targetPath shows
+// nothing by its name. Names are compared case-insensitively, so a name a
+// case-insensitive filesystem would find is inspected rather than reported
+// missing. Without a listing, nothing is known to be missing.
+func (c *Classifier) missing(targetPath string) bool {
+	if c.listings == nil {
+		return false
+	}
+	dir := filepath.Dir(targetPath)
+	names, listed := c.listings[dir]
+	if !listed {
+		names = c.list(dir)
+		c.listings[dir] = names
+	}
+	return names != nil && !names[strings.ToLower(filepath.Base(targetPath))]
+}
+
+// list returns the lower-cased names in dir, an empty set if dir does not
+// exist, or nil if dir cannot be listed or is not a directory, such as a
+// symlink to one.
+func (c *Classifier) list(dir string) map[string]bool {
+	names := make(map[string]bool)
+	err := orOS(c.FS).WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			if !d.IsDir() {
+				return errNotListable
+			}
+			return nil
+		}
+		names[strings.ToLower(d.Name())] = true
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		return names
+	case errors.Is(err, fs.ErrNotExist):
+		return map[string]bool{}
+	default:
+		return nil
+	}
+}
+
+// errNotListable stops the listing of a target directory that is not one.
+var errNotListable = errors.New("not a directory")
+
+// Classify inspects targetPath, where pkgName's file at sourcePath is to be placed.
+func (c *Classifier) Classify(pkgName, sourcePath, targetPath string) (Classification, error) {
+	if c.missing(targetPath) {
+		return Classification{State: TargetMissing}, nil
+	}
+	fi, err := orOS(c.FS).Lstat(targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Classification{State: TargetMissing}, nil
+		}
+		return Classification{}, fmt.Errorf("failed to stat target path %s: %w", targetPath, err)
+	}
+
+	result := Classification{Info: fi}
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		isCorrect, err := isCorrectSymlink(c.FS, targetPath, sourcePath)
+		if err != nil {
+			return Classification{}, err
+		}
+		if isCorrect {
+			result.State = TargetLinked
+		} else {
+			result.State = TargetForeignLink
+		}
+
+	case fi.IsDir() || isJunction(fi) && isBindMount(sourcePath, targetPath, fi):
+		result.State = TargetDirectory
+		if c.State != nil {
+			if entry, ok := c.State.Lookup(targetPath); ok && entry.Package == pkgName && entry.Mode == ModeBind {
+				result.Entry = entry
+			}
+		}
+		if isBindMount(sourcePath, targetPath, fi) {
+			result.State = TargetLinked
+		}
+
+	default:
+		result.State = TargetFile
+		if c.State == nil {
+			break
+		}
+
+		entry, ok := c.State.Lookup(targetPath)
+		if !ok || entry.Package != pkgName || entry.Mode == ModeLink {
+			break
+		}
+
+		hash, err := hashFile(targetPath)
+		if err != nil {
+			return Classification{}, fmt.Errorf("failed to hash target file %s: %w", targetPath, err)
+		}
+		result.Entry = entry
+		if hash == entry.Hash {
+			result.State = TargetDeployed
+		} else {
+			result.State = TargetModified
+		}
+	}
+
+	return result, nil
+}
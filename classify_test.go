@@ -0,0 +1,107 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"linked":      "l",
+		"foreign":     "f",
+		"file":        "u",
+		"directory":   "d",
+		"deployed":    "c",
+		"modified":    "m",
+		"missing":     "x",
+		"other_owner": "o",
+	})
+	source := func(name string) string { return filepath.Join(pkgPath, name) }
+	target := func(name string) string { return filepath.Join(targetDir, name) }
+
+	require.NoError(t, os.Symlink(source("linked"), target("linked")))
+	require.NoError(t, os.Symlink(source("file"), target("foreign")))
+	require.NoError(t, os.WriteFile(target("file"), []byte("u"), 0644))
+	require.NoError(t, os.Mkdir(target("directory"), 0755))
+
+	state, err := LoadState(filepath.Join(targetDir, StateFileName))
+	require.NoError(t, err)
+	for _, name := range []string{"deployed", "modified", "other_owner"} {
+		require.NoError(t, os.WriteFile(target(name), []byte("content"), 0644))
+		hash, err := hashFile(target(name))
+		require.NoError(t, err)
+		owner := "pkg"
+		if name == "other_owner" {
+			owner = "another"
+		}
+		state.Record(StateEntry{Package: owner, Source: source(name), Target: target(name), Mode: ModeCopy, Hash: hash})
+	}
+	require.NoError(t, os.WriteFile(target("modified"), []byte("edited"), 0644))
+
+	classifier := &Classifier{State: state}
+	expected := map[string]TargetState{
+		"linked":      TargetLinked,
+		"foreign":     TargetForeignLink,
+		"file":        TargetFile,
+		"directory":   TargetDirectory,
+		"deployed":    TargetDeployed,
+		"modified":    TargetModified,
+		"missing":     TargetMissing,
+		"other_owner": TargetFile,
+	}
+	for name, want := range expected {
+		got, err := classifier.Classify("pkg", source(name), target(name))
+		require.NoError(t, err)
+		assert.Equal(t, want, got.State, "Unexpected classification for %s: %s", name, got.State)
+	}
+
+	// Without a state manifest copies are indistinguishable from user files
+	got, err := (&Classifier{}).Classify("pkg", source("deployed"), target("deployed"))
+	require.NoError(t, err)
+	assert.Equal(t, TargetFile, got.State)
+}
+
+func TestSnapshotClassifier(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	realDir := filepath.Join(t.TempDir(), "real")
+	require.NoError(t, os.Mkdir(realDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(realDir, "inside"), nil, 0644))
+	require.NoError(t, os.Symlink(realDir, filepath.Join(targetDir, "linkeddir")))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "Upper"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "file"), nil, 0644))
+
+	classifier := newSnapshotClassifier(nil, nil)
+	classify := func(rel string) TargetState {
+		t.Helper()
+		c, err := classifier.Classify("pkg", filepath.Join(sourceDir, rel), filepath.Join(targetDir, rel))
+		require.NoError(t, err)
+		return c.State
+	}
+
+	assert.Equal(t, TargetFile, classify("file"))
+	assert.Equal(t, TargetMissing, classify("absent"))
+	assert.Equal(t, TargetMissing, classify(filepath.Join("no", "such", "dir")))
+	assert.Equal(t, TargetFile, classify(filepath.Join("linkeddir", "inside")), "Directories reached through a symlink are not listed")
+
+	// A name only differing in case is inspected, whatever the filesystem makes of it
+	_, err := os.Lstat(filepath.Join(targetDir, "upper"))
+	expected := TargetMissing
+	if err == nil {
+		expected = TargetFile
+	}
+	assert.Equal(t, expected, classify("upper"))
+
+	// The listing is a snapshot
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "late"), nil, 0644))
+	assert.Equal(t, TargetMissing, classify("late"))
+}
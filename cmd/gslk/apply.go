@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"path/filepath"
+)
+
+// runApply reconciles the target with the packages declared for this machine.
+func runApply(args []string) error {
+	fs := newCommandFlags("apply", "[options]")
+	file := fs.String("file", "", "Desired-state `file` listing the packages to link (default: the [apply] section of the config file, or "+gslk.DesiredFileName+" next to it).")
+	mode := fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for files: link (symlink) or copy.")
+	onConflict := fs.String("on-conflict", string(gslk.ConflictFail), "What to do with existing files in the way: fail, backup or adopt-identical.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("apply takes no arguments: list the packages in the desired-state file")
+	}
+	if err := validateModeFlags(mode, deployModes, onConflict); err != nil {
+		return err
+	}
+
+	config, err := loadConfig(*fs.config)
+	if err != nil {
+		return err
+	}
+	packages, err := desiredPackages(config, *file)
+	if err != nil {
+		return err
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*mode)
+	linker.ConflictPolicy = gslk.ConflictPolicy(*onConflict)
+	if err := askVars(linker, packages, *fs.batch); err != nil {
+		return err
+	}
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("apply", packages)
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	out.infof("Applying packages %v\n", packages)
+	result, err := linker.Apply(packages)
+	out.summary(result, err)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	if err == nil {
+		recordGeneration(linker)
+	}
+	return err
+}
+
+// desiredPackages returns the packages declared in file, or, if it is empty,
+// in the [apply] section of config, or in the desired-state file next to the
+// configuration file.
+func desiredPackages(config *gslk.Config, file string) ([]string, error) {
+	if file != "" {
+		return gslk.LoadDesired(file)
+	}
+	if config.Desired != nil {
+		return config.Desired, nil
+	}
+
+	configPath := config.Path
+	if configPath == "" {
+		var err error
+		if configPath, err = gslk.DefaultConfigPath(); err != nil {
+			return nil, fmt.Errorf("no desired-state file given and no configuration directory: %w", err)
+		}
+	}
+	file = filepath.Join(filepath.Dir(configPath), gslk.DesiredFileName)
+	if _, err := os.Stat(file); err != nil {
+		return nil, fmt.Errorf("no packages to apply: give a desired-state file with --file, add an [apply] section to the config file, or create %s", file)
+	}
+	return gslk.LoadDesired(file)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// aliasFlag defines alias as another name for the flag name of fs, sharing
+// its value.
+func aliasFlag(fs *flag.FlagSet, alias, name string) {
+	fs.Var(fs.Lookup(name).Value, alias, "Alias for -"+name+".")
+}
+
+// isBoolFlag reports whether f takes no value, as -n or --yes.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// normalizeArgs rewrites args the GNU way into what the flag package parses:
+// options following arguments are moved before them, as in
+// gslk vim -n --target=/tmp/home, and combined one-letter options are split,
+// so -nv is -n -v and -j4 is -j=4. Everything after "--" stays an argument.
+func normalizeArgs(fs *flag.FlagSet, args []string) []string {
+	args = append([]string(nil), args...)
+	var options, arguments []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			arguments = append(arguments, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			arguments = append(arguments, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil && !strings.HasPrefix(arg, "--") {
+			if split, ok := splitShortFlags(fs, arg[1:]); ok {
+				args = append(args[:i], append(split, args[i+1:]...)...)
+				i--
+				continue
+			}
+		}
+
+		options = append(options, arg)
+		if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			options = append(options, args[i])
+		}
+	}
+	if len(arguments) == 0 {
+		return options
+	}
+	return append(append(options, "--"), arguments...)
+}
+
+// splitShortFlags splits letters, the combined one-letter options of an
+// argument without its dash, into separate options. The first option taking
+// a value gets the rest of letters as its value, if any. It fails unless
+// every letter up to that one is an option of fs.
+func splitShortFlags(fs *flag.FlagSet, letters string) ([]string, bool) {
+	var split []string
+	for i := 0; i < len(letters); i++ {
+		f := fs.Lookup(letters[i : i+1])
+		if f == nil {
+			return nil, false
+		}
+		if isBoolFlag(f) {
+			split = append(split, "-"+f.Name)
+			continue
+		}
+		if rest := letters[i+1:]; rest != "" {
+			return append(split, "-"+f.Name+"="+strings.TrimPrefix(rest, "=")), true
+		}
+		return append(split, "-"+f.Name), true
+	}
+	return split, true
+}
+
+// packageArgs returns the package names given by args, where "-" stands for
+// the names read from standard input, followed by those listed in the file
+// at from, if any ("-" also reading standard input, which is only read once).
+func packageArgs(args []string, from string) ([]string, error) {
+	var names []string
+	readStdin := false
+	readList := func(path string) error {
+		if path == "-" {
+			if readStdin {
+				return nil
+			}
+			readStdin = true
+			list, err := readPackageList(stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read package names from standard input: %w", err)
+			}
+			names = append(names, list...)
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to read package list: %w", err)
+		}
+		defer f.Close()
+		list, err := readPackageList(f)
+		if err != nil {
+			return fmt.Errorf("failed to read package list %s: %w", path, err)
+		}
+		names = append(names, list...)
+		return nil
+	}
+
+	for _, arg := range args {
+		if arg != "-" {
+			names = append(names, arg)
+		} else if err := readList(arg); err != nil {
+			return nil, err
+		}
+	}
+	if from != "" {
+		if err := readList(from); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// readPackageList reads package names from r, one per line. Blank lines and
+// lines starting with # are skipped.
+func readPackageList(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name != "" && !strings.HasPrefix(name, "#") {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"path/filepath"
+)
+
+// runBootstrap links the default packages of the dotfiles repository it runs
+// in without ever asking anything, to serve as the install script GitHub
+// Codespaces and dev containers run after cloning the repository.
+func runBootstrap(args []string) error {
+	fs := newCommandFlags("bootstrap", "[options]")
+	dir := fs.String("dir", "", "Dotfiles `repository` (default: the repository the current directory is in, or the current directory).")
+	profileName := fs.String("profile", "", "Link the named `profile` of the repository's "+gslk.RepoConfigFileName+" (default: its profile named default, or else its [apply] packages).")
+	mode := fs.String("mode", "auto", "Deployment `mode` for files: auto (link where symbolic links can be created, copy elsewhere), link or copy.")
+	onConflict := fs.String("on-conflict", string(gslk.ConflictBackup), "What to do with existing files in the way: backup (move them to the quarantine directory for gslk review), fail or adopt-identical.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("bootstrap takes no arguments")
+	}
+	if err := validateModeFlags(mode, append([]gslk.DeployMode{"auto"}, deployModes...), onConflict); err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+
+	repoDir := *dir
+	if repoDir == "" {
+		if repoDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("could not determine current directory: %v", err)
+		}
+		if root, ok := gslk.FindSourceRoot(repoDir); ok {
+			repoDir = root
+		}
+	}
+	if repoDir, err = filepath.Abs(repoDir); err != nil {
+		return fmt.Errorf("failed to get absolute path for %s: %w", repoDir, err)
+	}
+	config, err := gslk.LoadConfig(filepath.Join(repoDir, gslk.RepoConfigFileName))
+	if err != nil {
+		return err
+	}
+	sources := *fs.sources
+	if len(sources) == 0 {
+		sources = config.Sources
+	}
+	if len(sources) == 0 {
+		sources = []string{repoDir}
+	}
+	linker, err := fs.linkerFor(config, sources)
+	if err != nil {
+		return err
+	}
+
+	// Nobody answers questions in a container being set up: files moved
+	// aside can be brought back, and missing template variables are errors
+	linker.Confirm = nil
+	if *mode == "auto" {
+		linker.Mode = gslk.AutoMode(linker.TargetDir)
+		out.infof("Deploying files as %s: detected for %s\n", linker.Mode, linker.TargetDir)
+	} else {
+		linker.Mode = gslk.DeployMode(*mode)
+	}
+	linker.ConflictPolicy = gslk.ConflictPolicy(*onConflict)
+
+	var profile *gslk.Profile
+	var packages []string
+	if _, ok := config.Profiles["default"]; ok || *profileName != "" {
+		name := *profileName
+		if name == "" {
+			name = "default"
+		}
+		p, err := config.Profile(name)
+		if err != nil {
+			return err
+		}
+		profile, packages = &p, p.Packages
+	} else if packages = config.Desired; packages == nil {
+		return fmt.Errorf("%s declares neither a default profile nor [apply] packages to link", filepath.Join(repoDir, gslk.RepoConfigFileName))
+	}
+	if linker.Vars, err = templateVars(config, profile, "", *fs.vars); err != nil {
+		return err
+	}
+
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("bootstrap", packages)
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	out.infof("Linking packages %v from %s to %s\n", packages, repoDir, linker.TargetDir)
+	var result *gslk.Result
+	if profile != nil {
+		result, err = linker.LinkProfile(*profile)
+	} else {
+		result, err = linker.Link(packages)
+	}
+	out.summary(result, err)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	if err != nil {
+		return err
+	}
+	recordGeneration(linker)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"strings"
+)
+
+// runCheck reports in one line whether the declared packages are fully
+// linked, failing otherwise, for systemd health checks and CI assertions.
+func runCheck(args []string) error {
+	fs := newCommandFlags("check", "[options] [package...]")
+	file := fs.String("file", "", "Desired-state `file` listing the packages to check (default: as for apply), unless packages are given.")
+	strict := fs.Bool("strict", false, "Also fail if deployed copies, templates or secrets no longer match their packages.")
+	fs.Parse(args)
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	packages := fs.Args()
+	if len(packages) == 0 {
+		config, err := loadConfig(*fs.config)
+		if err != nil {
+			return err
+		}
+		if packages, err = desiredPackages(config, *file); err != nil {
+			return err
+		}
+	}
+
+	result, err := linker.Check(packages)
+	if err != nil {
+		return err
+	}
+	failing := result.Failing(*strict)
+	for _, problem := range failing {
+		out.infof("%s: %s (package %s)\n", problem.Kind, problem.Target, problem.Package)
+	}
+	if len(failing) == 0 {
+		out.summaryf("OK: %d packages, %d files deployed\n", len(result.Packages), result.Files)
+		return nil
+	}
+
+	kinds := make(map[gslk.CheckProblemKind]int)
+	var order []string
+	for _, problem := range failing {
+		if kinds[problem.Kind] == 0 {
+			order = append(order, string(problem.Kind))
+		}
+		kinds[problem.Kind]++
+	}
+	counts := make([]string, len(order))
+	for i, kind := range order {
+		counts[i] = fmt.Sprintf("%d %s", kinds[gslk.CheckProblemKind(kind)], kind)
+	}
+	names := result.FailingPackages(*strict)
+	return fmt.Errorf("%d of %d packages not fully linked (%s): %s", len(names), len(result.Packages), strings.Join(counts, ", "), strings.Join(names, " "))
+}
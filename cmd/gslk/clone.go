@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"path/filepath"
+	"slices"
+)
+
+// runClone clones a dotfiles repository and links its default packages.
+func runClone(args []string) error {
+	fs := newCommandFlags("clone", "[options] <git-url>")
+	dir := fs.String("dir", "", "Clone into `directory` (default: ~/.dotfiles).")
+	profileName := fs.String("profile", "", "Link the packages of the named `profile` of the repository's "+gslk.RepoConfigFileName+" instead of its [apply] packages.")
+	mode := fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for files: link (symlink) or copy.")
+	onConflict := fs.String("on-conflict", string(gslk.ConflictFail), "What to do with existing files in the way: fail, backup or adopt-identical.")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("clone takes the URL of the repository")
+	}
+	if err := validateModeFlags(mode, deployModes, onConflict); err != nil {
+		return err
+	}
+	url := fs.Arg(0)
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+
+	repoDir := *dir
+	if repoDir == "" {
+		if repoDir, err = gslk.DefaultRepoDir(); err != nil {
+			return fmt.Errorf("no --dir given and no home directory: %w", err)
+		}
+	}
+	if repoDir, err = filepath.Abs(repoDir); err != nil {
+		return fmt.Errorf("failed to get absolute path for %s: %w", repoDir, err)
+	}
+
+	// Cloning again is not needed to retry linking after a failure
+	git := &gslk.Git{Dir: repoDir}
+	switch {
+	case git.IsRepo():
+		out.summaryf("Using the existing clone in %s\n", repoDir)
+	case *fs.dryRun:
+		fmt.Printf("Would clone %s into %s\n", url, repoDir)
+		return nil
+	default:
+		out.summaryf("Cloning %s into %s\n", url, repoDir)
+		if err := git.Clone(url); err != nil {
+			return err
+		}
+	}
+
+	config, err := gslk.LoadConfig(filepath.Join(repoDir, gslk.RepoConfigFileName))
+	if err != nil {
+		return err
+	}
+	sources := config.Sources
+	if len(sources) == 0 {
+		sources = []string{repoDir}
+	}
+	linker, err := fs.linkerFor(config, sources)
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*mode)
+	linker.ConflictPolicy = gslk.ConflictPolicy(*onConflict)
+
+	var profile *gslk.Profile
+	packages := config.Desired
+	if *profileName != "" {
+		p, err := config.Profile(*profileName)
+		if err != nil {
+			return err
+		}
+		profile, packages = &p, p.Packages
+	} else if packages == nil {
+		return fmt.Errorf("%s declares no [apply] packages to link: choose a profile with --profile", filepath.Join(repoDir, gslk.RepoConfigFileName))
+	}
+	if linker.Vars, err = templateVars(config, profile, "", *fs.vars); err != nil {
+		return err
+	}
+	if err := askVars(linker, packages, *fs.batch); err != nil {
+		return err
+	}
+
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("clone", packages)
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	out.infof("Linking packages %v from %s to %s\n", packages, repoDir, linker.TargetDir)
+	var result *gslk.Result
+	if profile != nil {
+		result, err = linker.LinkProfile(*profile)
+	} else {
+		result, err = linker.Link(packages)
+	}
+	out.summary(result, err)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	if err != nil {
+		return err
+	}
+	recordGeneration(linker)
+
+	// Other commands read the user's configuration, not the repository's
+	if userConfig, err := loadConfig(*fs.config); err == nil && !slices.Contains(userConfig.Sources, repoDir) {
+		out.summaryf("Run later commands with -s %s, or add sources = [%q] to the config file\n", repoDir, repoDir)
+	}
+	return nil
+}
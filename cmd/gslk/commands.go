@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gslk"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// command is a subcommand invoked as `gslk <name> [options] [args]`.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the available subcommands. Any other first argument is
+// handled by the default link/unlink/relink actions. It is filled in by init
+// since the completion command lists the subcommands itself.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"clone", "Clone a dotfiles repository and link its default packages: a one-command bootstrap", runClone},
+		{"bootstrap", "Link a repository's default profile without asking anything: the dotfiles install script for Codespaces and dev containers", runBootstrap},
+		{"sync", "Pull the source repository and relink the deployed packages its incoming changes affect", runSync},
+		{"apply", "Link exactly the packages declared for this machine, unlinking those no longer declared", runApply},
+		{"test-apply", "Link packages into a temporary directory instead of the target and show the resulting tree", runTestApply},
+		{"list", "List the packages with their file count, link status and description", runList},
+		{"files", "List the files of a package with their target paths and state", runFiles},
+		{"info", "Show the manifest of a package, its size, dependencies, hooks and link coverage", runInfo},
+		{"stats", "Show the file count, size, links, broken links and last change of each package", runStats},
+		{"explain", "Explain why a path of a package is linked, skipped, ignored or in conflict", runExplain},
+		{"diff", "Show how deployed copies and templates differ from their packages, and links pointing elsewhere", runDiff},
+		{"verify", "Check deployed copies and templates against their recorded checksums, telling target edits from package updates", runVerify},
+		{"report", "Write a Markdown or HTML report of the packages, their files and recent changes", runReport},
+		{"snapshot", "Record the files deployed in the target and the directories gslk created, for gslk drift", runSnapshot},
+		{"drift", "Report files edited, deleted or added in the managed part of the target since gslk snapshot", runDrift},
+		{"check", "Report in one line whether the declared packages are fully linked, failing otherwise", runCheck},
+		{"graph", "Draw the packages, their dependencies and the target directories they populate as Graphviz DOT or Mermaid", runGraph},
+		{"doctor", "Audit the target tree for broken links, permission and ownership problems", runDoctor},
+		{"scan-orphans", "Find links into the source directories anywhere in the target that no package accounts for", runScanOrphans},
+		{"which", "Show which package and source file a target path comes from", runWhich},
+		{"search", "Find the package files matching a name or pattern, and where they are linked", runSearch},
+		{"secret", "Encrypt files into packages (add) or edit encrypted files (edit) with age", runSecret},
+		{"link-file", "Link a single file of a package", runLinkFile},
+		{"unlink-file", "Unlink a single file of a package", runUnlinkFile},
+		{"import", "Move existing files from the target into a package and link them back", runImport},
+		{"init", "Create gslk.toml and the layout of a new dotfiles repository", runInit},
+		{"new", "Create a package with a manifest, an ignore file and a hooks skeleton", runNew},
+		{"migrate", "Convert a GNU Stow, chezmoi or yadm repository into gslk packages", runMigrate},
+		{"daemon", "Reconcile the target with the declared packages on a schedule, or install a systemd unit doing so", runDaemon},
+		{"watch", "Relink packages whenever files are added to, removed from or moved within them", runWatch},
+		{"repair", "Point links back into the source directories after they were moved", runRepair},
+		{"generations", "List the generations: snapshots of the deployed files recorded after each run", runGenerations},
+		{"rollback", "Restore the files deployed in a previous generation", runRollback},
+		{"teardown", "Remove exactly what --ephemeral runs deployed, without needing the packages", runTeardown},
+		{"history", "Show past runs and what they changed, e.g. which run changed a file", runHistory},
+		{"trash", "List, restore or empty files and directories removed with -f", runTrash},
+		{"review", "Review files quarantined by --on-conflict backup and merge them into packages", runReview},
+		{"completion", "Print the bash, zsh or fish completion script", runCompletion},
+	}
+}
+
+// findCommand looks up a subcommand by name.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// commandFlags is a flag set preloaded with the options shared by all subcommands.
+type commandFlags struct {
+	*flag.FlagSet
+	sources  *stringList
+	target   *string
+	config   *string
+	verbose  *bool
+	trace    *bool
+	quiet    *bool
+	noColor  *bool
+	events   *string
+	eventFD  *int
+	wait     *time.Duration
+	dryRun   *bool
+	readOnly *bool
+	yes      *bool
+	batch    *bool
+	vars     *stringList
+	out      *output
+}
+
+// newCommandFlags creates the flag set for a subcommand; usage describes its arguments.
+func newCommandFlags(name, usage string) *commandFlags {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cf := &commandFlags{
+		FlagSet:  fs,
+		sources:  stringListFlag(fs, "s", "Source `directory` containing packages, or a tarball or zip archive by path or HTTPS URL (default: current directory). May be repeated."),
+		target:   fs.String("t", "", "Target `directory` for symlinks (default: $HOME), or ssh://[user@]host[:port]/path (experimental)."),
+		config:   fs.String("config", "", "Configuration `file` (default: "+gslk.ConfigFileName+" in the user configuration directory)."),
+		verbose:  fs.Bool("v", false, "Increase verbosity."),
+		trace:    fs.Bool("vv", false, "Also print why files are linked, skipped or ignored."),
+		quiet:    fs.Bool("quiet", false, "Print errors only."),
+		noColor:  fs.Bool("no-color", false, "Do not color output, even on a terminal."),
+		events:   fs.String("events", "", "Write an event per operation and conflict in `format` ndjson."),
+		eventFD:  fs.Int("events-fd", 1, "File `descriptor` to write --events to."),
+		wait:     fs.Duration("wait", 0, "How long to wait for another run changing the target to finish (e.g. 30s); by default fail at once."),
+		dryRun:   fs.Bool("n", false, "Dry run: show what would be done without actually doing it."),
+		readOnly: fs.Bool("read-only", false, "Refuse every change to the target, whatever the other options say; checks and reports still work."),
+		yes:      fs.Bool("yes", false, "Do not ask before moving or deleting files."),
+		batch:    fs.Bool("non-interactive", false, "Never ask questions: fail instead. Overridden by --yes."),
+		vars:     stringListFlag(fs, "var", "Set the template variable given as `name=value`. May be repeated."),
+	}
+	aliasFlag(fs, "source", "s")
+	aliasFlag(fs, "target", "t")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n", filepath.Base(os.Args[0]), name, usage)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fs.PrintDefaults()
+	}
+	return cf
+}
+
+// Parse parses the subcommand's arguments, exiting on invalid flags.
+func (cf *commandFlags) Parse(args []string) {
+	parseFlags(cf.FlagSet, args)
+}
+
+// linker builds a Linker from the shared subcommand options.
+func (cf *commandFlags) linker() (*gslk.Linker, error) {
+	config, err := loadConfig(*cf.config)
+	if err != nil {
+		return nil, err
+	}
+	return cf.linkerFor(config, *cf.sources)
+}
+
+// linkerFor builds a Linker from config and the shared subcommand options,
+// with sources in place of the -s directories.
+func (cf *commandFlags) linkerFor(config *gslk.Config, sources []string) (*gslk.Linker, error) {
+	linker, err := newLinker(config, sources, *cf.target)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cf.output()
+	if err != nil {
+		return nil, err
+	}
+	linker.Verbose = out.verbosity >= verbosityTrace
+	linker.Logger = out
+	linker.DryRun = *cf.dryRun
+	linker.ReadOnly = *cf.readOnly
+	if linker.OnEvent, err = eventHandler(*cf.events, *cf.eventFD, out); err != nil {
+		return nil, err
+	}
+	linker.Confirm = confirmer(*cf.yes, *cf.batch)
+	if len(*cf.vars) > 0 {
+		vars := make(map[string]string)
+		maps.Copy(vars, linker.Vars)
+		if err := setVars(vars, *cf.vars); err != nil {
+			return nil, err
+		}
+		linker.Vars = vars
+	}
+	return linker, nil
+}
+
+// output returns the output for the shared verbosity and color options,
+// creating it on first use.
+func (cf *commandFlags) output() (*output, error) {
+	if cf.out != nil {
+		return cf.out, nil
+	}
+	out, err := newOutput(*cf.quiet, *cf.verbose, *cf.trace, *cf.noColor)
+	cf.out = out
+	return out, err
+}
+
+// lock takes the lock of linker's target for a subcommand changing it.
+func (cf *commandFlags) lock(linker *gslk.Linker) (unlock func(), err error) {
+	return lockTarget(linker, *cf.wait)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// runCompletion prints the completion script for a shell, or, with
+// --packages, the package and group names for the command line words given
+// after it, honouring their -s and --config options.
+func runCompletion(args []string) error {
+	if len(args) > 0 && args[0] == "--packages" {
+		return printPackageNames(args[1:])
+	}
+
+	fs := newCommandFlags("completion", "bash|zsh|fish")
+	fs.Usage = func() {
+		fmt.Println("Usage: gslk completion bash|zsh|fish")
+		fmt.Println("Load it with e.g. source <(gslk completion bash) in ~/.bashrc,")
+		fmt.Println("gslk completion zsh > \"${fpath[1]}/_gslk\", or")
+		fmt.Println("gslk completion fish > ~/.config/fish/completions/gslk.fish.")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a shell: bash, zsh or fish")
+	}
+
+	names, valueFlags, boolFlags := completionWords()
+	switch fs.Arg(0) {
+	case "bash":
+		fmt.Printf(bashCompletion, strings.Join(valueFlags, "|"), strings.Join(slices.Concat(valueFlags, boolFlags), " "), strings.Join(names, " "))
+	case "zsh":
+		fmt.Printf(zshCompletion, strings.Join(valueFlags, "|"), strings.Join(slices.Concat(valueFlags, boolFlags), " "), strings.Join(names, " "))
+	case "fish":
+		fmt.Print(fishCompletion(names))
+	default:
+		return fmt.Errorf("unsupported shell %q: must be bash, zsh or fish", fs.Arg(0))
+	}
+	return nil
+}
+
+// printPackageNames prints the packages and groups of the sources named on
+// the command line words, or of the configured sources.
+func printPackageNames(words []string) error {
+	var sources []string
+	var configPath string
+	for i := 0; i < len(words); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(words[i], "-"), "=")
+		if !strings.HasPrefix(words[i], "-") || (name != "s" && name != "source" && name != "config") {
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(words) {
+				break
+			}
+			i++
+			value = words[i]
+		}
+		if name == "config" {
+			configPath = value
+		} else {
+			sources = append(sources, value)
+		}
+	}
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	linker, err := newLinker(config, sources, "")
+	if err != nil {
+		return err
+	}
+	packages, err := linker.FindPackages()
+	if err != nil {
+		return err
+	}
+	for _, pkg := range packages {
+		fmt.Println(pkg.Name)
+	}
+	for group := range config.Groups {
+		fmt.Println(group)
+	}
+	return nil
+}
+
+// completionWords returns the subcommand names and the flags, as typed, of
+// the default action and the subcommands, split into those taking a value and
+// boolean ones.
+func completionWords() (names, valueFlags, boolFlags []string) {
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+
+	seen := make(map[string]bool)
+	visit := func(f *flag.Flag) {
+		word := flagWord(f.Name)
+		if seen[word] {
+			return
+		}
+		seen[word] = true
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			boolFlags = append(boolFlags, word)
+		} else {
+			valueFlags = append(valueFlags, word)
+		}
+	}
+	flag.CommandLine.VisitAll(visit)
+	newCommandFlags("", "").VisitAll(visit)
+	return names, valueFlags, boolFlags
+}
+
+// flagWord returns how flag name is typed: -x for short names such as -vv
+// and upper-case ones such as -GL, --name otherwise.
+func flagWord(name string) string {
+	if len(name) <= 2 || strings.ToUpper(name) == name {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// bashCompletion is the bash completion script; its verbs are the flags taking
+// a value (separated by |), all flags and the subcommands.
+const bashCompletion = `# bash completion for gslk
+_gslk() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        %[1]s)
+            COMPREPLY=($(compgen -f -- "$cur"))
+            return
+            ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "%[2]s" -- "$cur"))
+        return
+    fi
+    local words
+    words="$(gslk completion --packages "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null)"
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        words="%[3]s $words"
+    fi
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -F _gslk gslk
+`
+
+// zshCompletion is the zsh completion script, with the same verbs as
+// bashCompletion.
+const zshCompletion = `#compdef gslk
+# zsh completion for gslk
+_gslk() {
+    local -a candidates
+    case "${words[CURRENT-1]}" in
+        %[1]s)
+            _files
+            return
+            ;;
+    esac
+    if [[ "$PREFIX" == -* ]]; then
+        compadd -- %[2]s
+        return
+    fi
+    candidates=(${(f)"$(gslk completion --packages ${words[2,CURRENT-1]} 2>/dev/null)"})
+    if (( CURRENT == 2 )); then
+        candidates+=(%[3]s)
+    fi
+    compadd -- $candidates
+}
+compdef _gslk gslk
+`
+
+// fishCompletion returns the fish completion script for the given subcommands.
+func fishCompletion(names []string) string {
+	var sb strings.Builder
+	sb.WriteString("# fish completion for gslk\n")
+	sb.WriteString("function __gslk_packages\n    gslk completion --packages (commandline -opc)[2..-1] 2>/dev/null\nend\n")
+	sb.WriteString("complete -c gslk -f\n")
+	fmt.Fprintf(&sb, "complete -c gslk -n __fish_use_subcommand -a '%s'\n", strings.Join(names, " "))
+	sb.WriteString("complete -c gslk -a '(__gslk_packages)'\n")
+
+	seen := make(map[string]bool)
+	visit := func(f *flag.Flag) {
+		if seen[f.Name] {
+			return
+		}
+		seen[f.Name] = true
+		option := "-l " + f.Name
+		if word := flagWord(f.Name); !strings.HasPrefix(word, "--") {
+			option = "-o " + f.Name
+			if len(f.Name) == 1 {
+				option = "-s " + f.Name
+			}
+		}
+		value := " -r -F"
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			value = ""
+		}
+		_, usage := flag.UnquoteUsage(f)
+		usage, _, _ = strings.Cut(usage, ". ")
+		fmt.Fprintf(&sb, "complete -c gslk %s%s -d '%s'\n", option, value, strings.ReplaceAll(strings.TrimSuffix(usage, "."), "'", `\'`))
+	}
+	flag.CommandLine.VisitAll(visit)
+	newCommandFlags("", "").VisitAll(visit)
+	return sb.String()
+}
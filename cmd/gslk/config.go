@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gslk"
+	"maps"
+	"os"
+	"strings"
+)
+
+// stringList is a flag that may be repeated, collecting every value in order.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// stringListFlag defines a repeatable string flag on fs.
+func stringListFlag(fs *flag.FlagSet, name, usage string) *stringList {
+	list := &stringList{}
+	fs.Var(list, name, usage)
+	return list
+}
+
+// loadConfig reads the configuration file at path, or the default one if path
+// is empty. Only an explicitly requested file has to exist.
+func loadConfig(path string) (*gslk.Config, error) {
+	if path == "" {
+		defaultPath, err := gslk.DefaultConfigPath()
+		if err != nil {
+			return &gslk.Config{}, nil // No configuration directory, nothing to load
+		}
+		return gslk.LoadConfig(defaultPath)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+	return gslk.LoadConfig(path)
+}
+
+// templateVars returns the template variables for a run: the shared variables
+// of config, overridden by those of profile (if any), then by the vars file
+// at varsPath (if given) and last by the name=value assignments of overrides.
+func templateVars(config *gslk.Config, profile *gslk.Profile, varsPath string, overrides []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	maps.Copy(vars, config.Vars)
+	if profile != nil {
+		maps.Copy(vars, profile.Vars)
+	}
+
+	if varsPath != "" {
+		fileVars, err := gslk.LoadVars(varsPath)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(vars, fileVars)
+	}
+	if err := setVars(vars, overrides); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// setVars sets the variables of the name=value assignments in vars.
+func setVars(vars map[string]string, assignments []string) error {
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid --var %q: expected name=value", assignment)
+		}
+		vars[name] = value
+	}
+	return nil
+}
+
+// askVars asks on standard input for the template variables the packages
+// declare that are not set yet, unless nonInteractive, in which case linking
+// fails naming the first of them.
+func askVars(linker *gslk.Linker, packageNames []string, nonInteractive bool) error {
+	if nonInteractive || len(packageNames) == 0 {
+		return nil
+	}
+	missing, err := linker.MissingVars(packageNames)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	vars := make(map[string]string, len(linker.Vars)+len(missing))
+	maps.Copy(vars, linker.Vars)
+	for _, v := range missing {
+		question := v.Question
+		if question == "" {
+			question = "Value"
+		}
+		fmt.Printf("%s (%s, for package %s): ", question, v.Name, v.Package)
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return fmt.Errorf("no value given for template variable %s", v.Name)
+		}
+		vars[v.Name] = strings.TrimSpace(line)
+	}
+	linker.Vars = vars
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stdin reads the answers to questions, shared so that no answer is lost in
+// the buffer of another reader.
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirmer returns the Linker.Confirm function for the --yes and
+// --non-interactive options: with yes nothing is asked, with nonInteractive
+// every question fails the operation, and otherwise the question is asked on
+// standard input. Only "y" or "yes" confirms; closed input declines.
+func confirmer(yes, nonInteractive bool) func(string) (bool, error) {
+	switch {
+	case yes:
+		return nil
+	case nonInteractive:
+		return func(question string) (bool, error) {
+			return false, fmt.Errorf("%s? Refusing without confirmation in non-interactive mode (use --yes)", question)
+		}
+	}
+
+	return func(question string) (bool, error) {
+		fmt.Printf("%s? [y/N] ", question)
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return false, nil
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		return answer == "y" || answer == "yes", nil
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gslk"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// daemonUnitName is the name of the systemd user unit written by gslk daemon install.
+const daemonUnitName = "gslk.service"
+
+// daemonOptions are the options shared by gslk daemon and gslk daemon install.
+type daemonOptions struct {
+	schedule   *string
+	file       *string
+	mode       *string
+	onConflict *string
+	metrics    *string
+}
+
+// daemonFlags adds the daemon options to fs.
+func daemonFlags(fs *flag.FlagSet) daemonOptions {
+	return daemonOptions{
+		schedule:   fs.String("schedule", "1h", "When to reconcile: an `interval` such as 30m, or a cron expression such as \"0 */2 * * *\"."),
+		file:       fs.String("file", "", "Desired-state `file` listing the packages to link (default: as for apply)."),
+		mode:       fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for files: link (symlink) or copy."),
+		onConflict: fs.String("on-conflict", string(gslk.ConflictFail), "What to do with existing files in the way: fail, backup or adopt-identical."),
+		metrics:    fs.String("metrics-addr", "", "Serve Prometheus metrics at /metrics on `address`, e.g. localhost:9464."),
+	}
+}
+
+// runDaemon reconciles the target with the desired state on a schedule until
+// interrupted, or installs a systemd user unit doing so.
+func runDaemon(args []string) error {
+	if len(args) > 0 && args[0] == "install" {
+		return installDaemon(args[1:])
+	}
+
+	fs := newCommandFlags("daemon", "[options] | install [options]")
+	opts := daemonFlags(fs.FlagSet)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unknown daemon command %q: must be install", fs.Arg(0))
+	}
+	if err := validateModeFlags(opts.mode, deployModes, opts.onConflict); err != nil {
+		return err
+	}
+	schedule, err := gslk.ParseSchedule(*opts.schedule)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig(*fs.config)
+	if err != nil {
+		return err
+	}
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*opts.mode)
+	linker.ConflictPolicy = gslk.ConflictPolicy(*opts.onConflict)
+	if !*fs.yes {
+		// Nobody is there to answer
+		linker.Confirm = confirmer(false, true)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var metrics *gslk.Metrics
+	if *opts.metrics != "" {
+		metrics = &gslk.Metrics{}
+		stopMetrics, err := serveMetrics(*opts.metrics, metrics)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+		out.infof("Serving metrics at http://%s/metrics\n", *opts.metrics)
+	}
+
+	onEvent := linker.OnEvent
+	for {
+		if err := reconcile(linker, config, *opts.file, onEvent, fs, out, metrics); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never runs again", *opts.schedule)
+		}
+		out.infof("Next reconciliation at %s\n", next.Format(time.DateTime))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// serveMetrics serves metrics at /metrics on addr in the background and
+// returns the function stopping it. The address is bound at once, so that a
+// port in use is reported before the first reconciliation.
+func serveMetrics(addr string, metrics *gslk.Metrics) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "Error: metrics server: %v\n", err)
+		}
+	}()
+	return func() { server.Close() }, nil
+}
+
+// reconcile runs gslk apply once for the daemon: it reports the drift of the
+// desired packages, then links and unlinks packages and fixes the drift. Runs
+// that change nothing are not recorded in the history; every run is counted in
+// metrics, if not nil.
+func reconcile(linker *gslk.Linker, config *gslk.Config, file string, onEvent func(gslk.Event), fs *commandFlags, out *output, metrics *gslk.Metrics) (err error) {
+	var result *gslk.Result
+	var drifts []gslk.Drift
+	defer func() { metrics.Record(result, drifts, err) }()
+
+	// The desired state is read anew, since it may have changed since the last run
+	packages, err := desiredPackages(config, file)
+	if err != nil {
+		return err
+	}
+
+	if len(packages) > 0 {
+		// Drift of no packages would be that of every package
+		drifts, err = linker.Drift(packages)
+		if err != nil {
+			return err
+		}
+		for _, drift := range drifts {
+			out.summaryf("Drift in %s: %s (%s)\n", drift.Package, drift.Target, drift.Kind)
+		}
+	}
+
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("daemon", packages)
+	linker.OnEvent = chainEvents(onEvent, entry.Record)
+
+	out.infof("Reconciling packages %v\n", packages)
+	result, err = linker.Apply(packages)
+	err = applyError(result, err)
+	if len(result.Linked) > 0 || len(result.Unlinked) > 0 || len(result.Conflicts) > 0 || err != nil {
+		out.summary(result, err)
+	}
+	if linker.DryRun {
+		return err
+	}
+	if len(entry.Operations) > 0 || err != nil {
+		saveHistory(linker, "", entry, err)
+	}
+	if err == nil {
+		recordGeneration(linker)
+	}
+	return err
+}
+
+// installDaemon writes a systemd user unit running gslk daemon with the given
+// options, or prints it with --print.
+func installDaemon(args []string) error {
+	fs := newCommandFlags("daemon install", "[options]")
+	opts := daemonFlags(fs.FlagSet)
+	print := fs.Bool("print", false, "Print the unit instead of installing it.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("daemon install takes no arguments")
+	}
+	if _, err := gslk.ParseSchedule(*opts.schedule); err != nil {
+		return err
+	}
+	if err := validateModeFlags(opts.mode, deployModes, opts.onConflict); err != nil {
+		return err
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the gslk executable: %w", err)
+	}
+
+	// Everything the daemon needs is spelled out, since it does not run in
+	// the current directory
+	command := []string{exe, "daemon", "--schedule", *opts.schedule, "--mode", *opts.mode, "--on-conflict", *opts.onConflict}
+	for _, dir := range append([]string{linker.SourceDir}, linker.ExtraSources...) {
+		command = append(command, "-s", dir)
+	}
+	command = append(command, "-t", linker.TargetDir)
+	if *opts.metrics != "" {
+		command = append(command, "--metrics-addr", *opts.metrics)
+	}
+	for _, option := range []struct{ name, path string }{{"--config", *fs.config}, {"--file", *opts.file}} {
+		if option.path == "" {
+			continue
+		}
+		path, err := filepath.Abs(option.path)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path for %s: %w", option.path, err)
+		}
+		command = append(command, option.name, path)
+	}
+	unit := daemonUnit(command)
+
+	if *print {
+		fmt.Print(unit)
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("daemon install writes a systemd unit, which needs Linux; use --print to see it")
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to locate the systemd user unit directory: %w", err)
+	}
+	path := filepath.Join(configDir, "systemd", "user", daemonUnitName)
+	if linker.DryRun {
+		fmt.Printf("Would write %s\n", path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(unit), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Wrote %s\nStart it with: systemctl --user daemon-reload && systemctl --user enable --now %s\n", path, daemonUnitName)
+	return nil
+}
+
+// daemonUnit returns a systemd user unit running command.
+func daemonUnit(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = systemdQuote(arg)
+	}
+	return fmt.Sprintf(`[Unit]
+Description=gslk: keep dotfiles linked
+
+[Service]
+Type=simple
+ExecStart=%s
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, strings.Join(quoted, " "))
+}
+
+// systemdQuote quotes arg for a systemd command line, escaping the specifier
+// and variable characters systemd would otherwise expand.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
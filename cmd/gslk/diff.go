@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+)
+
+// runDiff shows how deployed files differ from their packages. Like diff(1),
+// it fails when differences are found so it can be used in scripts.
+func runDiff(args []string) error {
+	fs := newCommandFlags("diff", "[options] [package...]")
+	fs.Parse(args)
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	drifts, err := linker.Drift(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("No drift found.")
+		return nil
+	}
+
+	for _, d := range drifts {
+		switch {
+		case d.Kind == gslk.DriftWrongLink:
+			fmt.Printf("%s: links to %s instead of %s (package %s)\n", d.Target, d.Dest, d.Source, d.Package)
+		case d.Diff == "":
+			fmt.Printf("%s: secret of package %s was modified since it was deployed\n", d.Target, d.Package)
+		default:
+			fmt.Print(d.Diff)
+		}
+	}
+
+	return fmt.Errorf("%d file(s) differ from their packages", len(drifts))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runDoctor audits the target tree and prints each problem with a suggested fix.
+// It fails when problems are found so it can be used in scripts.
+func runDoctor(args []string) error {
+	fs := newCommandFlags("doctor", "[options]")
+	fs.Parse(args)
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	findings, err := linker.Doctor()
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Println("No problems found.")
+		return nil
+	}
+
+	for _, f := range findings {
+		fmt.Printf("[%s] %s\n", f.Kind, f.Path)
+		fmt.Printf("    %s\n", f.Message)
+		if len(f.Packages) > 0 && *fs.verbose {
+			fmt.Printf("    packages: %s\n", strings.Join(f.Packages, ", "))
+		}
+		fmt.Printf("    fix: %s\n", f.Fix)
+	}
+
+	return fmt.Errorf("found %d problem(s)", len(findings))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"slices"
+)
+
+// eventHandler returns the handler writing events in format (only "ndjson",
+// or "" for none) to file descriptor fd. When events go to standard output,
+// out is moved to standard error so that the stream stays parseable.
+func eventHandler(format string, fd int, out *output) (func(gslk.Event), error) {
+	switch format {
+	case "":
+		return nil, nil
+	case "ndjson":
+	default:
+		return nil, fmt.Errorf("invalid --events format '%s': must be 'ndjson'", format)
+	}
+
+	switch fd {
+	case 1:
+		out.w = os.Stderr
+		out.color = out.color && isTerminal(os.Stderr)
+		return gslk.JSONEvents(os.Stdout), nil
+	case 2:
+		return gslk.JSONEvents(os.Stderr), nil
+	}
+	f := os.NewFile(uintptr(fd), "events")
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("invalid --events-fd %d: %w", fd, err)
+	}
+	return gslk.JSONEvents(f), nil
+}
+
+// chainEvents returns a handler passing events to each non-nil handler, or
+// nil if there is none.
+func chainEvents(handlers ...func(gslk.Event)) func(gslk.Event) {
+	handlers = slices.DeleteFunc(handlers, func(h func(gslk.Event)) bool { return h == nil })
+	if len(handlers) == 0 {
+		return nil
+	}
+	return func(event gslk.Event) {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"gslk"
+	"os"
+)
+
+// Exit codes, so that scripts can tell failures apart without parsing
+// messages.
+const (
+	exitOK       = 0 // Success
+	exitUsage    = 1 // Invalid arguments, or any failure not listed below
+	exitConflict = 2 // A target path is occupied; nothing was changed
+	exitPartial  = 3 // Some files were processed, others failed
+	exitVerify   = 4 // Files of unlinked packages are still deployed
+)
+
+// partialError marks the failure of a run that changed some files.
+type partialError struct {
+	err error
+}
+
+func (e *partialError) Error() string { return e.err.Error() }
+func (e *partialError) Unwrap() error { return e.err }
+
+// applyError returns err, marked as a partial failure if result lists files
+// that failed.
+func applyError(result *gslk.Result, err error) error {
+	if err != nil && result != nil && len(result.Failed) > 0 {
+		return &partialError{err}
+	}
+	return err
+}
+
+// exitCode returns the exit code reporting err.
+func exitCode(err error) int {
+	var verifyErr *gslk.VerifyError
+	var partialErr *partialError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &verifyErr):
+		return exitVerify
+	case errors.Is(err, gslk.ErrConflict):
+		return exitConflict
+	case errors.As(err, &partialErr):
+		return exitPartial
+	default:
+		return exitUsage
+	}
+}
+
+// parseFlags parses args with fs, which must not exit on errors itself.
+// Options may follow arguments and be combined (see normalizeArgs). Invalid
+// flags exit with exitUsage, -h with exitOK, after fs has printed the problem
+// and its usage.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+}
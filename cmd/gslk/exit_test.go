@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the command instead of the tests when asked to by gslk, so
+// that tests can check the exit code of whole runs.
+func TestMain(m *testing.M) {
+	if os.Getenv("GSLK_TEST_MAIN") == "1" {
+		main()
+		os.Exit(exitOK)
+	}
+	os.Exit(m.Run())
+}
+
+// runGslk runs the command with args and returns its exit code.
+func runGslk(t *testing.T, args ...string) int {
+	cmd := exec.Command(os.Args[0], args...)
+	home := t.TempDir()
+	cmd.Env = append(os.Environ(), "GSLK_TEST_MAIN=1", "HOME="+home, "XDG_CONFIG_HOME="+home, "XDG_STATE_HOME="+home)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	require.NoError(t, err)
+	return exitOK
+}
+
+func TestExitCodeUnlinkModified(t *testing.T) {
+	sourceDir, targetDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "shell"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "shell", ".bashrc"), []byte("set -o vi\n"), 0644))
+
+	require.Equal(t, exitOK, runGslk(t, "--no-progress", "--mode", "copy", "-s", sourceDir, "-t", targetDir, "shell"))
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	require.NoError(t, os.WriteFile(bashrc, []byte("set -o emacs\n"), 0644))
+
+	// Unlinking a copy edited in the target is a conflict, not a usage error
+	assert.Equal(t, exitConflict, runGslk(t, "--no-progress", "-D", "-s", sourceDir, "-t", targetDir, "shell"))
+	assert.FileExists(t, bashrc)
+}
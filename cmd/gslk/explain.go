@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+)
+
+// runExplain prints the decisions gslk takes for a path of a package.
+func runExplain(args []string) error {
+	fs := newCommandFlags("explain", "[options] <package> <path>")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("a package and a path inside it must be specified")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	explanation, err := linker.Explain(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s (package %s)\n", explanation.Source, explanation.Package)
+	for i, step := range explanation.Steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+)
+
+// runFiles prints each file of a package with its target path and whether it
+// is deployed there.
+func runFiles(args []string) error {
+	fs := newCommandFlags("files", "[options] <package>")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one package must be specified")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	files, err := linker.Files(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		switch file.Status {
+		case gslk.FileIgnored:
+			fmt.Printf("%-8s  %s\n", file.Status, file.Source)
+		case gslk.FileConflict:
+			fmt.Printf("%-8s  %s -> %s (%s in the way)\n", file.Status, file.Source, file.Target, file.State)
+		default:
+			fmt.Printf("%-8s  %s -> %s\n", file.Status, file.Source, file.Target)
+		}
+	}
+	return nil
+}
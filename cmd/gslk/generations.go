@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runGenerations lists the recorded generations of the target directory.
+func runGenerations(args []string) error {
+	fs := newCommandFlags("generations", "[options]")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("generations takes no arguments")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	generations, err := linker.Generations()
+	if err != nil {
+		return err
+	}
+	if len(generations) == 0 {
+		fmt.Println("No generations recorded.")
+		return nil
+	}
+
+	for i, generation := range generations {
+		current := ""
+		if i == len(generations)-1 {
+			current = "  (current)"
+		}
+		fmt.Printf("%4d  %s  %d files: %s%s\n", generation.Number, generation.Time.Local().Format(time.DateTime), len(generation.Entries), strings.Join(generation.Packages(), " "), current)
+		if *fs.verbose {
+			for _, entry := range generation.Entries {
+				fmt.Printf("        %s %s (%s)\n", entry.Mode, entry.Target, entry.Source)
+			}
+		}
+	}
+	return nil
+}
+
+// runRollback restores the layout of a generation, by default the one
+// before the current one.
+func runRollback(args []string) error {
+	fs := newCommandFlags("rollback", "[options] [generation]")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected at most one generation number")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var n int
+	if fs.NArg() == 1 {
+		if n, err = strconv.Atoi(fs.Arg(0)); err != nil {
+			return fmt.Errorf("invalid generation %q: must be a number", fs.Arg(0))
+		}
+	} else {
+		generations, err := linker.Generations()
+		if err != nil {
+			return err
+		}
+		if len(generations) < 2 {
+			return fmt.Errorf("there is no previous generation to roll back to")
+		}
+		n = generations[len(generations)-2].Number
+	}
+
+	entry := newHistoryEntry("rollback", []string{strconv.Itoa(n)})
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	result, err := linker.Rollback(n)
+	out.summaryf("Rolled back to generation %d. Summary: %s\n", n, result)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	return err
+}
+
+// recordGeneration records the layout left by a successful run as a new
+// generation, warning if it cannot be saved. Ephemeral runs record none, so
+// gslk teardown leaves nothing of theirs behind.
+func recordGeneration(linker *gslk.Linker) {
+	if _, remote := linker.FS.(gslk.RemoteFS); remote || linker.DryRun || linker.ReadOnly || linker.Ephemeral {
+		return
+	}
+	if _, err := linker.RecordGeneration(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record generation: %v\n", err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"gslk"
+	"os"
+)
+
+// runGraph draws the packages, their dependencies and the target directories
+// they populate, to be rendered with Graphviz or Mermaid.
+func runGraph(args []string) error {
+	fs := newCommandFlags("graph", "[options] [package...]")
+	format := fs.String("format", string(gslk.GraphDOT), "Graph `format`: dot (Graphviz) or mermaid.")
+	depth := fs.Int("depth", 2, "Group target directories `n` levels below the target directory; 0 shows every directory.")
+	fs.Parse(args)
+	graphFormat, err := gslk.ParseGraphFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	graph, err := linker.Graph(fs.Args(), *depth)
+	if err != nil {
+		return err
+	}
+	return graph.Write(os.Stdout, graphFormat)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// outcomes names the exit codes in the audit log.
+var outcomes = map[int]string{
+	exitOK:       "success",
+	exitUsage:    "error",
+	exitConflict: "conflict",
+	exitPartial:  "partial failure",
+	exitVerify:   "verification failure",
+}
+
+// newHistoryEntry starts the audit log entry of a run performing action on
+// packages. Its Record method collects the operations as they are applied.
+func newHistoryEntry(action string, packages []string) *gslk.HistoryEntry {
+	return &gslk.HistoryEntry{
+		Time:     time.Now(),
+		Args:     os.Args[1:],
+		Action:   action,
+		Packages: packages,
+	}
+}
+
+// saveHistory appends entry, ended by err, to the audit log at path, or at
+// the default location if path is empty. The run has happened either way, so
+// a log that cannot be written only produces a warning. Read-only runs change
+// nothing and are not recorded.
+func saveHistory(linker *gslk.Linker, path string, entry *gslk.HistoryEntry, err error) {
+	if linker.ReadOnly {
+		return
+	}
+	entry.Outcome = outcomes[exitCode(err)]
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if path == "" {
+		var pathErr error
+		if path, pathErr = gslk.DefaultHistoryPath(); pathErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not locate the history: %v\n", pathErr)
+			return
+		}
+	}
+	if err := gslk.AppendHistory(path, *entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// runHistory shows the runs recorded in the audit log, most recent last.
+func runHistory(args []string) error {
+	fs := newCommandFlags("history", "[options]")
+	historyPath := fs.String("history", "", "Audit log `file` (default: "+gslk.HistoryFileName+" in the user state directory, e.g. ~/.local/state/gslk/).")
+	path := fs.String("path", "", "Only show runs that changed the file at `path`, and what they did to it.")
+	last := fs.Int("last", 20, "Show the last `n` runs; 0 shows all.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("history takes no arguments")
+	}
+
+	if *historyPath == "" {
+		var err error
+		if *historyPath, err = gslk.DefaultHistoryPath(); err != nil {
+			return fmt.Errorf("could not locate the history: %w", err)
+		}
+	}
+	entries, err := gslk.ReadHistory(*historyPath)
+	if err != nil {
+		return err
+	}
+
+	var target string
+	if *path != "" {
+		if target, err = filepath.Abs(*path); err != nil {
+			return fmt.Errorf("error resolving path %s: %v", *path, err)
+		}
+		var touching []gslk.HistoryEntry
+		for _, entry := range entries {
+			if entry.Touches(target) {
+				touching = append(touching, entry)
+			}
+		}
+		entries = touching
+	}
+	if *last > 0 && len(entries) > *last {
+		entries = entries[len(entries)-*last:]
+	}
+	if len(entries) == 0 {
+		fmt.Println("No runs recorded.")
+		return nil
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s  %s %s  %s, %d operations\n", entry.Time.Local().Format(time.DateTime), entry.Action, strings.Join(entry.Packages, " "), entry.Outcome, len(entry.Operations))
+		if *fs.verbose || target != "" {
+			fmt.Printf("  gslk %s\n", strings.Join(entry.Args, " "))
+			if entry.Error != "" {
+				fmt.Printf("  error: %s\n", strings.ReplaceAll(entry.Error, "\n", "\n  "))
+			}
+		}
+		for _, op := range entry.Operations {
+			if target != "" && op.Target != target || target == "" && !*fs.verbose {
+				continue
+			}
+			fmt.Printf("  %s %s", op.Op, op.Target)
+			if op.Source != "" {
+				fmt.Printf(" (%s)", op.Source)
+			}
+			if op.Error != "" {
+				fmt.Printf(": %s", op.Error)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
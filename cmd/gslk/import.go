@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+)
+
+// runImport moves existing files from the target into a package and links
+// them back.
+func runImport(args []string) error {
+	fs := newCommandFlags("import", "[options] --package <name> <path>...")
+	pkgName := fs.String("package", "", "Move the files into the package `name`, creating it in the first source directory if needed.")
+	mode := fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for the imported files: link (symlink) or copy.")
+	allowRoot := fs.Bool("allow-root", false, "Allow running as root, for system packages.")
+
+	fs.Parse(args)
+	paths := fs.Args()
+	if *pkgName == "" || len(paths) == 0 {
+		fs.Usage()
+		return fmt.Errorf("import takes a --package and the paths of the files to import")
+	}
+	if err := validateModeFlags(mode, deployModes, nil); err != nil {
+		return err
+	}
+
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*mode)
+	linker.AllowRoot = *allowRoot
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("import", []string{*pkgName})
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	out.infof("Importing %v into package %s\n", paths, *pkgName)
+	result, err := linker.Import(*pkgName, paths)
+	out.summary(result, err)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	if err == nil {
+		recordGeneration(linker)
+	}
+	return err
+}
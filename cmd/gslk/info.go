@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// runInfo prints the manifest of a package, its size, dependencies, hooks and
+// how much of it is deployed.
+func runInfo(args []string) error {
+	fs := newCommandFlags("info", "[options] <package>")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one package must be specified")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	info, err := linker.Info(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	manifest := info.Package.Manifest
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	field := func(name, format string, args ...any) {
+		fmt.Fprintf(w, "%s:\t"+format+"\n", append([]any{name}, args...)...)
+	}
+	field("Package", "%s", info.Package.Name)
+	if manifest.Description != "" {
+		field("Description", "%s", manifest.Description)
+	}
+	field("Source", "%s", strings.Join(info.Package.Layers, ", "))
+	if manifest.Path != "" {
+		field("Manifest", "%s", manifest.Path)
+	}
+	field("Target", "%s", info.Target)
+	for _, from := range slices.Sorted(maps.Keys(manifest.Rename)) {
+		field("Rename", "%s -> %s", from, manifest.Rename[from])
+	}
+	if len(manifest.OS) > 0 {
+		field("OS", "%s", strings.Join(manifest.OS, ", "))
+	}
+	if len(manifest.Depends) > 0 {
+		field("Depends", "%s", strings.Join(manifest.Depends, ", "))
+	}
+	if len(manifest.Copy) > 0 {
+		field("Copied", "%s", strings.Join(manifest.Copy, ", "))
+	}
+	for _, name := range slices.Sorted(maps.Keys(manifest.Vars)) {
+		field("Variable", "%s (%s)", name, manifest.Vars[name])
+	}
+	for _, hook := range manifest.Hooks {
+		field("Hook", "%s: %s", hook.When, hook.Run)
+	}
+	field("Files", "%d (%s), %d ignored", info.Files, formatSize(info.Size), info.Ignored)
+	field("Status", "%s, %d/%d deployed (%.0f%%)", info.Status, info.Deployed, info.Files, info.Coverage())
+	return w.Flush()
+}
+
+// formatSize writes size in bytes with a binary unit.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
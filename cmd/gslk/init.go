@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+)
+
+// runInit creates the layout of a new dotfiles repository.
+func runInit(args []string) error {
+	fs := newCommandFlags("init", "[options] [directory]")
+	noGit := fs.Bool("no-git", false, "Do not create a git repository.")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("init takes at most one directory")
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+
+	dir := fs.Arg(0)
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("could not determine current directory: %v", err)
+		}
+	}
+	if *fs.dryRun {
+		fmt.Printf("Would create %s and README.md in %s\n", gslk.RepoConfigFileName, dir)
+		return nil
+	}
+
+	created, err := gslk.InitRepo(dir)
+	for _, file := range created {
+		out.summaryf("Created %s\n", file)
+	}
+	if err != nil {
+		return err
+	}
+	if git := (&gslk.Git{Dir: dir}); !*noGit && !git.IsRepo() {
+		if err := git.Init(); err != nil {
+			return err
+		}
+		out.summaryf("Initialized a git repository in %s\n", dir)
+	}
+	return nil
+}
+
+// runNew scaffolds a package in the first source directory.
+func runNew(args []string) error {
+	fs := newCommandFlags("new", "[options] <package>")
+	description := fs.String("description", "", "Description of the package for its manifest.")
+	fs.Parse(args)
+	names := fs.Args()
+	if len(names) != 1 {
+		fs.Usage()
+		return fmt.Errorf("new takes the name of the package")
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	if linker.DryRun {
+		fmt.Printf("Would create package %s in %s\n", names[0], linker.SourceDir)
+		return nil
+	}
+	created, err := gslk.ScaffoldPackage(linker.SourceDir, names[0], *description)
+	for _, file := range created {
+		out.summaryf("Created %s\n", file)
+	}
+	return err
+}
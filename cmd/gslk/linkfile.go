@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+)
+
+// runLinkFile links a single file of a package.
+func runLinkFile(args []string) error {
+	return runFileCommand("link-file", args, (*gslk.Linker).LinkFile)
+}
+
+// runUnlinkFile unlinks a single file of a package.
+func runUnlinkFile(args []string) error {
+	return runFileCommand("unlink-file", args, (*gslk.Linker).UnlinkFile)
+}
+
+// runFileCommand parses the arguments shared by link-file and unlink-file and
+// applies action to the given file.
+func runFileCommand(name string, args []string, action func(*gslk.Linker, string, string) (*gslk.Result, error)) error {
+	fs := newCommandFlags(name, "[options] <package> <path in package>")
+	mode := fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for the file: link (symlink) or copy.")
+	onConflict := fs.String("on-conflict", string(gslk.ConflictFail), "What to do with an existing file in the way: fail, backup or adopt-identical.")
+	allowRoot := fs.Bool("allow-root", false, "Allow running as root, for system packages.")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected a package and the path of a file in it")
+	}
+	if err := validateModeFlags(mode, deployModes, onConflict); err != nil {
+		return err
+	}
+
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*mode)
+	linker.ConflictPolicy = gslk.ConflictPolicy(*onConflict)
+	linker.AllowRoot = *allowRoot
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry(name, []string{fs.Arg(0)})
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	result, err := action(linker, fs.Arg(0), fs.Arg(1))
+	out.summary(result, err)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	if err == nil {
+		recordGeneration(linker)
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// runList prints every package with its file count, link status in the
+// target and description.
+func runList(args []string) error {
+	fs := newCommandFlags("list", "[options]")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("list takes no arguments")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	statuses, err := linker.Status()
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		fmt.Printf("No packages found in %s.\n", strings.Join(append([]string{linker.SourceDir}, linker.ExtraSources...), ", "))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tFILES\tSTATUS\tDESCRIPTION")
+	for _, s := range statuses {
+		status := string(s.Status)
+		if s.Status == gslk.StatusPartial {
+			status = fmt.Sprintf("partial (%d/%d)", s.Deployed, s.Files)
+		}
+		description := s.Package.Manifest.Description
+		if !s.Package.Manifest.SupportsOS() {
+			description = strings.TrimSpace(description + " [only for " + strings.Join(s.Package.Manifest.OS, ", ") + "]")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", s.Package.Name, s.Files, status, description)
+		if *fs.verbose {
+			for _, layer := range s.Package.Layers {
+				fmt.Fprintf(w, "  %s\t\t\t\n", layer)
+			}
+		}
+	}
+	return w.Flush()
+}
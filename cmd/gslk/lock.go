@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"time"
+)
+
+// lockTarget takes the lock of linker's target directory for a run changing
+// it, waiting up to wait for other runs to finish, and returns the function
+// releasing it. Dry and read-only runs change nothing and take no lock, nor
+// do runs through sudo, which may not create the lock file in the root-owned
+// targets they are meant for, or runs on remote targets, which have no lock
+// file.
+func lockTarget(linker *gslk.Linker, wait time.Duration) (unlock func(), err error) {
+	_, sudo := linker.FS.(*gslk.SudoFS)
+	_, remote := linker.FS.(gslk.RemoteFS)
+	if sudo || remote || linker.DryRun || linker.ReadOnly {
+		return func() {}, nil
+	}
+	lock, err := linker.LockTarget(wait)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to release the lock: %v\n", err)
+		}
+	}, nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"gslk"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -18,46 +21,132 @@ const (
 
 // Flags
 var (
-	sourceDir       = flag.String("s", "", "Source `directory` containing packages (default: current directory). Can also use --source.")
-	targetDir       = flag.String("t", os.Getenv("HOME"), "Target `directory` for symlinks (default: $HOME). Can also use --target.")
-	deleteFlag      = flag.Bool("D", false, "Delete/unlink packages instead of linking. Cannot be used with -GL, --gslk or -R.")
-	linkFlag        = flag.Bool("GL", false, "Link packages (default action). Cannot be used with -D or -R. Alias: --gslk.")
-	gslkFlag        = flag.Bool("gslk", false, "Alias for -GL (Link packages). Cannot be used with -D or -R.")
-	relinkFlag      = flag.Bool("R", false, "Relink packages (unlink then link). Cannot be used with -D, -GL or --gslk.")
-	noopFlag        = flag.Bool("n", false, "Dry run: show what would be done without actually doing it.")
-	verboseFlag     = flag.Bool("v", false, "Increase verbosity.")
-	forceRemoveFlag = flag.Bool("f", false, "Force remove parent directories during unlink, even if not empty.")
-	_               = flag.String("source", "", "Alias for -s.")
-	_               = flag.String("target", "", "Alias for -t.")
-	_               = flag.Bool("force", false, "Alias for -f.")
+	sourceDirs         = stringListFlag(flag.CommandLine, "s", "Source `directory` containing packages, or a tarball or zip archive by path or HTTPS URL (default: current directory). Repeat to layer sources; later ones override earlier ones. Can also use --source.")
+	targetDir          = flag.String("t", "", "Target `directory` for symlinks (default: $HOME), or ssh://[user@]host[:port]/path for a directory on another machine (experimental). Can also use --target.")
+	configFlag         = flag.String("config", "", "Configuration `file` (default: "+gslk.ConfigFileName+" in the user configuration directory, e.g. ~/.config/gslk/).")
+	deleteFlag         = flag.Bool("D", false, "Delete/unlink packages instead of linking. Cannot be used with -GL, --gslk or -R.")
+	linkFlag           = flag.Bool("GL", false, "Link packages (default action). Cannot be used with -D or -R. Alias: --gslk.")
+	gslkFlag           = flag.Bool("gslk", false, "Alias for -GL (Link packages). Cannot be used with -D or -R.")
+	relinkFlag         = flag.Bool("R", false, "Relink packages, fixing only what differs from the packages. Cannot be used with -D, -GL or --gslk.")
+	noopFlag           = flag.Bool("n", false, "Dry run: show what would be done without actually doing it.")
+	readOnlyFlag       = flag.Bool("read-only", false, "Refuse every change to the target, whatever the other options say, failing if anything would change; for monitoring jobs.")
+	verboseFlag        = flag.Bool("v", false, "Print what is done to each file.")
+	veryVerboseFlag    = flag.Bool("vv", false, "Also print why files are linked, skipped or ignored.")
+	quietFlag          = flag.Bool("quiet", false, "Print errors only, not even a summary.")
+	noColorFlag        = flag.Bool("no-color", false, "Do not color output, even on a terminal.")
+	modeFlag           = flag.String("mode", string(gslk.ModeLink), "Deployment `mode` for files: link (symlink) or copy.")
+	ephemeralFlag      = flag.Bool("ephemeral", false, "For containers and CI: copy files instead of linking them and record what is deployed in "+gslk.TeardownFileName+" in the target, so gslk teardown can remove exactly that.")
+	onConflictFlag     = flag.String("on-conflict", string(gslk.ConflictFail), "What to do with existing files in the way: fail, backup (move them to the quarantine directory for `gslk review`), or adopt-identical (replace them if they hold exactly what would be deployed, fail otherwise).")
+	fsPolicyFlag       = flag.String("fs-policy", "", "What to do when the target is on a filesystem without symbolic links, such as FAT, or on a network filesystem, such as NFS or SMB: warn (link where possible, copy elsewhere), copy or refuse (default: warn, or fs_policy from the config file).")
+	packagesFromFlag   = flag.String("packages-from", "", "Also process the packages listed in `file`, one per line (- for standard input); blank lines and lines starting with # are skipped.")
+	profileFlag        = flag.String("profile", "", "Link the packages of the named `profile` from the config file instead of packages given as arguments. Packages of the previously linked profile not in it are unlinked.")
+	varsFlag           = flag.String("vars", "", "TOML or YAML (.yaml, .yml) `file` of template variables, overriding those of the config file and profile.")
+	varFlag            = stringListFlag(flag.CommandLine, "var", "Set the template variable given as `name=value`, overriding the config file, profile and vars file. May be repeated.")
+	overlayFlag        = flag.Bool("overlay", false, "Let packages listed later override files of earlier packages at the same target path instead of failing.")
+	yesFlag            = flag.Bool("yes", false, "Do not ask before moving conflicting files, non-empty directories or modified copies aside.")
+	nonInteractiveFlag = flag.Bool("non-interactive", false, "Never ask questions: fail instead of moving files aside that need confirmation. Overridden by --yes.")
+	noVerifyFlag       = flag.Bool("no-verify", false, "Skip checking that no file of the packages is still deployed after unlinking.")
+	resolveFlag        = flag.Bool("resolve-sources", false, "Resolve symbolic links in the source directories so links point at the real location of package files.")
+	ignoreFlag         = stringListFlag(flag.CommandLine, "ignore", "Skip package paths matching `pattern` (.gslk-ignore syntax) in every package, in addition to their ignore files. May be repeated.")
+	onlyFlag           = stringListFlag(flag.CommandLine, "only", "Only link (or unlink) package paths matching `pattern`, or in a directory matching it; everything else is skipped. May be repeated.")
+	strictIgnoreFlag   = flag.Bool("strict-ignore", false, "Fail on invalid patterns in --ignore, --only and the ignore and include files and manifests of packages instead of warning and skipping them.")
+	ignoreRegexFlag    = stringListFlag(flag.CommandLine, "ignore-regex", "Skip package paths matching the regular `expression` in every package. May be repeated.")
+	maxDepthFlag       = flag.Int("max-depth", 0, "Skip package paths more than `levels` below the package root, and directories at that depth (0: no limit).")
+	skipHiddenFlag     = flag.Bool("skip-hidden", false, "Skip hidden files and directories nested inside packages, such as the .git directories of vendored plugins; those directly in a package are still linked.")
+	deferFlag          = stringListFlag(flag.CommandLine, "defer", "Leave target paths matching the regular `expression` (relative to the target, anchored at the start) to the package they are already linked from. May be repeated.")
+	overrideFlag       = stringListFlag(flag.CommandLine, "override", "Relink target paths matching the regular `expression` (relative to the target, anchored at the start) that are linked from another package. May be repeated.")
+	dependentsFlag     = flag.Bool("unlink-dependents", false, "When unlinking, also unlink the packages that depend on the given ones.")
+	dirModeFlag        = flag.String("dir-mode", "", "Octal `permissions` of the directories gslk creates (default 0755).")
+	fileModeFlag       = stringListFlag(flag.CommandLine, "file-mode", "Set the permissions of copied and rendered files matching a pattern, given as `pattern=mode` (e.g. 'bin/*=0755'). May be repeated; the last matching rule wins.")
+	allowRootFlag      = flag.Bool("allow-root", false, "Allow running as root, for system packages targeting locations such as /etc.")
+	ignoreCaseFlag     = flag.Bool("ignore-case", false, "Match package names regardless of case, as long as only one package matches.")
+	sudoFlag           = flag.Bool("sudo", false, "Create and remove links and directories through sudo, for system packages targeting root-owned locations such as /etc.")
+	sudoScriptFlag     = flag.String("sudo-script", "", "Instead of changing anything, write the sudo commands that would be run to a shell script `file`.")
+	eventsFlag         = flag.String("events", "", "Write an event per operation and conflict as it happens, in `format` ndjson (one JSON object per line), for tools following progress.")
+	eventsFDFlag       = flag.Int("events-fd", 1, "File `descriptor` to write --events to (default: standard output, in which case other output goes to standard error).")
+	noProgressFlag     = flag.Bool("no-progress", false, "Do not draw a progress line on standard error while files are planned and processed.")
+	historyFlag        = flag.String("history", "", "Audit log `file` recording each run (default: "+gslk.HistoryFileName+" in the user state directory, e.g. ~/.local/state/gslk/). See gslk history.")
+	noHistoryFlag      = flag.Bool("no-history", false, "Do not record this run in the audit log.")
+	waitFlag           = flag.Duration("wait", 0, "How long to wait for another run changing the target to finish, e.g. 30s or 2m (default: fail at once).")
+	jobsFlag           = flag.Int("j", 1, "Number of `jobs`: independent packages planned and files processed in parallel, which speeds up large runs. Output stays in package order.")
+	retriesFlag        = flag.Int("retries", 0, "Retry creating and removing files in the target up to `n` times after errors network filesystems report transiently, such as ESTALE or EBUSY.")
+	retryBackoffFlag   = flag.Duration("retry-backoff", gslk.DefaultRetryBackoff, "How long to wait before the first retry (see --retries); each further retry waits twice as long.")
+	throttleFlag       = flag.Int("throttle", 0, "Limit filesystem operations to `n` a second, to run on busy shared servers or slow network homes without saturating the storage (default: no limit).")
+	forceRemoveFlag    = flag.Bool("f", false, "Force remove directories gslk created during unlink, even if not empty, and locally modified copies, moving them to the trash (see gslk trash).")
 )
 
+func init() {
+	aliasFlag(flag.CommandLine, "source", "s")
+	aliasFlag(flag.CommandLine, "target", "t")
+	aliasFlag(flag.CommandLine, "force", "f")
+	aliasFlag(flag.CommandLine, "jobs", "j")
+}
+
+// deployModes are the values --mode takes in most commands.
+var deployModes = []gslk.DeployMode{gslk.ModeLink, gslk.ModeCopy}
+
+// validateModeFlags checks the value of a --mode flag against modes, and that
+// of an --on-conflict flag; either flag is nil for commands without it.
+func validateModeFlags(mode *string, modes []gslk.DeployMode, onConflict *string) error {
+	if mode != nil && !slices.Contains(modes, gslk.DeployMode(*mode)) {
+		quoted := make([]string, len(modes))
+		for i, m := range modes {
+			quoted[i] = "'" + string(m) + "'"
+		}
+		last := len(quoted) - 1
+		return fmt.Errorf("invalid mode '%s': must be %s or %s", *mode, strings.Join(quoted[:last], ", "), quoted[last])
+	}
+	if onConflict != nil {
+		switch gslk.ConflictPolicy(*onConflict) {
+		case gslk.ConflictFail, gslk.ConflictBackup, gslk.ConflictAdoptIdentical:
+		default:
+			return fmt.Errorf("invalid conflict policy '%s': must be 'fail', 'backup' or 'adopt-identical'", *onConflict)
+		}
+	}
+	return nil
+}
+
+// userHomeDir returns the current user's home directory ($HOME, or %USERPROFILE% on Windows).
+func userHomeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
+
 // printUsage displays the command usage information
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [options] <package1> [package2] ...\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "       %s <command> [options] [args]\n", filepath.Base(os.Args[0]))
 	fmt.Fprintln(os.Stderr, "Description: Creates or removes symlinks for packages.")
 	fmt.Fprintln(os.Stderr, "Default action is to link packages (-GL or --gslk).")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
 	fmt.Fprintln(os.Stderr, "Options:")
 	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "Exit status: 0 success, 1 usage or other error, 2 conflict, 3 partial failure, 4 verification failure.")
 	fmt.Fprintln(os.Stderr, "Example:")
 	fmt.Fprintf(os.Stderr, "  %s -s ./dotfiles -t $HOME zsh vim git       (Link packages zsh, vim, git - default action)\n", filepath.Base(os.Args[0]))
 	fmt.Fprintf(os.Stderr, "  %s -GL -s ./dotfiles -t $HOME zsh vim git   (Explicitly link packages zsh, vim, git)\n", filepath.Base(os.Args[0]))
 	fmt.Fprintf(os.Stderr, "  %s --gslk -s ./dotfiles -t $HOME zsh vim git (Explicitly link packages zsh, vim, git)\n", filepath.Base(os.Args[0]))
 	fmt.Fprintf(os.Stderr, "  %s -D -s ./dotfiles -t $HOME zsh           (Unlink package zsh with verification)\n", filepath.Base(os.Args[0]))
 	fmt.Fprintf(os.Stderr, "  %s -R -v -s ./dotfiles -t $HOME vim        (Relink package vim verbosely)\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "  %s --mode copy -s ./dotfiles -t /mnt/usb vim (Copy package vim instead of linking)\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "  %s --profile work                          (Link the packages of profile work)\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "  %s --sudo -s ./system -t / etc             (Link system package etc through sudo)\n", filepath.Base(os.Args[0]))
 }
 
 // validateFlags checks for flag conflicts and proper usage
 func validateFlags(packageNames []string) (string, error) {
-	// Check for misinterpreted flags in packageNames
-	for _, name := range packageNames {
-		if strings.HasPrefix(name, "-") {
-			return "", fmt.Errorf("'%s' looks like a flag but was interpreted as a package name. All flags must come before package names", name)
+	// Check for package names; a profile provides its own
+	if *profileFlag != "" {
+		if len(packageNames) > 0 {
+			return "", fmt.Errorf("package names and --packages-from cannot be combined with --profile")
 		}
-	}
-
-	// Check for package names
-	if len(packageNames) == 0 {
+	} else if len(packageNames) == 0 {
 		return "", fmt.Errorf("at least one package name must be provided as an argument")
 	}
 
@@ -82,6 +171,18 @@ func validateFlags(packageNames []string) (string, error) {
 		return "", fmt.Errorf("only one action type (-D, [-GL|--gslk], -R) can be specified")
 	}
 
+	if *jobsFlag < 1 {
+		return "", fmt.Errorf("invalid number of jobs %d: must be at least 1", *jobsFlag)
+	}
+
+	if err := validateModeFlags(modeFlag, deployModes, onConflictFlag); err != nil {
+		return "", err
+	}
+
+	if _, err := gslk.ParseFilesystemPolicy(*fsPolicyFlag); err != nil {
+		return "", err
+	}
+
 	// Determine action
 	action := actionLink // Default action
 	if *deleteFlag {
@@ -93,102 +194,332 @@ func validateFlags(packageNames []string) (string, error) {
 	return action, nil
 }
 
-// setupLinker creates and configures the gslk.Linker instance
-func setupLinker() (*gslk.Linker, error) {
-	// Get current directory for default source
-	currentDir, err := os.Getwd()
+// repoSources returns the source directories of the dotfiles repository at
+// root: the sources of its configuration file, or else root itself.
+func repoSources(root string) ([]string, error) {
+	config, err := gslk.LoadConfig(filepath.Join(root, gslk.RepoConfigFileName))
 	if err != nil {
-		return nil, fmt.Errorf("could not determine current directory: %v", err)
+		return nil, err
+	}
+	if len(config.Sources) == 0 {
+		return []string{root}, nil
+	}
+	return config.Sources, nil
+}
+
+// newLinker creates a gslk.Linker for the given source and target directories,
+// resolving them to absolute paths. Sources and target not given on the
+// command line are taken from config; otherwise the source is the dotfiles
+// repository the current directory is in (see gslk.FindSourceRoot) or the
+// current directory, and the target the home directory.
+func newLinker(config *gslk.Config, sourceDirectories []string, targetDirectory string) (*gslk.Linker, error) {
+	if len(sourceDirectories) == 0 {
+		sourceDirectories = config.Sources
+	}
+	// If no source dir was specified, use the dotfiles repository the current
+	// directory is in, or else the current directory
+	if len(sourceDirectories) == 0 {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("could not determine current directory: %v", err)
+		}
+		sourceDirectories = []string{currentDir}
+		if root, ok := gslk.FindSourceRoot(currentDir); ok {
+			if sourceDirectories, err = repoSources(root); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if targetDirectory == "" {
+		targetDirectory = config.Target
+	}
+	if targetDirectory == "" {
+		targetDirectory = userHomeDir()
+	}
+
+	// Resolve paths to absolute for consistency, extracting archives
+	absSources := make([]string, 0, len(sourceDirectories))
+	cache := &gslk.SourceCache{}
+	for _, sourceDirectory := range sourceDirectories {
+		sourceDirectory, err := cache.Resolve(sourceDirectory)
+		if err != nil {
+			return nil, err
+		}
+		absSource, err := filepath.Abs(sourceDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving source directory path %s: %v", sourceDirectory, err)
+		}
+		absSources = append(absSources, absSource)
+	}
+
+	// A target on another machine is managed over ssh
+	var remote []gslk.Option
+	if strings.HasPrefix(targetDirectory, "ssh://") {
+		fsys, err := gslk.ParseSSHTarget(targetDirectory)
+		if err != nil {
+			return nil, err
+		}
+		targetDirectory = fsys.Root
+		remote = append(remote, gslk.WithFS(fsys))
 	}
 
-	// If source dir wasn't specified, use current directory
-	sourceDirectory := *sourceDir
-	if sourceDirectory == "" {
-		sourceDirectory = currentDir
+	absTarget, err := filepath.Abs(targetDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving target directory path %s: %v", targetDirectory, err)
 	}
 
-	// Resolve paths to absolute for consistency
-	absSource, err := filepath.Abs(sourceDirectory)
+	return gslk.New(absSources[0], absTarget, append([]gslk.Option{
+		gslk.WithExtraSources(absSources[1:]...),
+		gslk.WithMapper(gslk.Mapper{TemplateSuffix: gslk.DefaultTemplateSuffix, SecretSuffix: gslk.DefaultSecretSuffix}),
+		gslk.WithVars(config.Vars),
+		gslk.WithAge(config.Age),
+		gslk.WithIgnore(config.Ignore...),
+		gslk.WithGroups(config.Groups),
+		gslk.WithPackageDirs(config.PackageDirs...),
+		gslk.WithDirMode(config.DirMode),
+		gslk.WithFileModes(config.FileModes...),
+		gslk.WithStrictEnv(config.StrictEnv),
+		gslk.WithStrictIgnore(config.StrictIgnore),
+		gslk.WithFilesystemPolicy(config.FilesystemPolicy),
+	}, remote...)...), nil
+}
+
+// setupLinker creates and configures the gslk.Linker instance
+func setupLinker(config *gslk.Config, out *output) (*gslk.Linker, error) {
+	linker, err := newLinker(config, *sourceDirs, *targetDir)
 	if err != nil {
-		return nil, fmt.Errorf("error resolving source directory path %s: %v", sourceDirectory, err)
+		return nil, err
 	}
 
-	absTarget, err := filepath.Abs(*targetDir)
+	linker.Verbose = out.verbosity >= verbosityTrace
+	linker.Logger = out
+	linker.DryRun = *noopFlag
+	linker.ReadOnly = *readOnlyFlag
+	events, err := eventHandler(*eventsFlag, *eventsFDFlag, out)
 	if err != nil {
-		return nil, fmt.Errorf("error resolving target directory path %s: %v", *targetDir, err)
+		return nil, err
+	}
+	var progress func(gslk.Event)
+	if out.progress != nil {
+		progress = out.update
+	}
+	linker.OnEvent = chainEvents(events, progress)
+	linker.Mode = gslk.DeployMode(*modeFlag)
+	linker.Ephemeral = *ephemeralFlag
+	linker.ConflictPolicy = gslk.ConflictPolicy(*onConflictFlag)
+	if *fsPolicyFlag != "" {
+		linker.FilesystemPolicy = gslk.FilesystemPolicy(*fsPolicyFlag)
+	}
+	linker.ForceRemove = *forceRemoveFlag
+	linker.Overlay = *overlayFlag
+	linker.Concurrency = *jobsFlag
+	linker.Retries = *retriesFlag
+	linker.RetryBackoff = *retryBackoffFlag
+	linker.Throttle = gslk.NewThrottle(*throttleFlag)
+	linker.NoVerify = *noVerifyFlag
+	linker.ResolveSources = *resolveFlag
+	linker.Confirm = confirmer(*yesFlag, *nonInteractiveFlag)
+	linker.Ignore = append(linker.Ignore, *ignoreFlag...)
+	linker.Only = *onlyFlag
+	linker.StrictIgnore = linker.StrictIgnore || *strictIgnoreFlag
+	linker.MaxDepth = *maxDepthFlag
+	linker.SkipHidden = *skipHiddenFlag
+	linker.UnlinkDependents = *dependentsFlag
+	linker.AllowRoot = *allowRootFlag
+	linker.IgnoreCase = *ignoreCaseFlag
+
+	if *sudoFlag || *sudoScriptFlag != "" {
+		if _, remote := linker.FS.(gslk.RemoteFS); remote {
+			return nil, fmt.Errorf("--sudo cannot be used with a remote target")
+		}
+		linker.FS = &gslk.SudoFS{}
+		// The script itself is created by openSudoScript once every option
+		// is known to be valid
+		if *sudoScriptFlag != "" {
+			linker.NoVerify = true // Nothing is unlinked until the script runs
+		}
 	}
 
-	return &gslk.Linker{
-		SourceDir:   absSource,
-		TargetDir:   absTarget,
-		Verbose:     *verboseFlag,
-		DryRun:      *noopFlag,
-		ForceRemove: *forceRemoveFlag,
-	}, nil
+	if *retriesFlag < 0 {
+		return nil, fmt.Errorf("invalid --retries %d: must not be negative", *retriesFlag)
+	}
+	if *throttleFlag < 0 {
+		return nil, fmt.Errorf("invalid --throttle %d: must not be negative", *throttleFlag)
+	}
+	if *maxDepthFlag < 0 {
+		return nil, fmt.Errorf("invalid --max-depth %d: must not be negative", *maxDepthFlag)
+	}
+	if *dirModeFlag != "" {
+		if linker.DirMode, err = gslk.ParsePerm(*dirModeFlag); err != nil {
+			return nil, fmt.Errorf("invalid --dir-mode: %w", err)
+		}
+	}
+	for _, rule := range *fileModeFlag {
+		mode, err := gslk.ParseFileMode(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --file-mode: %w", err)
+		}
+		linker.FileModes = append(linker.FileModes, mode)
+	}
+
+	for _, pattern := range slices.Concat(linker.Ignore, linker.Only) {
+		if err := gslk.CheckPattern(pattern); err != nil {
+			err = &gslk.PatternError{Pattern: pattern, Err: err}
+			if linker.StrictIgnore {
+				return nil, err
+			}
+			out.Logf(gslk.LevelWarn, "Warning: %v, skipping it\n", err)
+		}
+	}
+
+	if linker.IgnoreRegexp, err = compileRegexps("ignore-regex", *ignoreRegexFlag); err != nil {
+		return nil, err
+	}
+	if linker.Defer, err = compileRegexps("defer", *deferFlag); err != nil {
+		return nil, err
+	}
+	if linker.Override, err = compileRegexps("override", *overrideFlag); err != nil {
+		return nil, err
+	}
+	return linker, nil
 }
 
-// performAction executes the specified action
-func performAction(linker *gslk.Linker, action string, packageNames []string) error {
-	if *verboseFlag {
-		fmt.Printf("Source: %s\nTarget: %s\n", linker.SourceDir, linker.TargetDir)
+// openSudoScript creates the --sudo-script file, if one was given, and makes
+// the sudo filesystem of linker write its commands to it.
+func openSudoScript(linker *gslk.Linker) error {
+	fsys, ok := linker.FS.(*gslk.SudoFS)
+	if !ok || *sudoScriptFlag == "" {
+		return nil
+	}
+	script, err := os.Create(*sudoScriptFlag)
+	if err != nil {
+		return fmt.Errorf("failed to create sudo script: %w", err)
+	}
+	fsys.Script = script
+	if _, err := fmt.Fprintln(script, "#!/bin/sh\nset -e"); err != nil {
+		return errors.Join(fmt.Errorf("failed to write sudo script: %w", err), script.Close())
+	}
+	return nil
+}
+
+// closeSudoScript closes the script opened by openSudoScript, if any,
+// reporting whatever of it failed to be written.
+func closeSudoScript(linker *gslk.Linker) error {
+	fsys, ok := linker.FS.(*gslk.SudoFS)
+	if !ok {
+		return nil
+	}
+	script, ok := fsys.Script.(*os.File)
+	if !ok {
+		return nil
+	}
+	if err := script.Close(); err != nil {
+		return fmt.Errorf("failed to write sudo script: %w", err)
+	}
+	return nil
+}
+
+// compileRegexps compiles the regular expressions given with flag name.
+func compileRegexps(name string, exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s expression: %w", name, err)
+		}
+		res = append(res, re)
 	}
+	return res, nil
+}
+
+// performAction executes the specified action
+func performAction(linker *gslk.Linker, out *output, action string, packageNames []string) (*gslk.Result, error) {
+	out.infof("Source: %s\nTarget: %s\n", linker.SourceDir, linker.TargetDir)
 
 	switch action {
 	case actionLink:
-		if *verboseFlag {
-			fmt.Printf("Linking packages %v from %s to %s\n", packageNames, linker.SourceDir, linker.TargetDir)
-		}
+		out.infof("Linking packages %v from %s to %s\n", packageNames, linker.SourceDir, linker.TargetDir)
 		return linker.Link(packageNames)
 
 	case actionUnlink:
-		if *verboseFlag {
-			fmt.Printf("Unlinking packages %v from %s in %s\n", packageNames, linker.SourceDir, linker.TargetDir)
-			fmt.Println("Verification will ensure all symbolic links are properly removed")
-		}
+		out.infof("Unlinking packages %v from %s in %s\n", packageNames, linker.SourceDir, linker.TargetDir)
+		out.infof("Verification will ensure all symbolic links are properly removed\n")
 		return linker.Unlink(packageNames)
 
 	case actionRelink:
-		if *verboseFlag {
-			fmt.Printf("Unlinking packages %v from %s in %s (part of relink)\n", packageNames, linker.SourceDir, linker.TargetDir)
-		}
+		out.infof("Relinking packages %v from %s in %s\n", packageNames, linker.SourceDir, linker.TargetDir)
+		return linker.Relink(packageNames)
 
-		err := linker.Unlink(packageNames)
-		if err != nil {
-			return fmt.Errorf("error during unlink phase of relink: %w", err)
-		}
+	default:
+		return &gslk.Result{}, fmt.Errorf("unknown action: %s", action)
+	}
+}
+
+// performProfileAction executes the specified action for the packages of profile
+func performProfileAction(linker *gslk.Linker, out *output, action string, profile gslk.Profile) (*gslk.Result, error) {
+	out.infof("Profile %s: packages %v\n", profile.Name, profile.Packages)
+
+	switch action {
+	case actionLink:
+		return linker.LinkProfile(profile)
 
-		if *verboseFlag {
-			fmt.Printf("Linking packages %v from %s to %s (part of relink)\n", packageNames, linker.SourceDir, linker.TargetDir)
+	case actionUnlink:
+		return linker.UnlinkProfile(profile)
+
+	case actionRelink:
+		// Relink fixes the profile's packages; LinkProfile then unlinks those of
+		// the previous profile and records the new one
+		result, err := linker.Relink(profile.Packages)
+		if err != nil {
+			return result, err
 		}
-		return linker.Link(packageNames)
+		linkResult, err := linker.LinkProfile(profile)
+		result.Merge(linkResult)
+		return result, err
 
 	default:
-		return fmt.Errorf("unknown action: %s", action)
+		return &gslk.Result{}, fmt.Errorf("unknown action: %s", action)
 	}
 }
 
 // simulateAction performs a dry run of the specified action
-func simulateAction(linker *gslk.Linker, action string, packageNames []string) {
-	fmt.Printf("DRY RUN: Would %s packages %v from %s to %s\n", action, packageNames, linker.SourceDir, linker.TargetDir)
+func simulateAction(linker *gslk.Linker, out *output, action string, packageNames []string) {
+	out.summaryf("DRY RUN: Would %s packages %v from %s to %s\n", action, packageNames, linker.SourceDir, linker.TargetDir)
 
 	switch action {
 	case actionLink:
-		fmt.Println("DRY RUN: Simulating link operation.")
+		out.summaryf("DRY RUN: Simulating link operation.\n")
 	case actionUnlink:
-		fmt.Println("DRY RUN: Simulating unlink operation.")
+		out.summaryf("DRY RUN: Simulating unlink operation.\n")
 	case actionRelink:
-		fmt.Println("DRY RUN: Simulating unlink operation (part of relink).")
-		fmt.Println("DRY RUN: Simulating link operation (part of relink).")
+		out.summaryf("DRY RUN: Simulating relink operation.\n")
 	}
 
-	fmt.Printf("DRY RUN: Action '%s' simulation completed for packages %v.\n", action, packageNames)
+	out.summaryf("DRY RUN: Action '%s' simulation completed for packages %v.\n", action, packageNames)
 }
 
 func main() {
+	// Subcommands are dispatched before flag parsing: gslk <command> [options] [args]
+	if len(os.Args) > 1 {
+		if cmd, ok := findCommand(os.Args[1]); ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+	}
+
 	flag.Usage = printUsage
-	flag.Parse()
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	parseFlags(flag.CommandLine, os.Args[1:])
 
-	packageNames := flag.Args()
+	packageNames, err := packageArgs(flag.Args(), *packagesFromFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
 
 	// Validate flags and determine action
 	action, err := validateFlags(packageNames)
@@ -196,30 +527,104 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintln(os.Stderr, "")
 		printUsage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	out, err := newOutput(*quietFlag, *verboseFlag, *veryVerboseFlag, *noColorFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	if !*noProgressFlag {
+		out.showProgress()
+	}
+
+	config, err := loadConfig(*configFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
 	}
 
 	// Setup linker
-	linker, err := setupLinker()
+	linker, err := setupLinker(config, out)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	var profile *gslk.Profile
+	if *profileFlag != "" {
+		p, err := config.Profile(*profileFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		profile = &p
+		packageNames = profile.Packages
+	}
+
+	linker.Vars, err = templateVars(config, profile, *varsFlag, *varFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if action != actionUnlink {
+		if err := askVars(linker, packageNames, *nonInteractiveFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	if err := openSudoScript(linker); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
 	}
 
 	// Handle dry run mode
 	if *noopFlag {
-		simulateAction(linker, action, packageNames)
-		os.Exit(0)
+		simulateAction(linker, out, action, packageNames)
+		if err := closeSudoScript(linker); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		os.Exit(exitOK)
 	}
 
+	// Keep other runs from changing the target at the same time
+	unlock, err := lockTarget(linker, *waitFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	defer unlock()
+
 	// Perform the actual action
-	fmt.Printf("Performing action '%s' for packages %v...\n", action, packageNames)
+	out.infof("Performing action '%s' for packages %v...\n", action, packageNames)
 
-	err = performAction(linker, action, packageNames)
+	entry := newHistoryEntry(action, packageNames)
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	var result *gslk.Result
+	if profile != nil {
+		result, err = performProfileAction(linker, out, action, *profile)
+	} else {
+		result, err = performAction(linker, out, action, packageNames)
+	}
+	out.endProgress()
+	if closeErr := closeSudoScript(linker); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+	if !*noHistoryFlag {
+		saveHistory(linker, *historyFlag, entry, applyError(result, err))
+	}
 	if err != nil {
+		out.summary(result, err)
 		fmt.Fprintf(os.Stderr, "Error performing %s action: %v\n", action, err)
-		os.Exit(1)
+		os.Exit(exitCode(applyError(result, err)))
 	}
 
-	fmt.Printf("Action '%s' completed successfully for packages %v.\n", action, packageNames)
+	recordGeneration(linker)
+	out.infof("Action '%s' completed successfully for packages %v.\n", action, packageNames)
+	out.summaryf("Summary: %s\n", result)
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"path/filepath"
+)
+
+// runMigrate converts a dotfiles repository managed by another tool into
+// gslk packages.
+func runMigrate(args []string) error {
+	fs := newCommandFlags("migrate", "[options] --from stow|chezmoi|yadm <dir>")
+	from := fs.String("from", "", "Layout of the repository: stow, chezmoi or yadm.")
+	to := fs.String("to", "", "Write the packages to `directory`, which must not exist or be empty (default: <dir>-gslk).")
+	pkgName := fs.String("package", gslk.DefaultMigratePackage, "Package `name` for the files of chezmoi and yadm repositories.")
+	fs.Parse(args)
+	if fs.NArg() != 1 || *from == "" {
+		fs.Usage()
+		return fmt.Errorf("migrate takes --from and the directory of the repository")
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+
+	srcDir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %s: %w", fs.Arg(0), err)
+	}
+	destDir := *to
+	if destDir == "" {
+		destDir = srcDir + "-gslk"
+	}
+
+	migration := &gslk.Migration{From: gslk.MigrateFormat(*from), Package: *pkgName, DryRun: *fs.dryRun}
+	report, err := migration.Run(srcDir, destDir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range report.Files {
+		if file.From == "" {
+			out.infof("  %s (new)\n", file.To)
+		} else {
+			out.infof("  %s <- %s\n", file.To, file.From)
+		}
+	}
+	verb := "Migrated"
+	if migration.DryRun {
+		verb = "Would migrate"
+	}
+	out.summaryf("%s %d files into packages %v in %s\n", verb, len(report.Files), report.Packages, destDir)
+	if len(report.Untranslated) > 0 {
+		fmt.Fprintf(os.Stderr, "Not translated:\n")
+		for _, note := range report.Untranslated {
+			fmt.Fprintf(os.Stderr, "  %s\n", note)
+		}
+	}
+	if !migration.DryRun {
+		config := filepath.Join(destDir, report.Config)
+		out.summaryf("Review %s, then link the packages with: gslk apply --config %s -s %s\n", config, config, destDir)
+	}
+	return nil
+}
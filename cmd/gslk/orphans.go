@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"slices"
+)
+
+// runScanOrphans walks the target tree for links into the source directories
+// that no current package file accounts for. It fails when some are found.
+func runScanOrphans(args []string) error {
+	fs := newCommandFlags("scan-orphans", "[options]")
+	maxDepth := fs.Int("max-depth", 0, "Do not enter directories more than `levels` below the target directory (0: no limit).")
+	ignore := stringListFlag(fs.FlagSet, "ignore", "Skip target paths matching `pattern` (.gslk-ignore syntax), in addition to .git, .cache, node_modules and gslk's own directories. May be repeated.")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("scan-orphans takes no arguments")
+	}
+	if *maxDepth < 0 {
+		return fmt.Errorf("invalid --max-depth %d: must not be negative", *maxDepth)
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	findings, err := linker.ScanOrphans(*maxDepth, slices.Concat(gslk.DefaultScanIgnore, *ignore))
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Println("No orphaned links found.")
+		return nil
+	}
+
+	for _, f := range findings {
+		fmt.Printf("[%s] %s\n", f.Kind, f.Path)
+		fmt.Printf("    %s\n", f.Message)
+		fmt.Printf("    fix: %s\n", f.Fix)
+	}
+	return fmt.Errorf("found %d orphaned link(s)", len(findings))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verbosity levels of the command line output.
+const (
+	verbosityQuiet   = -1 // --quiet: errors only
+	verbosityDefault = 0  // Warnings and summaries
+	verbosityFiles   = 1  // -v: what is done to each file
+	verbosityTrace   = 2  // -vv: why it is done
+)
+
+// ANSI escape sequences used to color output.
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorFaint  = "\033[2m"
+)
+
+// output prints progress messages as far as the chosen verbosity asks for,
+// coloring them when writing to a terminal. It is the Logger of the Linkers
+// built by the command line.
+type output struct {
+	w         io.Writer
+	verbosity int
+	color     bool
+
+	mu       sync.Mutex // Keeps messages and the progress line apart
+	progress io.Writer  // Where the progress line is drawn; nil for none
+	drawn    time.Time  // When the progress line was last drawn
+}
+
+// newOutput creates the output for the --quiet, -v, -vv and --no-color flags.
+func newOutput(quiet, verbose, veryVerbose, noColor bool) (*output, error) {
+	out := &output{w: os.Stdout, verbosity: verbosityDefault}
+	switch {
+	case quiet && (verbose || veryVerbose):
+		return nil, fmt.Errorf("--quiet cannot be combined with -v or -vv")
+	case quiet:
+		out.verbosity = verbosityQuiet
+	case veryVerbose:
+		out.verbosity = verbosityTrace
+	case verbose:
+		out.verbosity = verbosityFiles
+	}
+	out.color = !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	return out, nil
+}
+
+// isTerminal reports whether f is a terminal that understands escape
+// sequences. TERM=dumb says it does not; the Windows console needs them
+// enabled explicitly, so they are left off there.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" || runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Printf prints a message about a file, shown from -v on.
+func (o *output) Printf(format string, args ...any) {
+	o.Logf(gslk.LevelInfo, format, args...)
+}
+
+// Logf prints a message of the given level if the verbosity allows it.
+func (o *output) Logf(level gslk.Level, format string, args ...any) {
+	switch level {
+	case gslk.LevelWarn:
+		if o.verbosity < verbosityDefault {
+			return
+		}
+	case gslk.LevelInfo:
+		if o.verbosity < verbosityFiles {
+			return
+		}
+	default:
+		if o.verbosity < verbosityTrace {
+			return
+		}
+	}
+	o.print(level, fmt.Sprintf(format, args...))
+}
+
+// infof prints a message shown from -v on.
+func (o *output) infof(format string, args ...any) {
+	o.Logf(gslk.LevelInfo, format, args...)
+}
+
+// summary prints the summary of result, unless err failed the run before
+// anything was applied: "0 conflicts" would then only mislead.
+func (o *output) summary(result *gslk.Result, err error) {
+	if err != nil && result.Empty() {
+		return
+	}
+	o.summaryf("Summary: %s\n", result)
+}
+
+// summaryf prints a message shown unless --quiet is given.
+func (o *output) summaryf(format string, args ...any) {
+	if o.verbosity >= verbosityDefault {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.clearProgress()
+		fmt.Fprintf(o.w, format, args...)
+	}
+}
+
+// print writes msg in the color of its level and kind.
+func (o *output) print(level gslk.Level, msg string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.clearProgress()
+
+	color := messageColor(level, msg)
+	if !o.color || color == "" {
+		fmt.Fprint(o.w, msg)
+		return
+	}
+	text, newline := strings.CutSuffix(msg, "\n")
+	fmt.Fprint(o.w, color, text, colorReset)
+	if newline {
+		fmt.Fprintln(o.w)
+	}
+}
+
+// messageColor returns the color of a message: green for files created, red
+// for files removed, yellow for conflicts and warnings and faint for traces.
+func messageColor(level gslk.Level, msg string) string {
+	switch level {
+	case gslk.LevelWarn:
+		return colorYellow
+	case gslk.LevelDebug:
+		return colorFaint
+	}
+	for _, prefix := range []string{"Quarantining", "Kept non-empty", "Skipped non-empty"} {
+		if strings.HasPrefix(msg, prefix) {
+			return colorYellow
+		}
+	}
+	for _, prefix := range []string{"Unlinking", "Removing", "Removed", "Moving to trash", "Emptying trash", "Discarding"} {
+		if strings.HasPrefix(msg, prefix) {
+			return colorRed
+		}
+	}
+	for _, prefix := range []string{"Linking", "Copying", "Rendering", "Decrypting", "Replacing", "Updating", "Restoring", "Merging", "Repairing"} {
+		if strings.HasPrefix(msg, prefix) {
+			return colorGreen
+		}
+	}
+	return ""
+}
+
+// progressWidth is the width of the progress line, in characters.
+const progressWidth = 79
+
+// progressInterval is the minimum time between two redraws of the progress line.
+const progressInterval = 100 * time.Millisecond
+
+// showProgress draws a progress line on standard error while plans are
+// applied, if it is a terminal and only warnings and summaries are printed.
+func (o *output) showProgress() {
+	if o.verbosity == verbosityDefault && isTerminal(os.Stderr) {
+		o.progress = os.Stderr
+	}
+}
+
+// update redraws the progress line for event, at most every progressInterval.
+// It is the event handler of the Linker.
+func (o *output) update(event gslk.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.progress == nil || time.Since(o.drawn) < progressInterval {
+		return
+	}
+	o.drawn = time.Now()
+
+	var line string
+	switch {
+	case event.Type == gslk.EventPlanned:
+		line = fmt.Sprintf("Planning: %d files ", event.Done)
+	case event.Total > 0:
+		const barWidth = 20
+		filled := barWidth * event.Done / event.Total
+		line = fmt.Sprintf("[%s%s] %d/%d ", strings.Repeat("#", filled), strings.Repeat("-", barWidth-filled), event.Done, event.Total)
+	default:
+		return
+	}
+	if room := progressWidth - len(line); room > 3 {
+		target := event.Target
+		if len(target) > room {
+			target = "..." + target[len(target)-room+3:]
+		}
+		line += target
+	}
+	fmt.Fprint(o.progress, "\r\033[K", line)
+}
+
+// endProgress removes the progress line once the work is done.
+func (o *output) endProgress() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.clearProgress()
+}
+
+// clearProgress erases the progress line, if drawn, before other output.
+// o.mu must be held.
+func (o *output) clearProgress() {
+	if o.progress != nil && !o.drawn.IsZero() {
+		fmt.Fprint(o.progress, "\r\033[K")
+		o.drawn = time.Time{}
+	}
+}
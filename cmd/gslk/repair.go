@@ -0,0 +1,36 @@
+package main
+
+// runRepair points links back into the source directories after they moved.
+func runRepair(args []string) error {
+	fs := newCommandFlags("repair", "[options] [package...]")
+	oldSources := stringListFlag(fs.FlagSet, "old-source", "Previous location of a source `directory`. May be repeated. Without it, broken links whose destination ends in the package file's path are repaired.")
+	fs.Parse(args)
+
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("repair", fs.Args())
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	result, err := linker.Repair(fs.Args(), *oldSources...)
+	out.summary(result, err)
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	if err == nil {
+		recordGeneration(linker)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+)
+
+// runReport writes a report of the packages, their files and the recent
+// changes to the target, documenting the configuration of the machine.
+func runReport(args []string) error {
+	fs := newCommandFlags("report", "[options]")
+	format := fs.String("format", string(gslk.ReportMarkdown), "Report `format`: md (Markdown) or html.")
+	output := fs.String("o", "", "Write the report to `file` instead of standard output.")
+	historyPath := fs.String("history", "", "Audit log `file` listing the recent changes (default: "+gslk.HistoryFileName+" in the user state directory).")
+	last := fs.Int("last", 10, "Show the last `n` runs that changed the target; 0 shows none, -1 all.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("report takes no arguments")
+	}
+	reportFormat, err := gslk.ParseReportFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	if *historyPath == "" {
+		if *historyPath, err = gslk.DefaultHistoryPath(); err != nil {
+			return fmt.Errorf("could not locate the history: %w", err)
+		}
+	}
+	history, err := gslk.ReadHistory(*historyPath)
+	if err != nil {
+		return err
+	}
+	report, err := linker.Report(history, *last)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return report.Write(os.Stdout, reportFormat)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := report.Write(f, reportFormat); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report %s: %w", *output, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"gslk"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runReview walks through quarantined conflict files, showing a diff against the
+// package file and letting the user keep, take or merge the saved version.
+func runReview(args []string) error {
+	fs := newCommandFlags("review", "[options] [package...]")
+	fs.Parse(args)
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := linker.Quarantined(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("Nothing to review.")
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for i, entry := range entries {
+		fmt.Printf("\n[%d/%d] %s (package %s)\n", i+1, len(entries), entry.Target, entry.Package)
+		fmt.Printf("Saved as %s on %s\n", entry.Saved, entry.Time.Format(time.DateTime))
+
+		saved, err := os.ReadFile(entry.Saved)
+		if err != nil {
+			return fmt.Errorf("failed to read saved file %s: %w", entry.Saved, err)
+		}
+		current, err := os.ReadFile(entry.Source)
+		if err != nil {
+			return fmt.Errorf("failed to read package file %s: %w", entry.Source, err)
+		}
+
+		printReviewDiff(entry, current, saved)
+
+		quit, err := reviewEntry(linker, reader, entry, current, saved)
+		if err != nil {
+			return err
+		}
+		if quit {
+			break
+		}
+	}
+
+	return nil
+}
+
+// printReviewDiff shows how the saved file differs from the package file.
+func printReviewDiff(entry gslk.QuarantineEntry, current, saved []byte) {
+	switch {
+	case bytes.Equal(current, saved):
+		fmt.Println("Saved file is identical to the package file.")
+	case bytes.IndexByte(current, 0) >= 0 || bytes.IndexByte(saved, 0) >= 0:
+		fmt.Println("Binary files differ.")
+	default:
+		fmt.Print(gslk.UnifiedDiff(entry.Source, entry.Saved, string(current), string(saved)))
+	}
+}
+
+// reviewEntry prompts for a decision on a single quarantined file and applies it.
+// It reports whether the user asked to stop reviewing.
+func reviewEntry(linker *gslk.Linker, reader *bufio.Reader, entry gslk.QuarantineEntry, current, saved []byte) (bool, error) {
+	for {
+		fmt.Print("[k]eep package version, [t]ake saved version, [m]erge in editor, [s]kip, [q]uit? ")
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			// Input closed, leave the remaining files for a later review
+			fmt.Println()
+			return true, nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "k":
+			return false, linker.DiscardQuarantined(entry)
+		case "t":
+			return false, linker.MergeQuarantined(entry, saved)
+		case "m":
+			merged, err := mergeInEditor(entry, current, saved)
+			if err != nil {
+				return false, err
+			}
+			return false, linker.MergeQuarantined(entry, merged)
+		case "s":
+			return false, nil
+		case "q":
+			return true, nil
+		}
+	}
+}
+
+// mergeInEditor opens $VISUAL or $EDITOR on a file containing both versions
+// separated by conflict markers and returns the edited result.
+func mergeInEditor(entry gslk.QuarantineEntry, current, saved []byte) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "gslk-merge-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<<<<<<< package %s\n", entry.Source)
+	buf.Write(ensureTrailingNewline(current))
+	buf.WriteString("=======\n")
+	buf.Write(ensureTrailingNewline(saved))
+	fmt.Fprintf(&buf, ">>>>>>> saved %s\n", entry.Saved)
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write merge file: %w", err)
+	}
+	tmp.Close()
+
+	if err := editFile(tmp.Name()); err != nil {
+		return nil, err
+	}
+
+	merged, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merge result: %w", err)
+	}
+	if bytes.Contains(merged, []byte("<<<<<<< ")) || bytes.Contains(merged, []byte(">>>>>>> ")) {
+		return nil, fmt.Errorf("merge result for %s still contains conflict markers", entry.Source)
+	}
+	return merged, nil
+}
+
+// editFile opens path in $VISUAL or $EDITOR (vi by default) and waits for the editor to exit.
+func editFile(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor setting may carry arguments, e.g. "code --wait"
+	editorArgs := append(strings.Fields(editor), path)
+	cmd := exec.Command(editorArgs[0], editorArgs[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	return nil
+}
+
+// ensureTrailingNewline returns data terminated by a newline.
+func ensureTrailingNewline(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		return append(data, '\n')
+	}
+	return data
+}
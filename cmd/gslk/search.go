@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runSearch prints the package files matching the given patterns, with the
+// package providing them and where they are linked.
+func runSearch(args []string) error {
+	fs := newCommandFlags("search", "[options] <pattern>...")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no pattern specified")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	results, err := linker.Search(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no package file matches %s", strings.Join(fs.Args(), " or "))
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Ignored:
+			fmt.Printf("%s: %s (ignored)\n", result.Package, result.RelPath)
+		default:
+			fmt.Printf("%s: %s -> %s\n", result.Package, result.RelPath, result.Target)
+		}
+		if *fs.verbose {
+			fmt.Printf("    source: %s\n", result.Source)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"gslk"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runSecret dispatches the secret subcommands.
+func runSecret(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: gslk secret add|edit [options] <package> ...")
+	}
+
+	switch args[0] {
+	case "add":
+		return runSecretAdd(args[1:])
+	case "edit":
+		return runSecretEdit(args[1:])
+	default:
+		return fmt.Errorf("unknown secret command %q: must be add or edit", args[0])
+	}
+}
+
+// runSecretAdd encrypts a plaintext file into a package.
+func runSecretAdd(args []string) error {
+	fs := newCommandFlags("secret add", "[options] <package> <file> [<path in package>]")
+	remove := fs.Bool("rm", false, "Remove the plaintext file once it is encrypted.")
+	fs.Parse(args)
+
+	if fs.NArg() < 2 || fs.NArg() > 3 {
+		fs.Usage()
+		return fmt.Errorf("expected a package, a file and optionally its path in the package")
+	}
+	pkgName, plaintextPath := fs.Arg(0), fs.Arg(1)
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	relPath := fs.Arg(2)
+	if relPath == "" {
+		relPath, err = defaultSecretPath(linker, plaintextPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := os.ReadFile(plaintextPath)
+	if err != nil {
+		return err
+	}
+
+	secretPath := linker.SecretPath(pkgName, relPath)
+	if *fs.dryRun {
+		fmt.Printf("DRY RUN: Would encrypt %s to %s\n", plaintextPath, secretPath)
+		return nil
+	}
+	if err := linker.Age.EncryptFile(secretPath, plaintext); err != nil {
+		return err
+	}
+	fmt.Printf("Encrypted %s to %s\n", plaintextPath, secretPath)
+
+	if *remove {
+		if err := os.Remove(plaintextPath); err != nil {
+			return fmt.Errorf("failed to remove plaintext %s: %w", plaintextPath, err)
+		}
+		fmt.Printf("Removed plaintext %s\n", plaintextPath)
+	}
+	return nil
+}
+
+// defaultSecretPath returns the package path for a plaintext file: its path
+// relative to the target directory if it lies inside it (so ~/.ssh/config
+// becomes .ssh/config), or its base name otherwise.
+func defaultSecretPath(linker *gslk.Linker, plaintextPath string) (string, error) {
+	absPath, err := filepath.Abs(plaintextPath)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(linker.TargetDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(absPath), nil
+	}
+	return rel, nil
+}
+
+// runSecretEdit decrypts a secret into a private temporary file, opens it in
+// the editor and encrypts the result back into the package.
+func runSecretEdit(args []string) error {
+	fs := newCommandFlags("secret edit", "[options] <package> <path in package>")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected a package and the path of the secret in it")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	secretPath := linker.SecretPath(fs.Arg(0), fs.Arg(1))
+	plaintext, err := linker.Age.Decrypt(secretPath)
+	if err != nil {
+		return err
+	}
+
+	// The temporary directory is only accessible by the current user
+	tmpDir, err := os.MkdirTemp("", "gslk-secret-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := strings.TrimSuffix(filepath.Base(secretPath), gslk.DefaultSecretSuffix)
+	tmpPath := filepath.Join(tmpDir, name)
+	if err := os.WriteFile(tmpPath, plaintext, 0600); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := editFile(tmpPath); err != nil {
+		return err
+	}
+
+	edited, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to read edited secret: %w", err)
+	}
+	if bytes.Equal(edited, plaintext) {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	if *fs.dryRun {
+		fmt.Printf("DRY RUN: Would re-encrypt %s\n", secretPath)
+		return nil
+	}
+	if err := linker.Age.EncryptFile(secretPath, edited); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s\n", secretPath)
+	return nil
+}
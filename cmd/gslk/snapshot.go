@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"path/filepath"
+	"time"
+)
+
+// runSnapshot records the managed portion of the target for gslk drift.
+func runSnapshot(args []string) error {
+	fs := newCommandFlags("snapshot", "[options]")
+	file := fs.String("o", "", "Write the snapshot to `file` (default: "+gslk.SnapshotFileName+" in the target directory).")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("snapshot takes no arguments")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	snapshot, err := linker.TakeSnapshot()
+	if err != nil {
+		return err
+	}
+	path := snapshotPath(linker, *file)
+	if linker.DryRun {
+		fmt.Printf("Would write a snapshot of %d files to %s\n", len(snapshot.Files), path)
+		return nil
+	}
+	if err := linker.SaveSnapshot(snapshot, path); err != nil {
+		return err
+	}
+	out.summaryf("Recorded %d files and %d directories in %s\n", len(snapshot.Files), len(snapshot.Dirs), path)
+	return nil
+}
+
+// runDrift compares the target with the snapshot taken by gslk snapshot.
+// Like diff, it fails when the target changed so it can be used in scripts.
+func runDrift(args []string) error {
+	fs := newCommandFlags("drift", "[options]")
+	file := fs.String("snapshot", "", "Snapshot `file` to compare with (default: "+gslk.SnapshotFileName+" in the target directory).")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("drift takes no arguments")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	snapshot, err := gslk.LoadSnapshot(snapshotPath(linker, *file))
+	if err != nil {
+		return err
+	}
+	changes, err := snapshot.Compare()
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case gslk.SnapshotModified:
+			fmt.Printf("modified: %s (package %s): %s\n", change.Target, change.Package, change.Detail)
+		case gslk.SnapshotDeleted:
+			fmt.Printf("deleted:  %s (package %s)\n", change.Target, change.Package)
+		default:
+			fmt.Printf("foreign:  %s\n", change.Target)
+		}
+	}
+	taken := snapshot.Time.Local().Format(time.DateTime)
+	if len(changes) > 0 {
+		return fmt.Errorf("%d change(s) since the snapshot of %s", len(changes), taken)
+	}
+	out.summaryf("No changes since the snapshot of %s (%d files)\n", taken, len(snapshot.Files))
+	return nil
+}
+
+// snapshotPath returns file, or the default snapshot file of linker's target.
+func snapshotPath(linker *gslk.Linker, file string) string {
+	if file != "" {
+		return file
+	}
+	return filepath.Join(linker.TargetDir, gslk.SnapshotFileName)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"gslk"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// statsOrders compares package statistics for each --sort key, in the order
+// listed by default: names alphabetically, everything else largest or most
+// recent first.
+var statsOrders = map[string]func(a, b gslk.PackageStats) int{
+	"name":     func(a, b gslk.PackageStats) int { return strings.Compare(a.Package.Name, b.Package.Name) },
+	"files":    func(a, b gslk.PackageStats) int { return cmp.Compare(b.Files, a.Files) },
+	"size":     func(a, b gslk.PackageStats) int { return cmp.Compare(b.Size, a.Size) },
+	"links":    func(a, b gslk.PackageStats) int { return cmp.Compare(b.Links, a.Links) },
+	"broken":   func(a, b gslk.PackageStats) int { return cmp.Compare(b.Broken, a.Broken) },
+	"modified": func(a, b gslk.PackageStats) int { return b.Modified.Compare(a.Modified) },
+}
+
+// runStats prints the file count, size, deployment and last modification of
+// every package, or of those matching the given patterns.
+func runStats(args []string) error {
+	fs := newCommandFlags("stats", "[options] [pattern...]")
+	sortBy := fs.String("sort", "name", "Sort packages by `key`: name, files, size, links, broken or modified.")
+	reverse := fs.Bool("reverse", false, "Reverse the sort order.")
+	brokenOnly := fs.Bool("broken", false, "Only show packages with broken links.")
+	fs.Parse(args)
+
+	order, ok := statsOrders[*sortBy]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q: must be one of %s", *sortBy, strings.Join(slices.Sorted(maps.Keys(statsOrders)), ", "))
+	}
+	for _, pattern := range fs.Args() {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	stats, err := linker.Stats()
+	if err != nil {
+		return err
+	}
+
+	stats = slices.DeleteFunc(stats, func(s gslk.PackageStats) bool {
+		if *brokenOnly && s.Broken == 0 {
+			return true
+		}
+		for _, pattern := range fs.Args() {
+			if matched, _ := path.Match(pattern, s.Package.Name); matched {
+				return false
+			}
+		}
+		return fs.NArg() > 0
+	})
+	slices.SortStableFunc(stats, func(a, b gslk.PackageStats) int {
+		if *reverse {
+			return order(b, a)
+		}
+		return order(a, b)
+	})
+	if len(stats) == 0 {
+		fmt.Println("No matching packages.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tFILES\tSIZE\tLINKS\tCOPIES\tBROKEN\tMODIFIED")
+	var total gslk.PackageStats
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\t%s\n", s.Package.Name, s.Files, formatSize(s.Size), s.Links, s.Copies, s.Broken, formatModified(s.Modified))
+		total.Files += s.Files
+		total.Size += s.Size
+		total.Links += s.Links
+		total.Copies += s.Copies
+		total.Broken += s.Broken
+		if s.Modified.After(total.Modified) {
+			total.Modified = s.Modified
+		}
+	}
+	if len(stats) > 1 {
+		fmt.Fprintf(w, "TOTAL\t%d\t%s\t%d\t%d\t%d\t%s\n", total.Files, formatSize(total.Size), total.Links, total.Copies, total.Broken, formatModified(total.Modified))
+	}
+	return w.Flush()
+}
+
+// formatModified writes a modification time, or "-" for none.
+func formatModified(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"slices"
+	"strings"
+)
+
+// runSync pulls the source directories that are git checkouts and relinks
+// the deployed packages the incoming changes affect.
+func runSync(args []string) error {
+	fs := newCommandFlags("sync", "[options]")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("sync takes no arguments")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+
+	statuses, err := linker.Status()
+	if err != nil {
+		return err
+	}
+	deployed := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		deployed[status.Package.Name] = status.Status != gslk.StatusUnlinked
+	}
+
+	// Fetch every checkout first, so nothing changes unless all fetches work
+	var checkouts []*gslk.Git
+	var affected, removed []string
+	for _, dir := range append([]string{linker.SourceDir}, linker.ExtraSources...) {
+		git := &gslk.Git{Dir: dir}
+		if !git.IsRepo() {
+			out.infof("Skipping %s: not a git checkout\n", dir)
+			continue
+		}
+		if err := git.Fetch(); err != nil {
+			return err
+		}
+		changes, err := git.Incoming()
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			out.summaryf("%s is up to date\n", dir)
+			continue
+		}
+		checkouts = append(checkouts, git)
+
+		out.summaryf("Incoming changes in %s:\n", dir)
+		for _, change := range changes {
+			pkg, _, inPackage := strings.Cut(change.Path, "/")
+			note := ""
+			if inPackage && deployed[pkg] {
+				note = "  (deployed)"
+				if !slices.Contains(affected, pkg) {
+					affected = append(affected, pkg)
+					// A package deleted upstream has to be unlinked while its files still exist
+					if !git.HasPath("@{upstream}", pkg) {
+						removed = append(removed, pkg)
+					}
+				}
+			}
+			out.summaryf("  %s %s%s\n", change.Status, change.Path, note)
+		}
+	}
+	if len(checkouts) == 0 {
+		return nil
+	}
+
+	if linker.DryRun {
+		fmt.Printf("Would pull and relink packages %v\n", affected)
+		return nil
+	}
+
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := newHistoryEntry("sync", affected)
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	result, err := pullAndRelink(linker, out, checkouts, affected, removed)
+	out.summary(result, err)
+	err = applyError(result, err)
+	saveHistory(linker, "", entry, err)
+	if err == nil {
+		recordGeneration(linker)
+	}
+	return err
+}
+
+// pullAndRelink unlinks the packages removed upstream, pulls the checkouts,
+// then relinks the affected packages that still exist.
+func pullAndRelink(linker *gslk.Linker, out *output, checkouts []*gslk.Git, affected, removed []string) (*gslk.Result, error) {
+	result := &gslk.Result{}
+	if len(removed) > 0 {
+		out.infof("Unlinking packages removed upstream %v\n", removed)
+		unlinked, err := linker.Unlink(removed)
+		result.Merge(unlinked)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for _, git := range checkouts {
+		out.infof("Pulling %s\n", git.Dir)
+		if err := git.Pull(); err != nil {
+			return result, err
+		}
+	}
+
+	// A package removed from one source may still exist in another
+	packages, err := linker.FindPackages()
+	if err != nil {
+		return result, err
+	}
+	var relink []string
+	for _, name := range affected {
+		if slices.ContainsFunc(packages, func(pkg gslk.Package) bool { return pkg.Name == name }) {
+			relink = append(relink, name)
+		}
+	}
+	if len(relink) == 0 {
+		return result, nil
+	}
+	out.infof("Relinking packages %v\n", relink)
+	relinked, err := linker.Relink(relink)
+	result.Merge(relinked)
+	return result, err
+}
@@ -0,0 +1,45 @@
+package main
+
+import "fmt"
+
+// runTeardown removes what --ephemeral runs deployed into the target, as
+// recorded in its teardown manifest.
+func runTeardown(args []string) error {
+	fs := newCommandFlags("teardown", "[options]")
+	force := fs.Bool("f", false, "Also remove files edited since they were deployed, moving them to the trash (see gslk trash).")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("teardown takes no arguments")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	unlock, err := fs.lock(linker)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	linker.ForceRemove = *force
+
+	entry := newHistoryEntry("teardown", nil)
+	linker.OnEvent = chainEvents(linker.OnEvent, entry.Record)
+
+	result, err := linker.Teardown()
+	if err == nil {
+		out.summaryf("Tore down %s. Summary: %s\n", linker.TargetDir, result)
+	} else {
+		out.summary(result, err)
+	}
+	err = applyError(result, err)
+	if !linker.DryRun {
+		saveHistory(linker, "", entry, err)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"os"
+	"path/filepath"
+)
+
+// runTestApply links packages into a temporary directory instead of the
+// target and prints the resulting tree, to validate packages and templates
+// without touching the target.
+func runTestApply(args []string) error {
+	fs := newCommandFlags("test-apply", "[options] [package...]")
+	file := fs.String("file", "", "Desired-state `file` listing the packages to apply (default: as for apply), unless packages are given.")
+	mode := fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for files: link (symlink) or copy.")
+	dir := fs.String("dir", "", "Apply into `directory`, which must be empty or not exist, and keep it (default: a temporary directory, removed afterwards).")
+	fs.Parse(args)
+	if err := validateModeFlags(mode, deployModes, nil); err != nil {
+		return err
+	}
+
+	config, err := loadConfig(*fs.config)
+	if err != nil {
+		return err
+	}
+	packages := fs.Args()
+	if len(packages) == 0 {
+		if packages, err = desiredPackages(config, *file); err != nil {
+			return err
+		}
+	}
+	linker, err := fs.linkerFor(config, *fs.sources)
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*mode)
+	if err := askVars(linker, packages, *fs.batch); err != nil {
+		return err
+	}
+
+	sandboxDir := *dir
+	if sandboxDir == "" {
+		if sandboxDir, err = os.MkdirTemp("", "gslk-test-apply-"); err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(sandboxDir)
+	} else if entries, err := os.ReadDir(sandboxDir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", sandboxDir)
+	} else if err := os.MkdirAll(sandboxDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", sandboxDir, err)
+	}
+
+	out.infof("Applying packages %v into %s, standing in for %s\n", packages, sandboxDir, linker.TargetDir)
+	result, err := linker.Sandbox(sandboxDir).Link(packages)
+	if err != nil {
+		out.summary(result, err)
+		return err
+	}
+
+	tree, err := gslk.ListTree(sandboxDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range tree {
+		path := filepath.Join(linker.TargetDir, entry.Path)
+		switch {
+		case entry.Mode.IsDir():
+			fmt.Printf("%s%c\n", path, filepath.Separator)
+		case entry.Link != "":
+			fmt.Printf("%s -> %s\n", path, entry.Link)
+		default:
+			fmt.Printf("%s (%s, %04o)\n", path, formatSize(entry.Size), entry.Mode.Perm())
+		}
+	}
+	out.summaryf("Summary: %s\n", result)
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+	"path/filepath"
+	"time"
+)
+
+// runTrash lists what -f moved to the trash, restores it or empties the trash.
+func runTrash(args []string) error {
+	fs := newCommandFlags("trash", "[options] [list | restore [path...] | empty]")
+	fs.Parse(args)
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	action := "list"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+
+	switch action {
+	case "list":
+		if fs.NArg() > 1 {
+			return fmt.Errorf("trash list takes no arguments")
+		}
+		return listTrash(linker)
+	case "restore":
+		unlock, err := fs.lock(linker)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return restoreTrash(linker, fs.Args()[1:])
+	case "empty":
+		if fs.NArg() > 1 {
+			return fmt.Errorf("trash empty takes no arguments")
+		}
+		unlock, err := fs.lock(linker)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return linker.EmptyTrash()
+	default:
+		return fmt.Errorf("unknown trash command %q: must be list, restore or empty", action)
+	}
+}
+
+// listTrash prints the trashed files and directories.
+func listTrash(linker *gslk.Linker) error {
+	entries, err := linker.Trashed()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("Trash is empty.")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s  %s (package %s)\n", entry.Time.Format(time.DateTime), entry.Path, entry.Package)
+	}
+	return nil
+}
+
+// restoreTrash restores the trashed entries removed from paths, or all of them
+// if no path is given. Paths trashed several times are restored from the most
+// recent entry.
+func restoreTrash(linker *gslk.Linker, paths []string) error {
+	entries, err := linker.Trashed()
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		restored := make(map[string]bool)
+		for i := len(entries) - 1; i >= 0; i-- {
+			if restored[entries[i].Path] {
+				continue // Older copies stay in the trash
+			}
+			if err := linker.RestoreTrashed(entries[i]); err != nil {
+				return err
+			}
+			restored[entries[i].Path] = true
+		}
+		return nil
+	}
+
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Path == absPath {
+				if err := linker.RestoreTrashed(entries[i]); err != nil {
+					return err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is not in the trash", absPath)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"gslk"
+)
+
+// runVerify checks deployed copies, templates and secrets against the
+// checksums recorded when they were deployed. Like diff, it fails when a file
+// no longer matches so it can be used in scripts.
+func runVerify(args []string) error {
+	fs := newCommandFlags("verify", "[options] [package...]")
+	fs.Parse(args)
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	results, err := linker.Verify(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var edited, updated, missing, failed []gslk.Verification
+	problems := 0
+	for _, v := range results {
+		if v.OK() {
+			out.infof("ok: %s (%s of package %s)\n", v.Target, v.Mode, v.Package)
+			continue
+		}
+		problems++
+		switch {
+		case v.TargetStatus == gslk.ChecksumMissing || v.SourceStatus == gslk.ChecksumMissing:
+			missing = append(missing, v)
+			continue
+		case v.TargetStatus == gslk.ChecksumChanged:
+			edited = append(edited, v)
+		}
+		switch {
+		case v.SourceStatus == gslk.ChecksumChanged:
+			updated = append(updated, v)
+		case v.Err != nil:
+			failed = append(failed, v)
+		}
+	}
+
+	printVerifications("Edited in the target since they were deployed (see gslk diff):", edited, func(v gslk.Verification) string {
+		return fmt.Sprintf("%s (%s of package %s)", v.Target, v.Mode, v.Package)
+	})
+	printVerifications("Updated in the package, not deployed yet (relink with -R):", updated, func(v gslk.Verification) string {
+		return fmt.Sprintf("%s (from %s)", v.Target, v.Source)
+	})
+	printVerifications("Missing:", missing, func(v gslk.Verification) string {
+		if v.TargetStatus == gslk.ChecksumMissing {
+			return fmt.Sprintf("%s: the deployed %s is gone or replaced", v.Target, v.Mode)
+		}
+		return fmt.Sprintf("%s: its package file %s is gone", v.Target, v.Source)
+	})
+	printVerifications("Could not be checked:", failed, func(v gslk.Verification) string {
+		return fmt.Sprintf("%s: %v", v.Target, v.Err)
+	})
+
+	out.summaryf("Verified %d files: %d edited in the target, %d updated in the package, %d missing\n", len(results), len(edited), len(updated), len(missing))
+	if problems > 0 {
+		return fmt.Errorf("%d file(s) no longer match their recorded checksums", problems)
+	}
+	return nil
+}
+
+// printVerifications prints the title and a line per verification, if any.
+func printVerifications(title string, list []gslk.Verification, line func(gslk.Verification) string) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Println(title)
+	for _, v := range list {
+		fmt.Printf("  %s\n", line(v))
+	}
+}
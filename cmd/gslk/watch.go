@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gslk"
+	"os"
+	"os/signal"
+)
+
+// runWatch relinks packages whenever their files change, until interrupted.
+func runWatch(args []string) error {
+	fs := newCommandFlags("watch", "[options] <package>...")
+	mode := fs.String("mode", string(gslk.ModeLink), "Deployment `mode` for files: link (symlink) or copy.")
+	onConflict := fs.String("on-conflict", string(gslk.ConflictFail), "What to do with existing files in the way: fail, backup or adopt-identical.")
+	debounce := fs.Duration("debounce", gslk.DefaultWatchDebounce, "How long the packages must stay unchanged before they are relinked.")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no packages to watch")
+	}
+	if err := validateModeFlags(mode, deployModes, onConflict); err != nil {
+		return err
+	}
+	packages := fs.Args()
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+	out, err := fs.output()
+	if err != nil {
+		return err
+	}
+	linker.Mode = gslk.DeployMode(*mode)
+	linker.ConflictPolicy = gslk.ConflictPolicy(*onConflict)
+
+	// Every relink is a run of its own in the audit log
+	entry := newHistoryEntry("watch", packages)
+	linker.OnEvent = chainEvents(linker.OnEvent, func(event gslk.Event) { entry.Record(event) })
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out.infof("Watching packages %v, press Ctrl-C to stop\n", packages)
+	return linker.Watch(ctx, packages, gslk.WatchOptions{
+		Debounce: *debounce,
+		Lock: func() (func(), error) {
+			entry = newHistoryEntry("watch", packages)
+			return fs.lock(linker)
+		},
+		OnRelink: func(result *gslk.Result, err error) {
+			out.summary(result, err)
+			err = applyError(result, err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			if linker.DryRun {
+				return
+			}
+			saveHistory(linker, "", entry, err)
+			if err == nil {
+				recordGeneration(linker)
+			}
+		},
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+)
+
+// runWhich prints the package and source file each target path comes from.
+func runWhich(args []string) error {
+	fs := newCommandFlags("which", "[options] <path>...")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no path specified")
+	}
+
+	linker, err := fs.linker()
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, path := range fs.Args() {
+		owner, err := linker.Owner(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("%s: package %s, file %s (%s)\n", owner.Target, owner.Package, owner.RelPath, owner.Mode)
+		if *fs.verbose {
+			fmt.Printf("    source: %s\n", owner.Source)
+		}
+		if owner.Missing {
+			fmt.Printf("    warning: source file %s no longer exists\n", owner.Source)
+		}
+		if owner.Drifted {
+			fmt.Printf("    warning: modified since it was deployed\n")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d path(s) are not managed by gslk", failed)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// packageLanes splits packages into lanes that may be planned in parallel:
+// packages depending on one another, or whose top-level target paths are the
+// same or nested, share a lane. Each lane lists the indexes of its packages in
+// their order in packages, and the lanes are ordered by their first package.
+func (l *Linker) packageLanes(packages []Package) [][]int {
+	parent := make([]int, len(packages))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if a, b := find(i), find(j); a != b {
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	byName := make(map[string]int, len(packages))
+	roots := make([][]string, len(packages))
+	for i, pkg := range packages {
+		if j, ok := byName[pkg.Name]; ok {
+			union(i, j)
+		}
+		byName[pkg.Name] = i
+		roots[i] = l.targetRoots(pkg)
+	}
+	for i, pkg := range packages {
+		for _, dep := range pkg.Manifest.Depends {
+			if j, ok := byName[dep]; ok {
+				union(i, j)
+			}
+		}
+		for j := range i {
+			if find(i) != find(j) && rootsOverlap(roots[i], roots[j]) {
+				union(i, j)
+			}
+		}
+	}
+
+	var lanes [][]int
+	lane := make(map[int]int)
+	for i := range packages {
+		root := find(i)
+		n, ok := lane[root]
+		if !ok {
+			n = len(lanes)
+			lane[root] = n
+			lanes = append(lanes, nil)
+		}
+		lanes[n] = append(lanes[n], i)
+	}
+	return lanes
+}
+
+// targetRoots returns the target paths of the top-level entries of pkg and of
+// the paths its renames move elsewhere. A nil result means the targets of pkg
+// cannot be told in advance, as with a PathMapper, and may overlap anything.
+func (l *Linker) targetRoots(pkg Package) []string {
+	if l.PathMapper != nil {
+		return nil
+	}
+	var roots []string
+	if pkg.Subpath != "" {
+		roots = append(roots, l.targetPath(pkg, stripAlternates(pkg.Subpath)))
+	} else {
+		for _, layer := range pkg.Layers {
+			entries, err := os.ReadDir(layer)
+			if err != nil {
+				return nil
+			}
+			for _, entry := range entries {
+				if !isControlFile(entry.Name()) {
+					roots = append(roots, l.targetPath(pkg, stripAlternates(entry.Name())))
+				}
+			}
+		}
+	}
+	mapper := l.mapper(pkg)
+	for _, renamed := range mapper.Renames {
+		roots = append(roots, filepath.Join(l.TargetDir, pkg.Manifest.Target, filepath.FromSlash(renamed)))
+	}
+	if slices.Contains(roots, l.TargetDir) {
+		return nil
+	}
+	return roots
+}
+
+// rootsOverlap reports whether a target path in a is the same as, or nested
+// in, one in b. Unknown roots overlap everything.
+func rootsOverlap(a, b []string) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y || isSubPath(x, y) || isSubPath(y, x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// plannedPackage is what planning one package in parallel produced, kept
+// until the packages before it are done.
+type plannedPackage struct {
+	plan   Plan
+	logs   bufferedLogger
+	events []Event
+	err    error
+}
+
+// planLinkParallel plans packages like planLink, planning up to Concurrency
+// lanes (see packageLanes) at once. Messages, events and operations are
+// reported in package order, as if the packages were planned one at a time,
+// and the error of the first failing package is returned. The packages after
+// it may have been planned already, but nothing is reported for them.
+func (l *Linker) planLinkParallel(span Span, packages []Package, state *State, mode DeployMode, providers map[string]string) (*Plan, error) {
+	lanes := l.packageLanes(packages)
+	results := make([]plannedPackage, len(packages))
+
+	var resolverMu sync.Mutex
+	resolver := l.ConflictResolver
+	if resolver != nil {
+		resolver = ConflictResolverFunc(func(c Conflict) (ConflictAction, error) {
+			resolverMu.Lock()
+			defer resolverMu.Unlock()
+			return l.ConflictResolver.Resolve(c)
+		})
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, l.Concurrency)
+	for _, lane := range lanes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			// Each lane sees the target on its own, like a sequential plan would
+			overlaps := newOverlaps()
+			classifier := newSnapshotClassifier(state, l.FS)
+			for _, i := range lane {
+				result := &results[i]
+				worker := *l
+				worker.Logger = &result.logs
+				worker.ConflictResolver = resolver
+				if l.OnEvent != nil {
+					worker.OnEvent = func(event Event) { result.events = append(result.events, event) }
+				}
+				planned := 0
+				if result.err = worker.planPackage(span, &result.plan, classifier, overlaps, mode, packages[i], providers, &planned); result.err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	plan := &Plan{Packages: packages}
+	planned := 0
+	for i := range results {
+		result := &results[i]
+		result.logs.flush(l.Logger)
+		for _, event := range result.events {
+			if event.Type == EventPlanned {
+				planned++
+				event.Done = planned
+			}
+			l.emit(event)
+		}
+		if result.err != nil {
+			return nil, result.err
+		}
+		plan.Operations = append(plan.Operations, result.plan.Operations...)
+		plan.Ignored = append(plan.Ignored, result.plan.Ignored...)
+	}
+	return plan, nil
+}
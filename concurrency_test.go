@@ -0,0 +1,83 @@
+package gslk
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageLanes(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{".config/nvim/init.lua": "--"})
+	createDummyPackage(t, filepath.Join(sourceDir, "zsh"), map[string]string{".zshrc": "", ".zsh/prompt": ""})
+	createDummyPackage(t, filepath.Join(sourceDir, "fish"), map[string]string{".config/fish/config.fish": ""})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": ""})
+	createDummyPackage(t, filepath.Join(sourceDir, "prompt"), map[string]string{
+		".starship.toml": "",
+		ManifestFileName: "depends = [\"zsh\"]\n",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "scripts"), map[string]string{
+		"bin/hello":      "",
+		ManifestFileName: "[rename]\n\"bin/\" = \".zsh/bin/\"\n",
+	})
+
+	linker := New(sourceDir, targetDir)
+	packages, err := linker.lookupPackages([]string{"nvim", "zsh", "fish", "git", "prompt", "scripts"})
+	require.NoError(t, err)
+	// nvim and fish share .config, prompt depends on zsh, and scripts is
+	// renamed into .zsh
+	assert.Equal(t, [][]int{{0, 2}, {1, 4, 5}, {3}}, linker.packageLanes(packages))
+
+	WithPathMapper(PathMapperFunc(func(pkg Package, relPath string) string { return relPath }))(linker)
+	assert.Equal(t, [][]int{{0, 1, 2, 3, 4, 5}}, linker.packageLanes(packages))
+}
+
+func TestPlanLinkParallel(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	var names []string
+	for i := range 8 {
+		name := fmt.Sprintf("pkg%d", i)
+		names = append(names, name)
+		createDummyPackage(t, filepath.Join(sourceDir, name), map[string]string{
+			fmt.Sprintf(".%src", name): "rc",
+			fmt.Sprintf(".%s/a", name): "a",
+			fmt.Sprintf(".%s/b", name): "b",
+		})
+	}
+
+	plan := func(opts ...Option) (*Plan, []Event, string) {
+		var logs bytes.Buffer
+		var events []Event
+		opts = append(opts, WithVerbose(), WithLogger(log.New(&logs, "", 0)), WithEvents(func(e Event) { events = append(events, e) }))
+		plan, err := New(sourceDir, targetDir, opts...).PlanLink(names)
+		require.NoError(t, err)
+		for i := range events {
+			events[i].Time = time.Time{}
+		}
+		return plan, events, logs.String()
+	}
+
+	// Every package is a lane of its own, and planning them in parallel
+	// reports exactly what planning them in order does
+	sequential, sequentialEvents, sequentialLogs := plan()
+	parallel, parallelEvents, parallelLogs := plan(WithConcurrency(4))
+	assert.Equal(t, sequential.Operations, parallel.Operations)
+	assert.Equal(t, sequentialEvents, parallelEvents)
+	assert.Equal(t, sequentialLogs, parallelLogs)
+
+	// A conflict in any lane fails the plan
+	createDummyPackage(t, filepath.Join(sourceDir, "clash"), map[string]string{".pkg3rc": "clash"})
+	names = append(names, "clash")
+	_, err := New(sourceDir, targetDir, WithConcurrency(4), WithLogger(log.New(&bytes.Buffer{}, "", 0))).PlanLink(names)
+	assert.ErrorIs(t, err, ErrConflict)
+}
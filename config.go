@@ -0,0 +1,387 @@
+package gslk
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFileName is the name of the gslk configuration file inside the user's
+// configuration directory.
+const ConfigFileName = "config.toml"
+
+// Config holds settings read from a configuration file. Empty fields are unset
+// and leave the corresponding command-line defaults in place.
+//
+// Paths may start with "~" for the home directory and refer to environment
+// variables as $NAME or ${NAME} (see ExpandEnv); relative paths are resolved
+// against the directory containing the configuration file.
+type Config struct {
+	Path    string   // File the configuration was read from, empty if none was found
+	Sources []string // Source directories, later ones overriding earlier ones
+	Target  string   // Target directory
+	Ignore  []string // Patterns ignored in every package, merged with their ignore files
+
+	PackageDirs []string // Directories of the sources that are packages; empty means all
+
+	DirMode   os.FileMode // Permissions of created directories (dir_mode = "0755"); 0 if unset
+	FileModes []FileMode  // Permissions of copied and rendered files (file_modes = ["bin/*=0755"])
+
+	Age Age // Secret encryption settings ([secrets] identity, recipients and command)
+
+	Vars     map[string]string   // Variables shared by all profiles ([vars])
+	Profiles map[string]Profile  // Named profiles ([profiles.<name>])
+	Groups   map[string][]string // Package groups by name ([groups])
+
+	Desired []string // Packages gslk apply reconciles the target to ([apply] packages); nil if unset
+
+	StrictEnv    bool // Undefined environment variables in paths are errors (strict_env = true)
+	StrictIgnore bool // Invalid ignore, include and copy patterns are errors (strict_ignore = true)
+
+	FilesystemPolicy FilesystemPolicy // What to do with targets on network or symlink-less filesystems (fs_policy = "copy"); empty if unset
+}
+
+// Profile returns the profile called name, with the shared variables merged
+// under its own.
+func (c *Config) Profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if c.Path == "" {
+			return Profile{}, fmt.Errorf("profile %q is not defined: no config file found", name)
+		}
+		return Profile{}, fmt.Errorf("profile %q is not defined in %s", name, c.Path)
+	}
+
+	vars := make(map[string]string, len(c.Vars)+len(profile.Vars))
+	maps.Copy(vars, c.Vars)
+	maps.Copy(vars, profile.Vars)
+	profile.Vars = vars
+	return profile, nil
+}
+
+// DefaultConfigPath returns the location of the user's configuration file,
+// $XDG_CONFIG_HOME/gslk/config.toml or its platform equivalent.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gslk", ConfigFileName), nil
+}
+
+// LoadConfig reads the configuration file at path. A missing file yields an
+// empty configuration.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	config := &Config{Path: path}
+	baseDir := filepath.Dir(path)
+
+	if config.StrictEnv, err = tomlBool(doc, "strict_env"); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if config.StrictIgnore, err = tomlBool(doc, "strict_ignore"); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	sources, err := tomlStringList(doc, "sources")
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	for _, source := range sources {
+		if !IsRemoteSource(source) {
+			if source, err = config.expandPath(source, baseDir); err != nil {
+				return nil, fmt.Errorf("config file %s: sources: %w", path, err)
+			}
+		}
+		config.Sources = append(config.Sources, source)
+	}
+
+	target, err := tomlString(doc, "target")
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if target != "" {
+		if config.Target, err = config.expandPath(target, baseDir); err != nil {
+			return nil, fmt.Errorf("config file %s: target: %w", path, err)
+		}
+	}
+
+	policy, err := tomlString(doc, "fs_policy")
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if policy != "" {
+		if config.FilesystemPolicy, err = ParseFilesystemPolicy(policy); err != nil {
+			return nil, fmt.Errorf("config file %s: fs_policy: %w", path, err)
+		}
+	}
+
+	if config.Ignore, err = tomlStringList(doc, "ignore"); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if config.PackageDirs, err = tomlStringList(doc, "package_dirs"); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if err := config.loadModes(doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if err := config.loadSecrets(doc, baseDir); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if config.Vars, err = tomlVars(doc, "vars"); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	groups, ok := doc["groups"].(map[string]any)
+	if !ok && doc["groups"] != nil {
+		return nil, fmt.Errorf("config file %s: groups must be a table", path)
+	}
+	config.Groups = make(map[string][]string, len(groups))
+	for name := range groups {
+		if config.Groups[name], err = tomlStringList(groups, name); err != nil {
+			return nil, fmt.Errorf("config file %s: group %w", path, err)
+		}
+	}
+
+	if value, ok := doc["apply"]; ok {
+		apply, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("config file %s: apply must be a table", path)
+		}
+		if config.Desired, err = tomlStringList(apply, "packages"); err != nil {
+			return nil, fmt.Errorf("config file %s: apply: %w", path, err)
+		}
+	}
+
+	profiles, ok := doc["profiles"].(map[string]any)
+	if !ok && doc["profiles"] != nil {
+		return nil, fmt.Errorf("config file %s: profiles must be a table", path)
+	}
+	config.Profiles = make(map[string]Profile, len(profiles))
+	for name, value := range profiles {
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("config file %s: profile %s must be a table", path, name)
+		}
+		profile := Profile{Name: name}
+		if profile.Packages, err = tomlStringList(table, "packages"); err != nil {
+			return nil, fmt.Errorf("config file %s: profile %s: %w", path, name, err)
+		}
+		if profile.Vars, err = tomlVars(table, "vars"); err != nil {
+			return nil, fmt.Errorf("config file %s: profile %s: %w", path, name, err)
+		}
+		config.Profiles[name] = profile
+	}
+
+	return config, nil
+}
+
+// loadModes reads dir_mode and file_modes from doc.
+func (c *Config) loadModes(doc map[string]any) error {
+	dirMode, err := tomlString(doc, "dir_mode")
+	if err != nil {
+		return err
+	}
+	if dirMode != "" {
+		if c.DirMode, err = ParsePerm(dirMode); err != nil {
+			return fmt.Errorf("dir_mode: %w", err)
+		}
+	}
+
+	rules, err := tomlStringList(doc, "file_modes")
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		mode, err := ParseFileMode(rule)
+		if err != nil {
+			return fmt.Errorf("file_modes: %w", err)
+		}
+		c.FileModes = append(c.FileModes, mode)
+	}
+	return nil
+}
+
+// loadSecrets reads the [secrets] table of doc.
+func (c *Config) loadSecrets(doc map[string]any, baseDir string) error {
+	value, ok := doc["secrets"]
+	if !ok {
+		return nil
+	}
+	secrets, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("secrets must be a table")
+	}
+
+	identity, err := tomlString(secrets, "identity")
+	if err != nil {
+		return fmt.Errorf("secrets: %w", err)
+	}
+	if identity != "" {
+		if c.Age.Identity, err = c.expandPath(identity, baseDir); err != nil {
+			return fmt.Errorf("secrets: identity: %w", err)
+		}
+	}
+	if c.Age.Recipients, err = tomlStringList(secrets, "recipients"); err != nil {
+		return fmt.Errorf("secrets: %w", err)
+	}
+	if c.Age.Command, err = tomlString(secrets, "command"); err != nil {
+		return fmt.Errorf("secrets: %w", err)
+	}
+	return nil
+}
+
+// expandPath expands the environment variables of path and resolves it
+// against baseDir.
+func (c *Config) expandPath(path, baseDir string) (string, error) {
+	path, err := ExpandEnv(path, c.StrictEnv)
+	if err != nil {
+		return "", err
+	}
+	return expandPath(path, baseDir), nil
+}
+
+// tomlBool returns the boolean value of key in table, or false if it is not set.
+func tomlBool(table map[string]any, key string) (bool, error) {
+	value, ok := table[key]
+	if !ok {
+		return false, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", key)
+	}
+	return b, nil
+}
+
+// tomlString returns the string value of key in table, or "" if it is not set.
+func tomlString(table map[string]any, key string) (string, error) {
+	value, ok := table[key]
+	if !ok {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+	return s, nil
+}
+
+// tomlStringList returns the string array value of key in table. A single
+// string is accepted as a one-element list.
+func tomlStringList(table map[string]any, key string) ([]string, error) {
+	switch value := table[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", key)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", key)
+	}
+}
+
+// tomlVars returns the table value of key in table as variables. Numbers and
+// booleans are converted to their string form.
+func tomlVars(table map[string]any, key string) (map[string]string, error) {
+	value, ok := table[key]
+	if !ok {
+		return nil, nil
+	}
+	vars, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table", key)
+	}
+
+	result := make(map[string]string, len(vars))
+	for name, v := range vars {
+		switch v := v.(type) {
+		case string:
+			result[name] = v
+		case int64, float64, bool:
+			result[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%s.%s must be a string, number or boolean", key, name)
+		}
+	}
+	return result, nil
+}
+
+// xdgDefaults are the values the XDG base directory variables stand for when
+// they are not set, relative to the home directory.
+var xdgDefaults = map[string]string{
+	"XDG_CONFIG_HOME": ".config",
+	"XDG_DATA_HOME":   ".local/share",
+	"XDG_STATE_HOME":  ".local/state",
+	"XDG_CACHE_HOME":  ".cache",
+}
+
+// ExpandEnv expands a leading "~" to the home directory and $NAME and ${NAME}
+// to the values of environment variables in path. The XDG base directory
+// variables, such as XDG_CONFIG_HOME, default to their standard locations
+// below the home directory. Other variables that are not set expand to
+// nothing, or are an error if strict.
+func ExpandEnv(path string, strict bool) (string, error) {
+	var undefined []string
+	expanded := os.Expand(path, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if dir, ok := xdgDefaults[name]; ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				return filepath.Join(home, filepath.FromSlash(dir))
+			}
+		}
+		undefined = append(undefined, name)
+		return ""
+	})
+	if strict && len(undefined) > 0 {
+		return "", fmt.Errorf("environment variable %s in %s is not set", undefined[0], path)
+	}
+	return expandHome(expanded), nil
+}
+
+// expandHome expands a leading "~" to the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// expandPath expands a leading "~" to the home directory and resolves
+// relative paths against baseDir.
+func expandPath(path, baseDir string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Clean(path)
+}
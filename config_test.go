@@ -0,0 +1,144 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`
+sources = ["base", "/abs/overlay", "~/personal", "https://example.com/bundle.tar.gz"]
+target = "~"
+ignore = ["*.md", ".git"]
+package_dirs = ["zsh", "n*"]
+dir_mode = "0700"
+file_modes = ["bin/*=0755"]
+strict_ignore = true
+
+[apply]
+packages = ["git", "vim"]
+`), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, path, config.Path)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "base"),
+		filepath.Clean("/abs/overlay"),
+		filepath.Join(home, "personal"),
+		"https://example.com/bundle.tar.gz",
+	}, config.Sources)
+	assert.Equal(t, home, config.Target)
+	assert.Equal(t, []string{"*.md", ".git"}, config.Ignore)
+	assert.True(t, config.StrictIgnore)
+	assert.Equal(t, []string{"zsh", "n*"}, config.PackageDirs)
+	assert.Equal(t, os.FileMode(0700), config.DirMode)
+	assert.Equal(t, []FileMode{{Pattern: "bin/*", Mode: 0755}}, config.FileModes)
+	assert.Equal(t, []string{"git", "vim"}, config.Desired)
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GSLK_TEST_DOTFILES", "/srv/dotfiles")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	os.Unsetenv("XDG_CONFIG_HOME")
+	os.Unsetenv("GSLK_TEST_UNSET")
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`
+sources = ["$GSLK_TEST_DOTFILES/base", "${XDG_CONFIG_HOME}/gslk/local", "x$GSLK_TEST_UNSET"]
+target = "$HOME"
+`), 0644))
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Clean("/srv/dotfiles/base"),
+		filepath.Join(home, ".config", "gslk", "local"),
+		filepath.Join(dir, "x"),
+	}, config.Sources)
+	assert.Equal(t, filepath.Clean(os.Getenv("HOME")), config.Target)
+
+	require.NoError(t, os.WriteFile(path, []byte("strict_env = true\nsources = [\"$GSLK_TEST_UNSET/base\"]\n"), 0644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "environment variable GSLK_TEST_UNSET in $GSLK_TEST_UNSET/base is not set")
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	config, err := LoadConfig(filepath.Join(t.TempDir(), ConfigFileName))
+	require.NoError(t, err)
+	assert.Empty(t, config.Path)
+	assert.Empty(t, config.Sources)
+	assert.Nil(t, config.Desired)
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`sources = [1, 2]`), 0644))
+
+	_, err := LoadConfig(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sources must be a list of strings")
+}
+
+func TestConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`
+[vars]
+email = "me@home.example"
+editor = "nvim"
+
+[profiles.work]
+packages = ["git", "ssh"]
+vars = { email = "me@work.example", vpn = true }
+
+[profiles.server]
+packages = ["zsh"]
+
+[groups]
+shell = ["zsh", "tmux"]
+editors = "nvim"
+`), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"shell": {"zsh", "tmux"}, "editors": {"nvim"}}, config.Groups)
+
+	work, err := config.Profile("work")
+	require.NoError(t, err)
+	assert.Equal(t, "work", work.Name)
+	assert.Equal(t, []string{"git", "ssh"}, work.Packages)
+	assert.Equal(t, map[string]string{"email": "me@work.example", "editor": "nvim", "vpn": "true"}, work.Vars)
+
+	server, err := config.Profile("server")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"email": "me@home.example", "editor": "nvim"}, server.Vars)
+
+	_, err = config.Profile("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `profile "missing" is not defined`)
+}
+
+func TestConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`
+[secrets]
+identity = "keys/age.txt"
+recipients = ["age1example"]
+`), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, Age{Identity: filepath.Join(dir, "keys", "age.txt"), Recipients: []string{"age1example"}}, config.Age)
+}
@@ -0,0 +1,58 @@
+package gslk
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// copyFile copies sourcePath to targetPath with permissions perm, or those of
+// the source file if perm is 0.
+func copyFile(sourcePath, targetPath string, perm os.FileMode) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if perm == 0 {
+		sourceFi, err := source.Stat()
+		if err != nil {
+			return err
+		}
+		perm = sourceFi.Mode().Perm()
+	}
+
+	return writeFile(targetPath, source, perm)
+}
+
+// writeFile writes content to targetPath with the given permissions. The
+// content is written to a temporary file next to the target and renamed into
+// place so readers never observe a partially written file.
+func writeFile(targetPath string, content io.Reader, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".gslk-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
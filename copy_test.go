@@ -0,0 +1,186 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkCopyMode(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "copy_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"file1.txt":        "content1",
+		"subdir/file2.txt": "content2",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mode:      ModeCopy,
+	}
+
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err, "Copy-mode link failed")
+
+	for relPath, content := range map[string]string{"file1.txt": "content1", "subdir/file2.txt": "content2"} {
+		targetPath := filepath.Join(targetDir, relPath)
+		fi, err := os.Lstat(targetPath)
+		require.NoError(t, err)
+		assert.True(t, fi.Mode().IsRegular(), "Target %s should be a regular file", targetPath)
+
+		data, err := os.ReadFile(targetPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	entry, ok := state.Lookup(filepath.Join(targetDir, "file1.txt"))
+	require.True(t, ok, "Copy should be recorded in state")
+	assert.Equal(t, ModeCopy, entry.Mode)
+	assert.Equal(t, pkgName, entry.Package)
+}
+
+func TestLinkCopyModeIdempotence(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "copy_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"stable.txt":  "unchanged",
+		"changed.txt": "old",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mode:      ModeCopy,
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	stablePath := filepath.Join(targetDir, "stable.txt")
+	before, err := os.Stat(stablePath)
+	require.NoError(t, err)
+
+	// Change one source file and link again
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "changed.txt"), []byte("new"), 0644))
+	_, err = linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(targetDir, "changed.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data), "Changed source file should be re-copied")
+
+	after, err := os.Stat(stablePath)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after), "Unchanged file should not be re-copied")
+}
+
+func TestLinkCopyModeConflicts(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "copy_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"owned.txt": "source",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mode:      ModeCopy,
+	}
+
+	// A pre-existing file that gslk did not deploy is a conflict
+	foreignPath := filepath.Join(targetDir, "owned.txt")
+	require.NoError(t, os.WriteFile(foreignPath, []byte("user data"), 0644))
+	_, err := linker.Link([]string{pkgName})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflict: target")
+	data, _ := os.ReadFile(foreignPath)
+	assert.Equal(t, "user data", string(data))
+
+	// A deployed copy edited in place is a conflict too
+	require.NoError(t, os.Remove(foreignPath))
+	_, err = linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(foreignPath, []byte("edited"), 0644))
+	_, err = linker.Link([]string{pkgName})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modified since it was deployed")
+}
+
+func TestSwitchBetweenLinkAndCopyMode(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "switch_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"rc": "settings",
+	})
+	targetPath := filepath.Join(targetDir, "rc")
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	linker.Mode = ModeCopy
+	_, err = linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	fi, err := os.Lstat(targetPath)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular(), "Link should be replaced by a copy")
+
+	linker.Mode = ModeLink
+	_, err = linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	fi, err = os.Lstat(targetPath)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode()&os.ModeSymlink != 0, "Copy should be replaced by a link")
+
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	_, ok := state.Lookup(targetPath)
+	assert.False(t, ok, "Replaced copy should be forgotten")
+}
+
+func TestUnlinkCopyMode(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "copy_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "b",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mode:      ModeCopy,
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	_, err = linker.Unlink([]string{pkgName})
+	require.NoError(t, err)
+
+	for _, relPath := range []string{"a.txt", "sub/b.txt"} {
+		_, err := os.Lstat(filepath.Join(targetDir, relPath))
+		assert.True(t, os.IsNotExist(err), "Copy %s should be removed by unlink", relPath)
+	}
+}
@@ -0,0 +1,166 @@
+package gslk
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// withDependencies returns packages preceded by the packages they depend on,
+// directly or indirectly, so that every dependency is linked before the
+// packages needing it. Packages otherwise keep their order. Dependencies that
+// are not supported on the running system are skipped, and a dependency cycle
+// is an error.
+func (l *Linker) withDependencies(packages []Package) ([]Package, error) {
+	if !slices.ContainsFunc(packages, func(pkg Package) bool { return len(pkg.Manifest.Depends) > 0 }) {
+		return packages, nil
+	}
+
+	byName, err := l.packagesByName()
+	if err != nil {
+		return nil, err
+	}
+	// Packages given explicitly keep their subpath
+	for _, pkg := range packages {
+		byName[pkg.Name] = pkg
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	marks := make(map[string]int)
+	var ordered []Package
+
+	var visit func(pkg Package, chain []string) error
+	visit = func(pkg Package, chain []string) error {
+		switch marks[pkg.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(chain, pkg.Name), " -> "))
+		}
+		marks[pkg.Name] = visiting
+
+		chain = append(slices.Clip(chain), pkg.Name)
+		for _, name := range pkg.Manifest.Depends {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("package %s depends on %s, which was not found", pkg.Name, name)
+			}
+			if !dep.Manifest.SupportsOS() {
+				l.printf("Skipping package %s, needed by %s: it only supports %s\n", name, pkg.Name, strings.Join(dep.Manifest.OS, ", "))
+				continue
+			}
+			if marks[name] == 0 && !slices.ContainsFunc(packages, func(p Package) bool { return p.Name == name }) {
+				l.printf("Adding package %s, needed by %s\n", name, pkg.Name)
+			}
+			if err := visit(dep, chain); err != nil {
+				return err
+			}
+		}
+
+		marks[pkg.Name] = done
+		ordered = append(ordered, pkg)
+		return nil
+	}
+
+	for _, pkg := range packages {
+		if err := visit(pkg, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// withDependents returns packages preceded by every package depending on them,
+// directly or indirectly, warning about each one added.
+func (l *Linker) withDependents(packages []Package) ([]Package, error) {
+	all, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	selected := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		selected[pkg.Name] = true
+	}
+
+	// Keep adding packages depending on a selected one until none is left
+	var dependents []Package
+	for added := true; added; {
+		added = false
+		for _, pkg := range all {
+			if selected[pkg.Name] {
+				continue
+			}
+			for _, dep := range pkg.Manifest.Depends {
+				if selected[dep] {
+					l.warnf("Warning: also unlinking package %s, which depends on %s\n", pkg.Name, dep)
+					selected[pkg.Name] = true
+					dependents = append(dependents, pkg)
+					added = true
+					break
+				}
+			}
+		}
+	}
+
+	// Dependents found later depend on those found earlier; unlink them first
+	slices.Reverse(dependents)
+	return append(dependents, packages...), nil
+}
+
+// packagesByName returns the packages in the source directories by name.
+func (l *Linker) packagesByName() (map[string]Package, error) {
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+	byName := make(map[string]Package, len(packages))
+	for _, pkg := range packages {
+		byName[pkg.Name] = pkg
+	}
+	return byName, nil
+}
+
+// expandGroups replaces the names of groups in names by their members,
+// recursively, dropping names already seen. A name may not be both a group
+// and a package.
+func (l *Linker) expandGroups(names []string, packagesByName map[string]Package) ([]string, error) {
+	if len(l.Groups) == 0 {
+		return names, nil
+	}
+
+	var expanded []string
+	seen := make(map[string]bool)
+	var expand func(names, chain []string) error
+	expand = func(names, chain []string) error {
+		for _, name := range names {
+			members, isGroup := l.Groups[name]
+			if !isGroup {
+				if !seen[name] {
+					seen[name] = true
+					expanded = append(expanded, name)
+				}
+				continue
+			}
+
+			if _, ok := packagesByName[name]; ok {
+				return fmt.Errorf("%s is both a package and a group", name)
+			}
+			if slices.Contains(chain, name) {
+				return fmt.Errorf("group cycle: %s", strings.Join(append(chain, name), " -> "))
+			}
+			if err := expand(members, append(slices.Clip(chain), name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := expand(names, nil); err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
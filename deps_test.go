@@ -0,0 +1,133 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createDependentPackage creates package name with a single file and a
+// manifest depending on deps.
+func createDependentPackage(t *testing.T, sourceDir, name string, deps ...string) {
+	t.Helper()
+	pkgPath := filepath.Join(sourceDir, name)
+	createDummyPackage(t, pkgPath, map[string]string{name + ".conf": name})
+	if len(deps) == 0 {
+		return
+	}
+	manifest := "depends = ["
+	for i, dep := range deps {
+		if i > 0 {
+			manifest += ", "
+		}
+		manifest += `"` + dep + `"`
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(manifest+"]\n"), 0644))
+}
+
+func packageNames(packages []Package) []string {
+	names := make([]string, len(packages))
+	for i, pkg := range packages {
+		names[i] = pkg.Name
+	}
+	return names
+}
+
+func TestLinkDependencies(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDependentPackage(t, sourceDir, "fonts")
+	createDependentPackage(t, sourceDir, "git")
+	createDependentPackage(t, sourceDir, "shell", "git")
+	createDependentPackage(t, sourceDir, "nvim", "fonts", "shell")
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+
+	// Dependencies come first, each once
+	plan, err := linker.PlanLink([]string{"git", "nvim"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"git", "fonts", "shell", "nvim"}, packageNames(plan.Packages))
+
+	_, err = linker.Link([]string{"nvim"})
+	require.NoError(t, err)
+	for _, name := range []string{"fonts", "git", "shell", "nvim"} {
+		assert.FileExists(t, filepath.Join(targetDir, name+".conf"))
+	}
+
+	// Unlinking leaves dependents alone unless asked to
+	_, err = linker.Unlink([]string{"git"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, "shell.conf"))
+
+	linker.UnlinkDependents = true
+	plan, err = linker.PlanUnlink([]string{"git"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nvim", "shell", "git"}, packageNames(plan.Packages))
+	_, err = linker.Unlink([]string{"git"})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(targetDir, "shell.conf"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "nvim.conf"))
+	assert.FileExists(t, filepath.Join(targetDir, "fonts.conf"))
+}
+
+func TestLinkDependencyErrors(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDependentPackage(t, sourceDir, "a", "b")
+	createDependentPackage(t, sourceDir, "b", "c")
+	createDependentPackage(t, sourceDir, "c", "a")
+	createDependentPackage(t, sourceDir, "broken", "missing")
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+
+	_, err := linker.PlanLink([]string{"a"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle: a -> b -> c -> a")
+
+	_, err = linker.PlanLink([]string{"broken"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "depends on missing, which was not found")
+}
+
+func TestLinkGroups(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	for _, name := range []string{"zsh", "tmux", "kitty"} {
+		createDependentPackage(t, sourceDir, name)
+	}
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Groups: map[string][]string{
+		"shell":   {"zsh", "tmux"},
+		"desktop": {"shell", "kitty", "zsh"},
+	}}
+
+	plan, err := linker.PlanLink([]string{"desktop"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zsh", "tmux", "kitty"}, packageNames(plan.Packages))
+
+	// Profiles remember the members, so a group losing one unlinks it
+	_, err = linker.LinkProfile(Profile{Name: "home", Packages: []string{"shell"}})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, "tmux.conf"))
+	linker.Groups["shell"] = []string{"zsh"}
+	_, err = linker.LinkProfile(Profile{Name: "home", Packages: []string{"shell"}})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(targetDir, "tmux.conf"))
+	assert.FileExists(t, filepath.Join(targetDir, "zsh.conf"))
+
+	linker.Groups["zsh"] = []string{"tmux"}
+	_, err = linker.PlanLink([]string{"zsh"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "zsh is both a package and a group")
+
+	linker.Groups = map[string][]string{"a": {"b"}, "b": {"a"}}
+	_, err = linker.PlanLink([]string{"a"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "group cycle: a -> b -> a")
+}
@@ -0,0 +1,150 @@
+package gslk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a unified diff.
+const diffContext = 3
+
+// diffOp is a single line-level edit produced by diffLines.
+type diffOp struct {
+	kind byte // ' ' for unchanged, '-' for removed, '+' for added
+	line string
+}
+
+// UnifiedDiff returns a unified diff turning a into b, labelled with the given names.
+// It returns an empty string if the contents are identical.
+func UnifiedDiff(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+
+	// Group edits into hunks separated by more than 2*diffContext unchanged lines
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk until a long enough run of unchanged lines
+		end := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+				continue
+			}
+			if i-end >= 2*diffContext {
+				break
+			}
+		}
+
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(end+diffContext, len(ops))
+		writeHunk(&sb, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes ops[from:to] as a single hunk including its @@ header.
+func writeHunk(sb *strings.Builder, ops []diffOp, from, to int) {
+	// Line numbers of the hunk start in a and b (1-based)
+	aLine, bLine := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+
+	aCount, bCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+
+	// An empty range is reported as starting at the line before it
+	if aCount == 0 {
+		aLine--
+	}
+	if bCount == 0 {
+		bLine--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+	for _, op := range ops[from:to] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// splitLines splits content into lines without their trailing newlines.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines computes a line-level edit script turning a into b using the
+// longest common subsequence. This is quadratic, which is fine for the
+// configuration-sized files gslk manages.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
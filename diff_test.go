@@ -0,0 +1,48 @@
+package gslk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	assert.Empty(t, UnifiedDiff("a", "b", "same\n", "same\n"), "Identical content should produce no diff")
+
+	a := "one\ntwo\nthree\n"
+	b := "one\n2\nthree\nfour\n"
+	expected := "--- a\n+++ b\n" +
+		"@@ -1,3 +1,4 @@\n" +
+		" one\n" +
+		"-two\n" +
+		"+2\n" +
+		" three\n" +
+		"+four\n"
+	assert.Equal(t, expected, UnifiedDiff("a", "b", a, b))
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	var a, b string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a'+i)) + "\n"
+		a += line
+		switch i {
+		case 1:
+			b += "changed-b\n"
+		case 18:
+			b += "changed-s\n"
+		default:
+			b += line
+		}
+	}
+
+	diff := UnifiedDiff("old", "new", a, b)
+	assert.Contains(t, diff, "@@ -1,5 +1,5 @@\n")
+	assert.Contains(t, diff, "@@ -16,5 +16,5 @@\n")
+	assert.NotContains(t, diff, " j\n", "Unchanged lines far from any change should be omitted")
+}
+
+func TestUnifiedDiffEmptySide(t *testing.T) {
+	expected := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	assert.Equal(t, expected, UnifiedDiff("a", "b", "", "x\ny\n"))
+}
@@ -0,0 +1,286 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FindingKind categorises a problem reported by Doctor.
+type FindingKind string
+
+const (
+	FindingBrokenLink   FindingKind = "broken-link"   // Symlink into a package whose source file no longer exists
+	FindingOrphanLink   FindingKind = "orphan-link"   // Symlink into the source directory outside any existing package
+	FindingPermission   FindingKind = "permission"    // A source or target path gslk cannot read or write
+	FindingUnmanagedDir FindingKind = "unmanaged-dir" // A directory gslk created but can no longer manage
+	FindingStaleState   FindingKind = "stale-state"   // A state manifest entry that no longer matches the target
+	FindingOwnership    FindingKind = "ownership"     // Several packages provide the same target path
+)
+
+// Finding is a single problem found by Doctor, with a suggested fix.
+type Finding struct {
+	Kind     FindingKind
+	Path     string   // Affected path
+	Packages []string // Packages involved, if any
+	Message  string   // What is wrong
+	Fix      string   // Suggested remedy
+}
+
+// Doctor audits the target directory and the packages in the source directory
+// and reports problems. It never modifies anything.
+//
+// Only the directories the packages populate are scanned for links, so stray
+// links elsewhere in the target tree are not reported.
+func (l *Linker) Doctor() ([]Finding, error) {
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var findings []Finding
+	providers := make(map[string][]string) // Target path -> packages providing it
+	scanDirs := map[string]bool{l.TargetDir: true}
+
+	for _, pkg := range packages {
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if !path.isDir {
+				providers[path.targetPath] = append(providers[path.targetPath], pkg.Name)
+				scanDirs[filepath.Dir(path.targetPath)] = true
+			}
+			return nil
+		})
+		if err != nil {
+			if !errors.Is(err, fs.ErrPermission) {
+				return nil, err
+			}
+			findings = append(findings, Finding{
+				Kind:     FindingPermission,
+				Path:     pkg.Path,
+				Packages: []string{pkg.Name},
+				Message:  fmt.Sprintf("package cannot be read: %v", err),
+				Fix:      fmt.Sprintf("make the package readable, e.g. chmod -R u+rX %s", pkg.Path),
+			})
+			continue
+		}
+	}
+
+	findings = append(findings, checkOwnership(providers)...)
+	findings = append(findings, l.checkLinks(scanDirs, packages)...)
+	findings = append(findings, l.checkPermissions(scanDirs)...)
+	findings = append(findings, l.checkState(state, packages)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+	return findings, nil
+}
+
+// checkOwnership reports target paths provided by more than one package.
+func checkOwnership(providers map[string][]string) []Finding {
+	var findings []Finding
+	for targetPath, pkgNames := range providers {
+		if len(pkgNames) < 2 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:     FindingOwnership,
+			Path:     targetPath,
+			Packages: pkgNames,
+			Message:  fmt.Sprintf("provided by %d packages: %s", len(pkgNames), strings.Join(pkgNames, ", ")),
+			Fix:      "add the file to .gslk-ignore in all but one of these packages",
+		})
+	}
+	return findings
+}
+
+// checkLinks scans dirs for symlinks pointing into the source directory whose
+// destination no longer exists.
+func (l *Linker) checkLinks(dirs map[string]bool, packages []Package) []Finding {
+	packageNames := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		packageNames[pkg.Name] = true
+	}
+
+	var findings []Finding
+	for dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Missing directories are fine, unreadable ones are reported by checkPermissions
+		}
+
+		for _, entry := range entries {
+			if entry.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+
+			linkPath := filepath.Join(dir, entry.Name())
+			dest, err := resolveLinkTarget(l.FS, linkPath)
+			if err != nil {
+				continue
+			}
+			absSource, rel, ok := l.containingSource(dest)
+			if !ok {
+				continue
+			}
+			if _, err := os.Stat(dest); err == nil {
+				continue
+			}
+
+			pkgName := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+			if packageNames[pkgName] {
+				findings = append(findings, Finding{
+					Kind:     FindingBrokenLink,
+					Path:     linkPath,
+					Packages: []string{pkgName},
+					Message:  fmt.Sprintf("points to %s, which no longer exists in package %s", dest, pkgName),
+					Fix:      fmt.Sprintf("remove the link (rm %s) or restore the file in the package", linkPath),
+				})
+			} else {
+				findings = append(findings, Finding{
+					Kind:    FindingOrphanLink,
+					Path:    linkPath,
+					Message: fmt.Sprintf("points to %s, but there is no package %s in %s", dest, pkgName, absSource),
+					Fix:     fmt.Sprintf("remove the link (rm %s); the package was probably renamed or deleted", linkPath),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// checkPermissions reports target directories gslk would need to write to but cannot.
+// For directories that do not exist yet, the closest existing ancestor is checked.
+func (l *Linker) checkPermissions(dirs map[string]bool) []Finding {
+	checked := make(map[string]bool)
+	var findings []Finding
+
+	for dir := range dirs {
+		existing := dir
+		for {
+			if _, err := os.Stat(existing); err == nil {
+				break
+			}
+			parent := filepath.Dir(existing)
+			if parent == existing {
+				break
+			}
+			existing = parent
+		}
+
+		if checked[existing] {
+			continue
+		}
+		checked[existing] = true
+
+		if !isWritable(existing) {
+			findings = append(findings, Finding{
+				Kind:    FindingPermission,
+				Path:    existing,
+				Message: "directory is not writable by the current user",
+				Fix:     fmt.Sprintf("fix the ownership or permissions of %s (e.g. chmod u+w)", existing),
+			})
+		}
+	}
+	return findings
+}
+
+// checkState reports manifest entries that no longer match the target: copies
+// that disappeared, bind mounts that are gone, entries of removed packages, and created directories that
+// gslk could not remove or that were replaced.
+func (l *Linker) checkState(state *State, packages []Package) []Finding {
+	packageNames := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		packageNames[pkg.Name] = true
+	}
+
+	var findings []Finding
+	for targetPath, entry := range state.Entries {
+		if !packageNames[entry.Package] {
+			findings = append(findings, Finding{
+				Kind:     FindingStaleState,
+				Path:     targetPath,
+				Packages: []string{entry.Package},
+				Message:  fmt.Sprintf("deployed %s belongs to package %s, which no longer exists", entry.Mode, entry.Package),
+				Fix:      fmt.Sprintf("remove %s and its entry in %s", targetPath, StateFileName),
+			})
+		} else if fi, err := os.Lstat(targetPath); entry.Mode == ModeBind && (err != nil || !isBindMount(entry.Source, targetPath, fi)) {
+			findings = append(findings, Finding{
+				Kind:     FindingStaleState,
+				Path:     targetPath,
+				Packages: []string{entry.Package},
+				Message:  fmt.Sprintf("bind mount of %s is gone, e.g. after a reboot", entry.Source),
+				Fix:      fmt.Sprintf("relink package %s (gslk -R %s)", entry.Package, entry.Package),
+			})
+		} else if os.IsNotExist(err) {
+			findings = append(findings, Finding{
+				Kind:     FindingStaleState,
+				Path:     targetPath,
+				Packages: []string{entry.Package},
+				Message:  fmt.Sprintf("deployed %s is missing from the target", entry.Mode),
+				Fix:      fmt.Sprintf("relink package %s (gslk -R %s)", entry.Package, entry.Package),
+			})
+		}
+	}
+
+	for dir, owners := range state.Directories {
+		fi, err := os.Lstat(dir)
+		switch {
+		case err != nil && os.IsNotExist(err):
+			findings = append(findings, Finding{
+				Kind:     FindingUnmanagedDir,
+				Path:     dir,
+				Packages: owners,
+				Message:  "directory created by gslk no longer exists",
+				Fix:      "relink the packages that use it, or unlink them to forget the directory",
+			})
+		case err == nil && !fi.IsDir():
+			findings = append(findings, Finding{
+				Kind:     FindingUnmanagedDir,
+				Path:     dir,
+				Packages: owners,
+				Message:  "directory created by gslk was replaced by a file",
+				Fix:      fmt.Sprintf("move %s aside and relink the packages that use it", dir),
+			})
+		case len(owners) == 0:
+			findings = append(findings, Finding{
+				Kind:    FindingUnmanagedDir,
+				Path:    dir,
+				Message: "directory created by gslk was left behind because it is not empty",
+				Fix:     fmt.Sprintf("move your files out of %s and remove it", dir),
+			})
+		default:
+			var missing []string
+			for _, owner := range owners {
+				if !packageNames[owner] {
+					missing = append(missing, owner)
+				}
+			}
+			if len(missing) > 0 {
+				findings = append(findings, Finding{
+					Kind:     FindingUnmanagedDir,
+					Path:     dir,
+					Packages: missing,
+					Message:  fmt.Sprintf("directory is claimed by removed packages: %s", strings.Join(missing, ", ")),
+					Fix:      "remove the directory once empty, or restore the packages and unlink them",
+				})
+			}
+		}
+	}
+
+	// Keep the output stable regardless of map iteration order
+	for i := range findings {
+		slices.Sort(findings[i].Packages)
+	}
+	return findings
+}
@@ -0,0 +1,143 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findingsByKind groups findings by kind for easier assertions.
+func findingsByKind(findings []Finding) map[FindingKind][]Finding {
+	byKind := make(map[FindingKind][]Finding)
+	for _, f := range findings {
+		byKind[f.Kind] = append(byKind[f.Kind], f)
+	}
+	return byKind
+}
+
+func TestDoctorHealthyTree(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"a.conf":     "a",
+		"sub/b.conf": "b",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	findings, err := linker.Doctor()
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestDoctorBrokenLinks(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{"kept.conf": "k"})
+
+	// A link to a file removed from an existing package
+	brokenLink := filepath.Join(targetDir, "removed.conf")
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, "removed.conf"), brokenLink))
+	// A link into a package that no longer exists
+	orphanLink := filepath.Join(targetDir, "gone.conf")
+	require.NoError(t, os.Symlink(filepath.Join(sourceDir, "gone", "gone.conf"), orphanLink))
+	// A broken link pointing outside the source is not gslk's business
+	require.NoError(t, os.Symlink("/nonexistent/elsewhere", filepath.Join(targetDir, "other")))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	findings, err := linker.Doctor()
+	require.NoError(t, err)
+
+	byKind := findingsByKind(findings)
+	require.Len(t, byKind[FindingBrokenLink], 1)
+	assert.Equal(t, brokenLink, byKind[FindingBrokenLink][0].Path)
+	assert.Equal(t, []string{"pkg"}, byKind[FindingBrokenLink][0].Packages)
+	require.Len(t, byKind[FindingOrphanLink], 1)
+	assert.Equal(t, orphanLink, byKind[FindingOrphanLink][0].Path)
+	assert.Len(t, findings, 2)
+}
+
+func TestDoctorOwnershipConflict(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "first"), map[string]string{"shared.conf": "1"})
+	createDummyPackage(t, filepath.Join(sourceDir, "second"), map[string]string{"shared.conf": "2"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	findings, err := linker.Doctor()
+	require.NoError(t, err)
+
+	require.Len(t, findings, 1)
+	assert.Equal(t, FindingOwnership, findings[0].Kind)
+	assert.Equal(t, filepath.Join(targetDir, "shared.conf"), findings[0].Path)
+	assert.Equal(t, []string{"first", "second"}, findings[0].Packages)
+	assert.NotEmpty(t, findings[0].Fix)
+}
+
+func TestDoctorState(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "pkg"
+	createDummyPackage(t, filepath.Join(sourceDir, pkgName), map[string]string{
+		"copy.conf": "c",
+		"app/x":     "x",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	copyPath := deployCopy(t, linker, pkgName, "copy.conf", ModeCopy)
+	require.NoError(t, os.Remove(copyPath))
+
+	// Unlinking with a user file in app/ leaves it behind without owners
+	appDir := filepath.Join(targetDir, "app")
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "user"), []byte("u"), 0644))
+	_, err = linker.Unlink([]string{pkgName})
+	require.NoError(t, err)
+
+	// Redeploy the copy record so it is missing from the target again
+	deployCopy(t, linker, pkgName, "copy.conf", ModeCopy)
+	require.NoError(t, os.Remove(copyPath))
+
+	findings, err := linker.Doctor()
+	require.NoError(t, err)
+
+	byKind := findingsByKind(findings)
+	require.Len(t, byKind[FindingStaleState], 1)
+	assert.Equal(t, copyPath, byKind[FindingStaleState][0].Path)
+	require.Len(t, byKind[FindingUnmanagedDir], 1)
+	assert.Equal(t, appDir, byKind[FindingUnmanagedDir][0].Path)
+}
+
+func TestDoctorPermissions(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks are bypassed when running as root")
+	}
+
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{"locked/file.conf": "f"})
+
+	lockedDir := filepath.Join(targetDir, "locked")
+	require.NoError(t, os.Mkdir(lockedDir, 0555))
+	defer os.Chmod(lockedDir, 0755)
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	findings, err := linker.Doctor()
+	require.NoError(t, err)
+
+	require.Len(t, findings, 1)
+	assert.Equal(t, FindingPermission, findings[0].Kind)
+	assert.Equal(t, lockedDir, findings[0].Path)
+}
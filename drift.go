@@ -0,0 +1,120 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// DriftKind categorises a difference between what a package would deploy and
+// what the target holds.
+type DriftKind string
+
+const (
+	DriftContent   DriftKind = "content"    // A deployed copy or rendered file differs from the package version
+	DriftWrongLink DriftKind = "wrong-link" // A symlink at the target path points somewhere else
+)
+
+// Drift is a deployed file that no longer matches its package.
+type Drift struct {
+	Kind    DriftKind
+	Package string
+	Source  string // Package file
+	Target  string // Deployed path
+	Dest    string // Where the link points (DriftWrongLink)
+	Diff    string // Unified diff from the target to the package version (DriftContent); empty for secrets
+}
+
+// Drift compares the files of packageNames, or of every package if none is
+// given, with what is deployed at their target paths. Copies and rendered
+// files recorded in the state manifest are compared by content, with the
+// template rendered anew; symlinks pointing anywhere but their package file are
+// reported with their destination. Paths holding nothing or a file gslk did
+// not deploy are not drift. Nothing is modified.
+func (l *Linker) Drift(packageNames []string) ([]Drift, error) {
+	var packages []Package
+	var err error
+	if len(packageNames) > 0 {
+		packages, err = l.lookupPackages(packageNames)
+	} else if packages, err = l.FindPackages(); err != nil {
+		err = fmt.Errorf("failed to find packages: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	var drifts []Drift
+	for _, pkg := range packages {
+		if !pkg.Manifest.SupportsOS() {
+			continue
+		}
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+			drift := Drift{Package: pkg.Name, Source: path.sourcePath, Target: path.targetPath}
+
+			switch c.State {
+			case TargetForeignLink:
+				if drift.Dest, err = resolveLinkTarget(l.FS, path.targetPath); err != nil {
+					return err
+				}
+				drift.Kind = DriftWrongLink
+			case TargetDeployed, TargetModified:
+				drift.Kind = DriftContent
+				changed, err := l.contentDrift(&drift, c)
+				if err != nil || !changed {
+					return err
+				}
+			default:
+				return nil
+			}
+			drifts = append(drifts, drift)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Target < drifts[j].Target
+	})
+	return drifts, nil
+}
+
+// contentDrift compares the deployed file of d, classified as c, with what its
+// package would deploy now and sets d.Diff. Secrets are not decrypted; they
+// only drift when they were modified since they were deployed.
+func (l *Linker) contentDrift(d *Drift, c Classification) (bool, error) {
+	var want []byte
+	var err error
+	switch c.Entry.Mode {
+	case ModeSecret:
+		return c.State == TargetModified, nil
+	case ModeTemplate:
+		want, err = l.renderTemplate(d.Package, d.Source)
+	default:
+		want, err = os.ReadFile(d.Source)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read package version of %s: %w", d.Target, err)
+	}
+
+	have, err := os.ReadFile(d.Target)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", d.Target, err)
+	}
+	d.Diff = UnifiedDiff(d.Target, d.Source, string(have), string(want))
+	return d.Diff != "", nil
+}
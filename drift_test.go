@@ -0,0 +1,57 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrift(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{
+		".gitconfig.tmpl": "email = {{ .Vars.email }}\n",
+		".gitignore":      "*.o\n",
+		"unchanged":       "same\n",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{".vimrc": "set nu\n"})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mode:      ModeCopy,
+		Mapper:    Mapper{TemplateSuffix: DefaultTemplateSuffix},
+		Vars:      map[string]string{"email": "me@example.com"},
+	}
+	_, err := linker.Link([]string{"git"})
+	require.NoError(t, err)
+	linker.Mode = ModeLink
+	require.NoError(t, os.Symlink("/elsewhere/.vimrc", filepath.Join(targetDir, ".vimrc")))
+
+	drifts, err := linker.Drift(nil)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, Drift{
+		Kind:    DriftWrongLink,
+		Package: "vim",
+		Source:  filepath.Join(sourceDir, "vim", ".vimrc"),
+		Target:  filepath.Join(targetDir, ".vimrc"),
+		Dest:    "/elsewhere/.vimrc",
+	}, drifts[0])
+
+	// A changed variable and an edited copy both drift
+	linker.Vars["email"] = "work@example.com"
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, ".gitignore"), []byte("*.o\n*.a\n"), 0644))
+
+	drifts, err = linker.Drift([]string{"git"})
+	require.NoError(t, err)
+	require.Len(t, drifts, 2)
+	assert.Equal(t, DriftContent, drifts[0].Kind)
+	assert.Equal(t, filepath.Join(targetDir, ".gitconfig"), drifts[0].Target)
+	assert.Contains(t, drifts[0].Diff, "-email = me@example.com\n+email = work@example.com\n")
+	assert.Contains(t, drifts[1].Diff, "-*.a\n")
+}
@@ -0,0 +1,66 @@
+package gslk
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType tells what an Event reports.
+type EventType string
+
+const (
+	EventApplied  EventType = "applied"  // An operation was applied (or, in dry run mode, would have been)
+	EventFailed   EventType = "failed"   // An operation failed; Error says why
+	EventConflict EventType = "conflict" // A target is in the way; unless the conflict policy resolves it, planning stops
+	EventPlanned  EventType = "planned"  // A package path was planned; Done counts the paths planned so far
+)
+
+// Event describes an operation as it is applied, or a path planned or a
+// conflict found while planning, so that tools wrapping gslk can follow its
+// progress.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"event"`
+	Op      OpKind    `json:"op,omitempty"`
+	Package string    `json:"package,omitempty"`
+	Source  string    `json:"source,omitempty"`
+	Target  string    `json:"target"`
+	Current string    `json:"current,omitempty"` // What occupied Target before the operation
+	Reason  string    `json:"reason,omitempty"`  // Why the target is left alone (skip operations)
+	Error   string    `json:"error,omitempty"`
+	DryRun  bool      `json:"dry_run,omitempty"`
+
+	// Done and Total count the operations of the plan being applied, this one
+	// included; for planned events, Done counts the paths planned and Total is 0
+	Done  int `json:"done,omitempty"`
+	Total int `json:"total,omitempty"`
+}
+
+// newEvent returns the event of type typ for op.
+func newEvent(typ EventType, op Operation, dryRun bool) Event {
+	return Event{
+		Time:    time.Now(),
+		Type:    typ,
+		Op:      op.Kind,
+		Package: op.Package,
+		Source:  op.Source,
+		Target:  op.Target,
+		Current: op.Current.String(),
+		Reason:  op.Reason,
+		DryRun:  dryRun,
+	}
+}
+
+// JSONEvents returns an event handler writing each event to w as one line of
+// JSON (NDJSON). It may be called from several goroutines.
+func JSONEvents(w io.Writer) func(Event) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(event) // Progress reporting never stops the work
+	}
+}
@@ -0,0 +1,96 @@
+package gslk
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeEvents parses the NDJSON written by JSONEvents.
+func decodeEvents(t *testing.T, data []byte) []Event {
+	t.Helper()
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestJSONEvents(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"a.txt":        "a",
+		"dir/b.txt":    "b",
+		"skipped.conf": "s",
+	})
+
+	var out bytes.Buffer
+	linker := New(sourceDir, targetDir, WithEvents(JSONEvents(&out)))
+
+	t.Run("operations are reported as they are applied", func(t *testing.T) {
+		_, err := linker.Link([]string{"pkg"})
+		require.NoError(t, err)
+
+		var applied []Event
+		linked := map[string]Event{}
+		for _, event := range decodeEvents(t, out.Bytes()) {
+			assert.False(t, event.Time.IsZero())
+			if event.Type == EventApplied {
+				applied = append(applied, event)
+			}
+			if event.Op == OpLink {
+				linked[event.Target] = event
+			}
+		}
+		require.Len(t, linked, 3)
+		for i, event := range applied {
+			assert.Equal(t, i+1, event.Done)
+			assert.Equal(t, len(applied), event.Total)
+		}
+		event := linked[filepath.Join(targetDir, "dir", "b.txt")]
+		assert.Equal(t, "pkg", event.Package)
+		assert.Equal(t, filepath.Join(sourceDir, "pkg", "dir", "b.txt"), event.Source)
+		assert.Equal(t, "missing", event.Current)
+	})
+
+	t.Run("conflicts are reported before planning stops", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(targetDir, "a.txt")))
+		require.NoError(t, os.WriteFile(filepath.Join(targetDir, "a.txt"), []byte("mine"), 0644))
+		out.Reset()
+
+		_, err := linker.Link([]string{"pkg"})
+		require.Error(t, err)
+
+		events := decodeEvents(t, out.Bytes())
+		conflict := events[len(events)-1]
+		assert.Equal(t, EventConflict, conflict.Type)
+		assert.Equal(t, filepath.Join(targetDir, "a.txt"), conflict.Target)
+		assert.Equal(t, "file", conflict.Current)
+		assert.Contains(t, conflict.Error, "already exists")
+		for i, event := range events[:len(events)-1] {
+			assert.Equal(t, EventPlanned, event.Type)
+			assert.Equal(t, i+1, event.Done)
+		}
+	})
+
+	t.Run("failed operations carry their error", func(t *testing.T) {
+		var events []Event
+		executor := &Executor{TargetDir: targetDir, FS: OSFS{}, OnEvent: func(event Event) { events = append(events, event) }}
+		err := executor.Execute(Operation{Kind: OpLink, Package: "pkg", Source: "/x", Target: "/outside/target"})
+		require.Error(t, err)
+
+		require.Len(t, events, 1)
+		assert.Equal(t, EventFailed, events[0].Type)
+		assert.Equal(t, err.Error(), events[0].Error)
+	})
+}
@@ -0,0 +1,774 @@
+package gslk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Executor applies plan operations to the filesystem and keeps the state
+// manifest in sync with the copies it deploys or removes. Operations are
+// applied in order; a failure stops the plan.
+type Executor struct {
+	TargetDir   string // Root of the target tree; only directories inside it are tracked
+	State       *State // Manifest to update; if nil, changes are tracked in memory only
+	DryRun      bool   // Print operations without performing them
+	Verbose     bool   // Print skipped operations and directory creation
+	ForceRemove bool   // Move gslk-created directories that are not empty to the trash
+	Logger      Logger // Receives progress messages; if nil they are printed to standard output
+	Concurrency int    // Number of file operations applied in parallel; 0 or 1 applies them one at a time
+	FS          FS     // Filesystem links and directories are managed on; if nil, the local filesystem is used
+
+	DirMode   os.FileMode // Permissions of created directories; 0 means DefaultDirMode
+	AllowRoot bool        // As root, replace and remove files of other users
+
+	// Retries is how often creating and removing links, files and directories
+	// is retried after an error network filesystems report transiently, such
+	// as ESTALE or EBUSY; 0 never retries. RetryBackoff is the wait before the
+	// first retry, doubled for each further one; 0 means DefaultRetryBackoff
+	Retries      int
+	RetryBackoff time.Duration
+
+	// Throttle, if set, is waited for before each operation changing the
+	// filesystem
+	Throttle *Throttle
+
+	// Confirm is asked before files the user may still want are moved aside
+	// (see Linker.Confirm); if nil, everything is confirmed
+	Confirm func(question string) (bool, error)
+
+	// OnEvent, if set, is called after each operation with its outcome; calls
+	// are serialised even when operations run in parallel
+	OnEvent func(Event)
+
+	// Span, if set, holds a span per batch of operations Apply runs
+	Span Span
+
+	shared *executorShared // Shared with the copies applying operations in parallel
+}
+
+// executorShared is what the copies of an Executor applying a batch of
+// operations in parallel share with it.
+type executorShared struct {
+	dirMu     sync.Mutex // Serialises directory creation between parallel file operations
+	confirmMu sync.Mutex // Keeps questions from parallel operations apart
+	eventMu   sync.Mutex // Serialises calls to OnEvent
+	done      int        // Operations of the plan being applied reported to OnEvent
+	total     int        // Operations of the plan being applied
+}
+
+// executor returns an Executor configured from the Linker's options, timing
+// the operations it applies in spans nested in span, if not nil.
+func (l *Linker) executor(state *State, span Span) *Executor {
+	return &Executor{
+		TargetDir:   l.TargetDir,
+		State:       state,
+		DryRun:      l.DryRun,
+		Verbose:     l.Verbose,
+		ForceRemove: l.ForceRemove,
+		Logger:      l.Logger,
+		Concurrency: l.Concurrency,
+		FS:          l.fs(),
+		Confirm:     l.Confirm,
+		DirMode:     l.DirMode,
+		AllowRoot:   l.AllowRoot,
+		OnEvent:     l.OnEvent,
+		Span:        span,
+
+		Retries:      l.Retries,
+		RetryBackoff: l.RetryBackoff,
+		Throttle:     l.Throttle,
+	}
+}
+
+// printf logs a progress message
+func (e *Executor) printf(format string, args ...any) {
+	logf(e.Logger, LevelInfo, format, args...)
+}
+
+// warnf logs a warning
+func (e *Executor) warnf(format string, args ...any) {
+	logf(e.Logger, LevelWarn, format, args...)
+}
+
+// confirm asks question through Confirm. Nothing is asked in dry run mode.
+func (e *Executor) confirm(question string) (bool, error) {
+	if e.Confirm == nil || e.DryRun {
+		return true, nil
+	}
+	e.shared.confirmMu.Lock()
+	defer e.shared.confirmMu.Unlock()
+	return e.Confirm(question)
+}
+
+// logVerbose logs a message if verbose mode is enabled
+func (e *Executor) logVerbose(format string, args ...interface{}) {
+	if e.Verbose {
+		logf(e.Logger, LevelDebug, format, args...)
+	}
+}
+
+// Apply executes every operation of plan in order and reports what was done.
+// The result is never nil; on failure it covers the operations applied so far
+// and lists the failed targets.
+//
+// With Concurrency above 1, the file operations between two directory
+// operations are spread over that many goroutines. Directory operations still
+// run alone and in plan order, operations on the same target keep their
+// relative order, and the plan stops after the batch in which an operation
+// failed. Results, errors and the messages sent to Logger are reported in
+// plan order either way; messages of a batch are sent once it is done.
+func (e *Executor) Apply(plan *Plan) (*Result, error) {
+	if e.State == nil {
+		e.State = newState("")
+	}
+	if e.shared == nil {
+		e.shared = &executorShared{}
+	}
+
+	result := &Result{Ignored: plan.Ignored}
+	ops := plan.Operations
+	e.shared.done, e.shared.total = 0, len(ops)
+	defer func() { e.shared.total = 0 }()
+	var errs []error
+
+	for start := 0; start < len(ops) && len(errs) == 0; {
+		// A batch is a single directory operation or a run of file operations
+		end := start + 1
+		for !isDirOp(ops[start]) && end < len(ops) && !isDirOp(ops[end]) {
+			end++
+		}
+
+		span := orNoSpan(e.Span).Start("gslk.apply.batch", Attr{"gslk.operations", end - start}, Attr{"gslk.first", ops[start].Target})
+		ran, batchErrs := e.applyBatch(ops[start:end])
+		span.End(errors.Join(batchErrs...))
+		for i, op := range ops[start:end] {
+			switch {
+			case batchErrs[i] != nil:
+				result.Failed = append(result.Failed, op.Target)
+				errs = append(errs, batchErrs[i])
+			case ran[i]:
+				result.record(op)
+			}
+		}
+		start = end
+	}
+	return result, errors.Join(errs...)
+}
+
+// isDirOp reports whether op creates or removes a directory.
+func isDirOp(op Operation) bool {
+	return op.Kind == OpMkdir || op.Kind == OpRmdir
+}
+
+// applyBatch executes ops and reports for each operation whether it ran and
+// the error it failed with. Operations on the same target run in order on one
+// goroutine, and stop at the first failure, e.g. a link is not attempted when
+// quarantining the file in its way failed.
+func (e *Executor) applyBatch(ops []Operation) (ran []bool, errs []error) {
+	ran = make([]bool, len(ops))
+	errs = make([]error, len(ops))
+
+	if e.Concurrency <= 1 {
+		for i, op := range ops {
+			ran[i] = true
+			if errs[i] = e.Execute(op); errs[i] != nil {
+				break
+			}
+		}
+		return ran, errs
+	}
+
+	var groups [][]int
+	groupOf := make(map[string]int) // Target path -> index in groups
+	for i, op := range ops {
+		g, ok := groupOf[op.Target]
+		if !ok {
+			g = len(groups)
+			groupOf[op.Target] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	// Each group logs to its own buffer, sent on in plan order once the
+	// batch is done, so the output is the same from one run to the next
+	logs := make([]bufferedLogger, len(groups))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(e.Concurrency, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range work {
+				worker := *e
+				worker.Logger = &logs[g]
+				for _, i := range groups[g] {
+					ran[i] = true
+					if errs[i] = worker.Execute(ops[i]); errs[i] != nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	for g := range groups {
+		work <- g
+	}
+	close(work)
+	wg.Wait()
+
+	for g := range logs {
+		logs[g].flush(e.Logger)
+	}
+	return ran, errs
+}
+
+// Execute performs a single operation and reports its outcome to OnEvent,
+// counting it towards the progress of the plan being applied, if any.
+// Operations on paths outside TargetDir are refused, and so are operations
+// removing or replacing a file owned by another user, unless AllowRoot is set
+// and gslk runs as root, or the FS is a SudoFS. Unless in dry run mode, every
+// operation but OpSkip, and OpMkdir on an existing directory, is refused if
+// the FS is a ReadOnlyFS.
+func (e *Executor) Execute(op Operation) error {
+	if e.shared == nil {
+		e.shared = &executorShared{}
+	}
+	err := e.execute(op)
+	if e.OnEvent != nil {
+		event := newEvent(EventApplied, op, e.DryRun)
+		if err != nil {
+			event.Type = EventFailed
+			event.Error = err.Error()
+		}
+		e.shared.eventMu.Lock()
+		if e.shared.total > 0 {
+			e.shared.done++
+			event.Done, event.Total = e.shared.done, e.shared.total
+		}
+		e.OnEvent(event)
+		e.shared.eventMu.Unlock()
+	}
+	return err
+}
+
+// execute performs a single operation.
+func (e *Executor) execute(op Operation) error {
+	if e.State == nil {
+		e.State = newState("")
+	}
+	if op.Kind != OpSkip {
+		if err := checkInsideTarget(e.TargetDir, op.Target); err != nil {
+			return err
+		}
+	}
+	if op.Kind != OpSkip && op.Kind != OpMkdir && !e.DryRun {
+		// Copies, quarantined and trashed files bypass FS, so refuse here;
+		// directories that exist already are left alone by FS itself
+		if err := checkWritable(e.FS, string(op.Kind), op.Target); err != nil {
+			return err
+		}
+	}
+	if remote, ok := e.FS.(RemoteFS); ok && !remoteOp(op) {
+		return fmt.Errorf("cannot %s %s on %s: only links are managed on remote targets", op.Kind, op.Target, remote.Location())
+	}
+	if _, sudo := e.FS.(*SudoFS); replacesTarget(op) && !sudo {
+		if err := checkOwner(e.FS, op.Target, e.AllowRoot); err != nil {
+			return err
+		}
+	}
+
+	if op.Kind != OpSkip && !e.DryRun {
+		e.Throttle.Wait()
+	}
+
+	switch op.Kind {
+	case OpMkdir:
+		if err := e.makeDirs(op.Target, op.Package); err != nil {
+			return fmt.Errorf("failed to create target directory %s: %w", op.Target, err)
+		}
+		return nil
+	case OpRmdir:
+		return e.rmdir(op)
+	case OpLink:
+		return e.link(op)
+	case OpCopy:
+		return e.copy(op)
+	case OpRender:
+		return e.render(op)
+	case OpQuarantine:
+		return e.quarantine(op)
+	case OpUnlink:
+		return e.unlink(op)
+	case OpRemove:
+		return e.remove(op)
+	case OpMount:
+		return e.mount(op)
+	case OpUnmount:
+		return e.unmount(op)
+	case OpSkip:
+		e.logVerbose("Skipping %s: %s\n", op.Target, op.Reason)
+		return nil
+	default:
+		return fmt.Errorf("unknown operation %q for %s", op.Kind, op.Target)
+	}
+}
+
+// remoteOp reports whether op can be performed on a RemoteFS.
+func remoteOp(op Operation) bool {
+	switch op.Kind {
+	case OpMkdir, OpRmdir, OpLink, OpUnlink, OpSkip:
+		return true
+	default:
+		return false
+	}
+}
+
+// replacesTarget reports whether op removes or overwrites an existing target.
+func replacesTarget(op Operation) bool {
+	switch op.Kind {
+	case OpUnlink, OpRemove, OpQuarantine:
+		return true
+	case OpLink, OpCopy, OpRender:
+		return op.Current != TargetMissing
+	default:
+		return false
+	}
+}
+
+// makeDirs creates dir and any missing parents, recording every directory it
+// creates inside the target as owned by pkgName. Directories gslk created
+// earlier on the way to dir are claimed for pkgName too, so they are kept
+// until no package needs them; pre-existing directories are never recorded.
+func (e *Executor) makeDirs(dir, pkgName string) error {
+	if e.DryRun {
+		e.logVerbose("DRY RUN: Would create directory: %s\n", dir)
+		return nil
+	}
+
+	e.logVerbose("Ensuring directory exists: %s\n", dir)
+
+	// Parallel operations may share parents; checking and creating them as one
+	// step keeps every package's claim on the directories it needs
+	e.shared.dirMu.Lock()
+	defer e.shared.dirMu.Unlock()
+
+	var missing []string
+	for current := dir; isSubPath(e.TargetDir, current); current = filepath.Dir(current) {
+		if _, err := orOS(e.FS).Lstat(current); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			missing = append(missing, current)
+			continue
+		}
+		e.State.claimDir(current, pkgName, false)
+	}
+
+	mode := e.DirMode
+	if mode == 0 {
+		mode = DefaultDirMode
+	}
+	err := e.retry("create directory", dir, func() error {
+		return orOS(e.FS).MkdirAll(dir, mode)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, created := range missing {
+		e.State.claimDir(created, pkgName, true)
+	}
+	return nil
+}
+
+// rmdir drops op.Package's claim on a directory gslk created and removes the
+// directory once no package needs it anymore. Directories still holding other
+// files are kept (and stay recorded) unless ForceRemove is set, in which case
+// they are moved to the trash.
+func (e *Executor) rmdir(op Operation) error {
+	if owners := e.State.releaseDir(op.Target, op.Package); len(owners) > 0 {
+		e.logVerbose("Keeping directory %s: still used by %v\n", op.Target, owners)
+		return nil
+	}
+
+	if e.DryRun {
+		e.printf("Removing directory: %s\n", op.Target)
+		return nil
+	}
+
+	removeErr := e.retry("remove directory", op.Target, func() error {
+		return orOS(e.FS).Remove(op.Target)
+	})
+	switch {
+	case removeErr == nil || os.IsNotExist(removeErr):
+		e.printf("Removed directory: %s\n", op.Target)
+		e.State.forgetDir(op.Target)
+	case e.ForceRemove:
+		// Likely not empty; keep whatever is left in the trash instead of deleting it
+		ok, err := e.confirm(fmt.Sprintf("Move non-empty directory %s to the trash", op.Target))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			e.printf("Kept non-empty directory: %s\n", op.Target)
+			return nil
+		}
+		if err := e.trash(op.Target, op.Package); err != nil {
+			e.warnf("Failed to force-remove directory %s: %v\n", op.Target, err)
+		} else {
+			e.State.forgetDir(op.Target)
+		}
+	default:
+		// Likely not empty, which is expected behavior
+		e.printf("Skipped non-empty directory: %s\n", op.Target)
+	}
+	return nil
+}
+
+// link creates a symbolic link at op.Target pointing to op.Source. A deployed
+// copy or a link to another file of the package in the way is replaced
+// atomically: the new link is created under a temporary name and renamed over
+// it, so op.Target never disappears.
+func (e *Executor) link(op Operation) error {
+	switch {
+	case op.Adopt:
+		e.printf("Replacing identical file with link: %s\n", op.Target)
+	case op.Overwrite:
+		e.printf("Overwriting with link: %s\n", op.Target)
+	case op.Current == TargetDeployed:
+		e.printf("Replacing copy with link: %s\n", op.Target)
+	case op.Current == TargetForeignLink:
+		e.printf("Replacing link: %s\n", op.Target)
+	}
+
+	e.printf("Linking: %s -> %s\n", op.Source, op.Target)
+
+	if e.DryRun {
+		return nil
+	}
+
+	// Ensure parent directory exists
+	targetDir := filepath.Dir(op.Target)
+	if err := e.makeDirs(targetDir, op.Package); err != nil {
+		return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
+	}
+
+	// Create the symbolic link with absolute path
+	absSourcePath, err := filepath.Abs(op.Source)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for source %s: %w", op.Source, err)
+	}
+
+	if op.Current == TargetDeployed || op.Current == TargetForeignLink {
+		if err := e.replaceWithLink(absSourcePath, op.Target); err != nil {
+			return fmt.Errorf("failed to replace %s with a symlink to %s: %w", op.Target, op.Source, err)
+		}
+		e.State.Forget(op.Target)
+		return nil
+	}
+
+	err = e.retry("create symlink", op.Target, func() error {
+		return orOS(e.FS).Symlink(absSourcePath, op.Target)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create symlink from %s to %s: %w", op.Source, op.Target, err)
+	}
+	return nil
+}
+
+// replaceWithLink atomically replaces the file at target by a symlink to
+// source, creating the link next to it and renaming it into place.
+func (e *Executor) replaceWithLink(source, target string) error {
+	fsys := orOS(e.FS)
+	tmp := filepath.Join(filepath.Dir(target), fmt.Sprintf(".%s.gslk-%d", filepath.Base(target), time.Now().UnixNano()))
+	err := e.retry("create symlink", tmp, func() error {
+		return fsys.Symlink(source, tmp)
+	})
+	if err != nil {
+		return err
+	}
+	err = e.retry("rename symlink into", target, func() error {
+		return fsys.Rename(tmp, target)
+	})
+	if err != nil {
+		fsys.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// copy deploys a copy of op.Source at op.Target and records it in state.
+func (e *Executor) copy(op Operation) error {
+	action := "Copying"
+	switch op.Current {
+	case TargetLinked:
+		action = "Replacing link with copy"
+	case TargetDeployed:
+		action = "Updating copy"
+	}
+	if op.Adopt {
+		action = "Adopting identical file as copy"
+	}
+	if op.Overwrite {
+		action = "Overwriting with copy"
+	}
+	e.printf("%s: %s -> %s\n", action, op.Source, op.Target)
+
+	if e.DryRun {
+		return nil
+	}
+
+	targetDir := filepath.Dir(op.Target)
+	if err := e.makeDirs(targetDir, op.Package); err != nil {
+		return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
+	}
+
+	if err := copyFile(op.Source, op.Target, op.Perm); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", op.Source, op.Target, err)
+	}
+
+	e.State.Record(StateEntry{
+		Package: op.Package,
+		Source:  op.Source,
+		Target:  op.Target,
+		Mode:    ModeCopy,
+		Hash:    op.Hash,
+	})
+	return nil
+}
+
+// render writes the generated content of op to op.Target and records it in state.
+// Unless op.Perm is set, rendered templates keep the permissions of their
+// source and decrypted secrets are only readable by the owner.
+func (e *Executor) render(op Operation) error {
+	action := "Rendering"
+	if op.Mode == ModeSecret {
+		action = "Decrypting"
+	}
+	if op.Current == TargetDeployed {
+		action = fmt.Sprintf("Updating %s", op.Mode)
+	}
+	if op.Adopt {
+		action = fmt.Sprintf("Adopting identical file as %s", op.Mode)
+	}
+	if op.Overwrite {
+		action = fmt.Sprintf("Overwriting with %s", op.Mode)
+	}
+	e.printf("%s: %s -> %s\n", action, op.Source, op.Target)
+
+	if e.DryRun {
+		return nil
+	}
+
+	targetDir := filepath.Dir(op.Target)
+	if err := e.makeDirs(targetDir, op.Package); err != nil {
+		return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
+	}
+
+	perm := os.FileMode(0600)
+	switch {
+	case op.Perm != 0:
+		perm = op.Perm
+	case op.Mode != ModeSecret:
+		perm = 0644
+		if fi, err := os.Stat(op.Source); err == nil {
+			perm = fi.Mode().Perm()
+		}
+	}
+	if err := writeFile(op.Target, bytes.NewReader(op.Content), perm); err != nil {
+		return fmt.Errorf("failed to write %s %s: %w", op.Mode, op.Target, err)
+	}
+
+	e.State.Record(StateEntry{
+		Package: op.Package,
+		Source:  op.Source,
+		Target:  op.Target,
+		Mode:    op.Mode,
+		Hash:    op.Hash,
+	})
+	return nil
+}
+
+// quarantine moves the file occupying op.Target into the quarantine directory
+// and records it in state so it can be reviewed later.
+func (e *Executor) quarantine(op Operation) error {
+	ok, err := e.confirm(fmt.Sprintf("Move %s to the quarantine directory and replace it", op.Target))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: target %s already exists and was kept", ErrConflict, op.Target)
+	}
+
+	now := time.Now()
+	savedPath, err := saveAsidePath(filepath.Join(e.TargetDir, QuarantineDirName), e.TargetDir, op.Target, now)
+	if err != nil {
+		return err
+	}
+
+	e.printf("Quarantining: %s -> %s\n", op.Target, savedPath)
+
+	if e.DryRun {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(savedPath), 0700); err != nil {
+		return fmt.Errorf("failed to create quarantine directory for %s: %w", savedPath, err)
+	}
+	if err := os.Rename(op.Target, savedPath); err != nil {
+		return fmt.Errorf("failed to quarantine %s: %w", op.Target, err)
+	}
+
+	// A file quarantined in place of a deployed copy no longer belongs to gslk
+	e.State.Forget(op.Target)
+	e.State.addQuarantine(QuarantineEntry{
+		Package: op.Package,
+		Source:  op.Source,
+		Target:  op.Target,
+		Saved:   savedPath,
+		Time:    now,
+	})
+	return nil
+}
+
+// unlink removes the symlink at op.Target.
+func (e *Executor) unlink(op Operation) error {
+	e.printf("Unlinking: %s (link to %s)\n", op.Target, op.Source)
+
+	// In dry run mode, don't make actual changes
+	if e.DryRun {
+		return nil
+	}
+
+	err := e.retry("remove symlink", op.Target, func() error {
+		return orOS(e.FS).Remove(op.Target)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove symlink %s: %w", op.Target, err)
+	}
+	return nil
+}
+
+// mount bind-mounts the directory op.Source onto op.Target, creating the
+// mount point if needed, and records the mount in state so unlinking undoes
+// it. A mount point gslk creates is released like any directory it created.
+func (e *Executor) mount(op Operation) error {
+	if op.Current == TargetDirectory {
+		e.printf("Mounting over empty directory: %s\n", op.Target)
+	}
+	e.printf("Bind-mounting: %s -> %s\n", op.Source, op.Target)
+
+	if e.DryRun {
+		return nil
+	}
+
+	mounter, ok := orOS(e.FS).(Mounter)
+	if !ok {
+		return fmt.Errorf("cannot bind-mount %s: the filesystem does not support bind mounts", op.Target)
+	}
+	if err := e.makeDirs(op.Target, op.Package); err != nil {
+		return fmt.Errorf("failed to create mount point %s: %w", op.Target, err)
+	}
+	absSourcePath, err := filepath.Abs(op.Source)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for source %s: %w", op.Source, err)
+	}
+	err = e.retry("bind-mount", op.Target, func() error {
+		return mounter.BindMount(absSourcePath, op.Target)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to bind-mount %s on %s: %w", op.Source, op.Target, err)
+	}
+
+	e.State.Record(StateEntry{
+		Package: op.Package,
+		Source:  absSourcePath,
+		Target:  op.Target,
+		Mode:    ModeBind,
+	})
+	return nil
+}
+
+// unmount undoes the bind mount at op.Target and forgets it. A recorded
+// mount that is gone already, e.g. after a reboot, is only forgotten.
+func (e *Executor) unmount(op Operation) error {
+	if op.Current != TargetLinked {
+		e.logVerbose("Forgetting bind mount of %s: no longer mounted\n", op.Target)
+		if !e.DryRun {
+			e.State.Forget(op.Target)
+		}
+		return nil
+	}
+
+	e.printf("Unmounting: %s (bind mount of %s)\n", op.Target, op.Source)
+
+	if e.DryRun {
+		return nil
+	}
+
+	mounter, ok := orOS(e.FS).(Mounter)
+	if !ok {
+		return fmt.Errorf("cannot unmount %s: the filesystem does not support bind mounts", op.Target)
+	}
+	err := e.retry("unmount", op.Target, func() error {
+		return mounter.Unmount(op.Target)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unmount %s: %w", op.Target, err)
+	}
+	e.State.Forget(op.Target)
+	return nil
+}
+
+// remove deletes a copied or rendered file recorded in state. Files modified
+// since they were deployed are moved to the trash instead.
+func (e *Executor) remove(op Operation) error {
+	if op.Current == TargetModified {
+		e.warnf("Warning: removing locally modified %s %s\n", op.Mode, op.Target)
+	}
+
+	switch op.Mode {
+	case ModeTemplate:
+		e.printf("Removing rendered template: %s (rendered from %s)\n", op.Target, op.Source)
+	case ModeSecret:
+		e.printf("Removing decrypted secret: %s (decrypted from %s)\n", op.Target, op.Source)
+	default:
+		e.printf("Removing copy: %s (copied from %s)\n", op.Target, op.Source)
+	}
+
+	if op.Current == TargetModified {
+		ok, err := e.confirm(fmt.Sprintf("Move locally modified %s %s to the trash", op.Mode, op.Target))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("kept locally modified %s %s", op.Mode, op.Target)
+		}
+		if err := e.trash(op.Target, op.Package); err != nil {
+			return err
+		}
+		if !e.DryRun {
+			e.State.Forget(op.Target)
+		}
+		return nil
+	}
+
+	if e.DryRun {
+		return nil
+	}
+
+	err := e.retry("remove deployed file", op.Target, func() error {
+		return orOS(e.FS).Remove(op.Target)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove deployed file %s: %w", op.Target, err)
+	}
+	e.State.Forget(op.Target)
+	return nil
+}
@@ -0,0 +1,235 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Explanation is the chain of decisions gslk takes for one path of a package.
+type Explanation struct {
+	Package string
+	RelPath string   // Path relative to the package root
+	Source  string   // File in the source directory providing the path
+	Target  string   // Where the path is deployed; empty if it is not
+	Steps   []string // Decisions taken for the path, in order
+}
+
+// Explain reports why the path relPath of package pkgName is linked, skipped,
+// ignored or in conflict: the ignore or include rule deciding about it, where
+// it is placed, what occupies its target and what linking the package would
+// do there. Nothing is modified.
+func (l *Linker) Explain(pkgName, relPath string) (*Explanation, error) {
+	packages, err := l.packagesByName()
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := l.findPackage(pkgName, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	relPath = filepath.Clean(filepath.FromSlash(relPath))
+	if !filepath.IsLocal(relPath) {
+		return nil, fmt.Errorf("%s is not a path inside package %s", relPath, pkg.Name)
+	}
+	e := &Explanation{Package: pkg.Name, RelPath: relPath}
+
+	// Later layers override earlier ones
+	layers := pkg.Layers
+	if len(layers) == 0 {
+		layers = []string{pkg.Path}
+	}
+	var layer string
+	for _, candidate := range slices.Backward(layers) {
+		if _, err := os.Lstat(filepath.Join(candidate, relPath)); err == nil {
+			layer = candidate
+			break
+		}
+	}
+	if layer == "" {
+		return nil, fmt.Errorf("package %s has no %s", pkg.Name, relPath)
+	}
+	e.Source = filepath.Join(layer, relPath)
+	if len(layers) > 1 {
+		e.step("provided by source %s, which has the highest precedence of the %d sources of the package", filepath.Dir(layer), len(layers))
+	}
+
+	if !pkg.Manifest.SupportsOS() {
+		e.step("package only supports %s, so it is not linked on %s", strings.Join(pkg.Manifest.OS, ", "), runtime.GOOS)
+		return e, nil
+	}
+	if reason, ok := l.explainIgnored(pkg, layer, relPath); ok {
+		e.step("ignored: %s", reason)
+		return e, nil
+	}
+	e.step("not ignored by any rule")
+
+	e.Target = l.targetPath(pkg, relPath)
+	if pkg.Manifest.Target != "" {
+		e.step("placed below %s, the target of the package manifest", pkg.Manifest.Target)
+	}
+	mapper := l.mapper(pkg)
+	mapped := mapper.Map(relPath)
+	if mapped != relPath {
+		e.step("renamed to %s", mapped)
+	}
+	if remapped := l.mapPath(pkg, &mapper, relPath); remapped != mapped {
+		e.step("moved to %s by the path mapper", remapped)
+	}
+	e.step("target: %s", e.Target)
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	c, err := (&Classifier{State: state, FS: l.FS}).Classify(pkg.Name, e.Source, e.Target)
+	if err != nil {
+		return nil, err
+	}
+	e.step("target currently holds: %s", c.State)
+	switch c.State {
+	case TargetForeignLink:
+		if owner, err := l.Owner(e.Target); err == nil {
+			e.step("target is linked from package %s (%s)", owner.Package, owner.RelPath)
+		} else if dest, err := resolveLinkTarget(l.FS, e.Target); err == nil {
+			e.step("target links to %s, outside the source directories", dest)
+		}
+	case TargetFile:
+		if entry, ok := state.Lookup(e.Target); ok {
+			e.step("target is a %s deployed by package %s", entry.Mode, entry.Package)
+		}
+	}
+
+	plan, err := l.PlanLink([]string{pkg.Name + "/" + filepath.ToSlash(relPath)})
+	if err != nil {
+		e.step("linking fails with conflict policy %s: %v", l.conflictPolicy(), err)
+		return e, nil
+	}
+	for _, op := range plan.Operations {
+		if op.Target != e.Target {
+			continue
+		}
+		switch {
+		case op.Adopt:
+			e.step("linking replaces the identical file in the way (conflict policy %s)", l.conflictPolicy())
+		case op.Overwrite:
+			e.step("linking overwrites the file in the way (conflict resolver)")
+		case op.Kind == OpSkip:
+			e.step("linking skips it: %s", op.Reason)
+		case op.Kind == OpQuarantine:
+			e.step("linking moves the file in the way to the quarantine directory (conflict policy %s)", l.conflictPolicy())
+		case op.Kind == OpUnlink:
+			e.step("linking removes the link of package %s (--override)", op.Package)
+		default:
+			e.step("linking performs: %s", op.Kind)
+		}
+	}
+	return e, nil
+}
+
+// step appends a decision to the explanation.
+func (e *Explanation) step(format string, args ...any) {
+	e.Steps = append(e.Steps, fmt.Sprintf(format, args...))
+}
+
+// explainIgnored reports which rule excludes relPath, a path in the given
+// layer of pkg, mirroring the rules walkLayer applies.
+func (l *Linker) explainIgnored(pkg Package, layer, relPath string) (string, bool) {
+	slashed := filepath.ToSlash(relPath)
+	for _, part := range strings.Split(slashed, "/") {
+		if isControlFile(part) {
+			return fmt.Sprintf("%s is a gslk control file", part), true
+		}
+	}
+
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i := range parts {
+		p := filepath.Join(parts[:i+1]...)
+		isDir := i < len(parts)-1
+		if !isDir {
+			fi, err := os.Lstat(filepath.Join(layer, p))
+			isDir = err == nil && fi.IsDir()
+		}
+		if reason := l.walkLimit(p, isDir); reason != "" {
+			return fmt.Sprintf("%s is %s", p, reason), true
+		}
+	}
+
+	// Patterns in the order walkLayer applies them, the last match deciding
+	type ignorePattern struct {
+		origin, pattern string
+		compiled        []compiledPattern
+	}
+	var ignorePatterns []ignorePattern
+	filePatterns, _ := loadIgnorePatterns(layer)
+	for _, set := range []struct {
+		origin   string
+		patterns []string
+	}{
+		{"--ignore or the configuration file", l.Ignore},
+		{ManifestFileName, pkg.Manifest.Ignore},
+		{IgnoreFileName, filePatterns},
+	} {
+		for _, pattern := range set.patterns {
+			ignorePatterns = append(ignorePatterns, ignorePattern{set.origin, pattern, compilePatterns([]string{strings.TrimPrefix(pattern, "!")})})
+		}
+	}
+	// A matching directory excludes everything below it, so the walk stops at
+	// the first excluded ancestor
+	parts = strings.Split(slashed, "/")
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+		for _, rule := range slices.Backward(ignorePatterns) {
+			if matchPatterns(p, rule.compiled) {
+				if !strings.HasPrefix(rule.pattern, "!") {
+					return fmt.Sprintf("%s matches pattern %q from %s", p, rule.pattern, rule.origin), true
+				}
+				break
+			}
+		}
+		for _, re := range l.IgnoreRegexp {
+			if re.MatchString(p) {
+				return fmt.Sprintf("%s matches --ignore-regex %q", p, re), true
+			}
+		}
+		if l.IgnoreMatcher != nil {
+			isDir := i < len(parts)-1
+			if !isDir {
+				fi, err := os.Lstat(filepath.Join(layer, relPath))
+				isDir = err == nil && fi.IsDir()
+			}
+			if l.IgnoreMatcher.Ignore(pkg, filepath.FromSlash(p), isDir) {
+				return fmt.Sprintf("%s is matched by the ignore matcher", p), true
+			}
+		}
+	}
+
+	fileIncludes, _ := loadPatternFile(filepath.Join(layer, IncludeFileName))
+	includeSets := []struct {
+		origin   string
+		patterns []string
+	}{
+		{IncludeFileName + " and " + ManifestFileName, slices.Concat(fileIncludes, pkg.Manifest.Include)},
+		{"--only", l.Only},
+	}
+	if fi, err := os.Lstat(filepath.Join(layer, relPath)); err == nil && !fi.IsDir() {
+		for _, set := range includeSets {
+			if len(set.patterns) > 0 && !NewIgnoreRules(nil).WithInclude(set.patterns).Includes(relPath) {
+				return fmt.Sprintf("not selected by the include patterns of %s: %s", set.origin, strings.Join(set.patterns, ", ")), true
+			}
+		}
+	}
+	return "", false
+}
+
+// conflictPolicy returns the effective conflict policy.
+func (l *Linker) conflictPolicy() ConflictPolicy {
+	if l.ConflictPolicy == "" {
+		return ConflictFail
+	}
+	return l.ConflictPolicy
+}
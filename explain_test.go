@@ -0,0 +1,60 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		"init.lua":        "init",
+		"lua/plugins.lua": "plugins",
+		"lua/init.lua":    "init",
+		"docs/README.md":  "docs",
+		"local.lua":       "local",
+		IgnoreFileName:    "docs\nlua/*.lua\n!lua/init.lua",
+		ManifestFileName:  "target = \".config/nvim\"\nignore = [\"*.bak\"]\n",
+		"lazy-lock.json":  "lock",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "other"), map[string]string{".config/nvim/local.lua": "other"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, IgnoreRegexp: []*regexp.Regexp{regexp.MustCompile(`\.json$`)}}
+	_, err := linker.Link([]string{"other"})
+	require.NoError(t, err)
+
+	steps := func(relPath string) string {
+		t.Helper()
+		e, err := linker.Explain("nvim", relPath)
+		require.NoError(t, err)
+		return strings.Join(e.Steps, "\n")
+	}
+
+	assert.Contains(t, steps("docs/README.md"), `ignored: docs matches pattern "docs" from `+IgnoreFileName)
+	assert.Contains(t, steps("lua/init.lua"), "not ignored by any rule", "re-included by a negated pattern")
+	assert.Contains(t, steps("lazy-lock.json"), `matches --ignore-regex "\\.json$"`)
+	assert.Contains(t, steps("lua/plugins.lua"), `ignored: lua/plugins.lua matches pattern "lua/*.lua" from `+IgnoreFileName)
+
+	e, err := linker.Explain("nvim", "init.lua")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"), e.Target)
+	assert.Contains(t, strings.Join(e.Steps, "\n"), "linking performs: link")
+
+	local := steps("local.lua")
+	assert.Contains(t, local, "target is linked from package other")
+	assert.Contains(t, local, "linking fails with conflict policy fail")
+
+	require.NoError(t, os.Symlink(filepath.Join(sourceDir, "nvim", "init.lua"), e.Target))
+	assert.Contains(t, steps("init.lua"), "linking skips it")
+
+	_, err = linker.Explain("nvim", "missing.lua")
+	assert.ErrorContains(t, err, "package nvim has no missing.lua")
+}
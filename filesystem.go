@@ -0,0 +1,136 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Filesystem describes the filesystem holding a directory, as far as linking
+// into it is concerned.
+type Filesystem struct {
+	Type     string // Filesystem type, such as "ext4", "nfs" or "vfat"; empty if unknown
+	Network  bool   // Mounted over the network, where links may point elsewhere on other machines
+	Symlinks bool   // Symbolic links can be created in it
+}
+
+// noSymlinkFilesystems lists filesystem types without symbolic links.
+var noSymlinkFilesystems = map[string]bool{"vfat": true, "msdos": true, "fat": true, "fat32": true, "exfat": true}
+
+// DetectFilesystem inspects the filesystem holding dir, or its closest
+// existing parent if dir does not exist yet. The type is only known on Linux,
+// macOS and Windows.
+func DetectFilesystem(dir string) Filesystem {
+	dir = existingAncestor(dir)
+	typ, network := filesystemType(dir)
+	return Filesystem{
+		Type:     typ,
+		Network:  network,
+		Symlinks: !noSymlinkFilesystems[strings.ToLower(typ)] && symlinkSupported(dir),
+	}
+}
+
+// AutoMode returns the mode files are best deployed into the directory dir
+// with: ModeLink if symbolic links can be created there, ModeCopy otherwise.
+// Besides the filesystem type, a link is created and removed again to find
+// out, since some mounts of containers and virtual machines refuse links
+// whatever their type says. Where nothing can be created, links are assumed
+// to work.
+func AutoMode(dir string) DeployMode {
+	if !DetectFilesystem(dir).Symlinks {
+		return ModeCopy
+	}
+	dir = existingAncestor(dir)
+	if !isWritable(dir) {
+		return ModeLink
+	}
+	probe := filepath.Join(dir, fmt.Sprintf(".gslk-probe-%d", time.Now().UnixNano()))
+	if err := os.Symlink(".", probe); err != nil {
+		return ModeCopy
+	}
+	os.Remove(probe)
+	return ModeLink
+}
+
+// existingAncestor returns dir, or its closest parent that exists as a
+// directory.
+func existingAncestor(dir string) string {
+	for {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// FilesystemPolicy decides what Link does when the target directory is on a
+// filesystem without symbolic links, such as FAT, or on a network filesystem,
+// such as NFS or SMB, where links work but may point elsewhere when the
+// share is mounted on another machine.
+type FilesystemPolicy string
+
+const (
+	// FilesystemWarn links into network filesystems and copies files where
+	// links cannot be created, with a warning either way (default)
+	FilesystemWarn FilesystemPolicy = "warn"
+
+	FilesystemCopy   FilesystemPolicy = "copy"   // Copy files instead of linking them
+	FilesystemRefuse FilesystemPolicy = "refuse" // Fail without changing anything
+)
+
+// ParseFilesystemPolicy checks that s names a FilesystemPolicy; empty means
+// FilesystemWarn.
+func ParseFilesystemPolicy(s string) (FilesystemPolicy, error) {
+	switch policy := FilesystemPolicy(s); policy {
+	case "":
+		return FilesystemWarn, nil
+	case FilesystemWarn, FilesystemCopy, FilesystemRefuse:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid filesystem policy %q: must be 'warn', 'copy' or 'refuse'", s)
+}
+
+// deployMode returns the mode files are deployed with. In link mode a target
+// directory on a filesystem without symbolic links, as on Windows without
+// Developer Mode, or on a network filesystem is handled according to
+// FilesystemPolicy. Targets on another machine are not inspected.
+func (l *Linker) deployMode() (DeployMode, error) {
+	if l.Mode == ModeCopy || l.Ephemeral {
+		return ModeCopy, nil
+	}
+	if _, remote := l.FS.(RemoteFS); remote {
+		return ModeLink, nil
+	}
+	policy, err := ParseFilesystemPolicy(string(l.FilesystemPolicy))
+	if err != nil {
+		return "", err
+	}
+
+	fsys := DetectFilesystem(l.TargetDir)
+	var problem string
+	switch {
+	case !fsys.Symlinks:
+		problem = fmt.Sprintf("symbolic links are not supported in %s", l.TargetDir)
+	case fsys.Network:
+		problem = fmt.Sprintf("%s is on a network filesystem (%s), where links may break when it is mounted elsewhere", l.TargetDir, fsys.Type)
+	default:
+		return ModeLink, nil
+	}
+
+	switch {
+	case policy == FilesystemRefuse:
+		return "", fmt.Errorf("%s; refusing to link (filesystem policy %s)", problem, policy)
+	case policy == FilesystemCopy || !fsys.Symlinks:
+		l.warnf("Warning: %s, copying files instead\n", problem)
+		return ModeCopy, nil
+	default:
+		l.warnf("Warning: %s\n", problem)
+		return ModeLink, nil
+	}
+}
@@ -0,0 +1,67 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	fsys := DetectFilesystem(dir)
+	assert.False(t, fsys.Network, "Temporary directories are local")
+	if runtime.GOOS != "windows" {
+		assert.True(t, fsys.Symlinks)
+	}
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		assert.NotEmpty(t, fsys.Type)
+	}
+
+	assert.Equal(t, fsys, DetectFilesystem(filepath.Join(dir, "not", "created", "yet")), "Missing directories are on the filesystem of their parent")
+}
+
+func TestAutoMode(t *testing.T) {
+	dir := t.TempDir()
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, ModeLink, AutoMode(filepath.Join(dir, "home")))
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "The probe link is removed")
+}
+
+func TestFilesystemPolicy(t *testing.T) {
+	for s, want := range map[string]FilesystemPolicy{"": FilesystemWarn, "warn": FilesystemWarn, "copy": FilesystemCopy, "refuse": FilesystemRefuse} {
+		policy, err := ParseFilesystemPolicy(s)
+		require.NoError(t, err)
+		assert.Equal(t, want, policy)
+	}
+	_, err := ParseFilesystemPolicy("ignore")
+	assert.ErrorContains(t, err, "must be 'warn', 'copy' or 'refuse'")
+
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte("fs_policy = \"copy\"\n"), 0644))
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, FilesystemCopy, config.FilesystemPolicy)
+
+	require.NoError(t, os.WriteFile(path, []byte("fs_policy = \"sometimes\"\n"), 0644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "fs_policy")
+
+	// Local targets are linked whatever the policy
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{"file": "content"})
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, FilesystemPolicy: FilesystemRefuse}
+	if fsys := DetectFilesystem(targetDir); fsys.Network || !fsys.Symlinks {
+		t.Skip("the temporary directory is not on a local filesystem with symbolic links")
+	}
+	plan, err := linker.PlanLink([]string{"pkg"})
+	require.NoError(t, err)
+	assert.Equal(t, []OpKind{OpLink}, opsByTarget(t, plan, targetDir)["file"])
+}
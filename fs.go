@@ -0,0 +1,154 @@
+package gslk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is the set of filesystem operations gslk uses to walk packages and to
+// manage links and directories in the target tree. OSFS, the default, uses the
+// os package; other implementations can keep everything in memory for tests,
+// only record what would change, or reach another machine.
+//
+// Deploying copies, rendered templates and secrets, and quarantining files
+// still use the os package directly, as does the state manifest unless the FS
+// is a FileReader and a FileWriter.
+type FS interface {
+	Lstat(name string) (fs.FileInfo, error)
+	Stat(name string) (fs.FileInfo, error)
+	Readlink(name string) (string, error)
+	Symlink(oldname, newname string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldpath, newpath string) error
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// FileWriter is implemented by filesystems that write whole files themselves.
+// The state manifest is saved through the FS of the Linker if it implements
+// FileWriter.
+type FileWriter interface {
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// FileReader is implemented by filesystems that read whole files themselves.
+// The state manifest is read through the FS of the Linker if it implements
+// FileReader.
+type FileReader interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// Mounter is implemented by filesystems able to bind-mount a directory onto
+// another, which the directories matching the bind patterns of a package are
+// deployed with (see Manifest.Bind). OSFS mounts with mount(2) on Linux,
+// which takes root or CAP_SYS_ADMIN, as in a user namespace, and links the
+// directories with junctions on Windows, which, unlike symbolic links, need
+// neither Developer Mode nor administrator rights; SudoFS runs mount and
+// umount through sudo.
+type Mounter interface {
+	BindMount(source, target string) error
+	Unmount(target string) error
+}
+
+// RemoteFS is implemented by filesystems managing a target tree on another
+// machine. Only links and the directories holding them are managed there:
+// deploying copies, quarantining files and moving them to the trash use the os
+// package, and are refused. The state manifest is kept on the other machine
+// only if the filesystem is also a FileReader and a FileWriter.
+type RemoteFS interface {
+	FS
+	// Location describes where the target tree is, e.g. "ssh://host"
+	Location() string
+}
+
+// OSFS is the FS of the local operating system.
+type OSFS struct{}
+
+func (OSFS) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (OSFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (OSFS) Readlink(name string) (string, error)         { return os.Readlink(name) }
+func (OSFS) Symlink(oldname, newname string) error        { return os.Symlink(oldname, newname) }
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Remove(name string) error                     { return os.Remove(name) }
+func (OSFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (OSFS) WalkDir(root string, fn fs.WalkDirFunc) error { return filepath.WalkDir(root, fn) }
+
+// ErrReadOnly is wrapped by the errors of changes refused in read-only mode
+// (see Linker.ReadOnly).
+var ErrReadOnly = errors.New("read-only mode")
+
+// ReadOnlyFS wraps an FS, nil meaning the local filesystem, and refuses every
+// change with an error wrapping ErrReadOnly while reads go through. It is a
+// FileWriter refusing to write too, so the state manifest is never saved
+// through it. gslk also refuses the changes it makes outside FS, such as
+// deploying copies, quarantining files and running hooks, when the FS it
+// works on is a ReadOnlyFS.
+type ReadOnlyFS struct {
+	FS FS
+}
+
+func (r ReadOnlyFS) Lstat(name string) (fs.FileInfo, error) { return orOS(r.FS).Lstat(name) }
+func (r ReadOnlyFS) Stat(name string) (fs.FileInfo, error)  { return orOS(r.FS).Stat(name) }
+func (r ReadOnlyFS) Readlink(name string) (string, error)   { return orOS(r.FS).Readlink(name) }
+func (r ReadOnlyFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return orOS(r.FS).WalkDir(root, fn)
+}
+
+func (ReadOnlyFS) Symlink(oldname, newname string) error { return readOnlyError("symlink", newname) }
+
+// MkdirAll succeeds if path is a directory already, as that changes nothing.
+func (r ReadOnlyFS) MkdirAll(path string, perm fs.FileMode) error {
+	if fi, err := r.Stat(path); err == nil && fi.IsDir() {
+		return nil
+	}
+	return readOnlyError("mkdir", path)
+}
+
+func (ReadOnlyFS) Remove(name string) error             { return readOnlyError("remove", name) }
+func (ReadOnlyFS) Rename(oldpath, newpath string) error { return readOnlyError("rename", oldpath) }
+func (ReadOnlyFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return readOnlyError("write", name)
+}
+func (ReadOnlyFS) BindMount(source, target string) error { return readOnlyError("mount", target) }
+func (ReadOnlyFS) Unmount(target string) error           { return readOnlyError("unmount", target) }
+
+// ReadFile reads name through the wrapped FS if it is a FileReader.
+func (r ReadOnlyFS) ReadFile(name string) ([]byte, error) {
+	if fr, ok := r.FS.(FileReader); ok {
+		return fr.ReadFile(name)
+	}
+	return os.ReadFile(name)
+}
+
+// readOnlyError is the error of a change to path refused in read-only mode.
+func readOnlyError(op, path string) error {
+	return &fs.PathError{Op: op, Path: path, Err: ErrReadOnly}
+}
+
+// checkWritable returns the error of refusing the change op to path if fsys
+// is a ReadOnlyFS, for changes made without going through fsys.
+func checkWritable(fsys FS, op, path string) error {
+	if _, ok := fsys.(ReadOnlyFS); ok {
+		return readOnlyError(op, path)
+	}
+	return nil
+}
+
+// fs returns the FS changes to the target are made through: FS, or FS
+// wrapped in a ReadOnlyFS in read-only mode.
+func (l *Linker) fs() FS {
+	if l.ReadOnly {
+		return ReadOnlyFS{FS: l.FS}
+	}
+	return l.FS
+}
+
+// orOS returns fsys, or OSFS if fsys is nil.
+func orOS(fsys FS) FS {
+	if fsys == nil {
+		return OSFS{}
+	}
+	return fsys
+}
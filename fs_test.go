@@ -0,0 +1,112 @@
+package gslk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFS wraps the local filesystem, records the links it creates and
+// refuses to create links at the paths in fail.
+type recordingFS struct {
+	OSFS
+	fail map[string]bool
+
+	mu    sync.Mutex
+	links []string
+}
+
+func (r *recordingFS) Symlink(oldname, newname string) error {
+	if r.fail[newname] {
+		return &fs.PathError{Op: "symlink", Path: newname, Err: fs.ErrPermission}
+	}
+	r.mu.Lock()
+	r.links = append(r.links, newname)
+	r.mu.Unlock()
+	return r.OSFS.Symlink(oldname, newname)
+}
+
+func TestLinkUsesFS(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "fs_pkg"), map[string]string{
+		"a.txt":     "a",
+		"dir/b.txt": "b",
+	})
+
+	fsys := &recordingFS{}
+	linker := New(sourceDir, targetDir, WithFS(fsys))
+	_, err := linker.Link([]string{"fs_pkg"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(targetDir, "a.txt"), filepath.Join(targetDir, "dir", "b.txt")}, fsys.links)
+}
+
+func TestLinkFSFailure(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "fs_pkg"), map[string]string{"a.txt": "a"})
+	failing := filepath.Join(targetDir, "a.txt")
+
+	linker := New(sourceDir, targetDir, WithFS(&recordingFS{fail: map[string]bool{failing: true}}))
+	result, err := linker.Link([]string{"fs_pkg"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	assert.Equal(t, []string{failing}, result.Failed)
+
+	_, err = os.Lstat(failing)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadOnly(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "ro_pkg"), map[string]string{
+		"a.txt":     "a",
+		"dir/b.txt": "b",
+	})
+	readOnly := New(sourceDir, targetDir, WithReadOnly())
+
+	plan, err := readOnly.PlanLink([]string{"ro_pkg"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, plan.Operations)
+
+	result, err := readOnly.Link([]string{"ro_pkg"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.NotEmpty(t, result.Failed)
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing is created in read-only mode")
+
+	// Once linked, a read-only link has nothing to do and succeeds
+	_, err = New(sourceDir, targetDir).Link([]string{"ro_pkg"})
+	require.NoError(t, err)
+	_, err = readOnly.Link([]string{"ro_pkg"})
+	require.NoError(t, err)
+	_, err = readOnly.Status()
+	require.NoError(t, err)
+
+	_, err = readOnly.Unlink([]string{"ro_pkg"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	_, err = os.Lstat(filepath.Join(targetDir, "a.txt"))
+	assert.NoError(t, err, "links are left in place")
+
+	_, err = readOnly.LockTarget(0)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, readOnly.EmptyTrash(), ErrReadOnly)
+	_, err = readOnly.RecordGeneration()
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	// Dry runs report what they would do as usual
+	_, err = New(sourceDir, targetDir, WithReadOnly(), WithDryRun()).Unlink([]string{"ro_pkg"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,23 @@
+package gslk
+
+import "syscall"
+
+// networkFilesystems lists the macOS filesystem types mounted over the network.
+var networkFilesystems = map[string]bool{"nfs": true, "smbfs": true, "afpfs": true, "webdav": true, "cifs": true}
+
+// filesystemType returns the type of the filesystem holding dir and whether
+// it is mounted over the network.
+func filesystemType(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	name := make([]byte, 0, len(st.Fstypename))
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name), networkFilesystems[string(name)]
+}
@@ -0,0 +1,44 @@
+package gslk
+
+import "syscall"
+
+// linuxFilesystems names the filesystem types by the magic number statfs(2)
+// reports for them, and whether they are mounted over the network.
+var linuxFilesystems = map[uint32]struct {
+	name    string
+	network bool
+}{
+	0xEF53:     {"ext4", false},
+	0x9123683E: {"btrfs", false},
+	0x58465342: {"xfs", false},
+	0x2FC12FC1: {"zfs", false},
+	0xF2F52010: {"f2fs", false},
+	0x01021994: {"tmpfs", false},
+	0x794C7630: {"overlay", false},
+	0x65735546: {"fuse", false},
+	0x5346544E: {"ntfs", false},
+	0x4D44:     {"vfat", false},
+	0x2011BAB0: {"exfat", false},
+	0x6969:     {"nfs", true},
+	0x517B:     {"smb", true},
+	0xFF534D42: {"cifs", true},
+	0xFE534D42: {"smb2", true},
+	0x5346414F: {"afs", true},
+	0x00C36400: {"ceph", true},
+	0x73757245: {"coda", true},
+	0x01021997: {"9p", true}, // Also the Windows drives of WSL
+}
+
+// filesystemType returns the type of the filesystem holding dir and whether
+// it is mounted over the network.
+func filesystemType(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	fs, ok := linuxFilesystems[uint32(st.Type)]
+	if !ok {
+		return "", false
+	}
+	return fs.name, fs.network
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package gslk
+
+// filesystemType returns the type of the filesystem holding dir and whether
+// it is mounted over the network. It is not known on this platform.
+func filesystemType(dir string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,40 @@
+package gslk
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	procGetVolumePathNameW    = kernel32.NewProc("GetVolumePathNameW")
+	procGetVolumeInformationW = kernel32.NewProc("GetVolumeInformationW")
+	procGetDriveTypeW         = kernel32.NewProc("GetDriveTypeW")
+)
+
+// driveRemote is DRIVE_REMOTE, the drive type of network shares.
+const driveRemote = 4
+
+// filesystemType returns the type of the filesystem holding dir, such as
+// "NTFS" or "FAT32", and whether it is a network share.
+func filesystemType(dir string) (string, bool) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return "", false
+	}
+	var root [syscall.MAX_PATH + 1]uint16
+	if r, _, _ := procGetVolumePathNameW.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&root[0])), uintptr(len(root))); r == 0 {
+		return "", strings.HasPrefix(filepath.Clean(dir), `\\`)
+	}
+	r, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(&root[0])))
+	network := r == driveRemote
+
+	var name [syscall.MAX_PATH + 1]uint16
+	r, _, _ = procGetVolumeInformationW.Call(uintptr(unsafe.Pointer(&root[0])), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&name[0])), uintptr(len(name)))
+	if r == 0 {
+		return "", network
+	}
+	return syscall.UTF16ToString(name[:]), network
+}
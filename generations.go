@@ -0,0 +1,292 @@
+package gslk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerationsDirName is the directory in the target holding a snapshot of
+// the deployed files for every generation.
+const GenerationsDirName = ".gslk-generations"
+
+// Generation is a numbered snapshot of every file deployed from the source
+// directories into the target, recorded after a successful run.
+type Generation struct {
+	Number  int          `json:"number"`
+	Time    time.Time    `json:"time"`
+	Entries []StateEntry `json:"entries"` // Sorted by target; links have Mode ModeLink and no Hash
+}
+
+// Packages returns the names of the packages with files in the generation.
+func (g *Generation) Packages() []string {
+	names := make(map[string]bool)
+	for _, entry := range g.Entries {
+		names[entry.Package] = true
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// generationsDir returns the directory holding the generations of the target.
+func (l *Linker) generationsDir() string {
+	return filepath.Join(l.TargetDir, GenerationsDirName)
+}
+
+// generationPath returns the file of generation n.
+func (l *Linker) generationPath(n int) string {
+	return filepath.Join(l.generationsDir(), strconv.Itoa(n)+".json")
+}
+
+// checkLocalGenerations refuses generations on a remote target, whose
+// generations directory the linker cannot read or write.
+func (l *Linker) checkLocalGenerations() error {
+	if remote, ok := l.FS.(RemoteFS); ok {
+		return fmt.Errorf("generations are not supported on remote target %s", remote.Location())
+	}
+	return nil
+}
+
+// Generations returns the recorded generations, oldest first.
+func (l *Linker) Generations() ([]Generation, error) {
+	if err := l.checkLocalGenerations(); err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(l.generationsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read generations: %w", err)
+	}
+
+	var numbers []int
+	for _, dirEntry := range dirEntries {
+		n, err := strconv.Atoi(strings.TrimSuffix(dirEntry.Name(), ".json"))
+		if err == nil && strings.HasSuffix(dirEntry.Name(), ".json") {
+			numbers = append(numbers, n)
+		}
+	}
+	slices.Sort(numbers)
+
+	generations := make([]Generation, 0, len(numbers))
+	for _, n := range numbers {
+		generation, err := l.Generation(n)
+		if err != nil {
+			return nil, err
+		}
+		generations = append(generations, generation)
+	}
+	return generations, nil
+}
+
+// Generation reads generation n.
+func (l *Linker) Generation(n int) (Generation, error) {
+	data, err := os.ReadFile(l.generationPath(n))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Generation{}, fmt.Errorf("generation %d does not exist", n)
+		}
+		return Generation{}, fmt.Errorf("failed to read generation %d: %w", n, err)
+	}
+	var generation Generation
+	if err := json.Unmarshal(data, &generation); err != nil {
+		return Generation{}, fmt.Errorf("failed to parse generation %d: %w", n, err)
+	}
+	return generation, nil
+}
+
+// RecordGeneration snapshots the files currently deployed from the source
+// directories as a new generation, numbered after the latest one. If nothing
+// changed since the latest generation, it is returned instead. Nothing is
+// recorded in dry run mode.
+func (l *Linker) RecordGeneration() (Generation, error) {
+	if err := l.checkLocalGenerations(); err != nil {
+		return Generation{}, err
+	}
+	defer l.lockState()()
+
+	entries, err := l.deployedEntries()
+	if err != nil {
+		return Generation{}, err
+	}
+
+	generations, err := l.Generations()
+	if err != nil {
+		return Generation{}, err
+	}
+	next := Generation{Number: 1, Time: time.Now(), Entries: entries}
+	if len(generations) > 0 {
+		latest := generations[len(generations)-1]
+		if slices.Equal(latest.Entries, entries) {
+			return latest, nil
+		}
+		next.Number = latest.Number + 1
+	}
+	if l.DryRun {
+		return next, nil
+	}
+	if err := checkWritable(l.fs(), "write", l.generationPath(next.Number)); err != nil {
+		return Generation{}, err
+	}
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return Generation{}, fmt.Errorf("failed to encode generation: %w", err)
+	}
+	if err := os.MkdirAll(l.generationsDir(), 0755); err != nil {
+		return Generation{}, fmt.Errorf("failed to create generations directory: %w", err)
+	}
+	if err := os.WriteFile(l.generationPath(next.Number), append(data, '\n'), 0644); err != nil {
+		return Generation{}, fmt.Errorf("failed to write generation %d: %w", next.Number, err)
+	}
+	return next, nil
+}
+
+// deployedEntries returns an entry for every file of every package that is
+// deployed at its target, sorted by target. Modified copies are included as
+// recorded in the state manifest.
+func (l *Linker) deployedEntries() ([]StateEntry, error) {
+	// Recorded after a run, which already logged what walking the packages tells
+	quiet := *l
+	quiet.Logger = log.New(io.Discard, "", 0)
+	l = &quiet
+
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, err
+	}
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	var entries []StateEntry
+	for _, pkg := range packages {
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+			switch c.State {
+			case TargetLinked:
+				entries = append(entries, StateEntry{Package: pkg.Name, Source: path.sourcePath, Target: path.targetPath, Mode: ModeLink})
+			case TargetDeployed, TargetModified:
+				entries = append(entries, c.Entry)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+	}
+	slices.SortFunc(entries, func(a, b StateEntry) int { return strings.Compare(a.Target, b.Target) })
+	return entries, nil
+}
+
+// Rollback restores the layout of generation n: files deployed now but not
+// in it, or from another source, are unlinked, then the files of the
+// generation missing from the target are deployed again from their sources,
+// in the mode they had. Copies and templates get the current content of
+// their sources; files whose source no longer exists cannot be restored and
+// are reported. The restored layout is recorded as a new generation.
+//
+// The returned Result is never nil.
+func (l *Linker) Rollback(n int) (*Result, error) {
+	generation, err := l.Generation(n)
+	if err != nil {
+		return &Result{}, err
+	}
+	current, err := l.deployedEntries()
+	if err != nil {
+		return &Result{}, err
+	}
+
+	wanted := make(map[string]StateEntry, len(generation.Entries))
+	for _, entry := range generation.Entries {
+		wanted[entry.Target] = entry
+	}
+	deployed := make(map[string]StateEntry, len(current))
+	var stale []string
+	for _, entry := range current {
+		deployed[entry.Target] = entry
+		if want, ok := wanted[entry.Target]; !ok || want.Source != entry.Source || want.Mode != entry.Mode {
+			name, err := l.entryName(entry)
+			if err != nil {
+				return &Result{}, err
+			}
+			stale = append(stale, name)
+		}
+	}
+
+	// Files are deployed in the mode they had, so links and copies are
+	// planned apart
+	var links, copies []string
+	for _, entry := range generation.Entries {
+		if have, ok := deployed[entry.Target]; ok && have.Source == entry.Source && have.Mode == entry.Mode {
+			continue
+		}
+		if _, err := os.Stat(entry.Source); err != nil {
+			l.warnf("Warning: cannot restore %s: its source %s no longer exists\n", entry.Target, entry.Source)
+			continue
+		}
+		name, err := l.entryName(entry)
+		if err != nil {
+			return &Result{}, err
+		}
+		if entry.Mode == ModeLink {
+			links = append(links, name)
+		} else {
+			copies = append(copies, name)
+		}
+	}
+
+	result := &Result{}
+	if len(stale) > 0 {
+		unlinkResult, err := l.Unlink(stale)
+		result.Merge(unlinkResult)
+		if err != nil {
+			return result, err
+		}
+	}
+	for _, names := range []struct {
+		mode  DeployMode
+		names []string
+	}{{ModeLink, links}, {ModeCopy, copies}} {
+		if len(names.names) == 0 {
+			continue
+		}
+		linker := *l
+		linker.Mode = names.mode
+		linkResult, err := linker.Link(names.names)
+		result.Merge(linkResult)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := l.RecordGeneration(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// entryName returns the "package/path" name selecting the source file of entry.
+func (l *Linker) entryName(entry StateEntry) (string, error) {
+	_, rel, ok := l.containingSource(entry.Source)
+	if !ok {
+		return "", fmt.Errorf("%s is outside the source directories %s", entry.Source, strings.Join(l.sourceDirs(), ", "))
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,87 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerations(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{
+		".vimrc":            "set nu",
+		".vim/colors/x.vim": "hi",
+		".vim/plugin/p.vim": "p",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "zsh"), map[string]string{".zshrc": "z"})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": "g"})
+
+	linker := New(sourceDir, targetDir)
+	vimrc := filepath.Join(targetDir, ".vimrc")
+
+	_, err := linker.Link([]string{"vim", "git"})
+	require.NoError(t, err)
+	first, err := linker.RecordGeneration()
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Number)
+	assert.Equal(t, []string{"git", "vim"}, first.Packages())
+	assert.Len(t, first.Entries, 4)
+
+	t.Run("unchanged layout records no generation", func(t *testing.T) {
+		again, err := linker.RecordGeneration()
+		require.NoError(t, err)
+		assert.Equal(t, 1, again.Number)
+	})
+
+	_, err = linker.Unlink([]string{"vim", "git"})
+	require.NoError(t, err)
+	linker.Mode = ModeCopy
+	_, err = linker.Link([]string{"zsh", "git"})
+	require.NoError(t, err)
+	linker.Mode = ModeLink
+	second, err := linker.RecordGeneration()
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Number)
+	assert.Equal(t, []string{"git", "zsh"}, second.Packages())
+
+	t.Run("rollback restores the layout of a generation", func(t *testing.T) {
+		_, err := linker.Rollback(1)
+		require.NoError(t, err)
+
+		dest, err := os.Readlink(vimrc)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(sourceDir, "vim", ".vimrc"), dest)
+		assert.NoFileExists(t, filepath.Join(targetDir, ".zshrc"))
+
+		// The copy of .gitconfig is replaced by the link it was
+		fi, err := os.Lstat(filepath.Join(targetDir, ".gitconfig"))
+		require.NoError(t, err)
+		assert.NotZero(t, fi.Mode()&os.ModeSymlink)
+
+		generations, err := linker.Generations()
+		require.NoError(t, err)
+		require.Len(t, generations, 3)
+		assert.Equal(t, first.Entries, generations[2].Entries)
+	})
+
+	t.Run("rollback restores copies as copies", func(t *testing.T) {
+		_, err := linker.Rollback(2)
+		require.NoError(t, err)
+
+		fi, err := os.Lstat(filepath.Join(targetDir, ".zshrc"))
+		require.NoError(t, err)
+		assert.True(t, fi.Mode().IsRegular())
+		assert.NoFileExists(t, vimrc)
+		assert.Equal(t, ModeLink, linker.Mode)
+	})
+
+	t.Run("missing generations are reported", func(t *testing.T) {
+		_, err := linker.Rollback(9)
+		assert.ErrorContains(t, err, "generation 9 does not exist")
+	})
+}
@@ -0,0 +1,161 @@
+package gslk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RepoConfigFileName is the name of the configuration file at the root of a
+// dotfiles repository, read by gslk clone. It has the format of the user's
+// configuration file; relative paths are resolved against the repository.
+const RepoConfigFileName = "gslk.toml"
+
+// SourceMarkerName marks the root of a dotfiles repository without a
+// RepoConfigFileName, for FindSourceRoot.
+const SourceMarkerName = ".gslk"
+
+// FindSourceRoot returns the root of the dotfiles repository dir is in, as
+// git finds .git: the closest of dir and its parents holding a
+// RepoConfigFileName or SourceMarkerName. It reports false if there is none.
+func FindSourceRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		for _, name := range []string{RepoConfigFileName, SourceMarkerName} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// DefaultRepoDir returns where gslk clone puts dotfiles repositories: ~/.dotfiles.
+func DefaultRepoDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".dotfiles"), nil
+}
+
+// Git runs the git command-line tool on a repository, typically the one
+// holding the source directory.
+type Git struct {
+	Command string // Path or name of the git binary; defaults to "git"
+	Dir     string // Working tree of the repository
+}
+
+// command returns the git binary to run.
+func (g *Git) command() string {
+	if g.Command == "" {
+		return "git"
+	}
+	return g.Command
+}
+
+// IsRepo reports whether Dir is inside a git working tree.
+func (g *Git) IsRepo() bool {
+	out, err := g.run("rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// Clone clones the repository at url into Dir, which must not exist or be empty.
+func (g *Git) Clone(url string) error {
+	if err := os.MkdirAll(filepath.Dir(g.Dir), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", g.Dir, err)
+	}
+	_, err := g.exec("", "clone", "--", url, g.Dir)
+	return err
+}
+
+// Init creates a git repository in Dir.
+func (g *Git) Init() error {
+	_, err := g.exec("", "init", "--quiet", "--", g.Dir)
+	return err
+}
+
+// GitChange is a file changed between two revisions.
+type GitChange struct {
+	Status string // A (added), M (modified), D (deleted) or T (type changed)
+	Path   string // Slash-separated path relative to Dir
+}
+
+// Fetch downloads the commits of the upstream branch without merging them.
+func (g *Git) Fetch() error {
+	_, err := g.run("fetch", "--quiet")
+	return err
+}
+
+// Incoming lists the files below Dir changed by the fetched commits of the
+// upstream branch that the current branch does not have yet. Renamed files
+// are listed as deleted and added.
+func (g *Git) Incoming() ([]GitChange, error) {
+	out, err := g.run("diff", "--name-status", "--no-renames", "--relative", "-z", "HEAD...@{upstream}")
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields alternate between status and path, each terminated by NUL
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("unexpected output of git diff in %s", g.Dir)
+	}
+	changes := make([]GitChange, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		changes = append(changes, GitChange{Status: fields[i], Path: fields[i+1]})
+	}
+	return changes, nil
+}
+
+// HasPath reports whether path, relative to Dir, exists at revision rev.
+func (g *Git) HasPath(rev, path string) bool {
+	_, err := g.run("cat-file", "-e", rev+":./"+filepath.ToSlash(path))
+	return err == nil
+}
+
+// Pull fast-forwards the current branch to the fetched upstream branch. It
+// fails rather than merge if the branches diverged.
+func (g *Git) Pull() error {
+	_, err := g.run("merge", "--ff-only", "--quiet", "@{upstream}")
+	return err
+}
+
+// run executes git with args in Dir and returns its output.
+func (g *Git) run(args ...string) ([]byte, error) {
+	return g.exec(g.Dir, args...)
+}
+
+// exec executes git with args in dir, or in the current directory if dir is
+// empty, and returns its output.
+func (g *Git) exec(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command(g.command(), args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("git is not installed (looked for %q in PATH)", g.command())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %s", g.command(), args[0], msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", g.command(), args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
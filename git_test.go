@@ -0,0 +1,135 @@
+package gslk
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepo creates a git repository in dir holding files, committed.
+func gitRepo(t *testing.T, dir string, files map[string]string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	createDummyPackage(t, dir, files)
+	runGit(t, dir, "init", "-q")
+	commitAll(t, dir)
+}
+
+// commitAll commits every change in the git repository in dir.
+func commitAll(t *testing.T, dir string) {
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "update")
+}
+
+// runGit runs git with args in dir.
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+}
+
+func TestGitClone(t *testing.T) {
+	tempDir := t.TempDir()
+	origin := filepath.Join(tempDir, "origin")
+	gitRepo(t, origin, map[string]string{"zsh/.zshrc": "zsh", RepoConfigFileName: "[apply]\npackages = [\"zsh\"]\n"})
+
+	git := &Git{Dir: filepath.Join(tempDir, "clones", "dotfiles")}
+	assert.False(t, git.IsRepo())
+	require.NoError(t, git.Clone(origin))
+	assert.True(t, git.IsRepo())
+
+	data, err := os.ReadFile(filepath.Join(git.Dir, "zsh", ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "zsh", string(data))
+
+	// The directory is no longer empty
+	assert.Error(t, git.Clone(origin))
+}
+
+func TestFindSourceRoot(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := filepath.Join(tempDir, "dotfiles")
+	createDummyPackage(t, repo, map[string]string{
+		RepoConfigFileName:           "",
+		"nvim/.config/nvim/init.lua": "-- nvim",
+	})
+
+	root, ok := FindSourceRoot(filepath.Join(repo, "nvim", ".config", "nvim"))
+	require.True(t, ok)
+	assert.Equal(t, repo, root)
+	root, ok = FindSourceRoot(repo)
+	require.True(t, ok)
+	assert.Equal(t, repo, root)
+
+	// The closest marker wins
+	nested := filepath.Join(repo, "nvim", SourceMarkerName)
+	require.NoError(t, os.Mkdir(nested, 0755))
+	root, ok = FindSourceRoot(filepath.Join(repo, "nvim", ".config"))
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(repo, "nvim"), root)
+
+	_, ok = FindSourceRoot(tempDir)
+	assert.False(t, ok)
+
+	// A marker directory is not a package
+	linker := &Linker{SourceDir: filepath.Join(repo, "nvim")}
+	createDummyPackage(t, filepath.Join(repo, "nvim", "zsh"), map[string]string{".zshrc": "zsh"})
+	packages, err := linker.FindPackages()
+	require.NoError(t, err)
+	var names []string
+	for _, pkg := range packages {
+		names = append(names, pkg.Name)
+	}
+	assert.ElementsMatch(t, []string{".config", "zsh"}, names)
+}
+
+func TestGitMissingCommand(t *testing.T) {
+	git := &Git{Command: "gslk-no-such-git", Dir: t.TempDir()}
+	err := git.Clone("https://example.com/dotfiles.git")
+	assert.ErrorContains(t, err, "git is not installed")
+}
+
+func TestGitIncomingAndPull(t *testing.T) {
+	tempDir := t.TempDir()
+	origin := filepath.Join(tempDir, "origin")
+	gitRepo(t, origin, map[string]string{"zsh/.zshrc": "zsh", "old/.oldrc": "old"})
+
+	git := &Git{Dir: filepath.Join(tempDir, "dotfiles")}
+	require.NoError(t, git.Clone(origin))
+	require.NoError(t, git.Fetch())
+	changes, err := git.Incoming()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Change the origin: modify, add and delete files
+	require.NoError(t, os.WriteFile(filepath.Join(origin, "zsh", ".zshrc"), []byte("zsh 2"), 0644))
+	createDummyPackage(t, origin, map[string]string{"zsh/.aliases": "aliases"})
+	require.NoError(t, os.RemoveAll(filepath.Join(origin, "old")))
+	commitAll(t, origin)
+
+	require.NoError(t, git.Fetch())
+	changes, err = git.Incoming()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []GitChange{
+		{Status: "D", Path: "old/.oldrc"},
+		{Status: "A", Path: "zsh/.aliases"},
+		{Status: "M", Path: "zsh/.zshrc"},
+	}, changes)
+	assert.True(t, git.HasPath("@{upstream}", "zsh"))
+	assert.False(t, git.HasPath("@{upstream}", "old"))
+	assert.True(t, git.HasPath("HEAD", "old"))
+
+	require.NoError(t, git.Pull())
+	data, err := os.ReadFile(filepath.Join(git.Dir, "zsh", ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "zsh 2", string(data))
+	changes, err = git.Incoming()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,179 @@
+package gslk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// GraphFormat is the language Graph.Write draws a graph in.
+type GraphFormat string
+
+const (
+	GraphDOT     GraphFormat = "dot"     // Graphviz DOT
+	GraphMermaid GraphFormat = "mermaid" // A Mermaid flowchart
+)
+
+// ParseGraphFormat checks that s names a graph format.
+func ParseGraphFormat(s string) (GraphFormat, error) {
+	switch format := GraphFormat(s); format {
+	case GraphDOT, GraphMermaid:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown graph format %q: must be dot or mermaid", s)
+}
+
+// Graph shows how packages depend on each other and which directories of the
+// target they populate.
+type Graph struct {
+	TargetDir string
+	Packages  []GraphPackage // Sorted by name
+}
+
+// GraphPackage is a package in a Graph.
+type GraphPackage struct {
+	Name    string
+	Depends []string       // Packages it needs, as its manifest lists them
+	Dirs    map[string]int // Files deployed into each target directory, by slash-separated path relative to TargetDir; "." is TargetDir itself
+}
+
+// Graph collects the packages named, with the packages they depend on, or
+// every package if none is named, and the target directories their files are
+// deployed into. Directories more than depth levels below the target directory
+// are counted as their ancestor at that depth; 0 means no limit. Nothing is
+// modified.
+func (l *Linker) Graph(packageNames []string, depth int) (*Graph, error) {
+	var packages []Package
+	var err error
+	if len(packageNames) == 0 {
+		packages, err = l.FindPackages()
+	} else if packages, err = l.lookupPackages(packageNames); err == nil {
+		packages, err = l.withDependencies(packages)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &Graph{TargetDir: l.TargetDir}
+	for _, pkg := range packages {
+		node := GraphPackage{Name: pkg.Name, Depends: pkg.Manifest.Depends, Dirs: make(map[string]int)}
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			rel, err := filepath.Rel(l.TargetDir, filepath.Dir(path.targetPath))
+			if err != nil {
+				return err
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if depth > 0 && len(parts) > depth {
+				parts = parts[:depth]
+			}
+			node.Dirs[strings.Join(parts, "/")]++
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+		graph.Packages = append(graph.Packages, node)
+	}
+	slices.SortFunc(graph.Packages, func(a, b GraphPackage) int { return strings.Compare(a.Name, b.Name) })
+	return graph, nil
+}
+
+// dirs returns every target directory in the graph, sorted.
+func (g *Graph) dirs() []string {
+	var dirs []string
+	for _, pkg := range g.Packages {
+		for dir := range pkg.Dirs {
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	slices.Sort(dirs)
+	return dirs
+}
+
+// dirLabel names the target directory dir in a drawing.
+func (g *Graph) dirLabel(dir string) string {
+	if dir == "." {
+		return g.TargetDir
+	}
+	return dir
+}
+
+// Write draws the graph to w in format: a node per package and per target
+// directory, a dashed edge from each package to the packages it depends on,
+// and an edge labelled with the number of files from each package to the
+// directories it deploys them into.
+func (g *Graph) Write(w io.Writer, format GraphFormat) error {
+	b := bufio.NewWriter(w)
+	switch format {
+	case GraphDOT:
+		g.writeDOT(b)
+	case GraphMermaid:
+		g.writeMermaid(b)
+	default:
+		return fmt.Errorf("unknown graph format %q: must be dot or mermaid", format)
+	}
+	return b.Flush()
+}
+
+func (g *Graph) writeDOT(b *bufio.Writer) {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	fmt.Fprintln(b, "digraph gslk {")
+	fmt.Fprintln(b, "  rankdir=LR;")
+	fmt.Fprintln(b, "  node [shape=box];")
+	for _, pkg := range g.Packages {
+		fmt.Fprintf(b, "  \"package:%s\" [label=\"%s\"];\n", quote(pkg.Name), quote(pkg.Name))
+	}
+	for _, dir := range g.dirs() {
+		fmt.Fprintf(b, "  \"dir:%s\" [label=\"%s\", shape=folder];\n", quote(dir), quote(g.dirLabel(dir)))
+	}
+	for _, pkg := range g.Packages {
+		for _, dep := range pkg.Depends {
+			fmt.Fprintf(b, "  \"package:%s\" -> \"package:%s\" [style=dashed, label=\"depends\"];\n", quote(pkg.Name), quote(dep))
+		}
+		for _, dir := range slices.Sorted(maps.Keys(pkg.Dirs)) {
+			fmt.Fprintf(b, "  \"package:%s\" -> \"dir:%s\" [label=\"%d\"];\n", quote(pkg.Name), quote(dir), pkg.Dirs[dir])
+		}
+	}
+	fmt.Fprintln(b, "}")
+}
+
+func (g *Graph) writeMermaid(b *bufio.Writer) {
+	// Mermaid node IDs are identifiers, so nodes are numbered and named by labels
+	quote := strings.NewReplacer(`"`, "#quot;").Replace
+	ids := make(map[string]string)
+	fmt.Fprintln(b, "flowchart LR")
+	for i, pkg := range g.Packages {
+		ids["package:"+pkg.Name] = fmt.Sprintf("p%d", i)
+		fmt.Fprintf(b, "  p%d[\"%s\"]\n", i, quote(pkg.Name))
+	}
+	for i, dir := range g.dirs() {
+		ids["dir:"+dir] = fmt.Sprintf("d%d", i)
+		fmt.Fprintf(b, "  d%d[/\"%s\"/]\n", i, quote(g.dirLabel(dir)))
+	}
+	missing := len(g.Packages)
+	for _, pkg := range g.Packages {
+		for _, dep := range pkg.Depends {
+			id, ok := ids["package:"+dep]
+			if !ok {
+				// A dependency missing from the source directories
+				id = fmt.Sprintf("p%d", missing)
+				missing++
+				ids["package:"+dep] = id
+				fmt.Fprintf(b, "  %s[\"%s\"]\n", id, quote(dep))
+			}
+			fmt.Fprintf(b, "  %s -.->|depends| %s\n", ids["package:"+pkg.Name], id)
+		}
+		for _, dir := range slices.Sorted(maps.Keys(pkg.Dirs)) {
+			fmt.Fprintf(b, "  %s -->|%d| %s\n", ids["package:"+pkg.Name], pkg.Dirs[dir], ids["dir:"+dir])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package gslk
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		".config/nvim/init.lua":         "init",
+		".config/nvim/lua/plugins.lua":  "plugins",
+		".config/nvim/lua/settings.lua": "settings",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "nvim", ManifestFileName), []byte(`depends = ["shell", "fonts"]`), 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "shell"), map[string]string{".zshrc": "zsh", ".bashrc": "bash"})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": "[user]"})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	graph, err := linker.Graph(nil, 2)
+	require.NoError(t, err)
+	require.Len(t, graph.Packages, 3)
+	assert.Equal(t, "git", graph.Packages[0].Name)
+	assert.Equal(t, map[string]int{".config/nvim": 3}, graph.Packages[1].Dirs, "deeper directories are grouped at the depth")
+	assert.Equal(t, map[string]int{".": 2}, graph.Packages[2].Dirs)
+	var mermaid bytes.Buffer
+	require.NoError(t, graph.Write(&mermaid, GraphMermaid))
+	assert.Contains(t, mermaid.String(), "  p3[\"fonts\"]\n  p1 -.->|depends| p3\n", "missing dependencies still get a node")
+
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "nvim", ManifestFileName), []byte(`depends = ["shell"]`), 0644))
+
+	graph, err = linker.Graph([]string{"nvim"}, 0)
+	require.NoError(t, err)
+	require.Len(t, graph.Packages, 2, "dependencies are included")
+	assert.Equal(t, map[string]int{".config/nvim": 1, ".config/nvim/lua": 2}, graph.Packages[0].Dirs)
+
+	var dot bytes.Buffer
+	require.NoError(t, graph.Write(&dot, GraphDOT))
+	assert.Contains(t, dot.String(), `"package:nvim" -> "package:shell" [style=dashed, label="depends"];`)
+	assert.Contains(t, dot.String(), `"package:nvim" -> "dir:.config/nvim/lua" [label="2"];`)
+	assert.Contains(t, dot.String(), `"dir:." [label="`+targetDir+`", shape=folder];`)
+
+	mermaid.Reset()
+	require.NoError(t, graph.Write(&mermaid, GraphMermaid))
+	assert.Contains(t, mermaid.String(), "flowchart LR\n  p0[\"nvim\"]\n  p1[\"shell\"]\n")
+	assert.Contains(t, mermaid.String(), "  p0 -.->|depends| p1\n")
+	assert.Contains(t, mermaid.String(), "  p0 -->|2| d2\n")
+
+	_, err = ParseGraphFormat("svg")
+	assert.Error(t, err)
+	_, err = linker.Graph([]string{"nonexistent"}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package gslk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryFileName is the name of the audit log in the gslk state directory.
+const HistoryFileName = "history.jsonl"
+
+// HistoryEntry records a run of gslk in the audit log: how it was invoked,
+// which files it changed and how it ended.
+type HistoryEntry struct {
+	Time       time.Time          `json:"time"`
+	Args       []string           `json:"args"`
+	Action     string             `json:"action"`
+	Packages   []string           `json:"packages,omitempty"`
+	Operations []HistoryOperation `json:"operations,omitempty"`
+	Outcome    string             `json:"outcome"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// HistoryOperation is an operation applied, or attempted, during a run.
+type HistoryOperation struct {
+	Op      OpKind `json:"op"`
+	Package string `json:"package,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Target  string `json:"target"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Record is an event handler adding the operations applied or failed to e.
+// Skipped targets are left out since nothing happened to them.
+func (e *HistoryEntry) Record(event Event) {
+	if event.Type != EventApplied && event.Type != EventFailed || event.Op == OpSkip || event.DryRun {
+		return
+	}
+	e.Operations = append(e.Operations, HistoryOperation{
+		Op:      event.Op,
+		Package: event.Package,
+		Source:  event.Source,
+		Target:  event.Target,
+		Error:   event.Error,
+	})
+}
+
+// DefaultHistoryPath returns the location of the audit log,
+// $XDG_STATE_HOME/gslk/history.jsonl or its platform equivalent.
+func DefaultHistoryPath() (string, error) {
+	dir, err := userStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gslk", HistoryFileName), nil
+}
+
+// AppendHistory appends entry to the audit log at path as a line of JSON,
+// creating the log and its directory if needed. Existing entries are never
+// rewritten.
+func AppendHistory(path string, entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// ReadHistory returns the entries of the audit log at path, oldest first. A
+// missing log has no entries.
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20) // Runs over large packages make long lines
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history %s, line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Touches reports whether the run changed, or tried to change, path.
+func (e *HistoryEntry) Touches(path string) bool {
+	for _, op := range e.Operations {
+		if pathsEqual(op.Target, path) {
+			return true
+		}
+	}
+	return false
+}
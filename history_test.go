@@ -0,0 +1,70 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "bash"), map[string]string{".bashrc": "x"})
+	path := filepath.Join(t.TempDir(), "gslk", HistoryFileName)
+
+	t.Run("missing history has no entries", func(t *testing.T) {
+		entries, err := ReadHistory(path)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("runs are appended with their operations", func(t *testing.T) {
+		for _, action := range []string{"link", "unlink"} {
+			entry := HistoryEntry{Args: []string{"-s", sourceDir, "bash"}, Action: action, Packages: []string{"bash"}}
+			linker := New(sourceDir, targetDir, WithEvents(entry.Record))
+			var err error
+			if action == "link" {
+				_, err = linker.Link([]string{"bash"})
+			} else {
+				_, err = linker.Unlink([]string{"bash"})
+			}
+			require.NoError(t, err)
+			entry.Outcome = "success"
+			require.NoError(t, AppendHistory(path, entry))
+		}
+
+		entries, err := ReadHistory(path)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "link", entries[0].Action)
+		assert.Equal(t, "unlink", entries[1].Action)
+
+		bashrc := filepath.Join(targetDir, ".bashrc")
+		assert.Contains(t, entries[0].Operations, HistoryOperation{Op: OpLink, Package: "bash", Source: filepath.Join(sourceDir, "bash", ".bashrc"), Target: bashrc})
+		assert.Contains(t, entries[1].Operations, HistoryOperation{Op: OpUnlink, Package: "bash", Source: filepath.Join(sourceDir, "bash", ".bashrc"), Target: bashrc})
+		assert.True(t, entries[0].Touches(bashrc))
+		assert.False(t, entries[0].Touches(filepath.Join(targetDir, ".zshrc")))
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "the history may name private files")
+		}
+	})
+
+	t.Run("corrupt lines are reported", func(t *testing.T) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		require.NoError(t, err)
+		_, err = f.WriteString("{not json\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		_, err = ReadHistory(path)
+		assert.ErrorContains(t, err, "line 3")
+	})
+}
@@ -0,0 +1,379 @@
+package gslk
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// IgnoreFileName is the per-package file listing paths that should not be linked.
+const IgnoreFileName = ".gslk-ignore"
+
+// IncludeFileName is the per-package file listing the only paths that should be
+// linked. Packages without one link everything not ignored.
+const IncludeFileName = ".gslk-include"
+
+// IgnoreRules decides which paths of a package are skipped.
+//
+// Patterns use path.Match syntax with "/" separators and are matched against
+// the path relative to the package root. Patterns without a separator also
+// match the base name at any depth. A matching directory excludes everything
+// below it. As in .gitignore, a pattern starting with "!" re-includes paths
+// an earlier pattern excludes, the last matching pattern deciding; a path
+// below an excluded directory cannot be re-included. The zero value ignores
+// nothing.
+//
+// Include patterns, added with WithInclude, select the only files that are
+// kept: a file must match, or lie in a directory matching, one of them.
+type IgnoreRules struct {
+	patterns []string
+	compiled []compiledPattern
+	regexps  []*regexp.Regexp
+	include  [][]compiledPattern // Every set must include a file for it to be kept
+}
+
+// NewIgnoreRules returns rules matching the given patterns. The patterns are
+// compiled once here rather than for every path matched.
+func NewIgnoreRules(patterns []string) *IgnoreRules {
+	return &IgnoreRules{patterns: patterns, compiled: compilePatterns(patterns)}
+}
+
+// LoadIgnoreRules reads the ignore and include files of the package at
+// packagePath. A package without them yields empty rules. Invalid patterns
+// are left out; a Linker reports them when it walks the package.
+func LoadIgnoreRules(packagePath string) (*IgnoreRules, error) {
+	rules, _, err := loadPackageRules(packagePath, nil, nil)
+	return rules, err
+}
+
+// loadPackageRules reads the ignore and include files of the package at
+// packagePath and adds the given ignore and include patterns to theirs. The
+// invalid patterns of the files are left out and returned as PatternErrors.
+func loadPackageRules(packagePath string, ignore, include []string) (*IgnoreRules, []error, error) {
+	patterns, invalid, err := readPatternFile(filepath.Join(packagePath, IgnoreFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	includePatterns, invalidIncludes, err := readPatternFile(filepath.Join(packagePath, IncludeFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	rules := NewIgnoreRules(slices.Concat(ignore, patterns)).WithInclude(slices.Concat(includePatterns, include))
+	return rules, slices.Concat(invalid, invalidIncludes), nil
+}
+
+// PatternError reports an invalid ignore, include or copy pattern and where
+// it was written.
+type PatternError struct {
+	File    string // File the pattern was read from; empty for patterns given otherwise
+	Line    int    // Line of the pattern in File; 0 if unknown
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	switch {
+	case e.File == "":
+		return fmt.Sprintf("invalid pattern %q: %v", e.Pattern, e.Err)
+	case e.Line == 0:
+		return fmt.Sprintf("%s: invalid pattern %q: %v", e.File, e.Pattern, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: invalid pattern %q: %v", e.File, e.Line, e.Pattern, e.Err)
+}
+
+func (e *PatternError) Unwrap() error { return e.Err }
+
+// CheckPattern reports whether pattern is a valid ignore, include or copy
+// pattern. Invalid patterns match nothing.
+func CheckPattern(pattern string) error {
+	pattern, _ = strings.CutPrefix(filepath.ToSlash(pattern), "!")
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// checkPatterns returns a PatternError for each invalid pattern of patterns,
+// read from file.
+func checkPatterns(file string, patterns []string) []error {
+	var invalid []error
+	for _, pattern := range patterns {
+		if err := CheckPattern(pattern); err != nil {
+			invalid = append(invalid, &PatternError{File: file, Pattern: pattern, Err: err})
+		}
+	}
+	return invalid
+}
+
+// WithInclude returns rules that also skip every file not matching one of
+// patterns. Include patterns added by several calls must all match. Empty
+// patterns leave the rules unchanged.
+func (r *IgnoreRules) WithInclude(patterns []string) *IgnoreRules {
+	if len(patterns) == 0 {
+		return r
+	}
+	return &IgnoreRules{patterns: r.patterns, compiled: r.compiled, regexps: r.regexps, include: append(slices.Clone(r.include), compilePatterns(patterns))}
+}
+
+// WithRegexp returns rules that also ignore paths matching one of res. The
+// expressions are matched against the slash-separated path relative to the
+// package root and are not anchored.
+func (r *IgnoreRules) WithRegexp(res []*regexp.Regexp) *IgnoreRules {
+	if len(res) == 0 {
+		return r
+	}
+	return &IgnoreRules{patterns: r.patterns, compiled: r.compiled, regexps: append(slices.Clone(r.regexps), res...), include: r.include}
+}
+
+// Patterns returns the patterns the rules were built from.
+func (r *IgnoreRules) Patterns() []string {
+	return r.patterns
+}
+
+// Match reports whether relPath should be ignored.
+func (r *IgnoreRules) Match(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return matchPatterns(relPath, r.compiled) || matchesAny(r.regexps, relPath)
+}
+
+// Includes reports whether the file at relPath is selected by the include
+// patterns. Rules without include patterns include every file.
+func (r *IgnoreRules) Includes(relPath string) bool {
+	for _, patterns := range r.include {
+		included := false
+		for p := filepath.ToSlash(relPath); p != "." && !included; p = path.Dir(p) {
+			included = matchPatterns(p, patterns)
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// IgnoreMatcher decides which package paths are skipped with logic of its
+// own, such as honouring the .gitignore of the repository or consulting a
+// database, in addition to the ignore rules of the packages. Ignore is called
+// with the path relative to the package directory, using the separator of the
+// operating system, for every path the ignore rules keep; an ignored
+// directory excludes everything below it.
+type IgnoreMatcher interface {
+	Ignore(pkg Package, relPath string, isDir bool) bool
+}
+
+// IgnoreMatcherFunc adapts a function to an IgnoreMatcher.
+type IgnoreMatcherFunc func(pkg Package, relPath string, isDir bool) bool
+
+// Ignore calls f.
+func (f IgnoreMatcherFunc) Ignore(pkg Package, relPath string, isDir bool) bool {
+	return f(pkg, relPath, isDir)
+}
+
+// packageRules caches the ignore rules of the layers of a package, so the
+// several walks one operation makes over a package read and compile them once.
+// A nil cache loads the rules on every walk.
+type packageRules struct {
+	mu     sync.Mutex
+	layers map[string]*IgnoreRules
+	copy   []compiledPattern
+	copied bool // copy is compiled
+	bind   []compiledPattern
+	bound  bool // bind is compiled
+
+	templates []compiledPattern
+	templated bool // templates is compiled
+}
+
+// layer returns the rules of the package directory layer, calling load for
+// them the first time.
+func (c *packageRules) layer(layer string, load func() (*IgnoreRules, error)) (*IgnoreRules, error) {
+	if c == nil {
+		return load()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rules, ok := c.layers[layer]; ok {
+		return rules, nil
+	}
+	rules, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if c.layers == nil {
+		c.layers = make(map[string]*IgnoreRules)
+	}
+	c.layers[layer] = rules
+	return rules, nil
+}
+
+// copies reports whether the package file relPath matches one of the copy
+// patterns of the package's manifest.
+func (pkg Package) copies(relPath string) bool {
+	c := pkg.rules
+	if c == nil {
+		return isPathIgnored(relPath, pkg.Manifest.Copy)
+	}
+	c.mu.Lock()
+	if !c.copied {
+		c.copy, c.copied = compilePatterns(pkg.Manifest.Copy), true
+	}
+	c.mu.Unlock()
+	return matchPatterns(filepath.ToSlash(relPath), c.copy)
+}
+
+// renders reports whether the package file relPath matches one of the
+// templates patterns of the package's manifest.
+func (pkg Package) renders(relPath string) bool {
+	if len(pkg.Manifest.Templates) == 0 {
+		return false
+	}
+	c := pkg.rules
+	if c == nil {
+		return isPathIgnored(relPath, pkg.Manifest.Templates)
+	}
+	c.mu.Lock()
+	if !c.templated {
+		c.templates, c.templated = compilePatterns(pkg.Manifest.Templates), true
+	}
+	c.mu.Unlock()
+	return matchPatterns(filepath.ToSlash(relPath), c.templates)
+}
+
+// binds reports whether the package directory relPath matches one of the bind
+// patterns of the package's manifest.
+func (pkg Package) binds(relPath string) bool {
+	if len(pkg.Manifest.Bind) == 0 {
+		return false
+	}
+	c := pkg.rules
+	if c == nil {
+		return isPathIgnored(relPath, pkg.Manifest.Bind)
+	}
+	c.mu.Lock()
+	if !c.bound {
+		c.bind, c.bound = compilePatterns(pkg.Manifest.Bind), true
+	}
+	c.mu.Unlock()
+	return matchPatterns(filepath.ToSlash(relPath), c.bind)
+}
+
+// loadIgnorePatterns reads the .gslk-ignore file from the given package directory
+// and returns a list of ignore patterns. Returns an empty list if the file doesn't exist.
+func loadIgnorePatterns(packagePath string) ([]string, error) {
+	return loadPatternFile(filepath.Join(packagePath, IgnoreFileName))
+}
+
+// loadPatternFile reads a list of patterns, one per line, skipping blank lines
+// and comments. Returns an empty list if the file doesn't exist.
+func loadPatternFile(filePath string) ([]string, error) {
+	patterns, _, err := readPatternFile(filePath)
+	return patterns, err
+}
+
+// readPatternFile reads a list of patterns like loadPatternFile, leaving out
+// invalid patterns, which are returned as PatternErrors with their line.
+func readPatternFile(filePath string) ([]string, []error, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil, nil // No such file, return empty list
+		}
+		return nil, nil, fmt.Errorf("failed to open pattern file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	var invalid []error
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Ignore empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := CheckPattern(line); err != nil {
+			invalid = append(invalid, &PatternError{File: filePath, Line: lineNumber, Pattern: line, Err: err})
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading pattern file %s: %w", filePath, err)
+	}
+
+	return patterns, invalid, nil
+}
+
+// isPathIgnored checks if a path should be ignored based on the provided patterns.
+// Paths and patterns are compared in slash-separated form, so patterns written
+// with "/" work on every platform. Matching many paths against the same
+// patterns is cheaper with compilePatterns and matchPatterns.
+func isPathIgnored(relPath string, ignorePatterns []string) bool {
+	return matchPatterns(filepath.ToSlash(relPath), compilePatterns(ignorePatterns))
+}
+
+// compiledPattern is an ignore pattern prepared for matching many paths.
+type compiledPattern struct {
+	pattern  string // Slash-separated, without the "!" of a negated pattern
+	literal  bool   // Without wildcards, so compared as a plain string
+	anyDepth bool   // Without a separator, so also matched against the base name
+	negated  bool   // Written with a leading "!", so re-including what it matches
+}
+
+// compilePatterns converts patterns to slash-separated form and checks them
+// once. Invalid patterns, which Linker reports before walking a package, are
+// left out since they match nothing. A leading "!" negates a pattern; "\!"
+// starts one matching a literal "!".
+func compilePatterns(patterns []string) []compiledPattern {
+	compiled := make([]compiledPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if CheckPattern(pattern) != nil {
+			continue
+		}
+		pattern, negated := strings.CutPrefix(filepath.ToSlash(pattern), "!")
+		compiled = append(compiled, compiledPattern{
+			pattern:  pattern,
+			literal:  !strings.ContainsAny(pattern, `*?[\`),
+			anyDepth: !strings.Contains(pattern, "/"),
+			negated:  negated,
+		})
+	}
+	return compiled
+}
+
+// matchPatterns reports whether the slash-separated relPath, or for patterns
+// without a separator its base name, matches patterns: the last pattern it
+// matches must not be negated.
+func matchPatterns(relPath string, patterns []compiledPattern) bool {
+	baseName := path.Base(relPath)
+	for _, p := range slices.Backward(patterns) {
+		if p.match(relPath) || p.anyDepth && p.match(baseName) {
+			return !p.negated
+		}
+	}
+	return false
+}
+
+// match reports whether name matches the pattern.
+func (p compiledPattern) match(name string) bool {
+	if p.literal {
+		return name == p.pattern
+	}
+	matched, _ := path.Match(p.pattern, name)
+	return matched
+}
+
+// matchesAny reports whether s matches one of res.
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
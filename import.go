@@ -0,0 +1,215 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Import moves the files and directories at paths, which must be real files
+// inside the target directory, into package pkgName and links them back, so
+// that existing dotfiles can be put under management in one step. The package
+// is created in SourceDir if no source directory provides it yet. Each file
+// goes to the package path the Mapper places at its target path, so with a
+// DotPrefix of "dot-" ~/.vimrc becomes vim/dot-vimrc; files no package path
+// maps to are refused, as are files the package already has. Nothing is moved
+// unless every file can be.
+//
+// Files are moved by renaming them, so the source directory has to be on the
+// same filesystem as the target. In dry run mode nothing is moved and the
+// returned Result lists the target paths that would be linked. It is never nil.
+func (l *Linker) Import(pkgName string, paths []string) (*Result, error) {
+	if pkgName == "" || strings.ContainsAny(pkgName, `/\`) || strings.HasPrefix(pkgName, ".") {
+		return &Result{}, fmt.Errorf("invalid package name %q", pkgName)
+	}
+	if remote, ok := l.FS.(RemoteFS); ok {
+		return &Result{}, fmt.Errorf("cannot import files from remote target %s", remote.Location())
+	}
+	if err := l.checkRoot(); err != nil {
+		return &Result{}, err
+	}
+	if err := checkWritable(l.fs(), "import", l.TargetDir); err != nil && !l.DryRun {
+		return &Result{}, err
+	}
+
+	pkg, err := l.importPackage(pkgName)
+	if err != nil {
+		return &Result{}, err
+	}
+	mapper := l.mapper(pkg)
+
+	var moves []importMove
+	var names []string // Package subpaths to link once moved
+	for _, path := range paths {
+		found, name, err := l.importMoves(pkg, &mapper, path)
+		if err != nil {
+			return &Result{}, err
+		}
+		moves = append(moves, found...)
+		names = append(names, name)
+	}
+	for i, move := range moves {
+		if slices.ContainsFunc(moves[:i], func(other importMove) bool { return other.sourcePath == move.sourcePath }) {
+			return &Result{}, fmt.Errorf("cannot import %s: it is given more than once", move.targetPath)
+		}
+	}
+
+	if l.DryRun {
+		result := &Result{}
+		for _, move := range moves {
+			l.printf("Would move %s to %s\n", move.targetPath, move.sourcePath)
+			result.Linked = append(result.Linked, move.targetPath)
+		}
+		return result, nil
+	}
+
+	for _, move := range moves {
+		l.logVerbose("Moving %s to %s\n", move.targetPath, move.sourcePath)
+		if err := os.MkdirAll(filepath.Dir(move.sourcePath), 0755); err != nil {
+			return &Result{}, fmt.Errorf("failed to create directory for %s: %w", move.sourcePath, err)
+		}
+		if err := os.Rename(move.targetPath, move.sourcePath); err != nil {
+			return &Result{}, fmt.Errorf("failed to move %s into package %s: %w", move.targetPath, pkgName, err)
+		}
+	}
+	// Directories emptied by the moves are created again by linking
+	for _, path := range paths {
+		removeEmptyDirs(path)
+	}
+
+	result, err := l.Link(names)
+	if err != nil {
+		return result, fmt.Errorf("imported files into package %s but failed to link them back: %w", pkgName, err)
+	}
+	if len(result.Ignored) > 0 {
+		l.warnf("Imported files ignored by package %s are not linked back: %s\n", pkgName, strings.Join(result.Ignored, ", "))
+	}
+	return result, nil
+}
+
+// importMove is a file moved from the target into a package by Import.
+type importMove struct {
+	targetPath string
+	sourcePath string
+}
+
+// importPackage returns package pkgName, or the package to create in
+// SourceDir if no source directory provides it.
+func (l *Linker) importPackage(pkgName string) (Package, error) {
+	// Importing may create the first package of a new source directory
+	packages, err := l.packagesByName()
+	if err != nil && !errors.Is(err, errNoPackages) && !errors.Is(err, fs.ErrNotExist) {
+		return Package{}, err
+	}
+	if pkg, ok := packages[pkgName]; ok {
+		return pkg, nil
+	}
+	if _, ok := l.Groups[pkgName]; ok {
+		return Package{}, fmt.Errorf("cannot import into %s: it is a group, not a package", pkgName)
+	}
+	path := filepath.Join(l.SourceDir, pkgName)
+	return Package{Name: pkgName, Path: path, Layers: []string{path}}, nil
+}
+
+// importMoves returns the files below path to move into pkg and the package
+// subpath, "pkg/relpath", selecting them.
+func (l *Linker) importMoves(pkg Package, mapper *Mapper, path string) ([]importMove, string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+	}
+	if err := checkInsideTarget(l.TargetDir, absPath); err != nil {
+		return nil, "", err
+	}
+	for _, sourceDir := range l.sourceDirs() {
+		if absPath == sourceDir || isSubPath(sourceDir, absPath) || isSubPath(absPath, sourceDir) {
+			return nil, "", fmt.Errorf("cannot import %s: it holds or is inside source directory %s", path, sourceDir)
+		}
+	}
+	fi, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot import %s: %w", path, err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return nil, "", fmt.Errorf("cannot import %s: it is a symbolic link, not a file", path)
+	}
+
+	rel, err := filepath.Rel(l.TargetDir, absPath)
+	if err != nil {
+		return nil, "", err
+	}
+	pkgRel, err := l.unmapPath(pkg, mapper, rel)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var moves []importMove
+	err = filepath.WalkDir(absPath, func(targetPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.TargetDir, targetPath)
+		if err != nil {
+			return err
+		}
+		relPath, err := l.unmapPath(pkg, mapper, rel)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(l.FS, targetPath, l.AllowRoot); err != nil {
+			return err
+		}
+		for _, layer := range pkg.Layers {
+			if _, err := os.Lstat(filepath.Join(layer, relPath)); err == nil {
+				return fmt.Errorf("cannot import %s: package %s already has %s", targetPath, pkg.Name, filepath.ToSlash(relPath))
+			}
+		}
+		moves = append(moves, importMove{targetPath: targetPath, sourcePath: filepath.Join(pkg.Path, relPath)})
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return moves, pkg.Name + "/" + filepath.ToSlash(pkgRel), nil
+}
+
+// unmapPath returns the package path of pkg mapper places at the target path
+// targetRel, undoing the DotPrefix translation.
+func (l *Linker) unmapPath(pkg Package, mapper *Mapper, targetRel string) (string, error) {
+	relPath := targetRel
+	if mapper.DotPrefix != "" {
+		parts := strings.Split(relPath, string(filepath.Separator))
+		for i, part := range parts {
+			if strings.HasPrefix(part, ".") && len(part) > 1 {
+				parts[i] = mapper.DotPrefix + strings.TrimPrefix(part, ".")
+			}
+		}
+		relPath = filepath.Join(parts...)
+	}
+	if l.mapPath(pkg, mapper, relPath) != filepath.Clean(targetRel) {
+		return "", fmt.Errorf("cannot import %s: no package path is placed there by the name mapping", targetRel)
+	}
+	return relPath, nil
+}
+
+// removeEmptyDirs removes the directory tree at path, bottom up, as far as it
+// holds only empty directories.
+func removeEmptyDirs(path string) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Type()&os.ModeSymlink == 0 {
+			removeEmptyDirs(filepath.Join(path, entry.Name()))
+		}
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,79 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	vimrc := filepath.Join(targetDir, ".vimrc")
+	require.NoError(t, os.WriteFile(vimrc, []byte("set nu"), 0644))
+	nvimDir := filepath.Join(targetDir, ".config", "nvim")
+	require.NoError(t, os.MkdirAll(filepath.Join(nvimDir, "lua", "empty"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(nvimDir, "init.lua"), []byte("--"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(nvimDir, "lua", "plugins.lua"), []byte("--"), 0644))
+
+	linker := New(sourceDir, targetDir)
+	result, err := linker.Import("vim", []string{vimrc, nvimDir})
+	require.NoError(t, err)
+	assert.Len(t, result.Linked, 3)
+
+	for _, rel := range []string{".vimrc", ".config/nvim/init.lua", ".config/nvim/lua/plugins.lua"} {
+		dest, err := os.Readlink(filepath.Join(targetDir, rel))
+		require.NoError(t, err, rel)
+		assert.Equal(t, filepath.Join(sourceDir, "vim", rel), dest)
+	}
+	_, err = os.Stat(filepath.Join(nvimDir, "lua", "empty"))
+	assert.True(t, os.IsNotExist(err), "emptied directories are removed")
+
+	// Linked files and files the package has already are refused
+	_, err = linker.Import("vim", []string{vimrc})
+	assert.ErrorContains(t, err, "symbolic link")
+	require.NoError(t, os.WriteFile(filepath.Join(nvimDir, "ginit.vim"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "vim", ".config", "nvim", "ginit.vim"), nil, 0644))
+	_, err = linker.Import("vim", []string{filepath.Join(nvimDir, "ginit.vim")})
+	assert.ErrorContains(t, err, "already has")
+}
+
+func TestImportDotPrefix(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	require.NoError(t, os.WriteFile(bashrc, []byte("PS1=$"), 0644))
+
+	linker := New(sourceDir, targetDir, WithMapper(Mapper{DotPrefix: "dot-"}), WithDryRun())
+	result, err := linker.Import("bash", []string{bashrc})
+	require.NoError(t, err)
+	assert.Equal(t, []string{bashrc}, result.Linked)
+	_, err = os.Stat(filepath.Join(sourceDir, "bash"))
+	assert.True(t, os.IsNotExist(err), "dry run moves nothing")
+
+	linker.DryRun = false
+	_, err = linker.Import("bash", []string{bashrc})
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(sourceDir, "bash", "dot-bashrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "PS1=$", string(data))
+	dest, err := os.Readlink(bashrc)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sourceDir, "bash", "dot-bashrc"), dest)
+}
+
+func TestImportRefusesOutsideTarget(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	linker := New(sourceDir, targetDir)
+	_, err := linker.Import("vim", []string{filepath.Join(t.TempDir(), ".vimrc")})
+	assert.ErrorContains(t, err, "outside the target directory")
+	_, err = linker.Import("../vim", []string{filepath.Join(targetDir, ".vimrc")})
+	assert.ErrorContains(t, err, "invalid package name")
+}
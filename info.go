@@ -0,0 +1,52 @@
+package gslk
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// PackageInfo describes a package: its manifest, through Package, how many
+// of its files are deployed, and how much it weighs.
+type PackageInfo struct {
+	PackageStatus
+	Size    int64  // Bytes in the files the package manages
+	Ignored int    // Paths excluded by the package's ignore rules
+	Target  string // Directory the package is linked into, after the target of its manifest
+}
+
+// Info describes package pkgName: its manifest, its files, their size and how
+// many are deployed in the target directory. Locally modified copies count as
+// deployed, as with Status. Nothing is modified.
+func (l *Linker) Info(pkgName string) (*PackageInfo, error) {
+	packages, err := l.packagesByName()
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := l.findPackage(pkgName, packages)
+	if err != nil {
+		return nil, err
+	}
+	files, err := l.Files(pkg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PackageInfo{PackageStatus: PackageStatus{Package: pkg}, Target: filepath.Join(l.TargetDir, pkg.Manifest.Target)}
+	for _, file := range files {
+		switch file.Status {
+		case FileIgnored:
+			info.Ignored++
+			continue
+		case FileLinked, FileModified:
+			info.Deployed++
+		}
+		info.Files++
+		fi, err := orOS(l.FS).Stat(file.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+		info.Size += fi.Size()
+	}
+	info.Status = linkStatus(info.Deployed, info.Files)
+	return info, nil
+}
@@ -0,0 +1,63 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfo(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "nvim")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"init.lua":      "init",
+		"lua/opts.lua":  "opts",
+		"README.md":     "readme",
+		"lua/keys.lua":  "keys!",
+		"lua/extra.lua": "",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`
+description = "Neovim configuration"
+target = ".config/nvim"
+ignore = ["README.md"]
+depends = ["fonts"]
+
+[[hooks]]
+when = "post-link"
+run = "true"
+`), 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "fonts"), map[string]string{"font.ttf": "font"})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	info, err := linker.Info("nvim")
+	require.NoError(t, err)
+	assert.Equal(t, "Neovim configuration", info.Package.Manifest.Description)
+	assert.Equal(t, []string{"fonts"}, info.Package.Manifest.Depends)
+	assert.Len(t, info.Package.Manifest.Hooks, 1)
+	assert.Equal(t, filepath.Join(targetDir, ".config", "nvim"), info.Target)
+	assert.Equal(t, 4, info.Files)
+	assert.Equal(t, 1, info.Ignored)
+	assert.Equal(t, int64(len("init")+len("opts")+len("keys!")), info.Size)
+	assert.Equal(t, StatusUnlinked, info.Status)
+	assert.Zero(t, info.Coverage())
+
+	_, err = linker.Link([]string{"nvim"})
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(targetDir, ".config", "nvim", "init.lua")))
+
+	info, err = linker.Info("nvim")
+	require.NoError(t, err)
+	assert.Equal(t, 3, info.Deployed)
+	assert.Equal(t, StatusPartial, info.Status)
+	assert.Equal(t, 75.0, info.Coverage())
+
+	_, err = linker.Info("nonexistent")
+	assert.Error(t, err)
+}
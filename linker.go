@@ -1,261 +1,576 @@
 package gslk
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Package represents a directory containing files/folders to be linked.
 type Package struct {
 	Name string
-	Path string
+	Path string // Package directory in the first source directory providing it
+
+	// Layers lists the package directory in every source directory providing it,
+	// lowest precedence first; Layers[0] is Path
+	Layers []string
+
+	// Subpath limits linking and unlinking to a file or directory within the
+	// package, relative to the package directory; empty means the whole package
+	Subpath string
+
+	// Manifest is the metadata declared in the package's manifest file
+	Manifest Manifest
+
+	rules *packageRules // Compiled ignore rules, shared by copies of the Package
 }
 
 // Linker manages the process of linking and unlinking packages.
+//
+// A Linker may be used by several goroutines at once, provided its fields are
+// no longer changed once the first call started. Calls updating the state
+// manifest of the same target are serialized, including calls made through
+// different Linkers; calls on different targets run in parallel. Logger,
+// OnEvent and Confirm may then be called from several goroutines. Use
+// ForTarget to manage several targets with one configuration.
 type Linker struct {
-	SourceDir   string
-	TargetDir   string
+	SourceDir string
+	TargetDir string
+
+	// ExtraSources are further source directories layered over SourceDir. A file
+	// in a later source overrides the file at the same path of the same package
+	// in earlier ones; packages may exist in any subset of the sources
+	ExtraSources []string
+
 	Verbose     bool
 	DryRun      bool
-	ForceRemove bool // If true, force-remove parent directories even if not empty
+	ForceRemove bool       // If true, move directories gslk created that are not empty and locally modified copies to the trash
+	Mode        DeployMode // How files are placed in the target: ModeLink (default) or ModeCopy
+
+	// Ephemeral copies every file, bind patterns included, and records what
+	// each run deploys in the teardown manifest, so Teardown can remove it
+	// without the packages; for containers and CI
+	Ephemeral bool
+
+	// ConflictPolicy decides what happens to files occupying a target path: ConflictFail (default), ConflictBackup or ConflictAdoptIdentical
+	ConflictPolicy ConflictPolicy
+
+	// ConflictResolver, if set, decides what happens to each file occupying
+	// a target path in place of ConflictPolicy
+	ConflictResolver ConflictResolver
+
+	// FilesystemPolicy decides what happens in link mode when TargetDir is on
+	// a filesystem without symbolic links or on a network filesystem:
+	// FilesystemWarn (default), FilesystemCopy or FilesystemRefuse
+	FilesystemPolicy FilesystemPolicy
+
+	// Mapper maps package paths to target paths; the zero value keeps them unchanged
+	Mapper Mapper
+
+	// PathMapper, if set, adjusts every target path the Mapper computes;
+	// possibly called from several goroutines at once
+	PathMapper PathMapper
+
+	// Vars are the variables available to templates as {{ .Vars.name }}
+	Vars map[string]string
+
+	// Age decrypts secret files (see Mapper.SecretSuffix) and encrypts new ones
+	Age Age
+
+	// Overlay lets packages listed later override files of earlier packages at the
+	// same target path; otherwise such overlaps are reported as conflicts
+	Overlay bool
+
+	// Logger receives progress messages; if nil they are printed to standard output
+	Logger Logger
+
+	// Tracer, if set, receives spans timing the phases of Link, Unlink, Relink
+	// and planning
+	Tracer Tracer
+
+	// Concurrency is the number of packages planned and files processed in
+	// parallel; 0 or 1 processes them one at a time. Packages sharing target
+	// directories or depending on one another are planned one after the other,
+	// and messages and events are reported in package order either way
+	Concurrency int
+
+	// Retries is how often creating and removing links, files and directories
+	// in the target is retried after an error network filesystems report
+	// transiently, such as ESTALE on NFS or EBUSY on SMB; 0 never retries.
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// further one; 0 means DefaultRetryBackoff
+	Retries      int
+	RetryBackoff time.Duration
+
+	// Throttle, if set, limits how fast paths are walked and operations
+	// applied: planning and the reports wait once per path of a package, and
+	// applying a plan once per operation. Dry runs only wait while planning
+	Throttle *Throttle
+
+	// FS walks packages and manages links in the target; if nil, the local filesystem is used
+	FS FS
+
+	// ReadOnly refuses every change with an error wrapping ErrReadOnly,
+	// whatever the other options say, by working on FS wrapped in a
+	// ReadOnlyFS. Planning, Status, Drift, Verify and the other reports work
+	// as usual; hooks are not run
+	ReadOnly bool
+
+	// NoVerify skips the check after Unlink that no file of the packages is still deployed
+	NoVerify bool
+
+	// ResolveSources resolves symbolic links in the source directories, so that
+	// links point at the real location of package files rather than through a
+	// symlinked source directory. Links made either way are recognised
+	ResolveSources bool
+
+	// Confirm is asked a yes/no question before files the user may still want
+	// are moved aside: quarantining conflicting files, moving non-empty
+	// directories and modified copies to the trash, and emptying the trash.
+	// Declining keeps the file, failing the operation where it cannot proceed
+	// without it; an error fails the operation. If nil, everything is confirmed
+	Confirm func(question string) (bool, error)
+
+	// Ignore holds patterns skipped in every package, in addition to the
+	// patterns of each package's ignore file (see IgnoreRules)
+	Ignore []string
+
+	// Only restricts every package to the files matching one of its patterns,
+	// or lying in a directory that does; everything else is skipped. It
+	// narrows the package's own include file further rather than widening it
+	Only []string
+
+	// IgnoreRegexp skips package paths matching one of the expressions, like
+	// Ignore; they are matched against the slash-separated path relative to
+	// the package root
+	IgnoreRegexp []*regexp.Regexp
+
+	// IgnoreMatcher, if set, skips the package paths it matches, in addition
+	// to the ignore rules; possibly called from several goroutines at once
+	IgnoreMatcher IgnoreMatcher
+
+	// MaxDepth, if positive, skips package paths more than MaxDepth levels
+	// below the package root, and the directories at that depth along with
+	// them: with 1 only the files directly in a package are linked
+	MaxDepth int
+
+	// SkipHidden skips hidden files and directories, whose names start with a
+	// dot, nested inside packages, such as the .git directories of vendored
+	// plugins. Those directly in a package, such as .bashrc or .config, are
+	// still linked
+	SkipHidden bool
+
+	// Defer and Override decide what happens to a target path already linked
+	// from another package, instead of reporting a conflict: a path matching
+	// Defer keeps the other package's link, one matching Override is relinked
+	// to this package. They are matched against the slash-separated path
+	// relative to TargetDir, anchored at its start; Defer is checked first
+	Defer    []*regexp.Regexp
+	Override []*regexp.Regexp
+
+	// UnlinkDependents makes Unlink also unlink the packages depending on the
+	// given ones (see Manifest.Depends); Link always links dependencies first
+	UnlinkDependents bool
+
+	// PackageDirs, if set, lists the directories of the source directories that
+	// are packages, as names or filepath.Match patterns; others are skipped.
+	// Directories containing a NoPackageFileName marker are never packages
+	PackageDirs []string
+
+	// DirMode is the permissions of directories gslk creates; 0 means DefaultDirMode
+	DirMode os.FileMode
+
+	// FileModes set the permissions of copied and rendered files; the last
+	// matching rule wins. Copies otherwise keep the permissions of their
+	// source, and decrypted secrets are only readable by their owner
+	FileModes []FileMode
+
+	// IgnoreCase lets package names given in another case than that of the
+	// package directory name the package, as long as only one package matches
+	IgnoreCase bool
+
+	// AllowRoot lets gslk, when run as root, manage files of other users and
+	// target directories it does not own, for system packages targeting
+	// locations such as /etc. Without it root is held to the same ownership
+	// checks as any user, so that a stray sudo does not fill a home directory
+	// with root-owned files
+	AllowRoot bool
+
+	// Groups name lists of packages. A group name given where a package is
+	// expected stands for its members, which may be groups themselves
+	Groups map[string][]string
+
+	// OnEvent, if set, is called for every operation applied and every
+	// conflict found, possibly from several goroutines at once
+	OnEvent func(Event)
+
+	// StrictEnv makes environment variables in the paths of package
+	// manifests that are not set an error rather than expanding to nothing
+	StrictEnv bool
+
+	// StrictIgnore makes invalid patterns in the ignore and include files and
+	// manifests of packages an error rather than a warning
+	StrictIgnore bool
+
+	// sandboxFor is the target directory a Linker made by Sandbox stands in for
+	sandboxFor string
+}
+
+// printf logs a progress message
+func (l *Linker) printf(format string, args ...any) {
+	logf(l.Logger, LevelInfo, format, args...)
+}
+
+// emit sends event to OnEvent, if set.
+func (l *Linker) emit(event Event) {
+	if l.OnEvent != nil {
+		l.OnEvent(event)
+	}
+}
+
+// warnf logs a warning
+func (l *Linker) warnf(format string, args ...any) {
+	logf(l.Logger, LevelWarn, format, args...)
 }
 
 // logVerbose logs a message if verbose mode is enabled
 func (l *Linker) logVerbose(format string, args ...interface{}) {
 	if l.Verbose {
-		fmt.Printf(format, args...)
+		logf(l.Logger, LevelDebug, format, args...)
 	}
 }
 
-// FindPackages discovers packages (subdirectories) within the source directory.
-func (l *Linker) FindPackages() ([]Package, error) {
-	entries, err := os.ReadDir(l.SourceDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read source directory %s: %w", l.SourceDir, err)
-	}
+// errNoPackages is returned by FindPackages for source directories without packages.
+var errNoPackages = errors.New("no packages found")
 
+// FindPackages discovers packages (subdirectories) within the source directories.
+// A package found in several sources is returned once, with every layer.
+func (l *Linker) FindPackages() ([]Package, error) {
 	var packages []Package
-	for _, entry := range entries {
-		if entry.IsDir() {
-			// Assuming every directory directly under SourceDir is a package
+	index := make(map[string]int)
+
+	for _, sourceDir := range l.sourceDirs() {
+		entries, err := os.ReadDir(sourceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source directory %s: %w", sourceDir, err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			// Every directory directly under a source directory is a package,
+			// unless it carries the marker or is not among PackageDirs
 			packageName := entry.Name()
-			packagePath := filepath.Join(l.SourceDir, packageName)
-			packages = append(packages, Package{Name: packageName, Path: packagePath})
+			packagePath := filepath.Join(sourceDir, packageName)
+			if !l.isPackageDir(packagePath) {
+				continue
+			}
+			if i, ok := index[packageName]; ok {
+				packages[i].Layers = append(packages[i].Layers, packagePath)
+				continue
+			}
+			index[packageName] = len(packages)
+			packages = append(packages, Package{Name: packageName, Path: packagePath, Layers: []string{packagePath}, rules: &packageRules{}})
 		}
 	}
 
 	if len(packages) == 0 {
-		return nil, fmt.Errorf("no packages found in source directory %s", l.SourceDir)
+		return nil, fmt.Errorf("%w in source directory %s", errNoPackages, strings.Join(l.sourceDirs(), ", "))
+	}
+
+	// The manifest of the source with the highest precedence applies
+	for i := range packages {
+		for _, layer := range slices.Backward(packages[i].Layers) {
+			manifest, err := loadManifest(layer, l.StrictEnv)
+			if err != nil {
+				return nil, fmt.Errorf("package %s: %w", packages[i].Name, err)
+			}
+			if filepath.IsAbs(manifest.Target) {
+				rel, err := filepath.Rel(l.declaredTarget(), manifest.Target)
+				if err != nil || !filepath.IsLocal(rel) {
+					return nil, fmt.Errorf("package %s: target %s of %s is outside the target directory %s", packages[i].Name, manifest.Target, manifest.Path, l.declaredTarget())
+				}
+				if manifest.Target = rel; rel == "." {
+					manifest.Target = ""
+				}
+			}
+			if manifest.Path != "" {
+				packages[i].Manifest = manifest
+				break
+			}
+		}
 	}
 
 	return packages, nil
 }
 
-// loadIgnorePatterns reads the .gslk-ignore file from the given package directory
-// and returns a list of ignore patterns. Returns an empty list if the file doesn't exist.
-func loadIgnorePatterns(packagePath string) ([]string, error) {
-	ignoreFilePath := filepath.Join(packagePath, ".gslk-ignore")
-	file, err := os.Open(ignoreFilePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil // No ignore file, return empty list
-		}
-		return nil, fmt.Errorf("failed to open ignore file %s: %w", ignoreFilePath, err)
+// isPackageDir reports whether the directory at path, directly under a source
+// directory, is a package.
+func (l *Linker) isPackageDir(path string) bool {
+	// A source directory is often the root of a git checkout
+	if name := filepath.Base(path); name == ".git" || name == SourceMarkerName {
+		return false
 	}
-	defer file.Close()
-
-	var patterns []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Ignore empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		patterns = append(patterns, line)
+	if _, err := os.Lstat(filepath.Join(path, NoPackageFileName)); err == nil {
+		return false
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading ignore file %s: %w", ignoreFilePath, err)
+	if len(l.PackageDirs) == 0 {
+		return true
 	}
-
-	return patterns, nil
+	return slices.ContainsFunc(l.PackageDirs, func(pattern string) bool {
+		matched, _ := filepath.Match(pattern, filepath.Base(path))
+		return matched
+	})
 }
 
-// isPathIgnored checks if a path should be ignored based on the provided patterns
-func isPathIgnored(relPath string, ignorePatterns []string) bool {
-	for _, pattern := range ignorePatterns {
-		// Check against the full relative path first
-		matched, matchErr := filepath.Match(pattern, relPath)
-		if matchErr != nil {
-			// Log or handle bad patterns
-			fmt.Printf("Warning: Invalid pattern '%s': %v\n", pattern, matchErr)
+// sourceDirs returns SourceDir followed by ExtraSources, in increasing precedence.
+// They are made absolute, so the paths of package files are too and comparing
+// them needs no lookup of the working directory; with ResolveSources, symbolic
+// links in them are resolved as well.
+func (l *Linker) sourceDirs() []string {
+	dirs := append([]string{l.SourceDir}, l.ExtraSources...)
+	for i, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
 			continue
 		}
-
-		// If not matched and pattern doesn't contain a separator, try matching basename
-		if !matched && !strings.Contains(pattern, string(filepath.Separator)) {
-			baseName := filepath.Base(relPath)
-			matched, matchErr = filepath.Match(pattern, baseName)
-			if matchErr != nil {
-				fmt.Printf("Warning: Error matching pattern '%s' against base name '%s': %v\n", pattern, baseName, matchErr)
-				continue
-			}
+		if l.ResolveSources {
+			absDir = resolveDir(absDir)
 		}
+		dirs[i] = absDir
+	}
+	return dirs
+}
 
-		if matched {
-			return true
-		}
+// resolveDir returns dir with all symbolic links resolved, or dir itself if
+// it cannot be resolved.
+func resolveDir(dir string) string {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return dir
 	}
-	return false
+	return resolved
+}
+
+// resolveParent resolves symbolic links in the directory part of path, leaving
+// the last element alone since package files may be symlinks themselves.
+func resolveParent(path string) string {
+	return filepath.Join(resolveDir(filepath.Dir(path)), filepath.Base(path))
 }
 
 // removeParents attempts to remove the parent directory of targetPath
 // and continues removing parent directories upwards until
 // it hits the baseDir, root, or outside base.
-// If force is true, directories will be removed even if they're not empty.
-func removeParents(targetPath string, baseDir string, force bool) {
+// Only empty directories are removed. Progress is reported to log.
+func removeParents(log Logger, targetPath string, baseDir string) {
 	parentDir := filepath.Dir(targetPath)
 	// Ensure baseDir is absolute for reliable comparison
 	absBaseDir, err := filepath.Abs(baseDir)
 	if err != nil {
-		fmt.Printf("Warning: could not get absolute path for baseDir %s: %v\n", baseDir, err)
+		logf(log, LevelWarn, "Warning: could not get absolute path for baseDir %s: %v\n", baseDir, err)
 		absBaseDir = baseDir // Proceed with potentially relative path
 	}
 
 	for {
 		absParentDir, err := filepath.Abs(parentDir)
 		if err != nil {
-			fmt.Printf("Warning: could not get absolute path for parentDir %s: %v\n", parentDir, err)
+			logf(log, LevelWarn, "Warning: could not get absolute path for parentDir %s: %v\n", parentDir, err)
 			break // Cannot reliably compare, stop
 		}
 
-		// Stop conditions: reached base, root, or outside base
-		// Use filepath.Clean to handle trailing slashes etc.
-		if filepath.Clean(absParentDir) == filepath.Clean(absBaseDir) || absParentDir == "/" || absParentDir == "." || !strings.HasPrefix(absParentDir, absBaseDir) {
+		// Stop conditions: reached base, filesystem root, or outside base
+		if !isSubPath(absBaseDir, absParentDir) || filepath.Dir(absParentDir) == absParentDir {
 			break
 		}
 
-		// Attempt to remove the directory
-		var removeErr error
-		if force {
-			// Force remove the directory and all its contents
-			removeErr = os.RemoveAll(parentDir)
-		} else {
-			// Only remove if empty (default behavior)
-			removeErr = os.Remove(parentDir)
-		}
-
-		if removeErr == nil {
-			fmt.Printf("Removed directory: %s\n", parentDir)
-			// Move up to the next parent
-			parentDir = filepath.Dir(parentDir)
-		} else {
-			// Log the failure reason if verbose
-			if force {
-				fmt.Printf("Failed to force-remove directory %s: %v\n", parentDir, removeErr)
-			} else {
-				// Likely not empty, which is expected behavior
-				fmt.Printf("Skipped non-empty directory: %s\n", parentDir)
-			}
+		// Attempt to remove the directory, which fails unless it is empty
+		if err := os.Remove(parentDir); err != nil {
+			// Likely not empty, which is expected behavior
+			logf(log, LevelDebug, "Skipped non-empty directory: %s\n", parentDir)
 			break
 		}
+		logf(log, LevelInfo, "Removed directory: %s\n", parentDir)
+		// Move up to the next parent
+		parentDir = filepath.Dir(parentDir)
 	}
 }
 
-// processPackagePaths walks the package directory and returns a list of file paths to process
-// along with their corresponding target paths and relative paths
+// pathInfo describes a path managed by a package: its location in the
+// package, its target path, and its path relative to the package directory.
 type pathInfo struct {
 	sourcePath string
 	targetPath string
 	relPath    string
 	isDir      bool
+	bind       bool // A directory bind-mounted whole (see Manifest.Bind); its contents are not visited
 }
 
-func (l *Linker) processPackagePaths(pkg Package, ignorePatterns []string) ([]pathInfo, error) {
-	var paths []pathInfo
+// processPackagePaths walks the package directory and calls visit for every
+// path to process as it is found, so callers never need the whole package in
+// memory. An error returned by visit stops the walk and is returned as is.
+// Paths excluded by the ignore rules, and alternates (see AlternateSeparator)
+// for other machines, are returned as source paths; ignored directories are
+// listed without their contents. The chosen alternates are visited under
+// their names without conditions.
+func (l *Linker) processPackagePaths(pkg Package, packageDir string, ignore *IgnoreRules, visit func(pathInfo) error) ([]string, error) {
+	var ignored []string
+	alternates := make(map[string]map[string]bool) // Chosen alternates by directory
+	absTarget, err := filepath.Abs(l.TargetDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for target %s: %w", l.TargetDir, err)
+	}
 
-	err := filepath.WalkDir(pkg.Path, func(sourcePath string, d os.DirEntry, walkErr error) error {
+	err = orOS(l.FS).WalkDir(packageDir, func(sourcePath string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return fmt.Errorf("error accessing %s: %w", sourcePath, walkErr)
 		}
+		var err error
 
-		// Skip the root package directory itself and the ignore file
-		if sourcePath == pkg.Path || filepath.Base(sourcePath) == ".gslk-ignore" {
+		// Skip the root package directory itself and the ignore, include and manifest files
+		if sourcePath == packageDir || isControlFile(filepath.Base(sourcePath)) {
 			return nil
 		}
 
-		relPath, err := filepath.Rel(pkg.Path, sourcePath)
-		if err != nil {
-			return fmt.Errorf("failed to get relative path for %s: %w", sourcePath, err)
+		// Walked paths start with packageDir, so cutting it is enough
+		relPath, ok := strings.CutPrefix(sourcePath, packageDir+string(filepath.Separator))
+		if !ok {
+			if relPath, err = filepath.Rel(packageDir, sourcePath); err != nil {
+				return fmt.Errorf("failed to get relative path for %s: %w", sourcePath, err)
+			}
+		}
+
+		if reason := l.walkLimit(relPath, d.IsDir()); reason != "" {
+			l.logVerbose("Ignoring %s (%s)\n", relPath, reason)
+			ignored = append(ignored, sourcePath)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		// Check against ignore patterns
-		if isPathIgnored(relPath, ignorePatterns) {
+		if ignore.Match(relPath) {
 			l.logVerbose("Ignoring %s (matches ignore pattern)\n", relPath)
+			ignored = append(ignored, sourcePath)
 			if d.IsDir() {
 				return filepath.SkipDir // Skip the entire directory
 			}
 			return nil // Skip this file
 		}
+		if l.IgnoreMatcher != nil && l.IgnoreMatcher.Ignore(pkg, relPath, d.IsDir()) {
+			l.logVerbose("Ignoring %s (matched by the ignore matcher)\n", relPath)
+			ignored = append(ignored, sourcePath)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
-		targetPath := filepath.Join(l.TargetDir, relPath)
+		if _, _, ok := splitAlternate(d.Name()); ok {
+			dir := filepath.Dir(sourcePath)
+			chosen, ok := alternates[dir]
+			if !ok {
+				if chosen, err = l.chooseAlternates(dir); err != nil {
+					return err
+				}
+				alternates[dir] = chosen
+			}
+			if !chosen[d.Name()] {
+				l.logVerbose("Ignoring %s (alternate for another machine)\n", relPath)
+				ignored = append(ignored, sourcePath)
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		relPath = stripAlternates(relPath)
+
+		targetPath := l.targetPath(pkg, relPath)
+		if d.IsDir() && targetPath == l.TargetDir {
+			return nil // A stripped directory: its contents go straight into the target
+		}
+		if err := checkInside(absTarget, l.TargetDir, targetPath); err != nil {
+			return fmt.Errorf("%s maps outside the target directory: %w", sourcePath, err)
+		}
 
-		paths = append(paths, pathInfo{
+		bind := d.IsDir() && !l.Ephemeral && pkg.binds(relPath)
+		if err := visit(pathInfo{
 			sourcePath: sourcePath,
 			targetPath: targetPath,
 			relPath:    relPath,
 			isDir:      d.IsDir(),
-		})
-
-		return nil
+			bind:       bind,
+		}); err != nil || !bind {
+			return err
+		}
+		return filepath.SkipDir // The mount brings the contents along
 	})
 
-	return paths, err
+	return ignored, err
 }
 
-// ensureDirectory creates a directory if it doesn't exist
-func (l *Linker) ensureDirectory(path string) error {
-	if l.DryRun {
-		l.logVerbose("DRY RUN: Would create directory: %s\n", path)
-		return nil
+// walkLimit returns why MaxDepth or SkipHidden excludes the package path
+// relPath, or "" if they do not.
+func (l *Linker) walkLimit(relPath string, isDir bool) string {
+	depth := strings.Count(relPath, string(filepath.Separator)) + 1
+	switch {
+	case l.MaxDepth > 0 && (depth > l.MaxDepth || isDir && depth == l.MaxDepth):
+		return fmt.Sprintf("beyond the maximum depth of %d", l.MaxDepth)
+	case l.SkipHidden && depth > 1 && strings.HasPrefix(filepath.Base(relPath), "."):
+		return "hidden inside the package"
 	}
-
-	l.logVerbose("Ensuring directory exists: %s\n", path)
-	return os.MkdirAll(path, 0755)
+	return ""
 }
 
-// createSymlink creates a symbolic link from target to source
-func (l *Linker) createSymlink(sourcePath, targetPath string) error {
-	fmt.Printf("Linking: %s -> %s\n", sourcePath, targetPath)
+// isControlFile reports whether name is one of the files configuring a
+// package rather than a file to link.
+func isControlFile(name string) bool {
+	return name == IgnoreFileName || name == IncludeFileName || name == ManifestFileName
+}
 
-	if l.DryRun {
-		return nil
-	}
+// targetPath returns where the package-relative path relPath of pkg is placed,
+// applying the package's target directory and renames.
+func (l *Linker) targetPath(pkg Package, relPath string) string {
+	mapper := l.mapper(pkg)
+	return filepath.Join(l.TargetDir, pkg.Manifest.Target, l.mapPath(pkg, &mapper, relPath))
+}
 
-	// Ensure parent directory exists
-	targetDir := filepath.Dir(targetPath)
-	if err := l.ensureDirectory(targetDir); err != nil {
-		return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
+// mapPath returns the target-relative path of the package-relative path
+// relPath of pkg: mapper's, passed through the PathMapper if there is one.
+func (l *Linker) mapPath(pkg Package, mapper *Mapper, relPath string) string {
+	mapped := mapper.Map(relPath)
+	if l.PathMapper != nil {
+		mapped = filepath.Clean(l.PathMapper.MapPath(pkg, mapped))
 	}
+	return mapped
+}
 
-	// Create the symbolic link with absolute path
-	absSourcePath, absErr := filepath.Abs(sourcePath)
-	if absErr != nil {
-		return fmt.Errorf("failed to get absolute path for source %s: %w", sourcePath, absErr)
+// mapper returns the Mapper with the renames of pkg's manifest merged in.
+func (l *Linker) mapper(pkg Package) Mapper {
+	mapper := l.Mapper
+	if len(pkg.Manifest.Rename) > 0 {
+		mapper.Renames = make(map[string]string, len(l.Mapper.Renames)+len(pkg.Manifest.Rename))
+		maps.Copy(mapper.Renames, l.Mapper.Renames)
+		maps.Copy(mapper.Renames, pkg.Manifest.Rename)
 	}
-
-	return os.Symlink(absSourcePath, targetPath)
+	return mapper
 }
 
 // isCorrectSymlink checks if a symlink at targetPath correctly points to sourcePath
-func isCorrectSymlink(targetPath, sourcePath string) (bool, error) {
-	linkTarget, err := os.Readlink(targetPath)
+func isCorrectSymlink(fsys FS, targetPath, sourcePath string) (bool, error) {
+	absLinkTarget, err := resolveLinkTarget(fsys, targetPath)
 	if err != nil {
-		return false, fmt.Errorf("failed to read symlink %s: %w", targetPath, err)
+		return false, err
 	}
 
 	// Compare absolute paths for robustness
@@ -264,222 +579,513 @@ func isCorrectSymlink(targetPath, sourcePath string) (bool, error) {
 		return false, fmt.Errorf("failed to get absolute path for source %s: %w", sourcePath, err)
 	}
 
-	absLinkTarget, err := filepath.Abs(filepath.Join(filepath.Dir(targetPath), linkTarget))
+	// A symlinked source directory gives the same file two paths; links made
+	// through either of them are correct
+	return pathsEqual(absLinkTarget, absSourcePath) || pathsEqual(resolveParent(absLinkTarget), resolveParent(absSourcePath)), nil
+}
+
+// isBindMount reports whether targetPath, as described by fi from Lstat, is
+// the directory sourcePath mounted there: a directory cannot otherwise be the
+// same file at two paths. On Windows, it reports whether targetPath is a
+// junction to sourcePath.
+func isBindMount(sourcePath, targetPath string, fi os.FileInfo) bool {
+	if isJunction(fi) {
+		dest, err := resolveLinkTarget(nil, targetPath)
+		absSourcePath, absErr := filepath.Abs(sourcePath)
+		return err == nil && absErr == nil && pathsEqual(dest, absSourcePath)
+	}
+	source, err := os.Stat(sourcePath)
+	return err == nil && source.IsDir() && os.SameFile(source, fi)
+}
+
+// resolveLinkTarget returns the absolute path the symlink at linkPath points to,
+// without following any further links.
+func resolveLinkTarget(fsys FS, linkPath string) (string, error) {
+	linkTarget, err := orOS(fsys).Readlink(linkPath)
 	if err != nil {
-		// Try absolute if linkTarget itself was absolute
-		absLinkTarget, err = filepath.Abs(linkTarget)
-		if err != nil {
-			return false, fmt.Errorf("failed to get absolute path for link target %s: %w", linkTarget, err)
-		}
+		return "", fmt.Errorf("failed to read symlink %s: %w", linkPath, err)
 	}
 
-	return linkTarget == sourcePath || absLinkTarget == absSourcePath, nil
+	// Relative link targets are relative to the directory containing the link
+	linkTarget = normalizeLinkTarget(linkTarget)
+	if !filepath.IsAbs(linkTarget) {
+		linkTarget = filepath.Join(filepath.Dir(linkPath), linkTarget)
+	}
+	absLinkTarget, err := filepath.Abs(linkTarget)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for link target %s: %w", linkTarget, err)
+	}
+	return absLinkTarget, nil
 }
 
-// Link creates symbolic links for the specified packages from SourceDir to TargetDir.
-// It handles conflicts if a file/directory already exists at the target location.
-func (l *Linker) Link(packageNames []string) error {
-	allPackages, err := l.FindPackages()
+// isSubPath reports whether path lies strictly inside base. Both paths must be
+// absolute. Unlike a string prefix check, /home/user2 is not inside /home/user.
+func isSubPath(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkInsideTarget returns an error unless path lies strictly inside
+// targetDir once both are made absolute and cleaned. gslk never creates or
+// removes anything elsewhere, whatever a package, its mapping or a tampered
+// state manifest asks for.
+func checkInsideTarget(targetDir, path string) error {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for target %s: %w", targetDir, err)
+	}
+	return checkInside(absTarget, targetDir, path)
+}
+
+// checkInside is checkInsideTarget with targetDir already made absolute as
+// absTarget, for checking many paths against the same target.
+func checkInside(absTarget, targetDir, path string) error {
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return fmt.Errorf("failed to find packages: %w", err)
+		return fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+	}
+	// Both are clean, so a path below absTarget starts with it
+	if strings.HasPrefix(absPath, absTarget+string(filepath.Separator)) {
+		return nil
 	}
+	if !isSubPath(absTarget, absPath) {
+		return fmt.Errorf("refusing to touch %s: it is outside the target directory %s", path, targetDir)
+	}
+	return nil
+}
 
-	packagesToLink := make(map[string]Package)
-	for _, pkg := range allPackages {
-		packagesToLink[pkg.Name] = pkg
+// lookupPackages resolves package names to packages found in the source directory.
+func (l *Linker) lookupPackages(packageNames []string) ([]Package, error) {
+	packagesByName, err := l.packagesByName()
+	if err != nil {
+		return nil, err
+	}
+	if packageNames, err = l.expandGroups(packageNames, packagesByName); err != nil {
+		return nil, err
 	}
 
+	packages := make([]Package, 0, len(packageNames))
 	for _, name := range packageNames {
-		pkg, ok := packagesToLink[name]
-		if !ok {
-			return fmt.Errorf("package '%s' not found in source directory %s", name, l.SourceDir)
+		// "nvim/lua/plugins" names the lua/plugins subtree of package nvim
+		name, subpath, _ := strings.Cut(filepath.ToSlash(name), "/")
+		pkg, err := l.findPackage(name, packagesByName)
+		if err != nil {
+			return nil, err
+		}
+		if subpath = strings.Trim(subpath, "/"); subpath != "" {
+			pkg.Subpath = filepath.Clean(filepath.FromSlash(subpath))
+			if !pkg.hasSubpath() {
+				return nil, fmt.Errorf("package '%s' has no %s", name, subpath)
+			}
 		}
+		if !pkg.Manifest.SupportsOS() {
+			l.printf("Skipping package %s: it only supports %s\n", name, strings.Join(pkg.Manifest.OS, ", "))
+			continue
+		}
+		if pkg.Manifest.Description != "" {
+			l.logVerbose("Package %s: %s\n", name, pkg.Manifest.Description)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
 
-		// Load ignore patterns for this package
-		ignorePatterns, err := loadIgnorePatterns(pkg.Path)
+// hasSubpath reports whether Subpath exists in any layer of the package and
+// stays inside it.
+func (pkg Package) hasSubpath() bool {
+	if !filepath.IsLocal(pkg.Subpath) {
+		return false
+	}
+	layers := pkg.Layers
+	if len(layers) == 0 {
+		layers = []string{pkg.Path}
+	}
+	for _, layer := range layers {
+		if _, err := os.Lstat(filepath.Join(layer, pkg.Subpath)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// inSubpath reports whether relPath belongs to the part of pkg being worked
+// on: Subpath itself, anything below it, and the directories leading to it.
+func (pkg Package) inSubpath(relPath string) bool {
+	return pkg.Subpath == "" || relPath == pkg.Subpath || isSubPath(pkg.Subpath, relPath) || isSubPath(relPath, pkg.Subpath)
+}
+
+// walkPackage calls visit for every path pkg manages and returns the source
+// paths its ignore rules exclude. A package in a single source is streamed
+// straight from the walk, so an error from visit stops it early. A package
+// spread over several sources is merged first, since a path in a later layer
+// replaces the same path of earlier layers.
+func (l *Linker) walkPackage(pkg Package, visit func(pathInfo) error) ([]string, error) {
+	if l.Throttle != nil {
+		unthrottled := visit
+		visit = func(path pathInfo) error {
+			l.Throttle.Wait()
+			return unthrottled(path)
+		}
+	}
+	if len(pkg.Layers) > 1 {
+		paths, ignored, err := l.packagePaths(pkg)
 		if err != nil {
-			return fmt.Errorf("failed to load ignore patterns for package %s: %w", name, err)
+			return ignored, err
+		}
+		for _, path := range paths {
+			if err := visit(path); err != nil {
+				return ignored, err
+			}
 		}
+		return ignored, nil
+	}
 
-		l.logVerbose("Loaded %d ignore patterns for package %s\n", len(ignorePatterns), name)
+	return l.walkLayer(pkg, pkg.Path, visit)
+}
 
-		// Process all paths in the package
-		paths, err := l.processPackagePaths(pkg, ignorePatterns)
+// walkLayer loads the ignore rules of one source directory of pkg and walks it.
+func (l *Linker) walkLayer(pkg Package, layer string, visit func(pathInfo) error) ([]string, error) {
+	ignore, err := pkg.rules.layer(layer, func() (*IgnoreRules, error) {
+		rules, invalid, err := loadPackageRules(layer, slices.Concat(l.Ignore, pkg.Manifest.Ignore), pkg.Manifest.Include)
 		if err != nil {
-			return fmt.Errorf("failed to process paths for package %s: %w", name, err)
+			return nil, err
+		}
+		if filepath.Dir(pkg.Manifest.Path) == layer {
+			// Checked with the layer of the manifest, so reported once
+			invalid = append(invalid, checkPatterns(pkg.Manifest.Path, slices.Concat(pkg.Manifest.Ignore, pkg.Manifest.Include, pkg.Manifest.Copy, pkg.Manifest.Templates, pkg.Manifest.Bind))...)
+		}
+		if len(invalid) > 0 && l.StrictIgnore {
+			return nil, errors.Join(invalid...)
 		}
+		for _, err := range invalid {
+			l.warnf("Warning: %v, skipping it\n", err)
+		}
+		l.logVerbose("Loaded %d ignore patterns for package %s from %s\n", len(rules.Patterns()), pkg.Name, layer)
+		return rules.WithRegexp(l.IgnoreRegexp).WithInclude(l.Only), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ignore patterns for package %s: %w", pkg.Name, err)
+	}
 
-		// Handle each path
-		for _, path := range paths {
+	// With include patterns a directory is only visited once a file below it
+	// is included, so no empty directories are created for skipped files
+	var pending []pathInfo
+	var excluded []string
+
+	var visitErr error
+	ignored, err := l.processPackagePaths(pkg, layer, ignore, func(path pathInfo) error {
+		if !pkg.inSubpath(path.relPath) {
 			if path.isDir {
-				// For directories, just ensure they exist in target
-				if err := l.ensureDirectory(path.targetPath); err != nil {
-					return fmt.Errorf("failed to create target directory %s: %w", path.targetPath, err)
-				}
-				continue
+				return filepath.SkipDir
 			}
+			return nil
+		}
 
-			// For files, check if target already exists
-			targetFi, err := os.Lstat(path.targetPath)
-			if err == nil {
-				// Target exists, check if it's a symlink to the correct source
-				if targetFi.Mode()&os.ModeSymlink != 0 {
-					isCorrect, checkErr := isCorrectSymlink(path.targetPath, path.sourcePath)
-					if checkErr != nil {
-						return checkErr
-					}
-
-					if isCorrect {
-						// Already correctly linked, skip
-						l.logVerbose("Skipping already linked: %s -> %s\n", path.sourcePath, path.targetPath)
-						continue
-					}
+		if len(ignore.include) > 0 {
+			for len(pending) > 0 && !isSubPath(pending[len(pending)-1].relPath, path.relPath) {
+				pending = pending[:len(pending)-1]
+			}
+			if path.isDir && !path.bind {
+				pending = append(pending, path)
+				return nil
+			}
+			if !path.bind && !ignore.Includes(path.relPath) {
+				l.logVerbose("Skipping %s (matches no include pattern)\n", path.relPath)
+				excluded = append(excluded, path.sourcePath)
+				return nil
+			}
+			for _, dir := range pending {
+				if visitErr = visit(dir); visitErr != nil {
+					return visitErr
 				}
-				// Target exists but is not the correct symlink
-				return fmt.Errorf("conflict: target %s already exists and is not the expected symlink", path.targetPath)
-			} else if !os.IsNotExist(err) {
-				// Error during Lstat other than file not existing
-				return fmt.Errorf("failed to stat target path %s: %w", path.targetPath, err)
 			}
+			pending = pending[:0]
+		}
+
+		visitErr = visit(path)
+		return visitErr
+	})
+	ignored = append(ignored, excluded...)
+	if visitErr != nil {
+		return ignored, visitErr
+	}
+	if err != nil {
+		return ignored, fmt.Errorf("failed to process paths for package %s: %w", pkg.Name, err)
+	}
+	return ignored, nil
+}
+
+// packagePaths returns all paths pkg manages, and the source paths its ignore
+// rules exclude. Each layer is walked with its own ignore rules; a path in a
+// later layer replaces the same path of earlier layers.
+func (l *Linker) packagePaths(pkg Package) ([]pathInfo, []string, error) {
+	layers := pkg.Layers
+	if len(layers) == 0 {
+		layers = []string{pkg.Path}
+	}
 
-			// Create symlink
-			if err := l.createSymlink(path.sourcePath, path.targetPath); err != nil {
-				return fmt.Errorf("failed to create symlink from %s to %s: %w", path.sourcePath, path.targetPath, err)
+	var paths []pathInfo
+	var ignored []string
+	index := make(map[string]int) // Relative path -> position in paths
+
+	for _, layer := range layers {
+		layerIgnored, err := l.walkLayer(pkg, layer, func(path pathInfo) error {
+			i, ok := index[path.relPath]
+			if !ok {
+				index[path.relPath] = len(paths)
+				paths = append(paths, path)
+				return nil
+			}
+			if paths[i].isDir != path.isDir {
+				return fmt.Errorf("%w: %s is a file in one source of package %s and a directory in another", ErrConflict, path.relPath, pkg.Name)
+			}
+			if !path.isDir {
+				l.logVerbose("Using %s instead of %s\n", path.sourcePath, paths[i].sourcePath)
+				paths[i] = path
 			}
+			return nil
+		})
+		ignored = append(ignored, layerIgnored...)
+		if err != nil {
+			return nil, ignored, err
 		}
 	}
-
-	return nil
+	return paths, ignored, nil
 }
 
-// Unlink removes symbolic links for the specified packages from the TargetDir
-// that point back to the SourceDir. It also removes empty parent directories
-// created during linking.
-func (l *Linker) Unlink(packageNames []string) error {
-	allPackages, err := l.FindPackages()
+// Link creates symbolic links for the specified packages from SourceDir to TargetDir.
+// It handles conflicts if a file/directory already exists at the target location.
+// Package hooks run before and after the links are made, once planning succeeded.
+// The returned Result is never nil; it is empty if nothing could be planned.
+func (l *Linker) Link(packageNames []string) (result *Result, err error) {
+	span := l.startSpan("gslk.Link", Attr{"gslk.packages", packageNames})
+	defer func() { span.End(err) }()
+	defer l.lockState()()
+
+	// Load the state manifest to track copies across runs
+	state, err := l.loadState()
 	if err != nil {
-		return fmt.Errorf("failed to find packages: %w", err)
+		return &Result{}, fmt.Errorf("failed to load state: %w", err)
 	}
 
-	packagesToUnlink := make(map[string]Package)
-	for _, pkg := range allPackages {
-		packagesToUnlink[pkg.Name] = pkg
+	if err := l.checkRoot(); err != nil {
+		return &Result{}, err
 	}
 
-	for _, name := range packageNames {
-		pkg, ok := packagesToUnlink[name]
-		if !ok {
-			return fmt.Errorf("package '%s' not found in source directory %s, cannot determine links to remove", name, l.SourceDir)
-		}
+	plan, err := l.planLink(span, packageNames, state)
+	if err != nil {
+		return &Result{}, err
+	}
 
-		// Load ignore patterns for this package
-		ignorePatterns, err := loadIgnorePatterns(pkg.Path)
-		if err != nil {
-			return fmt.Errorf("failed to load ignore patterns for package %s: %w", name, err)
-		}
+	if err := l.runHooks(plan.Packages, HookPreLink); err != nil {
+		return &Result{}, err
+	}
 
-		l.logVerbose("Loaded %d ignore patterns for package %s for unlinking\n", len(ignorePatterns), name)
+	existingDirs := make(map[string]bool, len(state.Directories))
+	for dir := range state.Directories {
+		existingDirs[dir] = true
+	}
 
-		// Process all paths in the package
-		paths, err := l.processPackagePaths(pkg, ignorePatterns)
-		if err != nil {
-			return fmt.Errorf("failed to process paths for package %s: %w", name, err)
-		}
+	result, applyErr := l.executor(state, span).Apply(plan)
 
-		// Handle each path that is not a directory
-		for _, path := range paths {
-			if path.isDir {
-				continue // Skip directories during unlinking
+	// Persist whatever was deployed, even if a later operation failed
+	if !l.DryRun {
+		if err := state.Save(); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("failed to save state: %w", err)
+		}
+		if l.Ephemeral {
+			if err := l.recordTeardown(state, result, existingDirs); err != nil && applyErr == nil {
+				applyErr = err
 			}
+		}
+	}
+	if applyErr != nil {
+		return result, applyErr
+	}
 
-			targetFi, err := os.Lstat(path.targetPath)
-			if err != nil {
-				if os.IsNotExist(err) {
-					// Target doesn't exist, nothing to unlink
-					continue
-				}
-				// Other error stat-ing target
-				return fmt.Errorf("failed to stat target path %s: %w", path.targetPath, err)
-			}
+	return result, l.runHooks(plan.Packages, HookPostLink)
+}
 
-			// Target exists, check if it's a symlink pointing to our source
-			if targetFi.Mode()&os.ModeSymlink != 0 {
-				isCorrect, checkErr := isCorrectSymlink(path.targetPath, path.sourcePath)
-				if checkErr != nil {
-					return checkErr
-				}
+// Unlink removes symbolic links for the specified packages from the TargetDir
+// that point back to the SourceDir, along with copies recorded in the state
+// manifest. Directories created during linking are removed once empty and no
+// longer needed by another package. The returned Result is never nil.
+func (l *Linker) Unlink(packageNames []string) (result *Result, err error) {
+	span := l.startSpan("gslk.Unlink", Attr{"gslk.packages", packageNames})
+	defer func() { span.End(err) }()
+	defer l.lockState()()
+
+	// Load the state manifest to recognise copies and rendered templates we deployed
+	state, err := l.loadState()
+	if err != nil {
+		return &Result{}, fmt.Errorf("failed to load state: %w", err)
+	}
 
-				if isCorrect {
-					// This is the link we created, remove it
-					fmt.Printf("Unlinking: %s (link to %s)\n", path.targetPath, path.sourcePath)
-
-					// In dry run mode, don't make actual changes
-					if l.DryRun {
-						continue
-					}
-
-					removeErr := os.Remove(path.targetPath)
-					if removeErr != nil && !os.IsNotExist(removeErr) {
-						return fmt.Errorf("failed to remove symlink %s: %w", path.targetPath, removeErr)
-					}
-
-					// Attempt to remove empty parent directories
-					removeParents(path.targetPath, l.TargetDir, l.ForceRemove)
-				} else if l.Verbose {
-					// Symlink exists but points elsewhere
-					fmt.Printf("Skipping unlink for %s: symlink points elsewhere\n", path.targetPath)
-				}
-			} else if l.Verbose {
-				// Target exists but is not a symlink
-				fmt.Printf("Skipping unlink for %s: not a symlink\n", path.targetPath)
-			}
+	if err := l.checkRoot(); err != nil {
+		return &Result{}, err
+	}
+
+	plan, err := l.planUnlink(span, packageNames, state)
+	if err != nil {
+		return &Result{}, err
+	}
+
+	if err := l.runHooks(plan.Packages, HookPreUnlink); err != nil {
+		return &Result{}, err
+	}
+
+	// With a manifest, files gslk did not deploy are known not to be ours and
+	// only the files actually unlinked need checking
+	touchedOnly := state.exists
+
+	result, applyErr := l.executor(state, span).Apply(plan)
+
+	if !l.DryRun {
+		if err := state.Save(); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("failed to save state: %w", err)
 		}
 	}
+	if applyErr != nil {
+		return result, applyErr
+	}
 
 	// Verification pass if not in dry run mode
-	if !l.DryRun {
-		err = l.verifyUnlink(packageNames, packagesToUnlink)
+	if !l.DryRun && !l.NoVerify {
+		verifySpan := span.Start("gslk.verify", Attr{"gslk.operations", len(plan.Operations)})
+		err := l.verifyUnlink(plan, touchedOnly)
+		verifySpan.End(err)
 		if err != nil {
-			return err
+			var verifyErr *VerifyError
+			if errors.As(err, &verifyErr) {
+				for _, lingering := range verifyErr.Lingering {
+					result.Unlinked = slices.DeleteFunc(result.Unlinked, func(path string) bool { return path == lingering.Path })
+					result.Failed = append(result.Failed, lingering.Path)
+				}
+			}
+			return result, err
 		}
 	}
 
+	return result, l.runHooks(plan.Packages, HookPostUnlink)
+}
+
+// checkRoot refuses to work as root on a target directory owned by another
+// user unless AllowRoot is set, since everything created would belong to root.
+func (l *Linker) checkRoot() error {
+	if !isRoot() || l.AllowRoot || l.DryRun {
+		return nil
+	}
+	if err := checkOwner(l.FS, l.TargetDir, false); err != nil {
+		return fmt.Errorf("refusing to run as root on %s, which belongs to another user; use --allow-root for system packages", l.TargetDir)
+	}
 	return nil
 }
 
-// verifyUnlink performs a verification pass to ensure no lingering links exist
-func (l *Linker) verifyUnlink(packageNames []string, packagesToUnlink map[string]Package) error {
-	for _, name := range packageNames {
-		pkg, ok := packagesToUnlink[name]
-		if !ok {
-			continue // We've already checked this earlier
+// Lingering is a file still deployed after its package was unlinked.
+type Lingering struct {
+	Path string
+	Mode DeployMode // ModeLink for symbolic links, ModeBind for bind mounts
+}
+
+// VerifyError is returned by Unlink when files of the unlinked packages are
+// still deployed afterwards. It lists every such file.
+type VerifyError struct {
+	Lingering []Lingering
+}
+
+func (e *VerifyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "verification failed: %d files still exist after unlink operation:", len(e.Lingering))
+	for _, lingering := range e.Lingering {
+		if lingering.Mode == ModeLink {
+			fmt.Fprintf(&b, "\n  symbolic link %s", lingering.Path)
+		} else {
+			fmt.Fprintf(&b, "\n  deployed %s %s", lingering.Mode, lingering.Path)
 		}
+	}
+	return b.String()
+}
 
-		// Load ignore patterns again for verification
-		ignorePatterns, err := loadIgnorePatterns(pkg.Path)
-		if err != nil {
-			return fmt.Errorf("failed to load ignore patterns for package %s during verification: %w", name, err)
+// verifyUnlink checks that none of the files plan unlinked or skipped is
+// still deployed. It reuses the paths found while planning instead of walking
+// the packages again; files that were missing then are not rechecked. If
+// touchedOnly is set, skipped files are not rechecked either.
+func (l *Linker) verifyUnlink(plan *Plan, touchedOnly bool) error {
+	state, err := l.loadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state during verification: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	var lingering []Lingering
+	for _, op := range plan.Operations {
+		if op.Kind == OpRmdir || touchedOnly && op.Kind == OpSkip {
+			continue
 		}
 
-		// Process all paths for verification
-		paths, err := l.processPackagePaths(pkg, ignorePatterns)
+		classification, err := classifier.Classify(op.Package, op.Source, op.Target)
 		if err != nil {
-			return fmt.Errorf("failed to process paths for package %s during verification: %w", name, err)
+			continue // Unreadable leftovers are not ours to judge
 		}
 
-		// Check each file (not directory)
-		for _, path := range paths {
-			if !path.isDir {
-				targetFi, err := os.Lstat(path.targetPath)
-				if err == nil && targetFi.Mode()&os.ModeSymlink != 0 {
-					// Link still exists, check if it points to our source
-					isCorrect, _ := isCorrectSymlink(path.targetPath, path.sourcePath)
-					if isCorrect {
-						return fmt.Errorf("symbolic link %s still exists after unlink operation", path.targetPath)
-					}
-				}
+		switch classification.State {
+		case TargetLinked:
+			mode := ModeLink
+			if op.Kind == OpUnmount {
+				mode = ModeBind
 			}
+			lingering = append(lingering, Lingering{Path: op.Target, Mode: mode})
+		case TargetDeployed, TargetModified:
+			lingering = append(lingering, Lingering{Path: op.Target, Mode: classification.Entry.Mode})
 		}
 	}
+
+	if len(lingering) > 0 {
+		return &VerifyError{Lingering: lingering}
+	}
 	return nil
 }
+
+// LinkFile links the single file relPath of package pkgName, walking only the
+// directories leading to it rather than the whole package.
+func (l *Linker) LinkFile(pkgName, relPath string) (*Result, error) {
+	name, err := l.fileName(pkgName, relPath)
+	if err != nil {
+		return &Result{}, err
+	}
+	return l.Link([]string{name})
+}
+
+// UnlinkFile unlinks the single file relPath of package pkgName. Like
+// unlinking a subpath, the directories leading to it are left in place.
+func (l *Linker) UnlinkFile(pkgName, relPath string) (*Result, error) {
+	name, err := l.fileName(pkgName, relPath)
+	if err != nil {
+		return &Result{}, err
+	}
+	return l.Unlink([]string{name})
+}
+
+// fileName returns the "package/path" name selecting the file relPath of
+// package pkgName, checking that it names a file.
+func (l *Linker) fileName(pkgName, relPath string) (string, error) {
+	name := pkgName + "/" + filepath.ToSlash(filepath.Clean(relPath))
+	packages, err := l.lookupPackages([]string{name})
+	if err != nil {
+		return "", err
+	}
+	if len(packages) == 0 {
+		return "", fmt.Errorf("package %s does not support %s", pkgName, runtime.GOOS)
+	}
+
+	pkg := packages[0]
+	for _, layer := range pkg.Layers {
+		if fi, err := os.Stat(filepath.Join(layer, pkg.Subpath)); err == nil && fi.IsDir() {
+			return "", fmt.Errorf("%s in package %s is a directory, not a file", relPath, pkgName)
+		}
+	}
+	return name, nil
+}
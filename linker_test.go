@@ -1,8 +1,16 @@
 package gslk
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -71,7 +79,7 @@ func TestLink(t *testing.T) {
 	}
 
 	// Perform the link operation
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 	assert.NoError(t, err, "Link operation failed")
 
 	// Verify the links
@@ -130,7 +138,7 @@ func TestUnlink(t *testing.T) {
 	}
 
 	// Link it first
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 	require.NoError(t, err, "Pre-unlink Link operation failed")
 
 	// Quick check link exists (optional)
@@ -138,7 +146,7 @@ func TestUnlink(t *testing.T) {
 	require.NoError(t, err, "Link target check failed before unlink")
 
 	// --- Test: Perform the unlink operation ---
-	err = linker.Unlink([]string{pkgName})
+	_, err = linker.Unlink([]string{pkgName})
 	assert.NoError(t, err, "Unlink operation failed")
 
 	// --- Verification: Check links are removed ---
@@ -205,13 +213,14 @@ func TestLinkConflict(t *testing.T) {
 	}
 
 	// --- Test: Attempt to link, expecting conflict errors ---
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 
 	// --- Verification: Check for error and that conflicts remain ---
 	assert.Error(t, err, "Link should have returned an error due to conflict")
 	if err != nil {
 		// Check if the error message contains mentions of the conflict
 		assert.Contains(t, err.Error(), "conflict: target", "Error message should indicate a conflict")
+		assert.ErrorIs(t, err, ErrConflict)
 		// We expect the error on the first conflict found (`dir` or `file.txt`)
 		assert.Contains(t, err.Error(), filepath.Join(targetDir, ""), "Error message should contain conflicting target path")
 	}
@@ -248,11 +257,11 @@ func TestLinkAlreadyLinked(t *testing.T) {
 	}
 
 	// Link it once
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 	require.NoError(t, err, "First Link operation failed")
 
 	// --- Test: Link it again ---
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 
 	// --- Verification: No error should occur ---
 	assert.NoError(t, err, "Linking an already correctly linked package should not produce an error")
@@ -304,7 +313,7 @@ func TestLinkWithIgnore(t *testing.T) {
 	}
 
 	// --- Test: Perform Link ---
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 	assert.NoError(t, err, "Link operation with ignores failed")
 
 	// --- Verification ---
@@ -376,7 +385,7 @@ func TestUnlinkWithIgnore(t *testing.T) {
 	}
 
 	// --- Setup: Link the package first (respecting ignores) ---
-	err = linker.Link([]string{pkgName})
+	_, err = linker.Link([]string{pkgName})
 	require.NoError(t, err, "Pre-unlink Link operation failed")
 
 	// Quick check: ensure linked file exists, ignored file doesn't
@@ -388,7 +397,7 @@ func TestUnlinkWithIgnore(t *testing.T) {
 	require.True(t, os.IsNotExist(err), "Ignored directory exists after link")
 
 	// --- Test: Perform Unlink ---
-	err = linker.Unlink([]string{pkgName})
+	_, err = linker.Unlink([]string{pkgName})
 	assert.NoError(t, err, "Unlink operation with ignores failed")
 
 	// --- Verification ---
@@ -418,3 +427,785 @@ func TestUnlinkWithIgnore(t *testing.T) {
 		assert.True(t, os.IsNotExist(err), "Should be ignored: Target %s should not exist (stat err: %v)", targetPath, err)
 	}
 }
+
+func TestIsSubPath(t *testing.T) {
+	base := filepath.Join(string(filepath.Separator), "home", "user")
+	assert.True(t, isSubPath(base, filepath.Join(base, "dir")))
+	assert.True(t, isSubPath(base, filepath.Join(base, "dir", "file")))
+	assert.True(t, isSubPath(base, filepath.Join(base, "..dotdot")))
+	assert.False(t, isSubPath(base, base), "A directory is not inside itself")
+	assert.False(t, isSubPath(base, filepath.Dir(base)))
+	assert.False(t, isSubPath(base, base+"2"), "Sibling sharing a prefix is not inside base")
+	assert.False(t, isSubPath(base, filepath.Join(base+"2", "dir")))
+}
+
+func TestRemoveParentsStaysInsideBase(t *testing.T) {
+	tempDir := t.TempDir()
+	baseDir := filepath.Join(tempDir, "user")
+	siblingDir := filepath.Join(tempDir, "user2", "empty")
+	require.NoError(t, os.MkdirAll(baseDir, 0755))
+	require.NoError(t, os.MkdirAll(siblingDir, 0755))
+
+	// A path outside base that merely shares its prefix must not be pruned
+	removeParents(nil, filepath.Join(siblingDir, "file"), baseDir)
+	_, err := os.Stat(siblingDir)
+	assert.NoError(t, err, "Directory outside the base directory was removed")
+
+	nested := filepath.Join(baseDir, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+	removeParents(nil, filepath.Join(nested, "file"), baseDir)
+	_, err = os.Stat(filepath.Join(baseDir, "a"))
+	assert.True(t, os.IsNotExist(err), "Empty parents inside base should be removed")
+	_, err = os.Stat(baseDir)
+	assert.NoError(t, err, "Base directory itself must never be removed")
+}
+
+func TestIsCorrectSymlinkRelative(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	sourcePath := filepath.Join(sourceDir, "pkg", "file")
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{"file": "x"})
+
+	relTarget, err := filepath.Rel(targetDir, sourcePath)
+	require.NoError(t, err)
+	linkPath := filepath.Join(targetDir, "file")
+	require.NoError(t, os.Symlink(relTarget, linkPath))
+
+	isCorrect, err := isCorrectSymlink(nil, linkPath, sourcePath)
+	require.NoError(t, err)
+	assert.True(t, isCorrect, "Relative symlink to the source should be recognised")
+
+	isCorrect, err = isCorrectSymlink(nil, linkPath, filepath.Join(sourceDir, "other"))
+	require.NoError(t, err)
+	assert.False(t, isCorrect)
+}
+
+func TestLinkLayeredSources(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	overlayDir := filepath.Join(filepath.Dir(sourceDir), "overlay")
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{
+		".gitconfig":        "team",
+		".gitignore_global": "team",
+	})
+	createDummyPackage(t, filepath.Join(overlayDir, "git"), map[string]string{
+		".gitconfig": "personal",
+	})
+	createDummyPackage(t, filepath.Join(overlayDir, "personal_only"), map[string]string{
+		".notes": "mine",
+	})
+
+	linker := &Linker{
+		SourceDir:    sourceDir,
+		ExtraSources: []string{overlayDir},
+		TargetDir:    targetDir,
+	}
+
+	packages, err := linker.FindPackages()
+	require.NoError(t, err)
+	require.Len(t, packages, 2)
+	assert.Equal(t, []string{filepath.Join(sourceDir, "git"), filepath.Join(overlayDir, "git")}, packages[0].Layers)
+
+	_, err = linker.Link([]string{"git", "personal_only"})
+
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(targetDir, ".gitconfig"))
+	require.NoError(t, err)
+	assert.Equal(t, "personal", string(content), "Later sources override earlier ones")
+	content, err = os.ReadFile(filepath.Join(targetDir, ".gitignore_global"))
+	require.NoError(t, err)
+	assert.Equal(t, "team", string(content), "Files only in the base source are still linked")
+	_, err = os.Lstat(filepath.Join(targetDir, ".notes"))
+	assert.NoError(t, err)
+
+	// Once the overlay drops the file, the link is retargeted to the base file
+	require.NoError(t, os.Remove(filepath.Join(overlayDir, "git", ".gitconfig")))
+	_, err = linker.Link([]string{"git"})
+	require.NoError(t, err)
+	content, err = os.ReadFile(filepath.Join(targetDir, ".gitconfig"))
+	require.NoError(t, err)
+	assert.Equal(t, "team", string(content))
+
+	// Links into any source of the package are removed on unlink
+	createDummyPackage(t, filepath.Join(overlayDir, "git"), map[string]string{".gitconfig": "personal"})
+	_, err = linker.Unlink([]string{"git"})
+	require.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(targetDir, ".gitconfig"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLinkConcurrent(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	// Both packages populate the same new directory
+	first := make(map[string]string)
+	second := make(map[string]string)
+	for i := range 50 {
+		first[fmt.Sprintf(".config/plugins/first/%d.vim", i)] = "first"
+		second[fmt.Sprintf(".config/plugins/second/%d.vim", i)] = "second"
+	}
+	createDummyPackage(t, filepath.Join(sourceDir, "first"), first)
+	createDummyPackage(t, filepath.Join(sourceDir, "second"), second)
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Concurrency: 8}
+	result, err := linker.Link([]string{"first", "second"})
+	require.NoError(t, err)
+	assert.Len(t, result.Linked, 100)
+
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"first", "second"}, state.DirOwners(filepath.Join(targetDir, ".config", "plugins")))
+
+	// The shared directory outlives the first package
+	_, err = linker.Unlink([]string{"first"})
+	require.NoError(t, err)
+	assert.DirExists(t, filepath.Join(targetDir, ".config", "plugins", "second"))
+	assert.NoDirExists(t, filepath.Join(targetDir, ".config", "plugins", "first"))
+
+	result, err = linker.Unlink([]string{"second"})
+	require.NoError(t, err)
+	assert.Len(t, result.Unlinked, 50)
+	assert.NoDirExists(t, filepath.Join(targetDir, ".config"))
+}
+
+func TestWalkPackageStopsEarly(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "big")
+	files := make(map[string]string)
+	for i := range 20 {
+		files[fmt.Sprintf("file%02d.txt", i)] = "x"
+	}
+	createDummyPackage(t, pkgPath, files)
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	stop := errors.New("stop")
+	visited := 0
+	_, err := linker.walkPackage(Package{Name: "big", Path: pkgPath}, func(path pathInfo) error {
+		visited++
+		return stop
+	})
+	assert.Same(t, stop, err, "Errors from visit are returned unwrapped")
+	assert.Equal(t, 1, visited, "The walk stops at the first error")
+}
+
+// keepFS ignores requests to remove files, leaving them in place.
+type keepFS struct{ OSFS }
+
+func (keepFS) Remove(name string) error { return nil }
+
+func TestUnlinkReportsAllLingering(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "stuck"), map[string]string{
+		"a.txt":     "a",
+		"b.txt":     "b",
+		"dir/c.txt": "c",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"stuck"})
+	require.NoError(t, err)
+
+	linker.FS = keepFS{}
+	result, err := linker.Unlink([]string{"stuck"})
+	require.Error(t, err)
+
+	var verifyErr *VerifyError
+	require.True(t, errors.As(err, &verifyErr))
+	expected := []string{
+		filepath.Join(targetDir, "a.txt"),
+		filepath.Join(targetDir, "b.txt"),
+		filepath.Join(targetDir, "dir", "c.txt"),
+	}
+	var lingering []string
+	for _, l := range verifyErr.Lingering {
+		assert.Equal(t, ModeLink, l.Mode)
+		lingering = append(lingering, l.Path)
+	}
+	assert.ElementsMatch(t, expected, lingering)
+	assert.ElementsMatch(t, expected, result.Failed)
+	assert.Empty(t, result.Unlinked)
+	assert.Contains(t, err.Error(), "3 files still exist")
+}
+
+func TestUnlinkNoVerify(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "stuck"), map[string]string{"a.txt": "a"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"stuck"})
+	require.NoError(t, err)
+
+	linker.FS = keepFS{}
+	linker.NoVerify = true
+	result, err := linker.Unlink([]string{"stuck"})
+	require.NoError(t, err, "Lingering files go unnoticed without verification")
+	assert.Equal(t, []string{filepath.Join(targetDir, "a.txt")}, result.Unlinked)
+}
+
+func TestNeverTouchesOutsideTarget(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+	outside := filepath.Join(filepath.Dir(targetDir), "outside")
+	require.NoError(t, os.Mkdir(outside, 0755))
+
+	// A rename escaping the target is refused before anything is planned
+	createDummyPackage(t, filepath.Join(sourceDir, "evil"), map[string]string{"payload": "x"})
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mapper:    Mapper{Renames: map[string]string{"payload": "../outside/payload"}},
+	}
+	_, err := linker.Link([]string{"evil"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside the target directory")
+	assert.NoFileExists(t, filepath.Join(outside, "payload"))
+
+	// A target sharing the target directory's name as a prefix is outside too
+	sibling := targetDir + "2"
+	executor := &Executor{TargetDir: targetDir}
+	for _, op := range []Operation{
+		{Kind: OpLink, Source: filepath.Join(sourceDir, "evil", "payload"), Target: filepath.Join(sibling, "payload")},
+		{Kind: OpMkdir, Target: filepath.Join(outside, "dir")},
+		{Kind: OpRmdir, Target: outside},
+		{Kind: OpUnlink, Target: targetDir},
+	} {
+		assert.Error(t, executor.Execute(op), "%s %s", op.Kind, op.Target)
+	}
+	assert.DirExists(t, outside)
+	assert.NoDirExists(t, sibling)
+
+	// A symlinked directory inside a package is linked as it is, never walked into
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "linked_dir"), map[string]string{"real.txt": "x"})
+	require.NoError(t, os.Symlink(outside, filepath.Join(sourceDir, "linked_dir", "elsewhere")))
+	linker.Mapper = Mapper{}
+	_, err = linker.Link([]string{"linked_dir"})
+	require.NoError(t, err)
+	fi, err := os.Lstat(filepath.Join(targetDir, "elsewhere"))
+	require.NoError(t, err)
+	assert.True(t, fi.Mode()&os.ModeSymlink != 0)
+	dest, err := os.Readlink(filepath.Join(targetDir, "elsewhere"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sourceDir, "linked_dir", "elsewhere"), dest)
+}
+
+func TestSymlinkedSourceDir(t *testing.T) {
+	realSource, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(realSource, "vim"), map[string]string{".vimrc": "set nu"})
+	sourceLink := filepath.Join(filepath.Dir(realSource), "dotfiles")
+	require.NoError(t, os.Symlink(realSource, sourceLink))
+	// The temporary directory itself may be reached through a symlink (e.g. /tmp on macOS)
+	realSource, err := filepath.EvalSymlinks(realSource)
+	require.NoError(t, err)
+
+	// Links are made through the symlinked source directory...
+	linker := &Linker{SourceDir: sourceLink, TargetDir: targetDir}
+	_, err = linker.Link([]string{"vim"})
+	require.NoError(t, err)
+	dest, err := os.Readlink(filepath.Join(targetDir, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sourceLink, "vim", ".vimrc"), dest)
+
+	// ...and are still recognised once sources are resolved, and the other way round
+	resolving := &Linker{SourceDir: sourceLink, TargetDir: targetDir, ResolveSources: true}
+	result, err := resolving.Link([]string{"vim"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".vimrc")}, result.Skipped, "Existing link is correct")
+	owner, err := resolving.Owner(filepath.Join(targetDir, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "vim", owner.Package)
+
+	_, err = resolving.Unlink([]string{"vim"})
+	require.NoError(t, err)
+	_, err = resolving.Link([]string{"vim"})
+	require.NoError(t, err)
+	dest, err = os.Readlink(filepath.Join(targetDir, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(realSource, "vim", ".vimrc"), dest, "Resolved sources link to the real location")
+
+	result, err = linker.Link([]string{"vim"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".vimrc")}, result.Skipped)
+}
+
+func TestLinkSubpath(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		".config/nvim/init.lua":                  "init",
+		".config/nvim/lua/plugins/telescope.lua": "telescope",
+		".config/nvim/lua/plugins/lsp/init.lua":  "lsp",
+		".config/nvim/lua/options.lua":           "options",
+	})
+	target := func(rel string) string { return filepath.Join(targetDir, filepath.FromSlash(rel)) }
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	result, err := linker.Link([]string{"nvim/.config/nvim/lua/plugins"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		target(".config/nvim/lua/plugins/telescope.lua"),
+		target(".config/nvim/lua/plugins/lsp/init.lua"),
+	}, result.Linked)
+	assert.NoFileExists(t, target(".config/nvim/init.lua"))
+	assert.NoFileExists(t, target(".config/nvim/lua/options.lua"))
+
+	// Linking the rest of the package keeps the subtree linked
+	_, err = linker.Link([]string{"nvim"})
+	require.NoError(t, err)
+
+	// Unlinking the subtree leaves the rest, and the directories leading to it, in place
+	result, err = linker.Unlink([]string{"nvim/.config/nvim/lua/plugins"})
+	require.NoError(t, err)
+	assert.Len(t, result.Unlinked, 2)
+	assert.NoDirExists(t, target(".config/nvim/lua/plugins"))
+	assert.FileExists(t, target(".config/nvim/lua/options.lua"))
+	assert.FileExists(t, target(".config/nvim/init.lua"))
+
+	_, err = linker.Link([]string{"nvim/missing"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no missing")
+	_, err = linker.Link([]string{"nvim/../nvim"})
+	require.Error(t, err)
+}
+
+func TestLinkFile(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{
+		".vimrc":            "set nocompatible",
+		".vim/colors/x.vim": "colors",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+
+	result, err := linker.LinkFile("vim", ".vimrc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".vimrc")}, result.Linked)
+	assert.NoDirExists(t, filepath.Join(targetDir, ".vim"))
+
+	// Directories are refused, files below them are fine
+	_, err = linker.LinkFile("vim", ".vim/colors")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a directory")
+	_, err = linker.LinkFile("vim", ".vim/colors/x.vim")
+	require.NoError(t, err)
+
+	result, err = linker.UnlinkFile("vim", ".vim/colors/x.vim")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".vim/colors/x.vim")}, result.Unlinked)
+	assert.NoFileExists(t, filepath.Join(targetDir, ".vim/colors/x.vim"))
+	assert.FileExists(t, filepath.Join(targetDir, ".vimrc"))
+
+	_, err = linker.LinkFile("vim", "missing")
+	require.Error(t, err)
+}
+
+func TestLinkFileUnsupportedOS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the package only supports windows")
+	}
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "wsl"), map[string]string{
+		".wslconfig":     "[wsl2]",
+		ManifestFileName: `os = ["windows"]`,
+	})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.LinkFile("wsl", ".wslconfig")
+	assert.ErrorContains(t, err, "package wsl does not support "+runtime.GOOS)
+	_, err = linker.UnlinkFile("wsl", ".wslconfig")
+	assert.ErrorContains(t, err, "does not support")
+	assert.NoFileExists(t, filepath.Join(targetDir, ".wslconfig"))
+}
+
+func TestLinkWithExtraIgnore(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "docs_pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		".bashrc":   "bash",
+		"README.md": "readme",
+		"notes.txt": "notes",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, IgnoreFileName), []byte("notes.txt\n"), 0644))
+
+	linker := New(sourceDir, targetDir, WithIgnore("*.md"))
+	result, err := linker.Link([]string{"docs_pkg"})
+	require.NoError(t, err)
+
+	// Patterns given to the Linker merge with the package's ignore file
+	assert.FileExists(t, filepath.Join(targetDir, ".bashrc"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "README.md"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "notes.txt"))
+	assert.Len(t, result.Ignored, 2)
+}
+
+func TestLinkWithInclude(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "bundle")
+	createDummyPackage(t, pkgPath, map[string]string{
+		".config/app/app.conf": "conf",
+		".bashrc":              "bash",
+		"docs/usage.md":        "docs",
+		"tests/run.sh":         "test",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, IncludeFileName), []byte(".config\n.bashrc\n"), 0644))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	result, err := linker.Link([]string{"bundle"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, ".config/app/app.conf"))
+	assert.FileExists(t, filepath.Join(targetDir, ".bashrc"))
+	assert.NoDirExists(t, filepath.Join(targetDir, "docs"), "No directory is created for skipped files")
+	assert.NoDirExists(t, filepath.Join(targetDir, "tests"))
+	assert.NoFileExists(t, filepath.Join(targetDir, IncludeFileName))
+	assert.Len(t, result.Ignored, 2)
+
+	// Only narrows the include file further, also when unlinking
+	linker.Only = []string{"*.conf"}
+	result, err = linker.Unlink([]string{"bundle"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".config/app/app.conf")}, result.Unlinked)
+	assert.FileExists(t, filepath.Join(targetDir, ".bashrc"))
+}
+
+func TestIgnoreRulesIncludes(t *testing.T) {
+	rules := NewIgnoreRules(nil)
+	assert.True(t, rules.Includes("anything"))
+
+	rules = rules.WithInclude([]string{".config", "*.conf"})
+	assert.True(t, rules.Includes(".config/nvim/init.lua"))
+	assert.True(t, rules.Includes("etc/app.conf"))
+	assert.False(t, rules.Includes("README.md"))
+
+	rules = rules.WithInclude([]string{"*.lua"})
+	assert.True(t, rules.Includes(".config/nvim/init.lua"))
+	assert.False(t, rules.Includes("etc/app.conf"))
+}
+
+func TestIgnoreRulesCompiled(t *testing.T) {
+	rules := NewIgnoreRules([]string{"README.md", "*.bak", ".config/nvim/*.log", "[invalid", `dir\file`})
+	assert.Equal(t, []string{"README.md", "*.bak", ".config/nvim/*.log", "[invalid", `dir\file`}, rules.Patterns())
+
+	assert.True(t, rules.Match("README.md"))
+	assert.True(t, rules.Match(filepath.Join("docs", "README.md")), "Patterns without a separator match the base name")
+	assert.False(t, rules.Match("README.md.orig"))
+	assert.True(t, rules.Match(filepath.Join("a", "b", "x.bak")))
+	assert.True(t, rules.Match(filepath.Join(".config", "nvim", "lsp.log")))
+	assert.False(t, rules.Match(filepath.Join("other", "nvim", "lsp.log")))
+	assert.False(t, rules.Match("[invalid"), "Invalid patterns match nothing")
+}
+
+func TestIgnoreRulesNegation(t *testing.T) {
+	rules := NewIgnoreRules([]string{"*.log", "!important.log", "cache", "!cache/keep", `\!bang`})
+	assert.True(t, rules.Match("debug.log"))
+	assert.False(t, rules.Match("important.log"), "A negated pattern re-includes what an earlier one excludes")
+	assert.False(t, rules.Match(filepath.Join("logs", "important.log")))
+	assert.True(t, rules.Match("cache"))
+	assert.False(t, rules.Match(filepath.Join("cache", "keep")))
+	assert.True(t, rules.Match("!bang"), "An escaped ! matches literally")
+	assert.False(t, rules.Match("bang"))
+
+	// The last matching pattern decides
+	rules = NewIgnoreRules([]string{"!important.log", "*.log"})
+	assert.True(t, rules.Match("important.log"))
+}
+
+func TestLinkWithIgnoreNegation(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "logs"), map[string]string{
+		"debug.log":           "debug",
+		"important.log":       "important",
+		"cache/important.log": "cached",
+		IgnoreFileName:        "*.log\n!important.log\ncache\n",
+	})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"logs"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, "important.log"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "debug.log"))
+	assert.NoDirExists(t, filepath.Join(targetDir, "cache"), "Paths below an ignored directory stay ignored")
+}
+
+func TestLinkWithIgnoreMatcher(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "app"), map[string]string{
+		"app.conf":        "conf",
+		"app.conf.bak":    "backup",
+		"vendor/lib.conf": "lib",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "other"), map[string]string{"other.bak": "backup"})
+
+	var mu sync.Mutex
+	var dirs []string
+	matcher := IgnoreMatcherFunc(func(pkg Package, relPath string, isDir bool) bool {
+		if isDir {
+			mu.Lock()
+			dirs = append(dirs, relPath)
+			mu.Unlock()
+			return relPath == "vendor"
+		}
+		return pkg.Name == "app" && strings.HasSuffix(relPath, ".bak")
+	})
+	linker := New(sourceDir, targetDir, WithIgnoreMatcher(matcher), WithLogger(log.New(io.Discard, "", 0)))
+	result, err := linker.Link([]string{"app", "other"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, "app.conf"))
+	assert.FileExists(t, filepath.Join(targetDir, "other.bak"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "app.conf.bak"))
+	assert.NoDirExists(t, filepath.Join(targetDir, "vendor"))
+	assert.ElementsMatch(t, []string{filepath.Join(sourceDir, "app", "app.conf.bak"), filepath.Join(sourceDir, "app", "vendor")}, result.Ignored)
+	assert.Contains(t, dirs, "vendor")
+
+	e, err := linker.Explain("app", "vendor/lib.conf")
+	require.NoError(t, err)
+	assert.Contains(t, e.Steps[0], "vendor is matched by the ignore matcher")
+}
+
+func TestLinkWithInvalidIgnorePatterns(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"keep.txt":       "keep",
+		"debug.log":      "log",
+		IgnoreFileName:   "# logs\n[invalid\n*.log\n",
+		ManifestFileName: `copy = ["bad["]`,
+	})
+
+	var logs strings.Builder
+	linker := New(sourceDir, targetDir, WithLogger(log.New(&logs, "", 0)))
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), filepath.Join(pkgPath, IgnoreFileName)+`:2: invalid pattern "[invalid"`)
+	assert.Contains(t, logs.String(), filepath.Join(pkgPath, ManifestFileName)+`: invalid pattern "bad["`)
+	assert.Equal(t, 1, strings.Count(logs.String(), `"[invalid"`))
+	assert.FileExists(t, filepath.Join(targetDir, "keep.txt"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "debug.log"), "valid patterns still apply")
+
+	linker.StrictIgnore = true
+	_, err = linker.Unlink([]string{"pkg"})
+	var patternErr *PatternError
+	require.ErrorAs(t, err, &patternErr)
+	assert.Equal(t, filepath.Join(pkgPath, IgnoreFileName), patternErr.File)
+	assert.Equal(t, 2, patternErr.Line)
+	assert.Equal(t, "[invalid", patternErr.Pattern)
+	assert.FileExists(t, filepath.Join(targetDir, "keep.txt"), "nothing is unlinked")
+}
+
+func TestLinkDeferOverride(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "base"), map[string]string{
+		".bashrc":            "base",
+		".config/git/config": "base",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "personal"), map[string]string{
+		".bashrc":            "personal",
+		".config/git/config": "personal",
+		"notes.bak":          "backup",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"base"})
+	require.NoError(t, err)
+
+	// Without rules, links of other packages are conflicts
+	_, err = linker.PlanLink([]string{"personal"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already linked from package base")
+
+	linker.Defer = []*regexp.Regexp{regexp.MustCompile(`\.bashrc$`)}
+	linker.Override = []*regexp.Regexp{regexp.MustCompile(`\.config/`), regexp.MustCompile(`bashrc`)}
+	linker.IgnoreRegexp = []*regexp.Regexp{regexp.MustCompile(`\.bak$`)}
+	result, err := linker.Link([]string{"personal"})
+	require.NoError(t, err)
+
+	owner := func(rel string) string {
+		o, err := linker.Owner(filepath.Join(targetDir, rel))
+		require.NoError(t, err)
+		return o.Package
+	}
+	assert.Equal(t, "base", owner(".bashrc"), "Defer is checked before Override")
+	assert.Equal(t, "personal", owner(".config/git/config"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "notes.bak"))
+	assert.Len(t, result.Ignored, 1)
+
+	// Override is anchored at the start of the target path
+	linker.Defer = nil
+	linker.Override = []*regexp.Regexp{regexp.MustCompile(`bashrc`)}
+	_, err = linker.PlanLink([]string{"personal"})
+	require.Error(t, err)
+}
+
+func TestFindPackagesSkipsNonPackages(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	for _, name := range []string{"zsh", "git", "gitui", "scripts", ".github", ".git"} {
+		createDummyPackage(t, filepath.Join(sourceDir, name), map[string]string{"file": name})
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "scripts", NoPackageFileName), nil, 0644))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	packages, err := linker.FindPackages()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"zsh", "git", "gitui", ".github"}, packageNames(packages))
+
+	linker.PackageDirs = []string{"zsh", "git*", "scripts"}
+	packages, err = linker.FindPackages()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"zsh", "git", "gitui"}, packageNames(packages), "The marker wins over PackageDirs")
+
+	_, err = linker.Link([]string{"scripts"})
+	require.Error(t, err)
+}
+
+func TestOwnershipChecks(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() != 0 {
+		t.Skip("changing file ownership needs root on a Unix system")
+	}
+	const nobody = 65534
+
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{".bashrc": "bash"})
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	// A link handed over to another user is left alone
+	linkPath := filepath.Join(targetDir, ".bashrc")
+	require.NoError(t, os.Lchown(linkPath, nobody, nobody))
+	_, err = linker.Unlink([]string{"pkg"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to modify "+linkPath)
+	_, err = os.Lstat(linkPath)
+	require.NoError(t, err)
+
+	// Root does not work in another user's target directory by accident
+	require.NoError(t, os.Chown(targetDir, nobody, nobody))
+	_, err = linker.Unlink([]string{"pkg"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--allow-root")
+
+	linker.AllowRoot = true
+	_, err = linker.Unlink([]string{"pkg"})
+	require.NoError(t, err)
+	_, err = os.Lstat(linkPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLinkerConcurrentCalls(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	const packages = 32
+	var names []string
+	for i := range packages {
+		name := fmt.Sprintf("pkg%d", i)
+		createDummyPackage(t, filepath.Join(sourceDir, name), map[string]string{
+			fmt.Sprintf("file%d.txt", i):        "content",
+			fmt.Sprintf("shared/file%d.txt", i): "content",
+		})
+		names = append(names, name)
+	}
+
+	otherTarget := filepath.Join(filepath.Dir(targetDir), "other")
+	require.NoError(t, os.Mkdir(otherTarget, 0755))
+
+	// Copies are recorded in the state manifest, which every call updates
+	linker := New(sourceDir, targetDir, WithMode(ModeCopy), WithLogger(log.New(io.Discard, "", 0)))
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*packages)
+	for _, l := range []*Linker{linker, linker.ForTarget(otherTarget)} {
+		for _, name := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := l.Link([]string{name})
+				errs <- err
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	for _, target := range []string{targetDir, otherTarget} {
+		state, err := LoadState(filepath.Join(target, StateFileName))
+		require.NoError(t, err)
+		assert.Len(t, state.Entries, 2*packages, "state of %s lost entries", target)
+	}
+}
+
+func TestLinkMaxDepthSkipHidden(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		".vimrc":                                "top",
+		".config/nvim/init.lua":                 "init",
+		".config/nvim/pack/plugin/.git/HEAD":    "ref",
+		".config/nvim/pack/plugin/lua/mod.lua":  "mod",
+		".config/nvim/pack/plugin/.luarc.json":  "{}",
+		".config/nvim/pack/plugin/doc/help.txt": "help",
+	})
+
+	linkedFiles := func(linker *Linker) []string {
+		t.Helper()
+		plan, err := linker.PlanLink([]string{"nvim"})
+		require.NoError(t, err)
+		var files []string
+		for _, op := range plan.Operations {
+			if op.Kind == OpLink {
+				rel, err := filepath.Rel(targetDir, op.Target)
+				require.NoError(t, err)
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		return files
+	}
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, SkipHidden: true}
+	assert.ElementsMatch(t, []string{".vimrc", ".config/nvim/init.lua", ".config/nvim/pack/plugin/lua/mod.lua", ".config/nvim/pack/plugin/doc/help.txt"}, linkedFiles(linker))
+
+	linker = &Linker{SourceDir: sourceDir, TargetDir: targetDir, MaxDepth: 3}
+	assert.ElementsMatch(t, []string{".vimrc", ".config/nvim/init.lua"}, linkedFiles(linker))
+	plan, err := linker.PlanLink([]string{"nvim"})
+	require.NoError(t, err)
+	assert.NotContains(t, opsByTarget(t, plan, targetDir), ".config/nvim/pack", "Directories at the maximum depth are not created")
+
+	explanation, err := linker.Explain("nvim", filepath.Join(".config", "nvim", "pack", "plugin", "lua", "mod.lua"))
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(explanation.Steps, "\n"), filepath.Join(".config", "nvim", "pack")+" is beyond the maximum depth of 3")
+}
@@ -0,0 +1,98 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockFileName is the name of the lock file gslk keeps in the target
+// directory while it changes it.
+const LockFileName = ".gslk.lock"
+
+// lockPollInterval is how often a waiting run retries taking the lock.
+const lockPollInterval = 100 * time.Millisecond
+
+// ErrLocked is wrapped by the error of LockTarget when another run holds the
+// lock of the target directory.
+var ErrLocked = errors.New("target is locked")
+
+// TargetLock is an advisory lock on a target directory, held by one gslk run
+// at a time.
+type TargetLock struct {
+	f *os.File
+}
+
+// LockTarget takes the lock of the target directory, so that two runs (say,
+// a scheduled relink and a manual one) never change it at the same time. If
+// another run holds the lock, LockTarget waits for up to timeout for it to be
+// released; a timeout of 0 fails at once and a negative one waits forever.
+//
+// The lock is advisory: it only keeps out other runs taking it. It is
+// released by Unlock, or when the process exits. Creating the lock file is a
+// change too, so it is refused in read-only mode, where none is needed. A
+// missing target directory is created first, as the run would create it
+// anyway; in a dry run, which creates nothing, there is nothing to lock then.
+func (l *Linker) LockTarget(timeout time.Duration) (*TargetLock, error) {
+	path := filepath.Join(l.TargetDir, LockFileName)
+	if err := checkWritable(l.fs(), "lock", path); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(l.TargetDir); errors.Is(err, fs.ErrNotExist) {
+		if l.DryRun {
+			return &TargetLock{}, nil
+		}
+		mode := l.DirMode
+		if mode == 0 {
+			mode = DefaultDirMode
+		}
+		if err := orOS(l.fs()).MkdirAll(l.TargetDir, mode); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", l.TargetDir, err)
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		f, locked, err := lockFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", l.TargetDir, err)
+		}
+		if locked {
+			// Record who holds the lock for the message of runs kept out
+			f.Truncate(0)
+			f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			return &TargetLock{f: f}, nil
+		}
+		if timeout >= 0 && !time.Now().Before(deadline) {
+			return nil, lockedError(l.TargetDir, path, timeout)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// lockedError describes the lock at path as held by another run.
+func lockedError(targetDir, path string, waited time.Duration) error {
+	holder := "another gslk run"
+	if data, err := os.ReadFile(path); err == nil {
+		if pid := strings.TrimSpace(string(data)); pid != "" {
+			holder += " (process " + pid + ")"
+		}
+	}
+	if waited > 0 {
+		return fmt.Errorf("%w: %s is still being changed by %s after waiting %s", ErrLocked, targetDir, holder, waited)
+	}
+	return fmt.Errorf("%w: %s is being changed by %s; wait for it to finish, or retry with --wait", ErrLocked, targetDir, holder)
+}
+
+// Unlock releases the lock. The lock file stays, so that runs waiting for it
+// keep locking the same file.
+func (lk *TargetLock) Unlock() error {
+	if lk.f == nil {
+		return nil
+	}
+	return lk.f.Close()
+}
@@ -0,0 +1,73 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockTarget(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	linker := New(sourceDir, targetDir)
+	lock, err := linker.LockTarget(0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(targetDir, LockFileName))
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(data))
+
+	t.Run("a second run fails fast", func(t *testing.T) {
+		_, err := linker.LockTarget(0)
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.ErrorContains(t, err, fmt.Sprintf("process %d", os.Getpid()))
+	})
+
+	t.Run("a second run gives up after the timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := linker.LockTarget(250 * time.Millisecond)
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+	})
+
+	t.Run("a waiting run gets the lock once released", func(t *testing.T) {
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			lock.Unlock()
+		}()
+		second, err := linker.LockTarget(5 * time.Second)
+		require.NoError(t, err)
+		require.NoError(t, second.Unlock())
+	})
+}
+
+func TestLockMissingTarget(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+	createDummyPackage(t, filepath.Join(sourceDir, "shell"), map[string]string{".bashrc": "", ".profile": ""})
+	targetDir = filepath.Join(targetDir, "new", "home")
+
+	// A dry run creates nothing, not even the target to lock
+	dry := New(sourceDir, targetDir, WithDryRun())
+	lock, err := dry.LockTarget(0)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+	assert.NoDirExists(t, targetDir)
+
+	// Otherwise the target is created and locked before linking into it
+	linker := New(sourceDir, targetDir)
+	lock, err = linker.LockTarget(0)
+	require.NoError(t, err)
+	defer lock.Unlock()
+	assert.FileExists(t, filepath.Join(targetDir, LockFileName))
+
+	result, err := linker.Link([]string{"shell"})
+	require.NoError(t, err)
+	assert.Len(t, result.Linked, 2)
+}
@@ -0,0 +1,83 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+)
+
+// Logger receives the progress messages gslk prints while working, such as
+// "Linking: ..." lines and warnings. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// stdoutLogger prints messages to standard output unchanged.
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, args ...any) {
+	fmt.Fprintf(os.Stdout, format, args...)
+}
+
+// logger returns l, or a logger printing to standard output if l is nil.
+func logger(l Logger) Logger {
+	if l == nil {
+		return stdoutLogger{}
+	}
+	return l
+}
+
+// Level is the importance of a progress message.
+type Level int
+
+const (
+	LevelWarn  Level = iota // Something unexpected that did not stop gslk
+	LevelInfo               // What is done to a file or directory, such as "Linking: ..."
+	LevelDebug              // Why something is done or skipped; only sent in verbose mode
+)
+
+// LevelLogger is a Logger that is also told the level of each message, so
+// that it can filter or highlight them. Messages are sent through Logf when
+// the Logger of a Linker or Executor implements it.
+type LevelLogger interface {
+	Logger
+	Logf(level Level, format string, args ...any)
+}
+
+// logf sends a message of the given level to l, or prints it to standard
+// output if l is nil.
+func logf(l Logger, level Level, format string, args ...any) {
+	if ll, ok := l.(LevelLogger); ok {
+		ll.Logf(level, format, args...)
+		return
+	}
+	logger(l).Printf(format, args...)
+}
+
+// bufferedLogger keeps the messages sent to it until flush sends them on, so
+// that work done in parallel is reported in a fixed order. It is not safe for
+// concurrent use.
+type bufferedLogger struct {
+	messages []bufferedMessage
+}
+
+type bufferedMessage struct {
+	level  Level
+	format string
+	args   []any
+}
+
+func (b *bufferedLogger) Printf(format string, args ...any) {
+	b.Logf(LevelInfo, format, args...)
+}
+
+func (b *bufferedLogger) Logf(level Level, format string, args ...any) {
+	b.messages = append(b.messages, bufferedMessage{level, format, args})
+}
+
+// flush sends the messages kept so far to l (see logf) and forgets them.
+func (b *bufferedLogger) flush(l Logger) {
+	for _, m := range b.messages {
+		logf(l, m.level, m.format, m.args...)
+	}
+	b.messages = nil
+}
@@ -0,0 +1,224 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// ManifestFileName is the optional per-package file describing the package.
+const ManifestFileName = ".gslk-package.toml"
+
+// NoPackageFileName marks a directory of a source directory, such as scripts
+// or docs, as not being a package.
+const NoPackageFileName = ".gslk-nopackage"
+
+// Events package hooks run on.
+const (
+	HookPreLink    = "pre-link"    // Before the package's files are linked
+	HookPostLink   = "post-link"   // After the package's files were linked
+	HookPreUnlink  = "pre-unlink"  // Before the package's files are unlinked
+	HookPostUnlink = "post-unlink" // After the package's files were unlinked
+)
+
+// Hook is a shell command a package runs when it is linked or unlinked.
+type Hook struct {
+	When string // HookPreLink, HookPostLink, HookPreUnlink or HookPostUnlink
+	Run  string // Command run by the shell in the package directory
+}
+
+// Manifest is the metadata a package declares in its manifest file:
+//
+//	description = "Neovim configuration"
+//	target = ".config/nvim"   # Link the package below this directory of the target
+//	os = ["linux", "darwin"]  # Only link the package on these systems
+//	depends = ["fonts"]
+//	ignore = ["README.md"]
+//	include = ["init.lua", "lua"]
+//	copy = ["lazy-lock.json"] # Copied rather than linked, e.g. files rewritten in place
+//
+//	[rename]
+//	"init.vim" = "init.lua"
+//
+//	[vars]                    # Template variables the package needs, with the question asking for them
+//	email = "Email address for commits"
+//
+//	[[hooks]]
+//	when = "post-link"
+//	run = "nvim --headless '+Lazy! sync' +qa"
+//
+// A package spread over several sources uses the manifest of the source with
+// the highest precedence that has one. The zero value describes a package
+// without a manifest.
+type Manifest struct {
+	Path        string            // Manifest file, empty if the package has none
+	Description string            // What the package contains
+	Target      string            // Directory, relative to the target directory, the package is linked into; absolute only if written so (see LoadManifest)
+	OS          []string          // Values of runtime.GOOS the package supports; empty means all
+	Depends     []string          // Packages this package needs
+	Ignore      []string          // Patterns ignored in addition to those of the ignore file
+	Include     []string          // Patterns included in addition to those of the include file
+	Copy        []string          // Patterns of files copied rather than linked, in .gslk-ignore syntax
+	Templates   []string          // Patterns of files rendered as templates whatever their name, in .gslk-ignore syntax
+	Bind        []string          // Patterns of directories bind-mounted whole rather than linked file by file (Linux; junctions on Windows)
+	Rename      map[string]string // Package-relative path to target-relative path renames (see Mapper.Renames)
+	Vars        map[string]string // Template variables the package needs, with the question asking for each
+	Hooks       []Hook            // Commands run when the package is linked or unlinked
+}
+
+// SupportsOS reports whether the package may be linked on the running system.
+func (m Manifest) SupportsOS() bool {
+	return len(m.OS) == 0 || slices.Contains(m.OS, runtime.GOOS)
+}
+
+// LoadManifest reads the manifest of the package at packagePath. A package
+// without a manifest yields the zero Manifest.
+//
+// Environment variables and "~" in target are expanded (see ExpandEnv), so
+// target may be an absolute path such as ${XDG_CONFIG_HOME}/nvim, which the
+// Linker requires to lie inside its target directory.
+func LoadManifest(packagePath string) (Manifest, error) {
+	return loadManifest(packagePath, false)
+}
+
+// loadManifest reads the manifest of the package at packagePath; with
+// strictEnv, undefined environment variables in paths are errors.
+func loadManifest(packagePath string, strictEnv bool) (Manifest, error) {
+	path := filepath.Join(packagePath, ManifestFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Manifest{}, nil
+		}
+		return Manifest{}, fmt.Errorf("failed to read package manifest %s: %w", path, err)
+	}
+
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse package manifest %s: %w", path, err)
+	}
+
+	manifest, err := decodeManifest(doc, strictEnv)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("package manifest %s: %w", path, err)
+	}
+	manifest.Path = path
+	return manifest, nil
+}
+
+// decodeManifest builds a Manifest from a parsed manifest file.
+func decodeManifest(doc map[string]any, strictEnv bool) (Manifest, error) {
+	var m Manifest
+	var err error
+
+	if m.Description, err = tomlString(doc, "description"); err != nil {
+		return m, err
+	}
+	if m.Target, err = tomlString(doc, "target"); err != nil {
+		return m, err
+	}
+	if m.Target != "" {
+		if m.Target, err = ExpandEnv(m.Target, strictEnv); err != nil {
+			return m, fmt.Errorf("target: %w", err)
+		}
+		m.Target = filepath.Clean(filepath.FromSlash(m.Target))
+		if !filepath.IsAbs(m.Target) && !filepath.IsLocal(m.Target) {
+			return m, fmt.Errorf("target %q must be a relative path inside the target directory", m.Target)
+		}
+	}
+	if m.OS, err = tomlStringList(doc, "os"); err != nil {
+		return m, err
+	}
+	if m.Depends, err = tomlStringList(doc, "depends"); err != nil {
+		return m, err
+	}
+	if m.Ignore, err = tomlStringList(doc, "ignore"); err != nil {
+		return m, err
+	}
+	if m.Include, err = tomlStringList(doc, "include"); err != nil {
+		return m, err
+	}
+	if m.Copy, err = tomlStringList(doc, "copy"); err != nil {
+		return m, err
+	}
+	if m.Templates, err = tomlStringList(doc, "templates"); err != nil {
+		return m, err
+	}
+	if m.Bind, err = tomlStringList(doc, "bind"); err != nil {
+		return m, err
+	}
+	if m.Rename, err = tomlVars(doc, "rename"); err != nil {
+		return m, err
+	}
+	if m.Vars, err = tomlVars(doc, "vars"); err != nil {
+		return m, err
+	}
+
+	hooks, ok := doc["hooks"].([]any)
+	if !ok && doc["hooks"] != nil {
+		return m, fmt.Errorf("hooks must be an array of tables ([[hooks]])")
+	}
+	for i, value := range hooks {
+		table, ok := value.(map[string]any)
+		if !ok {
+			return m, fmt.Errorf("hook %d must be a table", i+1)
+		}
+		var hook Hook
+		if hook.When, err = tomlString(table, "when"); err != nil {
+			return m, fmt.Errorf("hook %d: %w", i+1, err)
+		}
+		if hook.Run, err = tomlString(table, "run"); err != nil {
+			return m, fmt.Errorf("hook %d: %w", i+1, err)
+		}
+		switch {
+		case hook.When != HookPreLink && hook.When != HookPostLink && hook.When != HookPreUnlink && hook.When != HookPostUnlink:
+			return m, fmt.Errorf("hook %d: when must be one of %s, %s, %s or %s", i+1, HookPreLink, HookPostLink, HookPreUnlink, HookPostUnlink)
+		case hook.Run == "":
+			return m, fmt.Errorf("hook %d: run must not be empty", i+1)
+		}
+		m.Hooks = append(m.Hooks, hook)
+	}
+	return m, nil
+}
+
+// runHooks runs the hooks of packages registered for when, in package order,
+// stopping at the first that fails. Hooks can change anything, so none runs in
+// read-only mode. Hooks belong to whole packages and are not
+// run when only a subpath is linked or unlinked.
+func (l *Linker) runHooks(packages []Package, when string) error {
+	for _, pkg := range packages {
+		if pkg.Subpath != "" {
+			continue
+		}
+		for _, hook := range pkg.Manifest.Hooks {
+			if hook.When != when {
+				continue
+			}
+			if l.DryRun {
+				l.printf("DRY RUN: Would run %s hook of package %s: %s\n", when, pkg.Name, hook.Run)
+				continue
+			}
+			if l.ReadOnly {
+				l.printf("Read-only: not running %s hook of package %s: %s\n", when, pkg.Name, hook.Run)
+				continue
+			}
+			if l.sandboxFor != "" {
+				l.printf("Sandbox: not running %s hook of package %s: %s\n", when, pkg.Name, hook.Run)
+				continue
+			}
+
+			l.printf("Running %s hook of package %s: %s\n", when, pkg.Name, hook.Run)
+			cmd := shellCommand(hook.Run)
+			cmd.Dir = filepath.Dir(pkg.Manifest.Path)
+			cmd.Env = append(os.Environ(), "GSLK_PACKAGE="+pkg.Name, "GSLK_TARGET="+l.TargetDir)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("%s hook of package %s failed: %w", when, pkg.Name, err)
+			}
+		}
+	}
+	return nil
+}
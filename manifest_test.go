@@ -0,0 +1,260 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFileName), []byte(`
+description = "Neovim configuration"
+target = ".config/nvim"
+os = ["linux", "darwin"]
+depends = ["fonts"]
+ignore = ["README.md"]
+templates = ["init.lua"]
+
+[rename]
+"init.vim" = "init.lua"
+
+[[hooks]]
+when = "post-link"
+run = "echo linked"
+`), 0644))
+
+	manifest, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ManifestFileName), manifest.Path)
+	assert.Equal(t, "Neovim configuration", manifest.Description)
+	assert.Equal(t, filepath.Join(".config", "nvim"), manifest.Target)
+	assert.Equal(t, []string{"linux", "darwin"}, manifest.OS)
+	assert.Equal(t, []string{"fonts"}, manifest.Depends)
+	assert.Equal(t, []string{"README.md"}, manifest.Ignore)
+	assert.Equal(t, []string{"init.lua"}, manifest.Templates)
+	assert.Equal(t, map[string]string{"init.vim": "init.lua"}, manifest.Rename)
+	assert.Equal(t, []Hook{{When: HookPostLink, Run: "echo linked"}}, manifest.Hooks)
+
+	// A package without a manifest has the zero Manifest
+	manifest, err = LoadManifest(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, Manifest{}, manifest)
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"outside target": `target = "../elsewhere"`,
+		"unknown hook":   "[[hooks]]\nwhen = \"sometime\"\nrun = \"true\"",
+		"empty hook":     "[[hooks]]\nwhen = \"pre-link\"",
+		"bad os":         `os = 1`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFileName), []byte(content), 0644))
+			_, err := LoadManifest(dir)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLinkWithManifestTargetFromEnv(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	t.Setenv("GSLK_TEST_CONFIG", filepath.Join(targetDir, ".config"))
+	os.Unsetenv("GSLK_TEST_UNSET")
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		ManifestFileName: `target = "${GSLK_TEST_CONFIG}/nvim"`,
+		"init.lua":       "init",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"nvim"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"))
+
+	t.Setenv("GSLK_TEST_CONFIG", t.TempDir())
+	_, err = linker.FindPackages()
+	assert.ErrorContains(t, err, "is outside the target directory")
+
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		ManifestFileName: `target = "$GSLK_TEST_UNSET/nvim"`,
+	})
+	linker.StrictEnv = true
+	_, err = linker.FindPackages()
+	assert.ErrorContains(t, err, "environment variable GSLK_TEST_UNSET")
+}
+
+func TestLinkWithManifest(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "nvim")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"init.vim":     "init",
+		"lua/opts.lua": "opts",
+		"README.md":    "docs",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "elsewhere"), map[string]string{"file": "x"})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`
+description = "Neovim configuration"
+target = ".config/nvim"
+ignore = ["README.md"]
+
+[rename]
+"init.vim" = "init.lua"
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "elsewhere", ManifestFileName), []byte(`os = ["plan9"]`), 0644))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+
+	packages, err := linker.FindPackages()
+	require.NoError(t, err)
+	for _, pkg := range packages {
+		if pkg.Name == "nvim" {
+			assert.Equal(t, "Neovim configuration", pkg.Manifest.Description)
+		}
+	}
+
+	_, err = linker.Link([]string{"nvim", "elsewhere"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, ".config/nvim/init.lua"))
+	assert.FileExists(t, filepath.Join(targetDir, ".config/nvim/lua/opts.lua"))
+	assert.NoFileExists(t, filepath.Join(targetDir, ".config/nvim/README.md"))
+	assert.NoFileExists(t, filepath.Join(targetDir, ".config/nvim", ManifestFileName))
+	assert.NoFileExists(t, filepath.Join(targetDir, "file"), "Packages for other systems are skipped")
+
+	_, err = linker.Unlink([]string{"nvim"})
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(targetDir, ".config"))
+}
+
+func TestPackageHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test use a POSIX shell")
+	}
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "hooked")
+	createDummyPackage(t, pkgPath, map[string]string{"file": "x"})
+	log := filepath.Join(t.TempDir(), "hooks.log")
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`
+[[hooks]]
+when = "pre-link"
+run = "test ! -e \"$GSLK_TARGET/file\" && echo pre-link $GSLK_PACKAGE >> `+log+`"
+
+[[hooks]]
+when = "post-link"
+run = "test -L \"$GSLK_TARGET/file\" && echo post-link >> `+log+`"
+
+[[hooks]]
+when = "post-unlink"
+run = "echo post-unlink >> `+log+`"
+`), 0644))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, DryRun: true}
+	_, err := linker.Link([]string{"hooked"})
+	require.NoError(t, err)
+	assert.NoFileExists(t, log, "Hooks do not run in dry run mode")
+
+	linker.DryRun = false
+	_, err = linker.Link([]string{"hooked"})
+	require.NoError(t, err)
+	_, err = linker.Unlink([]string{"hooked"})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "pre-link hooked\npost-link\npost-unlink\n", string(data))
+
+	// A failing hook stops linking before anything is changed
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte("[[hooks]]\nwhen = \"pre-link\"\nrun = \"exit 3\"\n"), 0644))
+	_, err = linker.Link([]string{"hooked"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pre-link hook of package hooked failed")
+	assert.NoFileExists(t, filepath.Join(targetDir, "file"))
+}
+
+func TestLinkWithManifestRenames(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "layout")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"home/.bashrc":   "bash",
+		"home/bin/tool":  "tool",
+		"kitty.conf":     "kitty",
+		"docs/notes.txt": "notes",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`
+[rename]
+"kitty.conf" = ".config/kitty/kitty.conf"
+"home/" = ""
+"home/bin/" = ".local/bin/"
+`), 0644))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"layout"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, ".bashrc"))
+	assert.FileExists(t, filepath.Join(targetDir, ".local/bin/tool"))
+	assert.FileExists(t, filepath.Join(targetDir, ".config/kitty/kitty.conf"))
+	assert.FileExists(t, filepath.Join(targetDir, "docs/notes.txt"))
+	assert.NoDirExists(t, filepath.Join(targetDir, "home"))
+
+	_, err = linker.Unlink([]string{"layout"})
+	require.NoError(t, err)
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.Equal(t, StateFileName, entry.Name(), "Only the state manifest may be left")
+	}
+}
+
+func TestLinkWithManifestCopyPatterns(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "desktop")
+	createDummyPackage(t, pkgPath, map[string]string{
+		ManifestFileName:           `copy = ["mimeapps.list", ".config/state/*.json"]`,
+		".config/mimeapps.list":    "[Default Applications]",
+		".config/user-dirs.dirs":   "XDG_DESKTOP_DIR=$HOME",
+		".config/state/tabs.json":  "{}",
+		".config/state/readme.txt": "linked",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"desktop"})
+	require.NoError(t, err)
+
+	for rel, copied := range map[string]bool{
+		".config/mimeapps.list":    true,
+		".config/state/tabs.json":  true,
+		".config/user-dirs.dirs":   false,
+		".config/state/readme.txt": false,
+	} {
+		fi, err := os.Lstat(filepath.Join(targetDir, filepath.FromSlash(rel)))
+		require.NoError(t, err, rel)
+		assert.Equal(t, copied, fi.Mode().IsRegular(), rel)
+	}
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	entry, ok := state.Lookup(filepath.Join(targetDir, ".config", "mimeapps.list"))
+	require.True(t, ok)
+	assert.Equal(t, ModeCopy, entry.Mode)
+
+	// Dropping the pattern links the file again
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), nil, 0644))
+	_, err = linker.Relink([]string{"desktop"})
+	require.NoError(t, err)
+	fi, err := os.Lstat(filepath.Join(targetDir, ".config", "mimeapps.list"))
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Mode()&os.ModeSymlink)
+}
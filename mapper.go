@@ -0,0 +1,96 @@
+package gslk
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Mapper computes the path, relative to the target directory, at which a
+// package path is placed. The zero value maps every path to itself.
+//
+// Rules are applied in order: an exact rename from Renames replaces the whole
+// path; otherwise a directory rename from Renames, whose key ends in "/",
+// moves the directory and everything below it (the longest matching key wins,
+// and an empty replacement strips the directory, so {"home/": ""} places
+// "home/.bashrc" at ".bashrc"); then each path component starting with DotPrefix has the prefix
+// replaced by "." (so "dot-config/nvim" becomes ".config/nvim"), and finally
+// TemplateSuffix or SecretSuffix is stripped from the last component of
+// template and secret files.
+type Mapper struct {
+	DotPrefix      string            // Component prefix translated to "." (e.g. "dot-"); empty disables it
+	Renames        map[string]string // Package-relative path (or directory, ending in "/") to target-relative path renames
+	TemplateSuffix string            // Suffix marking template files (e.g. ".tmpl"); empty disables it
+	SecretSuffix   string            // Suffix marking age-encrypted files (e.g. ".age"); empty disables it
+}
+
+// Map returns the target-relative path for the package-relative path relPath.
+func (m *Mapper) Map(relPath string) string {
+	relPath = filepath.Clean(relPath)
+
+	if renamed, ok := m.Renames[filepath.ToSlash(relPath)]; ok {
+		return filepath.Clean(filepath.FromSlash(renamed))
+	}
+	if dir, renamed, ok := m.renamedDir(filepath.ToSlash(relPath)); ok {
+		rest := strings.TrimPrefix(filepath.ToSlash(relPath)+"/", dir)
+		relPath = filepath.Clean(filepath.FromSlash(path.Join(renamed, rest)))
+	}
+
+	if m.DotPrefix != "" {
+		parts := strings.Split(relPath, string(filepath.Separator))
+		for i, part := range parts {
+			if strings.HasPrefix(part, m.DotPrefix) && len(part) > len(m.DotPrefix) {
+				parts[i] = "." + strings.TrimPrefix(part, m.DotPrefix)
+			}
+		}
+		relPath = filepath.Join(parts...)
+	}
+
+	if m.IsSecret(relPath) {
+		relPath = strings.TrimSuffix(relPath, m.SecretSuffix)
+	} else if m.IsTemplate(relPath) {
+		relPath = strings.TrimSuffix(relPath, m.TemplateSuffix)
+	}
+
+	return relPath
+}
+
+// PathMapper computes target paths with conventions of its own, such as
+// stripping a prefix or an encryption suffix the Mapper does not know about,
+// without changing how packages are walked. MapPath is called for every path
+// of every package with the target-relative path the Mapper computed for it,
+// which with the zero Mapper is the package-relative path itself, using the
+// separator of the operating system; it returns the target-relative path to
+// use instead, which must stay inside the target directory.
+type PathMapper interface {
+	MapPath(pkg Package, relPath string) string
+}
+
+// PathMapperFunc adapts a function to a PathMapper.
+type PathMapperFunc func(pkg Package, relPath string) string
+
+// MapPath calls f.
+func (f PathMapperFunc) MapPath(pkg Package, relPath string) string {
+	return f(pkg, relPath)
+}
+
+// renamedDir returns the longest directory rename, and its replacement, that
+// applies to the slash-separated path relPath.
+func (m *Mapper) renamedDir(relPath string) (dir, renamed string, ok bool) {
+	for key, value := range m.Renames {
+		if strings.HasSuffix(key, "/") && strings.HasPrefix(relPath+"/", key) && len(key) > len(dir) {
+			dir, renamed, ok = key, value, true
+		}
+	}
+	return dir, renamed, ok
+}
+
+// IsTemplate reports whether relPath names a template file under this mapping.
+func (m *Mapper) IsTemplate(relPath string) bool {
+	return m.TemplateSuffix != "" && strings.HasSuffix(relPath, m.TemplateSuffix) && len(filepath.Base(relPath)) > len(m.TemplateSuffix)
+}
+
+// IsSecret reports whether relPath names an encrypted secret under this mapping.
+func (m *Mapper) IsSecret(relPath string) bool {
+	return m.SecretSuffix != "" && strings.HasSuffix(relPath, m.SecretSuffix) && len(filepath.Base(relPath)) > len(m.SecretSuffix)
+}
@@ -0,0 +1,109 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapper(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapper   Mapper
+		relPath  string
+		expected string
+	}{
+		{"identity", Mapper{}, "dir/file.txt", "dir/file.txt"},
+		{"dot prefix", Mapper{DotPrefix: "dot-"}, "dot-config/nvim/init.lua", ".config/nvim/init.lua"},
+		{"dot prefix every component", Mapper{DotPrefix: "dot-"}, "dot-a/dot-b", ".a/.b"},
+		{"bare dot prefix kept", Mapper{DotPrefix: "dot-"}, "dot-", "dot-"},
+		{"rename", Mapper{Renames: map[string]string{"kitty.conf": ".config/kitty/kitty.conf"}}, "kitty.conf", ".config/kitty/kitty.conf"},
+		{"rename wins over prefix", Mapper{DotPrefix: "dot-", Renames: map[string]string{"dot-x": "y"}}, "dot-x", "y"},
+		{"directory rename", Mapper{Renames: map[string]string{"kitty/": ".config/kitty/"}}, "kitty/themes/dark.conf", ".config/kitty/themes/dark.conf"},
+		{"directory rename of the directory", Mapper{Renames: map[string]string{"kitty/": ".config/kitty"}}, "kitty", ".config/kitty"},
+		{"directory rename strips prefix", Mapper{Renames: map[string]string{"home/": ""}}, "home/.bashrc", ".bashrc"},
+		{"longest directory rename wins", Mapper{Renames: map[string]string{"home/": "", "home/bin/": ".local/bin/"}}, "home/bin/tool", ".local/bin/tool"},
+		{"directory rename then dot prefix", Mapper{DotPrefix: "dot-", Renames: map[string]string{"home/": ""}}, "home/dot-zshrc", ".zshrc"},
+		{"exact rename wins over directory rename", Mapper{Renames: map[string]string{"home/": "", "home/a": "b"}}, "home/a", "b"},
+		{"directory rename needs whole component", Mapper{Renames: map[string]string{"home/": ""}}, "homework/x", "homework/x"},
+		{"template suffix", Mapper{TemplateSuffix: ".tmpl"}, "dot-gitconfig.tmpl", "dot-gitconfig"},
+		{"bare template suffix kept", Mapper{TemplateSuffix: ".tmpl"}, "dir/.tmpl", "dir/.tmpl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, filepath.FromSlash(tt.expected), tt.mapper.Map(filepath.FromSlash(tt.relPath)))
+		})
+	}
+}
+
+func TestLinkWithMapper(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "mapped_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"dot-zshrc":          "zsh",
+		"dot-config/app.ini": "app",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mapper:    Mapper{DotPrefix: "dot-"},
+	}
+	_, err := linker.Link([]string{pkgName})
+	assert.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(targetDir, ".zshrc"))
+	assert.FileExists(t, filepath.Join(targetDir, ".config", "app.ini"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "dot-zshrc"))
+
+	_, err = linker.Unlink([]string{pkgName})
+	assert.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(targetDir, ".zshrc"))
+}
+
+func TestLinkWithPathMapper(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "shell"), map[string]string{
+		"_zshrc":           "zsh",
+		"_config/app.conf": "app",
+		"notes.txt":        "notes",
+	})
+
+	// Translate a "_" prefix of the first component to "." after the Mapper
+	mapper := PathMapperFunc(func(pkg Package, relPath string) string {
+		if pkg.Name == "shell" && strings.HasPrefix(relPath, "_") {
+			return "." + relPath[1:]
+		}
+		return relPath
+	})
+	linker := New(sourceDir, targetDir, WithMapper(Mapper{Renames: map[string]string{"notes.txt": "_notes"}}), WithPathMapper(mapper), WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"shell"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, ".zshrc"))
+	assert.FileExists(t, filepath.Join(targetDir, ".config", "app.conf"))
+	assert.FileExists(t, filepath.Join(targetDir, ".notes"), "the path mapper sees the paths the Mapper computed")
+
+	e, err := linker.Explain("shell", "_zshrc")
+	require.NoError(t, err)
+	assert.Contains(t, e.Steps, "moved to .zshrc by the path mapper")
+
+	_, err = linker.Unlink([]string{"shell"})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(targetDir, ".zshrc"))
+
+	// Paths mapped outside the target directory are refused
+	linker.PathMapper = PathMapperFunc(func(pkg Package, relPath string) string { return filepath.Join("..", relPath) })
+	_, err = linker.Link([]string{"shell"})
+	assert.ErrorContains(t, err, "maps outside the target directory")
+}
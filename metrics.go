@@ -0,0 +1,118 @@
+package gslk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Metrics counts what successive reconciliations, such as the runs of gslk
+// daemon, did to a target, and writes the counts in the Prometheus text
+// exposition format so fleets of machines can be monitored. The zero value is
+// ready to use, and a Metrics may be used from several goroutines at once.
+type Metrics struct {
+	mu              sync.Mutex
+	reconciliations int       // Runs recorded
+	errors          int       // Runs that failed
+	managed         int       // Files deployed by the packages after the last successful run
+	linked          int       // Files linked, copied, rendered or decrypted
+	unlinked        int       // Links and deployed files removed
+	driftCorrected  int       // Drifted files deployed anew
+	conflicts       int       // Files in the way quarantined, and runs stopped by one
+	last            time.Time // When the last run ended
+	lastSuccess     time.Time // When the last successful run ended
+}
+
+// Record adds a run to the metrics: result is what it applied, drifts the
+// drift found before applying, and err why it failed, if it did. result may be
+// nil for runs failing before anything was applied. A nil Metrics records
+// nothing.
+func (m *Metrics) Record(result *Result, drifts []Drift, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reconciliations++
+	m.last = time.Now()
+	if errors.Is(err, ErrConflict) {
+		m.conflicts++
+	}
+	if result != nil {
+		m.linked += len(result.Linked)
+		m.unlinked += len(result.Unlinked)
+		m.conflicts += len(result.Conflicts)
+
+		deployed := make(map[string]bool, len(result.Linked))
+		for _, target := range result.Linked {
+			deployed[target] = true
+		}
+		for _, drift := range drifts {
+			if deployed[drift.Target] {
+				m.driftCorrected++
+			}
+		}
+	}
+	if err != nil {
+		m.errors++
+		return
+	}
+	m.lastSuccess = m.last
+	if result != nil {
+		m.managed = len(result.Linked) + len(result.Skipped)
+	}
+}
+
+// metric is one sample written by Metrics.WriteTo.
+type metric struct {
+	name, kind, help string
+	value            float64
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+// Timestamps are in seconds since the Unix epoch, 0 before the first run.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	metrics := []metric{
+		{"gslk_reconciliations_total", "counter", "Reconciliations run.", float64(m.reconciliations)},
+		{"gslk_errors_total", "counter", "Reconciliations that failed.", float64(m.errors)},
+		{"gslk_links_managed", "gauge", "Files deployed by the packages after the last successful reconciliation.", float64(m.managed)},
+		{"gslk_links_created_total", "counter", "Files linked, copied, rendered or decrypted into the target.", float64(m.linked)},
+		{"gslk_links_removed_total", "counter", "Links and deployed files removed from the target.", float64(m.unlinked)},
+		{"gslk_drift_corrected_total", "counter", "Drifted files deployed anew.", float64(m.driftCorrected)},
+		{"gslk_conflicts_total", "counter", "Files in the way that were quarantined, and reconciliations stopped by one.", float64(m.conflicts)},
+		{"gslk_last_reconcile_timestamp_seconds", "gauge", "When the last reconciliation ended.", unixSeconds(m.last)},
+		{"gslk_last_success_timestamp_seconds", "gauge", "When the last successful reconciliation ended.", unixSeconds(m.lastSuccess)},
+	}
+	m.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, metric := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+	}
+	return b.WriteTo(w)
+}
+
+// unixSeconds returns t in seconds since the Unix epoch, or 0 if t is zero.
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixMilli()) / 1000
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
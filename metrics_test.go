@@ -0,0 +1,55 @@
+package gslk
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	var m Metrics
+	m.Record(&Result{
+		Linked:    []string{"/t/a", "/t/b", "/t/c"},
+		Skipped:   []string{"/t/d"},
+		Unlinked:  []string{"/t/old"},
+		Conflicts: []string{"/t/e"},
+	}, []Drift{{Kind: DriftWrongLink, Target: "/t/b"}, {Kind: DriftContent, Target: "/t/z"}}, nil)
+	m.Record(nil, nil, fmt.Errorf("%w: target /t/f already exists", ErrConflict))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+
+	samples := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		require.True(t, ok, line)
+		samples[name] = value
+	}
+	assert.Equal(t, "2", samples["gslk_reconciliations_total"])
+	assert.Equal(t, "1", samples["gslk_errors_total"])
+	assert.Equal(t, "4", samples["gslk_links_managed"], "the failed run keeps the count of the last successful one")
+	assert.Equal(t, "3", samples["gslk_links_created_total"])
+	assert.Equal(t, "1", samples["gslk_links_removed_total"])
+	assert.Equal(t, "1", samples["gslk_drift_corrected_total"])
+	assert.Equal(t, "2", samples["gslk_conflicts_total"])
+	assert.NotEqual(t, "0", samples["gslk_last_reconcile_timestamp_seconds"])
+	assert.NotEqual(t, "0", samples["gslk_last_success_timestamp_seconds"])
+	assert.Contains(t, rec.Body.String(), "# TYPE gslk_errors_total counter\n")
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	var unused *Metrics
+	unused.Record(&Result{}, nil, nil) // Must not panic
+}
@@ -0,0 +1,254 @@
+package gslk
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MigrateFormat names the layout of a dotfiles repository managed by another
+// tool, which a Migration converts into gslk packages.
+type MigrateFormat string
+
+const (
+	MigrateStow    MigrateFormat = "stow"    // GNU Stow: a directory of packages, with .stow-local-ignore and .stowrc
+	MigrateChezmoi MigrateFormat = "chezmoi" // chezmoi: a source state with dot_ prefixes, attributes and templates
+	MigrateYadm    MigrateFormat = "yadm"    // yadm: a home directory tree with ##alternate files
+)
+
+// DefaultMigratePackage is the package holding the files of layouts that are
+// a single tree rather than packages (chezmoi and yadm).
+const DefaultMigratePackage = "home"
+
+// Migration converts a dotfiles repository managed by GNU Stow, chezmoi or
+// yadm into gslk packages and a gslk.toml configuration in a new directory,
+// leaving the repository untouched. Conventions gslk has no equivalent for,
+// such as chezmoi scripts or yadm encryption, are reported rather than
+// translated.
+type Migration struct {
+	From    MigrateFormat // Layout of the repository
+	Package string        // Package for single-tree layouts; DefaultMigratePackage if empty
+	DryRun  bool          // Only report what would be written
+}
+
+// MigrationReport describes the outcome of a Migration.
+type MigrationReport struct {
+	Packages     []string       // Packages created
+	Files        []MigratedFile // Files written, sorted by destination
+	Config       string         // Configuration file written, relative to the destination
+	Untranslated []string       // What could not be translated, and why
+}
+
+// MigratedFile is a file written by a Migration. Paths are slash-separated
+// and relative to the repository and the destination.
+type MigratedFile struct {
+	From string // Repository file, or files separated by ", " when merged into a template
+	To   string // File in the destination
+}
+
+// migrateFile is a file a Migration writes: a copy of a repository file, new
+// content, or a symbolic link.
+type migrateFile struct {
+	MigratedFile
+	data []byte // Content to write instead of copying From
+	link string // Destination of a symbolic link to create instead
+	perm os.FileMode
+}
+
+// migrator accumulates what a Migration writes.
+type migrator struct {
+	src      string
+	pkgName  string
+	files    []migrateFile
+	packages []string
+	ignore   map[string][]string // Ignore patterns by package
+	vars     map[string]string
+	target   string
+	notes    []string
+}
+
+// untranslated reports something the migration could not translate.
+func (m *migrator) untranslated(format string, args ...any) {
+	m.notes = append(m.notes, fmt.Sprintf(format, args...))
+}
+
+// addPackage records pkgName as a package of the migrated repository.
+func (m *migrator) addPackage(pkgName string) {
+	if !slices.Contains(m.packages, pkgName) {
+		m.packages = append(m.packages, pkgName)
+	}
+}
+
+// copyFile records that the repository file from is copied to to with perm,
+// or to the permissions of from if perm is 0. Symbolic links are copied as
+// links.
+func (m *migrator) copyFile(from, to string, perm os.FileMode) error {
+	p := filepath.Join(m.src, filepath.FromSlash(from))
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	f := migrateFile{MigratedFile: MigratedFile{From: from, To: to}, perm: perm}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if f.link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	} else if f.perm == 0 {
+		f.perm = fi.Mode().Perm()
+	}
+	m.files = append(m.files, f)
+	return nil
+}
+
+// Run converts the repository in srcDir into destDir, which must not exist
+// or be empty. The returned report is never nil.
+func (mg *Migration) Run(srcDir, destDir string) (*MigrationReport, error) {
+	report := &MigrationReport{}
+	fi, err := os.Stat(srcDir)
+	if err != nil {
+		return report, fmt.Errorf("failed to read repository: %w", err)
+	}
+	if !fi.IsDir() {
+		return report, fmt.Errorf("%s is not a directory", srcDir)
+	}
+	if entries, err := os.ReadDir(destDir); err == nil && len(entries) > 0 {
+		return report, fmt.Errorf("refusing to migrate into %s: the directory is not empty", destDir)
+	}
+
+	m := &migrator{src: srcDir, pkgName: mg.Package, ignore: make(map[string][]string), vars: make(map[string]string)}
+	if m.pkgName == "" {
+		m.pkgName = DefaultMigratePackage
+	}
+	switch mg.From {
+	case MigrateStow:
+		err = m.stow()
+	case MigrateChezmoi:
+		err = m.chezmoi()
+	case MigrateYadm:
+		err = m.yadm()
+	default:
+		return report, fmt.Errorf("unknown layout %q: must be stow, chezmoi or yadm", mg.From)
+	}
+	if err != nil {
+		return report, err
+	}
+	if len(m.packages) == 0 {
+		return report, fmt.Errorf("no files to migrate found in %s", srcDir)
+	}
+
+	m.addGenerated()
+	slices.SortFunc(m.files, func(a, b migrateFile) int { return strings.Compare(a.To, b.To) })
+	for _, f := range m.files {
+		report.Files = append(report.Files, f.MigratedFile)
+	}
+	report.Packages = m.packages
+	report.Config = RepoConfigFileName
+	report.Untranslated = m.notes
+	if mg.DryRun {
+		return report, nil
+	}
+	return report, m.write(destDir)
+}
+
+// addGenerated adds the ignore files of the packages and the configuration
+// file to the files written.
+func (m *migrator) addGenerated() {
+	for _, pkgName := range m.packages {
+		if patterns := m.ignore[pkgName]; len(patterns) > 0 {
+			data := "# Translated from the ignore rules of the previous dotfiles manager\n" + strings.Join(patterns, "\n") + "\n"
+			m.files = append(m.files, migrateFile{MigratedFile: MigratedFile{To: pkgName + "/" + IgnoreFileName}, data: []byte(data), perm: 0644})
+		}
+	}
+
+	var config strings.Builder
+	fmt.Fprintf(&config, "# Migrated to gslk; see the README for the other settings\n")
+	if m.target != "" {
+		fmt.Fprintf(&config, "target = %s\n", tomlQuote(m.target))
+	}
+	fmt.Fprintf(&config, "\n[apply]\npackages = [")
+	for i, pkgName := range m.packages {
+		if i > 0 {
+			config.WriteString(", ")
+		}
+		config.WriteString(tomlQuote(pkgName))
+	}
+	config.WriteString("]\n")
+	if len(m.vars) > 0 {
+		config.WriteString("\n[vars]\n")
+		for _, name := range slices.Sorted(maps.Keys(m.vars)) {
+			fmt.Fprintf(&config, "%s = %s\n", tomlQuote(name), tomlQuote(m.vars[name]))
+		}
+	}
+	m.files = append(m.files, migrateFile{MigratedFile: MigratedFile{To: RepoConfigFileName}, data: []byte(config.String()), perm: 0644})
+}
+
+// write writes the files of the migration into destDir.
+func (m *migrator) write(destDir string) error {
+	for _, f := range m.files {
+		to := filepath.Join(destDir, filepath.FromSlash(f.To))
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", to, err)
+		}
+		var err error
+		switch {
+		case f.link != "":
+			err = os.Symlink(f.link, to)
+		case f.data != nil:
+			err = os.WriteFile(to, f.data, f.perm)
+		default:
+			err = copyFile(filepath.Join(m.src, filepath.FromSlash(f.From)), to, f.perm)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", to, err)
+		}
+	}
+	return nil
+}
+
+// walkRepo calls fn with the slash-separated path, relative to dir, of every file
+// below dir, skipping .git directories. fn may return fs.SkipDir for directories.
+func walkRepo(dir string, fn func(rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), d)
+	})
+}
+
+// tomlQuote returns s as a TOML basic string.
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
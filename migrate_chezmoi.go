@@ -0,0 +1,300 @@
+package gslk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// chezmoiTemplateVars maps the chezmoi template variables gslk has an
+// equivalent for to their TemplateData field.
+var chezmoiTemplateVars = map[string]string{
+	"os":       ".OS",
+	"arch":     ".Arch",
+	"hostname": ".Hostname",
+	"username": ".User",
+	"homeDir":  ".Home",
+}
+
+var (
+	templateActionRe = regexp.MustCompile(`(?s){{.*?}}`)
+	chezmoiVarRe     = regexp.MustCompile(`\.chezmoi\.(\w+)`)
+	templateFieldRe  = regexp.MustCompile(`(^|[\s({|-])\.([A-Za-z_]\w*)`)
+	scopeActionRe    = regexp.MustCompile(`^{{-?\s*(range|with)\b`)
+)
+
+// chezmoiScriptPrefixes mark source files that are not files in the target.
+var chezmoiScriptPrefixes = []string{"run_", "create_", "modify_", "remove_", "before_", "after_", "once_", "onchange_"}
+
+// chezmoi migrates a chezmoi source directory into a single package.
+func (m *migrator) chezmoi() error {
+	root := ""
+	if data, err := os.ReadFile(filepath.Join(m.src, ".chezmoiroot")); err == nil {
+		root = path.Clean(strings.TrimSpace(string(data)))
+		if !filepath.IsLocal(root) {
+			return fmt.Errorf(".chezmoiroot names %q, which is outside the repository", root)
+		}
+	}
+	if err := m.chezmoiData(root); err != nil {
+		return err
+	}
+
+	// Source directories, relative to the root, and the target directories they stand for
+	dirs := map[string]string{".": ""}
+	srcRoot := filepath.Join(m.src, filepath.FromSlash(root))
+	m.addPackage(m.pkgName)
+
+	return walkRepo(srcRoot, func(rel string, d fs.DirEntry) error {
+		name := path.Base(rel)
+		parent := dirs[path.Dir(rel)]
+		srcRel := path.Join(root, rel)
+
+		if strings.HasPrefix(name, ".") {
+			return m.chezmoiSpecial(srcRel, name, d)
+		}
+		if d.IsDir() {
+			target, ok := m.chezmoiDirName(srcRel, name)
+			if !ok {
+				return fs.SkipDir
+			}
+			dirs[rel] = path.Join(parent, target)
+			return nil
+		}
+		return m.chezmoiFile(srcRel, parent, name)
+	})
+}
+
+// chezmoiSpecial handles the source file name starting with "." at srcRel,
+// which chezmoi reads itself or ignores.
+func (m *migrator) chezmoiSpecial(srcRel, name string, d fs.DirEntry) error {
+	switch {
+	case name == ".chezmoiignore":
+		return m.chezmoiIgnore(srcRel)
+	case name == ".chezmoiroot", name == ".chezmoiversion", strings.HasPrefix(name, ".chezmoidata"):
+		// Read before walking, or of no use to gslk
+	case strings.HasPrefix(name, ".chezmoi"):
+		m.untranslated("%s: chezmoi %s has no gslk equivalent", srcRel, strings.TrimPrefix(name, "."))
+	}
+	if d.IsDir() {
+		return fs.SkipDir
+	}
+	return nil
+}
+
+// chezmoiDirName returns the target name of the source directory name, or
+// false if the directory is not migrated.
+func (m *migrator) chezmoiDirName(srcRel, name string) (string, bool) {
+	for {
+		switch {
+		case strings.HasPrefix(name, "literal_"):
+			return strings.TrimPrefix(name, "literal_"), true
+		case strings.HasPrefix(name, "remove_"), strings.HasPrefix(name, "external_"):
+			m.untranslated("%s/: %sdirectories are not supported; not migrated", srcRel, name[:strings.IndexByte(name, '_')+1])
+			return "", false
+		case strings.HasPrefix(name, "exact_"):
+			m.untranslated("%s/: exact_ directories are not supported; files gslk did not link are left in place", srcRel)
+			name = strings.TrimPrefix(name, "exact_")
+		case strings.HasPrefix(name, "private_"):
+			m.untranslated("%s/: private_ directories are not supported; set dir_mode in %s", srcRel, RepoConfigFileName)
+			name = strings.TrimPrefix(name, "private_")
+		case strings.HasPrefix(name, "readonly_"):
+			name = strings.TrimPrefix(name, "readonly_")
+		case strings.HasPrefix(name, "dot_"):
+			return "." + strings.TrimPrefix(name, "dot_"), true
+		default:
+			return name, true
+		}
+	}
+}
+
+// chezmoiFile migrates the source file name at srcRel into the target
+// directory dir.
+func (m *migrator) chezmoiFile(srcRel, dir, name string) error {
+	for _, prefix := range chezmoiScriptPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			m.untranslated("%s: chezmoi scripts and %sfiles are not supported; not migrated", srcRel, prefix)
+			return nil
+		}
+	}
+
+	perm := os.FileMode(0644)
+	var encrypted, symlink bool
+attributes:
+	for {
+		switch {
+		case strings.HasPrefix(name, "literal_"):
+			name = strings.TrimPrefix(name, "literal_")
+			break attributes
+		case strings.HasPrefix(name, "dot_"):
+			name = "." + strings.TrimPrefix(name, "dot_")
+			break attributes
+		case strings.HasPrefix(name, "symlink_"):
+			symlink = true
+		case strings.HasPrefix(name, "encrypted_"):
+			encrypted = true
+		case strings.HasPrefix(name, "private_"):
+			perm &^= 0077
+		case strings.HasPrefix(name, "readonly_"):
+			perm &^= 0222
+		case strings.HasPrefix(name, "executable_"):
+			perm |= 0111
+		case strings.HasPrefix(name, "empty_"):
+		default:
+			break attributes
+		}
+		name = name[strings.IndexByte(name, '_')+1:]
+	}
+
+	isTemplate := false
+	switch {
+	case strings.HasSuffix(name, ".literal"):
+		name = strings.TrimSuffix(name, ".literal")
+	case strings.HasSuffix(name, DefaultTemplateSuffix):
+		isTemplate = true
+	}
+	to := m.pkgName + "/" + path.Join(dir, name)
+
+	switch {
+	case encrypted && (isTemplate || symlink || !strings.HasSuffix(name, DefaultSecretSuffix)):
+		m.untranslated("%s: only age-encrypted files (%s) are supported as secrets; not migrated", srcRel, DefaultSecretSuffix)
+		return nil
+	case symlink && isTemplate:
+		m.untranslated("%s: symlink templates are not supported; not migrated", srcRel)
+		return nil
+	case symlink:
+		dest, err := os.ReadFile(filepath.Join(m.src, filepath.FromSlash(srcRel)))
+		if err != nil {
+			return err
+		}
+		m.files = append(m.files, migrateFile{MigratedFile: MigratedFile{From: srcRel, To: strings.TrimSuffix(to, DefaultTemplateSuffix)}, link: strings.TrimSpace(string(dest))})
+		return nil
+	case isTemplate:
+		return m.chezmoiTemplate(srcRel, to, perm)
+	}
+	return m.copyFile(srcRel, to, perm)
+}
+
+// chezmoiTemplate translates the chezmoi template at srcRel into a gslk
+// template at to.
+func (m *migrator) chezmoiTemplate(srcRel, to string, perm os.FileMode) error {
+	data, err := os.ReadFile(filepath.Join(m.src, filepath.FromSlash(srcRel)))
+	if err != nil {
+		return err
+	}
+
+	var vars []string
+	var scoped bool
+	text := templateActionRe.ReplaceAllStringFunc(string(data), func(action string) string {
+		scoped = scoped || scopeActionRe.MatchString(action)
+		action = chezmoiVarRe.ReplaceAllStringFunc(action, func(v string) string {
+			if field, ok := chezmoiTemplateVars[strings.TrimPrefix(v, ".chezmoi.")]; ok {
+				return field
+			}
+			return v
+		})
+		// Other top-level fields are the user's data, which gslk keeps in .Vars
+		return templateFieldRe.ReplaceAllStringFunc(action, func(field string) string {
+			sub := templateFieldRe.FindStringSubmatch(field)
+			if sub[2] == "chezmoi" || isTemplateDataField(sub[2]) {
+				return field
+			}
+			if !slices.Contains(vars, sub[2]) {
+				vars = append(vars, sub[2])
+			}
+			return sub[1] + ".Vars." + sub[2]
+		})
+	})
+	m.files = append(m.files, migrateFile{MigratedFile: MigratedFile{From: srcRel, To: to}, data: []byte(text), perm: perm})
+
+	if v := chezmoiVarRe.FindString(text); v != "" {
+		m.untranslated("%s: %s has no gslk equivalent; edit the template", srcRel, v)
+	}
+	if scoped {
+		m.untranslated("%s: check the variables used inside range and with, which were translated as top-level ones", srcRel)
+	}
+	for _, v := range vars {
+		if _, ok := m.vars[v]; !ok {
+			m.untranslated("%s: variable %s is not defined in .chezmoidata; add it to [vars] in %s", srcRel, v, RepoConfigFileName)
+		}
+	}
+	if _, err := template.New(srcRel).Funcs(templateFuncs("")).Parse(text); err != nil {
+		m.untranslated("%s: %v; edit the template", srcRel, err)
+	}
+	return nil
+}
+
+// isTemplateDataField reports whether name is a field of TemplateData.
+func isTemplateDataField(name string) bool {
+	switch name {
+	case "Vars", "Env", "Hostname", "OS", "Arch", "User", "Home", "Package":
+		return true
+	}
+	return false
+}
+
+// chezmoiIgnore translates the patterns of .chezmoiignore that are not
+// templated into ignore patterns of the package.
+func (m *migrator) chezmoiIgnore(srcRel string) error {
+	patterns, err := loadPatternFile(filepath.Join(m.src, filepath.FromSlash(srcRel)))
+	if err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "{{") || strings.HasPrefix(pattern, "!") || strings.Contains(pattern, "**") {
+			m.untranslated("%s: pattern %q is templated, negated or uses **; not migrated", srcRel, pattern)
+			continue
+		}
+		m.ignore[m.pkgName] = append(m.ignore[m.pkgName], strings.TrimPrefix(pattern, "/"))
+	}
+	return nil
+}
+
+// chezmoiData reads the template data of .chezmoidata.toml files in root
+// into the variables of the configuration.
+func (m *migrator) chezmoiData(root string) error {
+	dir := filepath.Join(m.src, filepath.FromSlash(root))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read repository: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, ".chezmoidata") {
+			continue
+		}
+		if entry.IsDir() {
+			m.untranslated("%s/: only .chezmoidata.toml files are translated into [vars]", path.Join(root, name))
+			continue
+		}
+		if filepath.Ext(name) != ".toml" {
+			m.untranslated("%s: only TOML data files are translated into [vars]", path.Join(root, name))
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		doc, err := parseTOML(string(data))
+		if err != nil {
+			m.untranslated("%s: %v", path.Join(root, name), err)
+			continue
+		}
+		for key, value := range doc {
+			switch value := value.(type) {
+			case string:
+				m.vars[key] = value
+			case int64, float64, bool:
+				m.vars[key] = fmt.Sprint(value)
+			default:
+				m.untranslated("%s: %s is not a string, number or boolean; not migrated to [vars]", path.Join(root, name), key)
+			}
+		}
+	}
+	return nil
+}
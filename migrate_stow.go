@@ -0,0 +1,268 @@
+package gslk
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// stowDefaultIgnore is the ignore list GNU Stow applies to packages without
+// a .stow-local-ignore file.
+var stowDefaultIgnore = []string{
+	`RCS`, `.+,v`, `CVS`, `\.\#.+`, `\.cvsignore`, `\.svn`, `_darcs`, `\.hg`, `\.git`,
+	`\.gitignore`, `\.gitmodules`, `.+~`, `\#.*\#`, `^/README.*`, `^/LICENSE.*`, `^/COPYING`,
+}
+
+// stowFiles are the files GNU Stow reads itself, which are not copied.
+var stowFiles = []string{".stowrc", ".stow-local-ignore", ".stow-global-ignore", ".stow"}
+
+// stow migrates a GNU Stow directory: each directory is already a package.
+func (m *migrator) stow() error {
+	dotfiles, err := m.stowrc()
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(m.src)
+	if err != nil {
+		return fmt.Errorf("failed to read repository: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case slices.Contains(stowFiles, name) || name == ".git":
+		case entry.IsDir() && strings.HasPrefix(name, "."):
+			m.untranslated("%s/ is not copied: hidden directories are not packages", name)
+		case entry.IsDir():
+			if err := m.stowPackage(name, dotfiles); err != nil {
+				return err
+			}
+		default:
+			// Files next to the packages, such as a README
+			if err := m.copyFile(name, name, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stowrc reads the options of .stowrc, returning whether --dotfiles is set.
+func (m *migrator) stowrc() (dotfiles bool, err error) {
+	file, err := os.Open(filepath.Join(m.src, ".stowrc"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read .stowrc: %w", err)
+	}
+	defer file.Close()
+
+	var options []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); !strings.HasPrefix(line, "#") {
+			options = append(options, strings.Fields(line)...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read .stowrc: %w", err)
+	}
+
+	for i := 0; i < len(options); i++ {
+		option, value, hasValue := strings.Cut(options[i], "=")
+		switch option {
+		case "--dotfiles":
+			dotfiles = true
+		case "-t", "--target":
+			if !hasValue && i+1 < len(options) {
+				i++
+				value = options[i]
+			}
+			m.target = value
+		case "-d", "--dir":
+			if !hasValue {
+				i++
+			}
+		default:
+			m.untranslated(".stowrc option %s has no equivalent in the gslk configuration", options[i])
+		}
+	}
+	return dotfiles, nil
+}
+
+// stowIgnore is an ignore pattern of a Stow package and the package paths it
+// matches.
+type stowIgnore struct {
+	pattern string
+	re      *regexp.Regexp
+	path    bool // Matched against the path from the package root rather than the base name
+	matched []string
+}
+
+// stowPackage copies package pkgName, translating its ignore list and, with
+// dotfiles, its dot- prefixes.
+func (m *migrator) stowPackage(pkgName string, dotfiles bool) error {
+	pkgDir := filepath.Join(m.src, pkgName)
+	patterns, err := loadPatternFile(filepath.Join(pkgDir, ".stow-local-ignore"))
+	if err != nil {
+		return err
+	}
+	local := len(patterns) > 0
+	if !local {
+		patterns = stowDefaultIgnore
+	}
+
+	var rules []*stowIgnore
+	for _, pattern := range patterns {
+		rule := &stowIgnore{pattern: pattern, path: strings.Contains(pattern, "/")}
+		expr := `^(?:` + pattern + `)$`
+		if rule.path {
+			expr = `(?:^|/)(?:` + pattern + `)$`
+		}
+		if rule.re, err = regexp.Compile(expr); err != nil {
+			m.untranslated("%s/.stow-local-ignore: pattern %q is not supported: %v", pkgName, pattern, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	m.addPackage(pkgName)
+	var ignoredDirs []string
+	err = walkRepo(pkgDir, func(rel string, d fs.DirEntry) error {
+		if rel == ".stow-local-ignore" {
+			return nil
+		}
+		to := rel
+		if dotfiles {
+			to = stowDotfiles(rel)
+		}
+		if !isUnderAny(ignoredDirs, rel) {
+			for _, rule := range rules {
+				subject := path.Base(rel)
+				if rule.path {
+					subject = "/" + rel
+				}
+				if rule.re.MatchString(subject) {
+					rule.matched = append(rule.matched, to)
+					if d.IsDir() {
+						ignoredDirs = append(ignoredDirs, rel)
+					}
+					break
+				}
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return m.copyFile(pkgName+"/"+rel, pkgName+"/"+to, 0)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read package %s: %w", pkgName, err)
+	}
+
+	for _, rule := range rules {
+		glob, ok := stowGlob(rule.pattern)
+		if ok && dotfiles {
+			glob = stowDotfiles(glob)
+		}
+		switch {
+		case ok && (local || len(rule.matched) > 0):
+			m.ignore[pkgName] = append(m.ignore[pkgName], glob)
+		case len(rule.matched) > 0:
+			for _, matched := range rule.matched {
+				m.ignore[pkgName] = append(m.ignore[pkgName], globLiteral(matched))
+			}
+			m.untranslated("%s/.stow-local-ignore: pattern %q has no glob equivalent; the %d paths it matches now are ignored instead", pkgName, rule.pattern, len(rule.matched))
+		case local:
+			m.untranslated("%s/.stow-local-ignore: pattern %q has no glob equivalent and matches nothing", pkgName, rule.pattern)
+		}
+	}
+	return nil
+}
+
+// isUnderAny reports whether the slash-separated path rel lies below one of dirs.
+func isUnderAny(dirs []string, rel string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// stowDotfiles replaces the "dot-" prefix of the components of rel by ".",
+// as stow --dotfiles does.
+func stowDotfiles(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "dot-") && len(part) > len("dot-") {
+			parts[i] = "." + strings.TrimPrefix(part, "dot-")
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// stowGlob translates a Stow ignore regexp into an ignore pattern, if it only
+// uses literal characters, ".", ".*", ".+" and anchors. Patterns with a "/"
+// must be anchored at the package root.
+func stowGlob(pattern string) (string, bool) {
+	pattern = strings.TrimPrefix(pattern, "^")
+	if strings.Contains(pattern, "/") {
+		rest, ok := strings.CutPrefix(pattern, "/")
+		if !ok {
+			return "", false
+		}
+		pattern = rest
+	}
+	if strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, `\$`) {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern) && !isAlnum(pattern[i+1]):
+			i++
+			b.WriteString(globLiteral(string(pattern[i])))
+		case c == '.' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			b.WriteString("*")
+		case c == '.' && i+1 < len(pattern) && pattern[i+1] == '+':
+			i++
+			b.WriteString("?*")
+		case c == '.':
+			b.WriteString("?")
+		case strings.IndexByte(`^$*+?()[]{}|\`, c) >= 0:
+			return "", false
+		default:
+			b.WriteString(globLiteral(string(c)))
+		}
+	}
+	return b.String(), b.Len() > 0
+}
+
+// globLiteral escapes the characters of s that are special in ignore
+// patterns, and a leading "#", which would start a comment.
+func globLiteral(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(`*?[\`, s[i]) >= 0 || (i == 0 && s[i] == '#') {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// isAlnum reports whether c is an ASCII letter or digit.
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
@@ -0,0 +1,220 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRepo creates the files of a repository below dir.
+func writeRepo(t *testing.T, dir string, files map[string]string) {
+	for rel, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func readMigrated(t *testing.T, dir, rel string) string {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+	require.NoError(t, err, rel)
+	return string(data)
+}
+
+func TestMigrateStow(t *testing.T) {
+	src, dest := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	writeRepo(t, src, map[string]string{
+		".stowrc":                    "--dotfiles\n--target=~\n--no-folding\n",
+		"README.md":                  "my dotfiles",
+		"vim/dot-vimrc":              "set nu",
+		"vim/README.md":              "about vim",
+		"vim/backup.swp~":            "",
+		"zsh/.stow-local-ignore":     "\\.zcompdump.*\n^/docs\n(foo|bar)\\.txt\n",
+		"zsh/dot-zshrc":              "PS1=$",
+		"zsh/docs/index.md":          "",
+		"zsh/foo.txt":                "",
+		"zsh/dot-config/zsh/env.zsh": "",
+	})
+
+	report, err := (&Migration{From: MigrateStow}).Run(src, dest)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vim", "zsh"}, report.Packages)
+
+	assert.Equal(t, "set nu", readMigrated(t, dest, "vim/.vimrc"))
+	assert.Equal(t, "my dotfiles", readMigrated(t, dest, "README.md"))
+	assert.FileExists(t, filepath.Join(dest, "zsh", ".config", "zsh", "env.zsh"))
+	assert.NoFileExists(t, filepath.Join(dest, "zsh", ".stow-local-ignore"))
+
+	// Only the default patterns matching something are kept
+	assert.Equal(t, []string{"?*~", "README*"}, ignoreLines(readMigrated(t, dest, "vim/"+IgnoreFileName)))
+	assert.Equal(t, []string{".zcompdump*", "docs", "foo.txt"}, ignoreLines(readMigrated(t, dest, "zsh/"+IgnoreFileName)))
+
+	config, err := LoadConfig(filepath.Join(dest, RepoConfigFileName))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vim", "zsh"}, config.Desired)
+	home, _ := os.UserHomeDir()
+	assert.Equal(t, home, config.Target)
+
+	assert.Len(t, report.Untranslated, 2)
+	assert.Contains(t, report.Untranslated[0], "--no-folding")
+	assert.Contains(t, report.Untranslated[1], "(foo|bar)")
+
+	_, err = (&Migration{From: MigrateStow}).Run(src, dest)
+	assert.ErrorContains(t, err, "not empty")
+}
+
+// ignoreLines returns the patterns of an ignore file.
+func ignoreLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestStowGlob(t *testing.T) {
+	for pattern, want := range map[string]string{
+		`\.git`:       ".git",
+		`.+~`:         "?*~",
+		`\#.*\#`:      `\#*\#`,
+		`^/README.*`:  "README*",
+		`^/docs/a\.b`: "docs/a.b",
+		`a\*b`:        `a\*b`,
+	} {
+		glob, ok := stowGlob(pattern)
+		assert.True(t, ok, pattern)
+		assert.Equal(t, want, glob, pattern)
+	}
+	for _, pattern := range []string{`(a|b)`, `a+`, `docs/x`, `[ab]`, `\d`} {
+		_, ok := stowGlob(pattern)
+		assert.False(t, ok, pattern)
+	}
+}
+
+func TestMigrateChezmoi(t *testing.T) {
+	src, dest := t.TempDir(), t.TempDir()
+	writeRepo(t, src, map[string]string{
+		".chezmoidata.toml":                  "email = \"me@example.com\"\n",
+		".chezmoiignore":                     "README.md\n{{ if ne .chezmoi.os \"darwin\" }}Library{{ end }}\n",
+		".chezmoiexternal.toml":              "",
+		"README.md":                          "",
+		"dot_gitconfig.tmpl":                 "[user]\n\temail = {{ .email }}\n{{- if eq .chezmoi.os \"darwin\" }}\n\thelper = osxkeychain\n{{- end }}\n",
+		"private_dot_ssh/private_config":     "Host *",
+		"private_dot_ssh/encrypted_key.age":  "age",
+		"dot_local/bin/executable_hello":     "#!/bin/sh",
+		"dot_config/symlink_editor":          "/usr/bin/vim\n",
+		"run_once_install.sh":                "",
+		"exact_dot_vim/literal_dot_notdot":   "",
+		"dot_bashrc.tmpl":                    "{{ .chezmoi.sourceDir }} {{ output \"date\" }}",
+		"modify_dot_profile":                 "",
+		"encrypted_private_dot_netrc.tmpl":   "",
+		"dot_config/nvim/init.lua":           "--",
+		"dot_config/nvim/literal_run_me.lua": "--",
+	})
+
+	report, err := (&Migration{From: MigrateChezmoi, Package: "dotfiles"}).Run(src, dest)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dotfiles"}, report.Packages)
+
+	gitconfig := readMigrated(t, dest, "dotfiles/.gitconfig.tmpl")
+	assert.Contains(t, gitconfig, "{{ .Vars.email }}")
+	assert.Contains(t, gitconfig, `{{- if eq .OS "darwin" }}`)
+	_, err = template.New("").Parse(gitconfig)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Host *", readMigrated(t, dest, "dotfiles/.ssh/config"))
+	assert.FileExists(t, filepath.Join(dest, "dotfiles", ".ssh", "key.age"))
+	assert.FileExists(t, filepath.Join(dest, "dotfiles", ".vim", "dot_notdot"))
+	assert.FileExists(t, filepath.Join(dest, "dotfiles", ".config", "nvim", "run_me.lua"))
+	assert.NoFileExists(t, filepath.Join(dest, "dotfiles", "run_once_install.sh"))
+	assert.NoFileExists(t, filepath.Join(dest, "dotfiles", ".chezmoidata.toml"))
+	dest2, err := os.Readlink(filepath.Join(dest, "dotfiles", ".config", "editor"))
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/vim", dest2)
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filepath.Join(dest, "dotfiles", ".ssh", "config"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+		fi, err = os.Stat(filepath.Join(dest, "dotfiles", ".local", "bin", "hello"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	}
+
+	assert.Equal(t, []string{"README.md"}, ignoreLines(readMigrated(t, dest, "dotfiles/"+IgnoreFileName)))
+	config, err := LoadConfig(filepath.Join(dest, RepoConfigFileName))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"email": "me@example.com"}, config.Vars)
+
+	notes := strings.Join(report.Untranslated, "\n")
+	for _, want := range []string{"Library", "chezmoiexternal", "run_once_install.sh", "exact_", ".chezmoi.sourceDir", `"output" not defined`, "modify_dot_profile", "encrypted_private_dot_netrc.tmpl"} {
+		assert.Contains(t, notes, want)
+	}
+}
+
+func TestMigrateYadm(t *testing.T) {
+	src, dest := t.TempDir(), t.TempDir()
+	writeRepo(t, src, map[string]string{
+		".bashrc":                          "alias ll='ls -l'",
+		".gitconfig##os.Darwin":            "mac {{x}}",
+		".gitconfig##os.Linux,hostname.pc": "linux pc",
+		".gitconfig##default":              "other",
+		".vimrc##default":                  "set nu",
+		".tmux.conf##class.Work":           "work",
+		".config/yadm/bootstrap":           "#!/bin/sh",
+		".profile##template":               "",
+	})
+
+	report, err := (&Migration{From: MigrateYadm}).Run(src, dest)
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultMigratePackage}, report.Packages)
+	assert.Equal(t, "alias ll='ls -l'", readMigrated(t, dest, "home/.bashrc"))
+	assert.Equal(t, "set nu", readMigrated(t, dest, "home/.vimrc"))
+	assert.NoFileExists(t, filepath.Join(dest, "home", ".config", "yadm", "bootstrap"))
+
+	gitconfig := readMigrated(t, dest, "home/.gitconfig.tmpl")
+	tmpl, err := template.New("").Option("missingkey=error").Parse(gitconfig)
+	require.NoError(t, err)
+	for _, c := range []struct {
+		data TemplateData
+		want string
+	}{
+		{TemplateData{OS: "darwin"}, "mac {{x}}"},
+		{TemplateData{OS: "linux", Hostname: "pc"}, "linux pc"},
+		{TemplateData{OS: "linux", Hostname: "server"}, "other"},
+	} {
+		var out strings.Builder
+		require.NoError(t, tmpl.Execute(&out, c.data))
+		assert.Equal(t, c.want, out.String())
+	}
+
+	assert.Equal(t, `{{ if (eq .Vars.class "Work") }}work{{ end }}`, readMigrated(t, dest, "home/.tmux.conf.tmpl"))
+	config, err := LoadConfig(filepath.Join(dest, RepoConfigFileName))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"class": ""}, config.Vars)
+
+	notes := strings.Join(report.Untranslated, "\n")
+	for _, want := range []string{".config/yadm", ".profile##template", ".tmux.conf has no ##default"} {
+		assert.Contains(t, notes, want)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	src, dest := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	writeRepo(t, src, map[string]string{"vim/.vimrc": "set nu"})
+
+	report, err := (&Migration{From: MigrateStow, DryRun: true}).Run(src, dest)
+	require.NoError(t, err)
+	assert.Equal(t, []MigratedFile{{To: RepoConfigFileName}, {From: "vim/.vimrc", To: "vim/.vimrc"}}, report.Files)
+	assert.NoDirExists(t, dest)
+
+	_, err = (&Migration{From: "homesick"}).Run(src, dest)
+	assert.ErrorContains(t, err, "unknown layout")
+}
@@ -0,0 +1,184 @@
+package gslk
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// yadmArch maps the machine names yadm compares arch conditions with to
+// values of runtime.GOARCH.
+var yadmArch = map[string]string{
+	"x86_64": "amd64", "amd64": "amd64", "aarch64": "arm64", "arm64": "arm64",
+	"i386": "386", "i686": "386", "armv6l": "arm", "armv7l": "arm",
+}
+
+// yadmAlternate is a file of a yadm repository with ##conditions in its name.
+type yadmAlternate struct {
+	srcRel     string
+	conditions []string // Template expressions that must all hold; none for ##default
+}
+
+// yadm migrates a yadm repository, a home directory tree, into a single
+// package. Alternate files are merged into a template choosing between them.
+func (m *migrator) yadm() error {
+	m.addPackage(m.pkgName)
+	alternates := make(map[string][]yadmAlternate) // By target path
+	err := walkRepo(m.src, func(rel string, d fs.DirEntry) error {
+		if rel == ".config/yadm" || rel == ".yadm" {
+			m.untranslated("%s/: yadm configuration, bootstrap and encryption are not supported; not migrated", rel)
+			return fs.SkipDir
+		}
+		base, conditions, isAlternate := strings.Cut(path.Base(rel), "##")
+		switch {
+		case isAlternate && d.IsDir():
+			m.untranslated("%s/: alternate directories are not supported; not migrated", rel)
+			return fs.SkipDir
+		case d.IsDir():
+			return nil
+		case !isAlternate:
+			return m.copyFile(rel, m.pkgName+"/"+rel, 0)
+		}
+
+		alt, ok := m.yadmConditions(rel, conditions)
+		if ok {
+			target := path.Join(path.Dir(rel), base)
+			alternates[target] = append(alternates[target], alt)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read repository: %w", err)
+	}
+
+	for _, target := range slices.Sorted(maps.Keys(alternates)) {
+		if err := m.yadmTemplate(target, alternates[target]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yadmConditions translates the ##conditions of the alternate file at rel
+// into template expressions, or returns false if they cannot be translated.
+func (m *migrator) yadmConditions(rel, conditions string) (yadmAlternate, bool) {
+	alt := yadmAlternate{srcRel: rel}
+	for _, condition := range strings.Split(conditions, ",") {
+		name, value, _ := strings.Cut(condition, ".")
+		switch name {
+		case "default":
+		case "e", "extension":
+			// Only there for editors
+		case "o", "os":
+			goos := strings.ToLower(value)
+			if goos == "wsl" {
+				m.untranslated("%s: the WSL condition is not supported; not migrated", rel)
+				return alt, false
+			}
+			alt.conditions = append(alt.conditions, "(eq .OS "+strconv.Quote(goos)+")")
+		case "a", "arch":
+			goarch, ok := yadmArch[value]
+			if !ok {
+				m.untranslated("%s: architecture %s is not supported; not migrated", rel, value)
+				return alt, false
+			}
+			alt.conditions = append(alt.conditions, "(eq .Arch "+strconv.Quote(goarch)+")")
+		case "h", "hostname":
+			alt.conditions = append(alt.conditions, "(eq .Hostname "+strconv.Quote(value)+")")
+		case "u", "user":
+			alt.conditions = append(alt.conditions, "(eq .User "+strconv.Quote(value)+")")
+		case "c", "class":
+			if _, ok := m.vars["class"]; !ok {
+				m.vars["class"] = ""
+				m.untranslated("classes are the variable class in [vars] of %s; set it on each machine", RepoConfigFileName)
+			}
+			alt.conditions = append(alt.conditions, "(eq .Vars.class "+strconv.Quote(value)+")")
+		case "t", "template":
+			m.untranslated("%s: yadm templates are not supported; translate it into a gslk template by hand", rel)
+			return alt, false
+		default:
+			m.untranslated("%s: condition %s is not supported; not migrated", rel, condition)
+			return alt, false
+		}
+	}
+	return alt, true
+}
+
+// yadmTemplate writes a template for target choosing between its alternates,
+// most specific first, as yadm does.
+func (m *migrator) yadmTemplate(target string, alts []yadmAlternate) error {
+	to := m.pkgName + "/" + target
+	if len(alts) == 1 && len(alts[0].conditions) == 0 {
+		return m.copyFile(alts[0].srcRel, to, 0)
+	}
+	slices.SortStableFunc(alts, func(a, b yadmAlternate) int { return len(b.conditions) - len(a.conditions) })
+
+	var text bytes.Buffer
+	var from []string
+	perm := os.FileMode(0644)
+	opened, hasDefault := false, false
+	for _, alt := range alts {
+		p := filepath.Join(m.src, filepath.FromSlash(alt.srcRel))
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data, 0) >= 0 {
+			m.untranslated("%s: binary alternates cannot be merged into a template; not migrated", alt.srcRel)
+			continue
+		}
+		if fi, err := os.Stat(p); err == nil && len(from) == 0 {
+			perm = fi.Mode().Perm()
+		}
+		from = append(from, alt.srcRel)
+
+		switch {
+		case len(alt.conditions) == 0:
+			hasDefault = true
+			if opened {
+				text.WriteString("{{ else }}")
+			}
+		case opened:
+			fmt.Fprintf(&text, "{{ else if %s }}", yadmCondition(alt.conditions))
+		default:
+			fmt.Fprintf(&text, "{{ if %s }}", yadmCondition(alt.conditions))
+			opened = true
+		}
+		// Keep text of the file that would start an action
+		text.Write(bytes.ReplaceAll(data, []byte("{{"), []byte(`{{"{{"}}`)))
+		if len(alt.conditions) == 0 {
+			break // Less specific alternates never apply
+		}
+	}
+	if len(from) == 0 {
+		return nil
+	}
+	if opened {
+		text.WriteString("{{ end }}")
+	}
+	if !hasDefault {
+		m.untranslated("%s has no ##default alternate; the rendered file is empty on other machines", target)
+	}
+
+	m.files = append(m.files, migrateFile{
+		MigratedFile: MigratedFile{From: strings.Join(from, ", "), To: to + DefaultTemplateSuffix},
+		data:         text.Bytes(),
+		perm:         perm,
+	})
+	return nil
+}
+
+// yadmCondition joins the template expressions of conditions with and.
+func yadmCondition(conditions []string) string {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return "and " + strings.Join(conditions, " ")
+}
@@ -0,0 +1,60 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultDirMode is the permissions of directories gslk creates when
+// Linker.DirMode is not set.
+const DefaultDirMode os.FileMode = 0755
+
+// FileMode sets the permissions of copied and rendered files matching Pattern.
+// Symbolic links have no permissions of their own, so links are unaffected.
+type FileMode struct {
+	Pattern string      // Pattern in .gslk-ignore syntax, matched against the package-relative path
+	Mode    os.FileMode // Permission bits
+}
+
+// ParsePerm parses octal permission bits such as "0755" or "600".
+func ParsePerm(s string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("invalid permissions %q: expected octal bits such as 0644", s)
+	}
+	return os.FileMode(perm), nil
+}
+
+// ParseFileMode parses a "pattern=mode" rule with an octal mode, such as "bin/*=0755".
+func ParseFileMode(s string) (FileMode, error) {
+	pattern, perm, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" {
+		return FileMode{}, fmt.Errorf("invalid file mode %q: expected pattern=mode", s)
+	}
+	if err := CheckPattern(pattern); err != nil {
+		return FileMode{}, fmt.Errorf("invalid file mode %q: %w", s, err)
+	}
+	mode, err := ParsePerm(perm)
+	if err != nil {
+		return FileMode{}, err
+	}
+	return FileMode{Pattern: pattern, Mode: mode}, nil
+}
+
+// filePerm returns the permissions for the package file relPath from the last
+// matching FileModes rule, or 0 to keep the default. Secrets never get group
+// or other permissions.
+func (l *Linker) filePerm(relPath string) os.FileMode {
+	var perm os.FileMode
+	for _, rule := range l.FileModes {
+		if isPathIgnored(relPath, []string{rule.Pattern}) {
+			perm = rule.Mode
+		}
+	}
+	if l.Mapper.IsSecret(relPath) {
+		perm &^= 0077
+	}
+	return perm
+}
@@ -0,0 +1,73 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileMode(t *testing.T) {
+	mode, err := ParseFileMode("bin/*=0755")
+	require.NoError(t, err)
+	assert.Equal(t, FileMode{Pattern: "bin/*", Mode: 0755}, mode)
+
+	for _, invalid := range []string{"bin/*", "=0755", "bin/*=0855", "bin/*=1777", "bin/*=rwx", "bin/[=0755"} {
+		_, err := ParseFileMode(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFilePerm(t *testing.T) {
+	linker := &Linker{
+		Mapper:    Mapper{SecretSuffix: DefaultSecretSuffix},
+		FileModes: []FileMode{{"*", 0644}, {"bin/*", 0755}, {"*.age", 0644}},
+	}
+	assert.Equal(t, os.FileMode(0755), linker.filePerm(filepath.FromSlash("bin/tool")))
+	assert.Equal(t, os.FileMode(0644), linker.filePerm(".bashrc"))
+	assert.Equal(t, os.FileMode(0600), linker.filePerm("token.age"), "Secrets are only readable by their owner")
+
+	assert.Equal(t, os.FileMode(0), (&Linker{}).filePerm(".bashrc"))
+}
+
+func TestCopyModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows does not have Unix permission bits")
+	}
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "tools"), map[string]string{
+		"bin/tool":         "#!/bin/sh",
+		".config/app.conf": "conf",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mode:      ModeCopy,
+		DirMode:   0700,
+		FileModes: []FileMode{{"bin/*", 0755}},
+	}
+	_, err := linker.Link([]string{"tools"})
+	require.NoError(t, err)
+
+	perm := func(rel string) os.FileMode {
+		fi, err := os.Stat(filepath.Join(targetDir, rel))
+		require.NoError(t, err)
+		return fi.Mode().Perm()
+	}
+	assert.Equal(t, os.FileMode(0755), perm("bin/tool"))
+	assert.Equal(t, os.FileMode(0700), perm("bin"))
+	assert.Equal(t, os.FileMode(0700), perm(".config"))
+
+	// Changed permissions are applied to up-to-date copies
+	linker.FileModes = []FileMode{{"bin/*", 0700}}
+	result, err := linker.Link([]string{"tools"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, "bin/tool")}, result.Linked)
+	assert.Equal(t, os.FileMode(0700), perm("bin/tool"))
+}
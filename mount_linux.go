@@ -0,0 +1,22 @@
+package gslk
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// BindMount bind-mounts the directory source onto target with mount(2).
+func (OSFS) BindMount(source, target string) error {
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND, ""); err != nil {
+		return &fs.PathError{Op: "mount", Path: target, Err: err}
+	}
+	return nil
+}
+
+// Unmount undoes the mount at target with umount(2).
+func (OSFS) Unmount(target string) error {
+	if err := syscall.Unmount(target, 0); err != nil {
+		return &fs.PathError{Op: "unmount", Path: target, Err: err}
+	}
+	return nil
+}
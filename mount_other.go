@@ -0,0 +1,19 @@
+//go:build !linux && !windows
+
+package gslk
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// errNoBindMounts is returned for bind mounts on platforms without them.
+var errNoBindMounts = errors.New("bind mounts are only supported on Linux and Windows")
+
+func (OSFS) BindMount(source, target string) error {
+	return &fs.PathError{Op: "mount", Path: target, Err: errNoBindMounts}
+}
+
+func (OSFS) Unmount(target string) error {
+	return &fs.PathError{Op: "unmount", Path: target, Err: errNoBindMounts}
+}
@@ -0,0 +1,89 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanBind(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "app"), map[string]string{
+		"config/settings.json": "{}",
+		"config/themes/dark":   "dark",
+		".apprc":               "rc",
+		ManifestFileName:       "bind = [\"config\"]\n",
+	})
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+
+	// The directory is mounted whole; its contents are not planned
+	plan, err := linker.PlanLink([]string{"app"})
+	require.NoError(t, err)
+	var kinds []string
+	for _, op := range plan.Operations {
+		kinds = append(kinds, string(op.Kind)+" "+op.RelPath)
+	}
+	assert.ElementsMatch(t, []string{"link .apprc", "mount config"}, kinds)
+
+	// An empty mount point is mounted over, anything else in it is a conflict
+	mountPoint := filepath.Join(targetDir, "config")
+	require.NoError(t, os.Mkdir(mountPoint, 0755))
+	_, err = linker.PlanLink([]string{"app"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mountPoint, "local"), nil, 0644))
+	_, err = linker.PlanLink([]string{"app"})
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestBindMount(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	probe := t.TempDir()
+	if err := (OSFS{}).BindMount(sourceDir, probe); err != nil {
+		t.Skipf("bind mounts are not available: %v", err)
+	}
+	require.NoError(t, OSFS{}.Unmount(probe))
+
+	createDummyPackage(t, filepath.Join(sourceDir, "app"), map[string]string{
+		"share/app/config/settings.json": "{}",
+		ManifestFileName:                 "bind = [\"share/app/config\"]\n",
+	})
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	result, err := linker.Link([]string{"app"})
+	require.NoError(t, err)
+	mountPoint := filepath.Join(targetDir, "share", "app", "config")
+	defer OSFS{}.Unmount(mountPoint)
+	assert.Equal(t, []string{mountPoint}, result.Linked)
+
+	// The files are reached through the mount, not through links
+	fi, err := os.Lstat(filepath.Join(mountPoint, "settings.json"))
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular())
+	state, err := linker.loadState()
+	require.NoError(t, err)
+	entry, ok := state.Lookup(mountPoint)
+	require.True(t, ok)
+	assert.Equal(t, ModeBind, entry.Mode)
+
+	result, err = linker.Link([]string{"app"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{mountPoint}, result.Skipped)
+
+	// Unlinking unmounts and removes the mount points gslk created
+	result, err = linker.Unlink([]string{"app"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{mountPoint}, result.Unlinked)
+	assert.NoDirExists(t, filepath.Join(targetDir, "share"))
+	assert.FileExists(t, filepath.Join(sourceDir, "app", "share", "app", "config", "settings.json"))
+	state, err = linker.loadState()
+	require.NoError(t, err)
+	assert.Empty(t, state.Entries)
+}
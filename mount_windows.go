@@ -0,0 +1,85 @@
+package gslk
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+// BindMount links target to the directory source with a junction, made on
+// the empty directory the mount point may be or on a new one. The junction is
+// written as a reparse point directly, as mklink /J would, without running
+// package paths through cmd.exe.
+func (OSFS) BindMount(source, target string) error {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return &fs.PathError{Op: "mount", Path: target, Err: err}
+	}
+	created := false
+	if fi, err := os.Lstat(target); err != nil || !fi.IsDir() {
+		if err := os.Mkdir(target, DefaultDirMode); err != nil {
+			return &fs.PathError{Op: "mount", Path: target, Err: err}
+		}
+		created = true
+	}
+	if err := setJunction(target, source); err != nil {
+		if created {
+			os.Remove(target)
+		}
+		return &fs.PathError{Op: "mount", Path: target, Err: err}
+	}
+	return nil
+}
+
+// setJunction turns the empty directory dir into a junction to the absolute
+// path dest, with a mount point reparse point.
+func setJunction(dir, dest string) error {
+	// The substitute name is the NT path, the print name the one shown to
+	// users; both are stored NUL-terminated, one after the other
+	substitute := utf16.Encode([]rune(`\??\` + dest))
+	display := utf16.Encode([]rune(dest))
+	names := make([]uint16, 0, len(substitute)+len(display)+2)
+	names = append(append(names, substitute...), 0)
+	names = append(append(names, display...), 0)
+
+	buf := make([]byte, 16+2*len(names))
+	binary.LittleEndian.PutUint32(buf[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(buf)-8))             // ReparseDataLength
+	binary.LittleEndian.PutUint16(buf[8:], 0)                              // SubstituteNameOffset
+	binary.LittleEndian.PutUint16(buf[10:], uint16(2*len(substitute)))     // SubstituteNameLength
+	binary.LittleEndian.PutUint16(buf[12:], uint16(2*(len(substitute)+1))) // PrintNameOffset
+	binary.LittleEndian.PutUint16(buf[14:], uint16(2*len(display)))        // PrintNameLength
+	for i, c := range names {
+		binary.LittleEndian.PutUint16(buf[16+2*i:], c)
+	}
+
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(path, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	var returned uint32
+	return windows.DeviceIoControl(h, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
+
+// Unmount removes the junction at target, which leaves the directory it links
+// to alone, and puts an empty directory back, as unmounting leaves the mount
+// point behind.
+func (OSFS) Unmount(target string) error {
+	if err := os.Remove(target); err != nil {
+		return &fs.PathError{Op: "unmount", Path: target, Err: err}
+	}
+	if err := os.Mkdir(target, DefaultDirMode); err != nil {
+		return &fs.PathError{Op: "unmount", Path: target, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package gslk
+
+import (
+	"os"
+	"regexp"
+	"time"
+)
+
+// Option configures a Linker created with New.
+type Option func(*Linker)
+
+// New returns a Linker linking packages from sourceDir into targetDir,
+// configured by opts. It is equivalent to setting the corresponding fields of
+// a Linker literal, but keeps working as Linker grows internal state.
+func New(sourceDir, targetDir string, opts ...Option) *Linker {
+	l := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// ForTarget returns a copy of the Linker deploying into targetDir instead,
+// sharing the rest of its configuration. The copy may be used concurrently
+// with the original, for instance to manage the targets of several users.
+func (l *Linker) ForTarget(targetDir string) *Linker {
+	linker := *l
+	linker.TargetDir = targetDir
+	return &linker
+}
+
+// WithExtraSources layers further source directories over the main one.
+func WithExtraSources(dirs ...string) Option {
+	return func(l *Linker) { l.ExtraSources = append(l.ExtraSources, dirs...) }
+}
+
+// WithDryRun makes the Linker report what it would do without changing anything.
+func WithDryRun() Option {
+	return func(l *Linker) { l.DryRun = true }
+}
+
+// WithReadOnly makes the Linker refuse every change to the target with an
+// error wrapping ErrReadOnly, whatever its other options say.
+func WithReadOnly() Option {
+	return func(l *Linker) { l.ReadOnly = true }
+}
+
+// WithVerbose enables verbose progress messages.
+func WithVerbose() Option {
+	return func(l *Linker) { l.Verbose = true }
+}
+
+// WithLogger sends progress messages to logger instead of standard output.
+func WithLogger(logger Logger) Option {
+	return func(l *Linker) { l.Logger = logger }
+}
+
+// WithTracer times Link, Unlink, Relink and planning in spans started by tracer.
+func WithTracer(tracer Tracer) Option {
+	return func(l *Linker) { l.Tracer = tracer }
+}
+
+// WithForceRemove moves non-empty directories gslk created and locally
+// modified copies to the trash when unlinking.
+func WithForceRemove() Option {
+	return func(l *Linker) { l.ForceRemove = true }
+}
+
+// WithEphemeral copies every file and records what is deployed in the
+// teardown manifest (see Linker.Ephemeral and Teardown).
+func WithEphemeral() Option {
+	return func(l *Linker) { l.Ephemeral = true }
+}
+
+// WithMode sets how files are placed into the target directory.
+func WithMode(mode DeployMode) Option {
+	return func(l *Linker) { l.Mode = mode }
+}
+
+// WithConflictPolicy sets what happens to files occupying a target path.
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(l *Linker) { l.ConflictPolicy = policy }
+}
+
+// WithConflictResolver lets r decide what happens to files occupying a target path.
+func WithConflictResolver(r ConflictResolver) Option {
+	return func(l *Linker) { l.ConflictResolver = r }
+}
+
+// WithMapper sets how package paths map to target paths.
+func WithMapper(mapper Mapper) Option {
+	return func(l *Linker) { l.Mapper = mapper }
+}
+
+// WithVars sets the variables available to templates.
+func WithVars(vars map[string]string) Option {
+	return func(l *Linker) { l.Vars = vars }
+}
+
+// WithAge sets how secrets are encrypted and decrypted.
+func WithAge(age Age) Option {
+	return func(l *Linker) { l.Age = age }
+}
+
+// WithOverlay lets packages listed later override files of earlier packages.
+func WithOverlay() Option {
+	return func(l *Linker) { l.Overlay = true }
+}
+
+// WithFilesystemPolicy decides what happens when the target directory is on a
+// filesystem without symbolic links or on a network filesystem.
+func WithFilesystemPolicy(policy FilesystemPolicy) Option {
+	return func(l *Linker) { l.FilesystemPolicy = policy }
+}
+
+// WithRetries retries creating and removing files in the target up to n times
+// after transient errors, waiting backoff before the first retry and twice as
+// long before each further one.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(l *Linker) { l.Retries, l.RetryBackoff = n, backoff }
+}
+
+// WithThrottle limits gslk to about opsPerSecond filesystem operations a
+// second (see Linker.Throttle); 0 means no limit.
+func WithThrottle(opsPerSecond int) Option {
+	return func(l *Linker) { l.Throttle = NewThrottle(opsPerSecond) }
+}
+
+// WithConcurrency plans up to n independent packages and processes up to n
+// files in parallel.
+func WithConcurrency(n int) Option {
+	return func(l *Linker) { l.Concurrency = n }
+}
+
+// WithFS makes the Linker walk packages and manage links on fsys instead of
+// the local filesystem.
+func WithFS(fsys FS) Option {
+	return func(l *Linker) { l.FS = fsys }
+}
+
+// WithNoVerify skips the check after unlinking that no file is still deployed.
+func WithNoVerify() Option {
+	return func(l *Linker) { l.NoVerify = true }
+}
+
+// WithConfirm asks confirm before files the user may still want are moved
+// aside or deleted.
+func WithConfirm(confirm func(question string) (bool, error)) Option {
+	return func(l *Linker) { l.Confirm = confirm }
+}
+
+// WithStrictEnv makes undefined environment variables in the paths of
+// package manifests an error.
+func WithStrictEnv(strict bool) Option {
+	return func(l *Linker) { l.StrictEnv = strict }
+}
+
+// WithStrictIgnore makes invalid patterns in the ignore and include files and
+// manifests of packages an error.
+func WithStrictIgnore(strict bool) Option {
+	return func(l *Linker) { l.StrictIgnore = strict }
+}
+
+// WithResolveSources makes links point at the real location of package files
+// when source directories are reached through symbolic links.
+func WithResolveSources() Option {
+	return func(l *Linker) { l.ResolveSources = true }
+}
+
+// WithIgnore skips paths matching patterns in every package.
+func WithIgnore(patterns ...string) Option {
+	return func(l *Linker) { l.Ignore = append(l.Ignore, patterns...) }
+}
+
+// WithOnly restricts every package to the files matching patterns.
+func WithOnly(patterns ...string) Option {
+	return func(l *Linker) { l.Only = append(l.Only, patterns...) }
+}
+
+// WithPathMapper passes every target path the Mapper computes through m.
+func WithPathMapper(m PathMapper) Option {
+	return func(l *Linker) { l.PathMapper = m }
+}
+
+// WithIgnoreMatcher skips the package paths m matches in every package.
+func WithIgnoreMatcher(m IgnoreMatcher) Option {
+	return func(l *Linker) { l.IgnoreMatcher = m }
+}
+
+// WithIgnoreRegexp skips package paths matching one of res in every package.
+func WithIgnoreRegexp(res ...*regexp.Regexp) Option {
+	return func(l *Linker) { l.IgnoreRegexp = append(l.IgnoreRegexp, res...) }
+}
+
+// WithMaxDepth skips package paths more than depth levels below the package
+// root.
+func WithMaxDepth(depth int) Option {
+	return func(l *Linker) { l.MaxDepth = depth }
+}
+
+// WithSkipHidden skips hidden files and directories nested inside packages.
+func WithSkipHidden() Option {
+	return func(l *Linker) { l.SkipHidden = true }
+}
+
+// WithDefer leaves target paths matching one of res to the package they are
+// already linked from.
+func WithDefer(res ...*regexp.Regexp) Option {
+	return func(l *Linker) { l.Defer = append(l.Defer, res...) }
+}
+
+// WithOverride relinks target paths matching one of res that are linked from
+// another package.
+func WithOverride(res ...*regexp.Regexp) Option {
+	return func(l *Linker) { l.Override = append(l.Override, res...) }
+}
+
+// WithUnlinkDependents makes Unlink also unlink the packages depending on the
+// given ones.
+func WithUnlinkDependents() Option {
+	return func(l *Linker) { l.UnlinkDependents = true }
+}
+
+// WithGroups lets the names of groups stand for their member packages.
+func WithGroups(groups map[string][]string) Option {
+	return func(l *Linker) { l.Groups = groups }
+}
+
+// WithPackageDirs only treats the directories of the source directories
+// matching one of patterns as packages.
+func WithPackageDirs(patterns ...string) Option {
+	return func(l *Linker) { l.PackageDirs = append(l.PackageDirs, patterns...) }
+}
+
+// WithDirMode creates directories with permissions mode.
+func WithDirMode(mode os.FileMode) Option {
+	return func(l *Linker) { l.DirMode = mode }
+}
+
+// WithFileModes sets the permissions of copied and rendered files matching
+// the rules' patterns.
+func WithFileModes(modes ...FileMode) Option {
+	return func(l *Linker) { l.FileModes = append(l.FileModes, modes...) }
+}
+
+// WithAllowRoot lets the Linker, when run as root, manage files of any user.
+func WithAllowRoot() Option {
+	return func(l *Linker) { l.AllowRoot = true }
+}
+
+// WithEvents sends an Event to handler for every operation applied and every
+// conflict found. See JSONEvents.
+func WithEvents(handler func(Event)) Option {
+	return func(l *Linker) { l.OnEvent = handler }
+}
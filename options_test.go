@@ -0,0 +1,97 @@
+package gslk
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var logged bytes.Buffer
+	logger := log.New(&logged, "", 0)
+
+	linker := New("/src", "/dst",
+		WithExtraSources("/overlay"),
+		WithDryRun(),
+		WithLogger(logger),
+		WithConflictPolicy(ConflictBackup),
+		WithMode(ModeCopy),
+	)
+
+	assert.Equal(t, "/src", linker.SourceDir)
+	assert.Equal(t, "/dst", linker.TargetDir)
+	assert.Equal(t, []string{"/overlay"}, linker.ExtraSources)
+	assert.True(t, linker.DryRun)
+	assert.Equal(t, ConflictBackup, linker.ConflictPolicy)
+	assert.Equal(t, ModeCopy, linker.Mode)
+	assert.Same(t, logger, linker.Logger)
+}
+
+func TestForTarget(t *testing.T) {
+	linker := New("/src", "/dst", WithMode(ModeCopy))
+	other := linker.ForTarget("/home/other")
+
+	assert.Equal(t, "/home/other", other.TargetDir)
+	assert.Equal(t, "/src", other.SourceDir)
+	assert.Equal(t, ModeCopy, other.Mode)
+	assert.Equal(t, "/dst", linker.TargetDir)
+}
+
+func TestWithLoggerCapturesOutput(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{"file.txt": "x"})
+
+	var logged bytes.Buffer
+	linker := New(sourceDir, targetDir, WithLogger(log.New(&logged, "", 0)))
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	assert.Contains(t, logged.String(), "Linking: "+filepath.Join(sourceDir, "pkg", "file.txt"))
+	_, err = os.Lstat(filepath.Join(targetDir, "file.txt"))
+	assert.NoError(t, err)
+}
+
+// levelRecorder is a LevelLogger recording the messages of each level.
+type levelRecorder map[Level][]string
+
+func (r levelRecorder) Printf(format string, args ...any) {
+	r.Logf(LevelInfo, format, args...)
+}
+
+func (r levelRecorder) Logf(level Level, format string, args ...any) {
+	r[level] = append(r[level], fmt.Sprintf(format, args...))
+}
+
+func TestWithLoggerLevels(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"file.txt":     "x",
+		"notes.bak":    "x",
+		".gslk-ignore": "*.bak\n",
+	})
+
+	logged := levelRecorder{}
+	linker := New(sourceDir, targetDir, WithLogger(logged), WithVerbose(), WithMode(ModeCopy))
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	assert.Contains(t, logged[LevelInfo], "Copying: "+filepath.Join(sourceDir, "pkg", "file.txt")+" -> "+filepath.Join(targetDir, "file.txt")+"\n")
+	assert.Contains(t, logged[LevelDebug], "Ignoring notes.bak (matches ignore pattern)\n")
+	assert.Empty(t, logged[LevelWarn])
+
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "file.txt"), []byte("edited"), 0644))
+	linker.ForceRemove = true
+	_, err = linker.Unlink([]string{"pkg"})
+	require.NoError(t, err)
+	assert.Contains(t, logged[LevelWarn], "Warning: removing locally modified copy "+filepath.Join(targetDir, "file.txt")+"\n")
+}
@@ -0,0 +1,137 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultScanIgnore lists the directories ScanOrphans skips unless told
+// otherwise: version control data, caches and the directories gslk keeps set
+// aside files in.
+var DefaultScanIgnore = []string{".git", ".cache", "node_modules", QuarantineDirName, TrashDirName, GenerationsDirName}
+
+// ScanOrphans walks the whole target directory, unlike Doctor, and reports
+// every symlink pointing into a source directory that is not the link of a
+// current package file: links into packages that no longer exist, to files
+// that were deleted or are now ignored, or at a path the package no longer
+// links the file at. No state manifest is needed.
+//
+// Directories deeper than maxDepth below the target directory are not entered
+// (0 means no limit), and paths matching one of ignore, in .gslk-ignore syntax
+// relative to the target directory, are skipped. Source directories inside
+// the target directory are never scanned. Nothing is modified.
+func (l *Linker) ScanOrphans(maxDepth int, ignore []string) ([]Finding, error) {
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	// Where each package file is linked, by package name and package path
+	linked := make(map[[2]string]string)
+	known := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		known[pkg.Name] = true
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if !path.isDir {
+				linked[[2]string{pkg.Name, path.relPath}] = path.targetPath
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrPermission) {
+			return nil, err
+		}
+	}
+
+	var sources []string
+	for _, source := range l.sourceDirs() {
+		if abs, err := filepath.Abs(source); err == nil {
+			sources = append(sources, abs)
+		}
+	}
+	rules := NewIgnoreRules(ignore)
+
+	var findings []Finding
+	err = filepath.WalkDir(l.TargetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != l.TargetDir {
+				return fs.SkipDir // Unreadable directories are reported by Doctor
+			}
+			return err
+		}
+		if path == l.TargetDir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.TargetDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rules.Match(rel) || slices.Contains(sources, path) || (maxDepth > 0 && strings.Count(rel, string(filepath.Separator)) >= maxDepth) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&os.ModeSymlink == 0 || rules.Match(rel) {
+			return nil
+		}
+
+		if finding, ok := l.checkOrphan(path, linked, known); ok {
+			findings = append(findings, finding)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", l.TargetDir, err)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+	return findings, nil
+}
+
+// checkOrphan reports the symlink at linkPath if it points into a source
+// directory but is not where linked says a package file is linked. known holds
+// the names of the current packages.
+func (l *Linker) checkOrphan(linkPath string, linked map[[2]string]string, known map[string]bool) (Finding, bool) {
+	dest, err := resolveLinkTarget(l.FS, linkPath)
+	if err != nil {
+		return Finding{}, false
+	}
+	absSource, rel, ok := l.containingSource(dest)
+	if !ok {
+		return Finding{}, false
+	}
+
+	pkgName, relPath, _ := strings.Cut(rel, string(filepath.Separator))
+	target, isLinked := linked[[2]string{pkgName, relPath}]
+	_, statErr := os.Stat(dest)
+	finding := Finding{Path: linkPath, Kind: FindingOrphanLink, Packages: []string{pkgName}}
+
+	switch {
+	case isLinked && target == linkPath:
+		return Finding{}, false
+	case isLinked:
+		finding.Message = fmt.Sprintf("links %s, which package %s now links at %s", dest, pkgName, target)
+		finding.Fix = fmt.Sprintf("remove the link (rm %s) and relink package %s", linkPath, pkgName)
+	case !known[pkgName]:
+		finding.Packages = nil
+		finding.Message = fmt.Sprintf("points to %s, but there is no package %s in %s", dest, pkgName, absSource)
+		finding.Fix = fmt.Sprintf("remove the link (rm %s); the package was probably renamed or deleted", linkPath)
+	case statErr != nil:
+		finding.Kind = FindingBrokenLink
+		finding.Message = fmt.Sprintf("points to %s, which no longer exists in package %s", dest, pkgName)
+		finding.Fix = fmt.Sprintf("remove the link (rm %s) or restore the file in the package", linkPath)
+	default:
+		finding.Message = fmt.Sprintf("points to %s, which package %s does not link (it is ignored or not a file)", dest, pkgName)
+		finding.Fix = fmt.Sprintf("remove the link (rm %s)", linkPath)
+	}
+	return finding, true
+}
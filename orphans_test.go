@@ -0,0 +1,57 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanOrphans(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "vim")
+	createDummyPackage(t, pkgPath, map[string]string{".vimrc": "vim", "notes.md": "notes", IgnoreFileName: "notes.md"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"vim"})
+	require.NoError(t, err)
+
+	deep := filepath.Join(targetDir, "a", "b", "c")
+	require.NoError(t, os.MkdirAll(deep, 0755))
+	links := map[string]string{
+		filepath.Join(targetDir, "a", "gone"):       filepath.Join(sourceDir, "emacs", ".emacs"),
+		filepath.Join(targetDir, "a", "b", "vimrc"): filepath.Join(pkgPath, ".vimrc"),
+		filepath.Join(targetDir, "a", "deleted"):    filepath.Join(pkgPath, ".gvimrc"),
+		filepath.Join(targetDir, "notes.md"):        filepath.Join(pkgPath, "notes.md"),
+		filepath.Join(deep, "too-deep"):             filepath.Join(pkgPath, ".vimrc"),
+		filepath.Join(targetDir, "elsewhere"):       "/etc/hosts",
+	}
+	for link, dest := range links {
+		require.NoError(t, os.Symlink(dest, link))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(targetDir, ".git"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, ".vimrc"), filepath.Join(targetDir, ".git", "skipped")))
+
+	findings, err := linker.ScanOrphans(2, DefaultScanIgnore)
+	require.NoError(t, err)
+
+	byPath := make(map[string]Finding)
+	for _, f := range findings {
+		byPath[f.Path] = f
+	}
+	assert.Len(t, byPath, 4)
+	assert.Equal(t, FindingOrphanLink, byPath[filepath.Join(targetDir, "a", "gone")].Kind)
+	assert.Contains(t, byPath[filepath.Join(targetDir, "a", "gone")].Message, "there is no package emacs")
+	assert.Contains(t, byPath[filepath.Join(targetDir, "a", "b", "vimrc")].Message, "now links at "+filepath.Join(targetDir, ".vimrc"))
+	assert.Equal(t, FindingBrokenLink, byPath[filepath.Join(targetDir, "a", "deleted")].Kind)
+	assert.Contains(t, byPath[filepath.Join(targetDir, "notes.md")].Message, "does not link")
+
+	// Without a depth limit the deepest link is found too
+	findings, err = linker.ScanOrphans(0, DefaultScanIgnore)
+	require.NoError(t, err)
+	assert.Len(t, findings, 5)
+}
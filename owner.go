@@ -0,0 +1,131 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Owner describes where a file in the target tree comes from.
+type Owner struct {
+	Package string     // Name of the package providing the file
+	Source  string     // Absolute path of the file in the package
+	RelPath string     // Path of the file relative to the package root
+	Target  string     // Absolute path in the target tree
+	Mode    DeployMode // How the file was deployed
+	Missing bool       // The source file no longer exists (the link is broken)
+	Drifted bool       // A deployed copy was modified since it was deployed
+}
+
+// Owner reports which package and source file the target path belongs to.
+// Symlinks are resolved against the source directory; copies and rendered
+// files are looked up in the state manifest. Paths gslk does not manage are
+// reported as an error.
+func (l *Linker) Owner(path string) (Owner, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Owner{}, fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+	}
+
+	fi, err := orOS(l.FS).Lstat(absPath)
+	if err != nil {
+		return Owner{}, fmt.Errorf("failed to stat %s: %w", absPath, err)
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return l.linkOwner(absPath)
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return Owner{}, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	entry, ok := state.Lookup(absPath)
+	if !ok {
+		return Owner{}, fmt.Errorf("%s is not managed by gslk", absPath)
+	}
+
+	owner := Owner{
+		Package: entry.Package,
+		Source:  entry.Source,
+		Target:  absPath,
+		Mode:    entry.Mode,
+	}
+	if _, rel, ok := l.containingSource(entry.Source); ok {
+		if parts := strings.SplitN(rel, string(filepath.Separator), 2); len(parts) == 2 {
+			owner.RelPath = parts[1]
+		}
+	}
+	if _, err := os.Stat(entry.Source); os.IsNotExist(err) {
+		owner.Missing = true
+	}
+	if hash, err := hashFile(absPath); err == nil && hash != entry.Hash {
+		owner.Drifted = true
+	}
+	return owner, nil
+}
+
+// linkOwner resolves the symlink at linkPath to a package in the source directory.
+func (l *Linker) linkOwner(linkPath string) (Owner, error) {
+	dest, err := resolveLinkTarget(l.FS, linkPath)
+	if err != nil {
+		return Owner{}, err
+	}
+
+	_, rel, ok := l.containingSource(dest)
+	if !ok {
+		return Owner{}, fmt.Errorf("%s links to %s, outside the source directories %s", linkPath, dest, strings.Join(l.sourceDirs(), ", "))
+	}
+
+	parts := strings.SplitN(rel, string(filepath.Separator), 2)
+	if len(parts) < 2 {
+		return Owner{}, fmt.Errorf("%s links to %s, which is a package directory rather than a package file", linkPath, dest)
+	}
+
+	owner := Owner{
+		Package: parts[0],
+		Source:  dest,
+		RelPath: parts[1],
+		Target:  linkPath,
+		Mode:    ModeLink,
+	}
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		owner.Missing = true
+	}
+	return owner, nil
+}
+
+// containingSource returns the absolute source directory path lies in and
+// the path relative to it. Later sources are checked first, so nested source
+// directories resolve to the innermost one listed last. Symbolic links are
+// resolved on both sides if the paths do not match as they are, so a link
+// into a symlinked source directory is recognised whichever of its paths it
+// was created with.
+func (l *Linker) containingSource(path string) (string, string, bool) {
+	sources := l.sourceDirs()
+	for _, resolve := range []bool{false, true} {
+		candidate := path
+		if resolve {
+			candidate = resolveParent(path)
+		}
+		for i := len(sources) - 1; i >= 0; i-- {
+			absSource, err := filepath.Abs(sources[i])
+			if err != nil {
+				continue
+			}
+			if resolve {
+				absSource = resolveDir(absSource)
+			}
+			if isSubPath(absSource, candidate) {
+				rel, err := filepath.Rel(absSource, candidate)
+				if err != nil {
+					continue
+				}
+				return absSource, rel, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,74 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwner(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "nvim")
+	createDummyPackage(t, pkgPath, map[string]string{
+		".config/nvim/init.lua": "vim.opt.number = true",
+		"copied.conf":           "copied",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"nvim"})
+	require.NoError(t, err)
+
+	linkPath := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+	owner, err := linker.Owner(linkPath)
+	require.NoError(t, err)
+	assert.Equal(t, Owner{
+		Package: "nvim",
+		Source:  filepath.Join(pkgPath, ".config", "nvim", "init.lua"),
+		RelPath: filepath.Join(".config", "nvim", "init.lua"),
+		Target:  linkPath,
+		Mode:    ModeLink,
+	}, owner)
+
+	// Copies are found through the state manifest
+	copyPath := filepath.Join(targetDir, "copied.conf")
+	require.NoError(t, os.Remove(copyPath))
+	deployCopy(t, linker, "nvim", "copied.conf", ModeCopy)
+	owner, err = linker.Owner(copyPath)
+	require.NoError(t, err)
+	assert.Equal(t, "nvim", owner.Package)
+	assert.Equal(t, "copied.conf", owner.RelPath)
+	assert.Equal(t, ModeCopy, owner.Mode)
+	assert.False(t, owner.Drifted)
+
+	require.NoError(t, os.WriteFile(copyPath, []byte("edited"), 0644))
+	owner, err = linker.Owner(copyPath)
+	require.NoError(t, err)
+	assert.True(t, owner.Drifted, "Edited copies should be reported as drifted")
+
+	// Broken links still report their package
+	require.NoError(t, os.Remove(filepath.Join(pkgPath, ".config", "nvim", "init.lua")))
+	owner, err = linker.Owner(linkPath)
+	require.NoError(t, err)
+	assert.True(t, owner.Missing)
+}
+
+func TestOwnerUnmanaged(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	userFile := filepath.Join(targetDir, "user.conf")
+	require.NoError(t, os.WriteFile(userFile, []byte("mine"), 0644))
+	foreignLink := filepath.Join(targetDir, "foreign")
+	require.NoError(t, os.Symlink(userFile, foreignLink))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	for _, path := range []string{userFile, foreignLink, filepath.Join(targetDir, "missing")} {
+		_, err := linker.Owner(path)
+		assert.Error(t, err, "%s should not have an owner", path)
+	}
+}
@@ -0,0 +1,714 @@
+package gslk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OpKind identifies the filesystem operation an Operation performs.
+type OpKind string
+
+const (
+	OpMkdir      OpKind = "mkdir"      // Create a directory in the target
+	OpLink       OpKind = "link"       // Create a symlink to the source file
+	OpCopy       OpKind = "copy"       // Copy the source file into the target
+	OpRender     OpKind = "render"     // Write content generated from the source (a template or secret) into the target
+	OpQuarantine OpKind = "quarantine" // Move a conflicting file into the quarantine directory
+	OpUnlink     OpKind = "unlink"     // Remove a symlink to the source file
+	OpRemove     OpKind = "remove"     // Remove a deployed copy or rendered file
+	OpRmdir      OpKind = "rmdir"      // Release a directory gslk created, removing it once unused
+	OpMount      OpKind = "mount"      // Bind-mount the source directory onto the target
+	OpUnmount    OpKind = "unmount"    // Undo a bind mount recorded in state
+	OpSkip       OpKind = "skip"       // Leave the target as it is
+)
+
+// Operation is a single step of a Plan.
+type Operation struct {
+	Kind      OpKind
+	Package   string
+	RelPath   string      // Path relative to the package root
+	Source    string      // Absolute source path
+	Target    string      // Absolute target path
+	Current   TargetState // What occupies Target before the operation
+	Mode      DeployMode  // Deployment mode of the file being written (OpRender) or removed (OpRemove)
+	Hash      string      // Checksum of the deployed content (OpCopy, OpRender)
+	Perm      os.FileMode // Permissions of the written file (OpCopy, OpRender); 0 keeps the default
+	Content   []byte      // Generated content (OpRender)
+	Reason    string      // Why the target is left alone (OpSkip)
+	Adopt     bool        // Target is a file identical to what is deployed, replaced in place (ConflictAdoptIdentical)
+	Overwrite bool        // Target is replaced in place as decided by the ConflictResolver
+}
+
+// Plan is an ordered list of operations. Plans are computed without touching
+// the filesystem and applied by an Executor.
+type Plan struct {
+	Operations []Operation
+	Ignored    []string  // Source paths excluded by ignore rules
+	Packages   []Package // Packages the plan was computed for
+}
+
+// add appends op to the plan.
+func (p *Plan) add(op Operation) {
+	p.Operations = append(p.Operations, op)
+}
+
+// PlanLink computes the operations needed to link the given packages.
+// Conflicts are reported as errors unless the conflict policy resolves them.
+func (l *Linker) PlanLink(packageNames []string) (*Plan, error) {
+	span := l.startSpan("gslk.PlanLink", Attr{"gslk.packages", packageNames})
+	state, err := l.loadState()
+	if err != nil {
+		err = fmt.Errorf("failed to load state: %w", err)
+		span.End(err)
+		return nil, err
+	}
+	plan, err := l.planLink(span, packageNames, state)
+	span.End(err)
+	return plan, err
+}
+
+// PlanUnlink computes the operations needed to unlink the given packages.
+func (l *Linker) PlanUnlink(packageNames []string) (*Plan, error) {
+	span := l.startSpan("gslk.PlanUnlink", Attr{"gslk.packages", packageNames})
+	state, err := l.loadState()
+	if err != nil {
+		err = fmt.Errorf("failed to load state: %w", err)
+		span.End(err)
+		return nil, err
+	}
+	plan, err := l.planUnlink(span, packageNames, state)
+	span.End(err)
+	return plan, err
+}
+
+// planLink computes the link plan for packageNames against state, timing each
+// package in a span nested in span. Packages are planned while they are
+// walked, so a conflict stops planning as soon as it is found. With Overlay,
+// the package deploying each target is only known once every package has been
+// seen, so the packages are walked once beforehand.
+func (l *Linker) planLink(span Span, packageNames []string, state *State) (*Plan, error) {
+	packages, err := l.lookupPackages(packageNames)
+	if err != nil {
+		return nil, err
+	}
+	if packages, err = l.withDependencies(packages); err != nil {
+		return nil, err
+	}
+	if missing := l.missingVars(packages); len(missing) > 0 {
+		return nil, fmt.Errorf("package %s needs template variable %s, which is not set", missing[0].Package, missing[0].Name)
+	}
+
+	var providers map[string]string
+	if l.Overlay && len(packages) > 1 {
+		if providers, err = l.resolveOverlaps(packages); err != nil {
+			return nil, err
+		}
+	}
+
+	mode, err := l.deployMode()
+	if err != nil {
+		return nil, err
+	}
+	if l.Concurrency > 1 && len(packages) > 1 {
+		return l.planLinkParallel(span, packages, state, mode, providers)
+	}
+
+	plan := &Plan{Packages: packages}
+	overlaps := newOverlaps()
+	classifier := newSnapshotClassifier(state, l.FS)
+	planned := 0
+	for _, pkg := range packages {
+		if err := l.planPackage(span, plan, classifier, overlaps, mode, pkg, providers, &planned); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// planPackage adds the operations linking pkg to plan. planned counts the
+// paths planned so far, for EventPlanned.
+func (l *Linker) planPackage(span Span, plan *Plan, classifier *Classifier, overlaps *overlaps, mode DeployMode, pkg Package, providers map[string]string, planned *int) error {
+	pkgSpan := span.Start("gslk.plan.package", Attr{"gslk.package", pkg.Name})
+	ignored, err := l.walkPackage(pkg, func(path pathInfo) error {
+		provider := pkg.Name
+		if providers != nil {
+			provider = providers[path.targetPath]
+		} else if err := l.addOverlap(overlaps, pkg.Name, path); err != nil {
+			return err
+		}
+		if err := l.planPath(plan, classifier, mode, pkg, path, provider); err != nil {
+			return err
+		}
+		if l.OnEvent != nil {
+			*planned++
+			l.emit(Event{Time: time.Now(), Type: EventPlanned, Package: pkg.Name, Source: path.sourcePath, Target: path.targetPath, Done: *planned})
+		}
+		return nil
+	})
+	pkgSpan.End(err)
+	plan.Ignored = append(plan.Ignored, ignored...)
+	return err
+}
+
+// planPath adds the operations deploying a single path of pkg. provider is
+// the package whose file is deployed at the path's target.
+func (l *Linker) planPath(plan *Plan, classifier *Classifier, mode DeployMode, pkg Package, path pathInfo, provider string) error {
+	op := Operation{
+		Package: pkg.Name,
+		RelPath: path.relPath,
+		Source:  path.sourcePath,
+		Target:  path.targetPath,
+	}
+
+	if path.bind {
+		return l.planBind(plan, classifier, op)
+	}
+	if path.isDir {
+		return l.planDirectory(plan, op)
+	}
+
+	if provider != pkg.Name {
+		op.Kind = OpSkip
+		op.Reason = fmt.Sprintf("overridden by package %s", provider)
+		plan.add(op)
+		return nil
+	}
+
+	classification, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+	if err != nil {
+		return err
+	}
+	op.Current = classification.State
+	op.Perm = l.filePerm(path.relPath)
+
+	if op.Current == TargetForeignLink {
+		if done := l.planOwnedLink(plan, &op); done {
+			return nil
+		}
+	}
+
+	switch {
+	case l.Mapper.IsSecret(path.relPath):
+		return l.planSecret(plan, op, classification)
+	case l.Mapper.IsTemplate(path.relPath) || pkg.renders(path.relPath):
+		return l.planTemplate(plan, op, classification)
+	case mode == ModeCopy || pkg.copies(path.relPath):
+		return l.planCopy(plan, op, classification)
+	default:
+		return l.planSymlink(plan, op)
+	}
+}
+
+// planOwnedLink applies Defer and Override to op.Target when it is linked from
+// another package. A deferred path is skipped and planOwnedLink reports it is
+// done; an overridden link is unlinked first, leaving op to be planned as if
+// the target were missing.
+func (l *Linker) planOwnedLink(plan *Plan, op *Operation) bool {
+	if len(l.Defer) == 0 && len(l.Override) == 0 {
+		return false
+	}
+	owner, err := l.linkOwner(op.Target)
+	if err != nil || owner.Package == op.Package {
+		return false
+	}
+	rel, err := filepath.Rel(l.TargetDir, op.Target)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	switch {
+	case matchesPrefix(l.Defer, rel):
+		skip := *op
+		skip.Kind = OpSkip
+		skip.Reason = fmt.Sprintf("deferred to package %s", owner.Package)
+		plan.add(skip)
+		return true
+	case matchesPrefix(l.Override, rel):
+		l.logVerbose("Package %s overrides %s linked from package %s\n", op.Package, op.Target, owner.Package)
+		plan.add(Operation{
+			Kind:    OpUnlink,
+			Package: owner.Package,
+			RelPath: owner.RelPath,
+			Source:  owner.Source,
+			Target:  op.Target,
+			Current: op.Current,
+		})
+		op.Current = TargetMissing
+	}
+	return false
+}
+
+// matchesPrefix reports whether one of res matches at the start of s.
+func matchesPrefix(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if loc := re.FindStringIndex(s); loc != nil && loc[0] == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps records which package provides each target path while packages
+// are walked.
+type overlaps struct {
+	files map[string]string // Target file -> package deploying it
+	dirs  map[string]string // Target directory -> first package providing it
+}
+
+func newOverlaps() *overlaps {
+	return &overlaps{
+		files: make(map[string]string),
+		dirs:  make(map[string]string),
+	}
+}
+
+// addOverlap records that pkgName provides path. Files provided by more than
+// one package are conflicts unless Overlay is set, in which case the package
+// added last wins. A file and a directory at the same target path always
+// conflict. Bind-mounted directories are provided whole, like files.
+func (l *Linker) addOverlap(o *overlaps, pkgName string, path pathInfo) error {
+	if path.isDir && !path.bind {
+		if other, ok := o.files[path.targetPath]; ok {
+			return fmt.Errorf("%w: %s is a file in package %s but a directory in package %s", ErrConflict, path.targetPath, other, pkgName)
+		}
+		if _, ok := o.dirs[path.targetPath]; !ok {
+			o.dirs[path.targetPath] = pkgName
+		}
+		return nil
+	}
+
+	if other, ok := o.dirs[path.targetPath]; ok {
+		return fmt.Errorf("%w: %s is a directory in package %s but a file in package %s", ErrConflict, path.targetPath, other, pkgName)
+	}
+	if other, ok := o.files[path.targetPath]; ok && other != pkgName {
+		if !l.Overlay {
+			return fmt.Errorf("%w: %s is provided by both package %s and package %s (use --overlay to let later packages take precedence)", ErrConflict, path.targetPath, other, pkgName)
+		}
+		l.logVerbose("Package %s overrides %s from package %s\n", pkgName, path.targetPath, other)
+	}
+	o.files[path.targetPath] = pkgName
+	return nil
+}
+
+// resolveOverlaps walks packages and returns the package whose file is
+// deployed at each target path they provide.
+func (l *Linker) resolveOverlaps(packages []Package) (map[string]string, error) {
+	o := newOverlaps()
+	for _, pkg := range packages {
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			return l.addOverlap(o, pkg.Name, path)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o.files, nil
+}
+
+// planDirectory adds the operation ensuring a package directory exists in the
+// target. Existing directories are kept in the plan so the package's claim on
+// directories gslk created is recorded.
+func (l *Linker) planDirectory(plan *Plan, op Operation) error {
+	fi, err := orOS(l.FS).Stat(op.Target)
+	if err == nil && !fi.IsDir() {
+		return fmt.Errorf("%w: target %s already exists and is not a directory", ErrConflict, op.Target)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat target path %s: %w", op.Target, err)
+	}
+
+	op.Kind = OpMkdir
+	plan.add(op)
+	return nil
+}
+
+// planBind adds the operations bind-mounting the source directory onto
+// op.Target. The mount point may be missing or an empty directory, such as
+// one left by a mount that did not survive a reboot; anything else in the way
+// is a conflict, as mounting over it would hide it.
+func (l *Linker) planBind(plan *Plan, classifier *Classifier, op Operation) error {
+	classification, err := classifier.Classify(op.Package, op.Source, op.Target)
+	if err != nil {
+		return err
+	}
+	op.Current = classification.State
+	op.Mode = ModeBind
+
+	switch op.Current {
+	case TargetMissing:
+		op.Kind = OpMount
+		plan.add(op)
+		return nil
+
+	case TargetLinked:
+		op.Kind = OpSkip
+		op.Reason = "already mounted"
+		plan.add(op)
+		return nil
+
+	case TargetDirectory:
+		op.Kind = OpMount
+		if entries, err := os.ReadDir(op.Target); err != nil || len(entries) > 0 {
+			return l.planConflict(plan, op, fmt.Errorf("%w: target %s is a directory that is not empty, mounting over it would hide its contents", ErrConflict, op.Target))
+		}
+		plan.add(op)
+		return nil
+
+	default:
+		op.Kind = OpMount
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and is not a directory to mount on", ErrConflict, op.Target))
+	}
+}
+
+// planSymlink adds the operations placing a symlink at op.Target.
+func (l *Linker) planSymlink(plan *Plan, op Operation) error {
+	switch op.Current {
+	case TargetMissing, TargetDeployed:
+		// A deployed copy is replaced by the link in place
+		op.Kind = OpLink
+		plan.add(op)
+		return nil
+
+	case TargetLinked:
+		op.Kind = OpSkip
+		op.Reason = "already linked"
+		plan.add(op)
+		return nil
+
+	case TargetModified:
+		op.Kind = OpLink
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s was modified since it was deployed", ErrConflict, op.Target))
+
+	case TargetForeignLink:
+		owner, err := l.linkOwner(op.Target)
+		if err == nil && owner.Package == op.Package {
+			// The link points to the same file in another source, or to the file
+			// the path came from before the package was reorganised; the executor
+			// replaces it in place
+			op.Kind = OpLink
+			plan.add(op)
+			return nil
+		}
+		op.Kind = OpLink
+		if err == nil {
+			return l.planConflict(plan, op, fmt.Errorf("%w: target %s is already linked from package %s", ErrConflict, op.Target, owner.Package))
+		}
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and is not the expected symlink", ErrConflict, op.Target))
+
+	default:
+		op.Kind = OpLink
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and is not the expected symlink", ErrConflict, op.Target))
+	}
+}
+
+// planCopy adds the operations placing a copy of the source file at op.Target.
+// Copies whose recorded checksum matches the source are left alone.
+func (l *Linker) planCopy(plan *Plan, op Operation, classification Classification) error {
+	sourceHash, err := hashFile(op.Source)
+	if err != nil {
+		return fmt.Errorf("failed to hash source file %s: %w", op.Source, err)
+	}
+	op.Hash = sourceHash
+
+	switch op.Current {
+	case TargetMissing, TargetLinked:
+		// A link we created earlier is swapped for the copy
+		op.Kind = OpCopy
+		plan.add(op)
+		return nil
+
+	case TargetDeployed:
+		if classification.Entry.Mode == ModeCopy && classification.Entry.Hash == sourceHash && permUpToDate(op, classification) {
+			op.Kind = OpSkip
+			op.Reason = "copy is up to date"
+		} else {
+			op.Kind = OpCopy
+		}
+		plan.add(op)
+		return nil
+
+	case TargetModified:
+		op.Kind = OpCopy
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s was modified since it was deployed", ErrConflict, op.Target))
+
+	case TargetForeignLink:
+		op.Kind = OpCopy
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and is not the expected symlink", ErrConflict, op.Target))
+
+	default:
+		op.Kind = OpCopy
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and was not deployed by gslk", ErrConflict, op.Target))
+	}
+}
+
+// permUpToDate reports whether the deployed file already has the permissions
+// op asks for.
+func permUpToDate(op Operation, classification Classification) bool {
+	return op.Perm == 0 || classification.Info == nil || classification.Info.Mode().Perm() == op.Perm
+}
+
+// planTemplate adds the operations rendering the source template to op.Target.
+func (l *Linker) planTemplate(plan *Plan, op Operation, classification Classification) error {
+	content, err := l.renderTemplate(op.Package, op.Source)
+	if err != nil {
+		return fmt.Errorf("failed to render template %s: %w", op.Source, err)
+	}
+	op.Mode = ModeTemplate
+	return l.planGenerated(plan, op, classification, content)
+}
+
+// planSecret adds the operations decrypting the source secret to op.Target.
+func (l *Linker) planSecret(plan *Plan, op Operation, classification Classification) error {
+	content, err := l.Age.Decrypt(op.Source)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secret %s: %w", op.Source, err)
+	}
+	op.Mode = ModeSecret
+	return l.planGenerated(plan, op, classification, content)
+}
+
+// planGenerated adds the operations writing content generated from op.Source
+// to op.Target. Files whose recorded checksum matches content are left alone.
+func (l *Linker) planGenerated(plan *Plan, op Operation, classification Classification, content []byte) error {
+	op.Content = content
+	op.Hash = hashBytes(content)
+
+	switch op.Current {
+	case TargetMissing, TargetLinked:
+		op.Kind = OpRender
+		plan.add(op)
+		return nil
+
+	case TargetDeployed:
+		if classification.Entry.Mode == op.Mode && classification.Entry.Hash == op.Hash && permUpToDate(op, classification) {
+			op.Kind = OpSkip
+			op.Reason = fmt.Sprintf("%s is up to date", op.Mode)
+		} else {
+			op.Kind = OpRender
+		}
+		plan.add(op)
+		return nil
+
+	case TargetModified:
+		op.Kind = OpRender
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s was modified since it was deployed", ErrConflict, op.Target))
+
+	case TargetForeignLink:
+		op.Kind = OpRender
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and is not the expected symlink", ErrConflict, op.Target))
+
+	default:
+		op.Kind = OpRender
+		return l.planConflict(plan, op, fmt.Errorf("%w: target %s already exists and was not deployed by gslk", ErrConflict, op.Target))
+	}
+}
+
+// planConflict resolves a conflict at op.Target according to the conflict policy.
+// With ConflictBackup the occupying file is quarantined before op is performed;
+// with ConflictAdoptIdentical a file holding what op deploys is replaced in
+// place. Otherwise, and always for directories, the conflict error is
+// returned. A ConflictResolver, if set, decides instead of the policy.
+// Conflicts not adopted by the policy are reported to OnEvent.
+func (l *Linker) planConflict(plan *Plan, op Operation, conflict error) error {
+	if l.ConflictResolver != nil {
+		event := newEvent(EventConflict, op, l.DryRun)
+		event.Error = conflict.Error()
+		l.emit(event)
+		return l.resolveConflict(plan, op, conflict)
+	}
+
+	if l.ConflictPolicy == ConflictAdoptIdentical && op.Current == TargetFile && l.isIdentical(op) {
+		op.Current = TargetDeployed
+		op.Adopt = true
+		plan.add(op)
+		return nil
+	}
+
+	event := newEvent(EventConflict, op, l.DryRun)
+	event.Error = conflict.Error()
+	l.emit(event)
+
+	if l.ConflictPolicy != ConflictBackup || op.Current == TargetDirectory {
+		// Directories may hold unrelated user data, never move them aside
+		return conflict
+	}
+
+	quarantine := op
+	quarantine.Kind = OpQuarantine
+	plan.add(quarantine)
+
+	op.Current = TargetMissing
+	plan.add(op)
+	return nil
+}
+
+// isIdentical reports whether the regular file at op.Target holds exactly
+// what op deploys: the generated content for OpRender, the source file
+// otherwise.
+func (l *Linker) isIdentical(op Operation) bool {
+	fi, err := orOS(l.FS).Lstat(op.Target)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	want := op.Content
+	if op.Kind != OpRender {
+		if want, err = os.ReadFile(op.Source); err != nil {
+			return false
+		}
+	}
+	if int64(len(want)) != fi.Size() {
+		return false
+	}
+	var have []byte
+	if r, ok := l.FS.(FileReader); ok {
+		have, err = r.ReadFile(op.Target)
+	} else {
+		have, err = os.ReadFile(op.Target)
+	}
+	return err == nil && bytes.Equal(have, want)
+}
+
+// isLayerLink reports whether the symlink at op.Target points to op.RelPath of
+// op.Package in a source directory other than the one op.Source comes from.
+func (l *Linker) isLayerLink(op Operation) bool {
+	owner, err := l.linkOwner(op.Target)
+	return err == nil && owner.Package == op.Package && owner.RelPath == op.RelPath
+}
+
+// planUnlink computes the unlink plan for packageNames against state, timing
+// each package in a span nested in span.
+func (l *Linker) planUnlink(span Span, packageNames []string, state *State) (*Plan, error) {
+	packages, err := l.lookupPackages(packageNames)
+	if err != nil {
+		return nil, err
+	}
+	if l.UnlinkDependents {
+		if packages, err = l.withDependents(packages); err != nil {
+			return nil, err
+		}
+	}
+
+	classifier := newSnapshotClassifier(state, l.FS)
+	plan := &Plan{Packages: packages}
+
+	for _, pkg := range packages {
+		pkgSpan := span.Start("gslk.plan.package", Attr{"gslk.package", pkg.Name})
+		ignored, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.bind {
+				return planUnbind(plan, classifier, pkg, path)
+			}
+			if path.isDir {
+				return nil // Directories gslk created are released once the package's files are gone
+			}
+
+			classification, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+
+			op := Operation{
+				Package: pkg.Name,
+				RelPath: path.relPath,
+				Source:  path.sourcePath,
+				Target:  path.targetPath,
+				Current: classification.State,
+				Mode:    classification.Entry.Mode,
+			}
+
+			switch classification.State {
+			case TargetMissing:
+				return nil // Nothing to unlink
+			case TargetLinked:
+				op.Kind = OpUnlink
+			case TargetDeployed:
+				op.Kind = OpRemove
+			case TargetModified:
+				if !l.ForceRemove {
+					return fmt.Errorf("%w: refusing to remove %s: file was modified since it was deployed (use -f to remove anyway)", ErrConflict, path.targetPath)
+				}
+				op.Kind = OpRemove
+			case TargetForeignLink:
+				if l.isLayerLink(op) {
+					op.Kind = OpUnlink
+					break
+				}
+				op.Kind = OpSkip
+				op.Reason = "symlink points elsewhere"
+			default:
+				op.Kind = OpSkip
+				op.Reason = "not a symlink"
+			}
+			plan.add(op)
+			return nil
+		})
+		pkgSpan.End(err)
+		plan.Ignored = append(plan.Ignored, ignored...)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only part of the package is unlinked; the directories leading to it stay,
+		// and with Only the directories may still hold files that were not selected
+		if len(l.Only) > 0 {
+			continue
+		}
+		root := l.TargetDir
+		if pkg.Subpath != "" {
+			root = l.targetPath(pkg, pkg.Subpath)
+		}
+		planReleaseDirs(plan, pkg, state, root)
+	}
+
+	return plan, nil
+}
+
+// planUnbind adds the operation undoing the bind mount of path, a directory
+// of pkg. A mount recorded in state that is gone already is only forgotten.
+func planUnbind(plan *Plan, classifier *Classifier, pkg Package, path pathInfo) error {
+	classification, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+	if err != nil {
+		return err
+	}
+	if classification.State != TargetLinked && classification.Entry.Mode != ModeBind {
+		return nil // Nothing to unmount
+	}
+	plan.add(Operation{
+		Kind:    OpUnmount,
+		Package: pkg.Name,
+		RelPath: path.relPath,
+		Source:  path.sourcePath,
+		Target:  path.targetPath,
+		Current: classification.State,
+		Mode:    ModeBind,
+	})
+	return nil
+}
+
+// planReleaseDirs adds operations releasing the directories gslk created for pkg
+// at or below root, deepest first so that children are removed before their parents.
+func planReleaseDirs(plan *Plan, pkg Package, state *State, root string) {
+	var dirs []string
+	for dir, owners := range state.Directories {
+		if slices.Contains(owners, pkg.Name) && (dir == root || isSubPath(root, dir)) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	slices.SortFunc(dirs, func(a, b string) int {
+		if depthA, depthB := strings.Count(a, string(filepath.Separator)), strings.Count(b, string(filepath.Separator)); depthA != depthB {
+			return depthB - depthA
+		}
+		return strings.Compare(a, b)
+	})
+
+	for _, dir := range dirs {
+		plan.add(Operation{Kind: OpRmdir, Package: pkg.Name, Target: dir})
+	}
+}
@@ -0,0 +1,233 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// opsByTarget indexes plan operations by target path relative to targetDir.
+func opsByTarget(t *testing.T, plan *Plan, targetDir string) map[string][]OpKind {
+	ops := make(map[string][]OpKind)
+	for _, op := range plan.Operations {
+		rel, err := filepath.Rel(targetDir, op.Target)
+		require.NoError(t, err)
+		ops[filepath.ToSlash(rel)] = append(ops[filepath.ToSlash(rel)], op.Kind)
+	}
+	return ops
+}
+
+func TestPlanLink(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "plan_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"new.txt":        "new",
+		"linked.txt":     "linked",
+		"sub/nested.txt": "nested",
+	})
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, "linked.txt"), filepath.Join(targetDir, "linked.txt")))
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+
+	plan, err := linker.PlanLink([]string{pkgName})
+	require.NoError(t, err)
+
+	ops := opsByTarget(t, plan, targetDir)
+	assert.Equal(t, []OpKind{OpLink}, ops["new.txt"])
+	assert.Equal(t, []OpKind{OpSkip}, ops["linked.txt"])
+	assert.Equal(t, []OpKind{OpMkdir}, ops["sub"])
+	assert.Equal(t, []OpKind{OpLink}, ops["sub/nested.txt"])
+
+	// Planning never touches the filesystem
+	_, err = os.Lstat(filepath.Join(targetDir, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Lstat(filepath.Join(targetDir, "sub"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPlanLinkConflicts(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "conflict_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"taken.txt": "package",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "taken.txt"), []byte("user"), 0644))
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+
+	_, err := linker.PlanLink([]string{pkgName})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflict: target")
+
+	linker.ConflictPolicy = ConflictBackup
+	plan, err := linker.PlanLink([]string{pkgName})
+	require.NoError(t, err)
+	assert.Equal(t, []OpKind{OpQuarantine, OpLink}, opsByTarget(t, plan, targetDir)["taken.txt"])
+}
+
+func TestPlanLinkPackageOverlap(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "base"), map[string]string{
+		".gitconfig": "base",
+		".bashrc":    "base",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "personal"), map[string]string{
+		".gitconfig": "personal",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+
+	// Overlaps between packages are reported naming both packages
+	_, err := linker.PlanLink([]string{"base", "personal"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "package base and package personal")
+
+	// With Overlay the package listed last wins
+	linker.Overlay = true
+	_, err = linker.Link([]string{"base", "personal"})
+	require.NoError(t, err)
+	owner, err := linker.Owner(filepath.Join(targetDir, ".gitconfig"))
+	require.NoError(t, err)
+	assert.Equal(t, "personal", owner.Package)
+	owner, err = linker.Owner(filepath.Join(targetDir, ".bashrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "base", owner.Package)
+}
+
+func TestPlanLinkFileDirectoryOverlap(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "file_pkg"), map[string]string{"app": "file"})
+	createDummyPackage(t, filepath.Join(sourceDir, "dir_pkg"), map[string]string{"app/conf": "dir"})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Overlay:   true, // A file cannot overlay a directory
+	}
+
+	_, err := linker.PlanLink([]string{"file_pkg", "dir_pkg"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a file in package file_pkg but a directory in package dir_pkg")
+}
+
+func TestPlanLinkNamesLinkingPackage(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "first"), map[string]string{"shared.conf": "1"})
+	createDummyPackage(t, filepath.Join(sourceDir, "second"), map[string]string{"shared.conf": "2"})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+	_, err := linker.Link([]string{"first"})
+	require.NoError(t, err)
+
+	_, err = linker.PlanLink([]string{"second"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already linked from package first")
+}
+
+func TestPlanUnlink(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "unlink_plan_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"linked.txt":  "linked",
+		"foreign.txt": "foreign",
+		"absent.txt":  "absent",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, "linked.txt"), filepath.Join(targetDir, "linked.txt")))
+	require.NoError(t, os.Symlink("/elsewhere", filepath.Join(targetDir, "foreign.txt")))
+
+	plan, err := linker.PlanUnlink([]string{pkgName})
+	require.NoError(t, err)
+
+	ops := opsByTarget(t, plan, targetDir)
+	assert.Equal(t, []OpKind{OpUnlink}, ops["linked.txt"])
+	assert.Equal(t, []OpKind{OpSkip}, ops["foreign.txt"])
+	assert.NotContains(t, ops, "absent.txt")
+}
+
+func TestExecutorDryRun(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	sourcePath := filepath.Join(sourceDir, "file.txt")
+	require.NoError(t, os.WriteFile(sourcePath, []byte("x"), 0644))
+
+	plan := &Plan{Operations: []Operation{
+		{Kind: OpMkdir, Target: filepath.Join(targetDir, "dir")},
+		{Kind: OpLink, Source: sourcePath, Target: filepath.Join(targetDir, "dir", "file.txt")},
+	}}
+
+	executor := &Executor{TargetDir: targetDir, DryRun: true}
+	result, err := executor.Apply(plan)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, "dir", "file.txt")}, result.Linked)
+	_, err = os.Lstat(filepath.Join(targetDir, "dir"))
+	assert.True(t, os.IsNotExist(err), "Dry run must not create anything")
+
+	executor.DryRun = false
+	_, err = executor.Apply(plan)
+	require.NoError(t, err)
+	linkTarget, err := os.Readlink(filepath.Join(targetDir, "dir", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, sourcePath, linkTarget)
+}
+
+func TestExecutorConcurrentErrors(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	var ops []Operation
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		sourcePath := filepath.Join(sourceDir, name)
+		require.NoError(t, os.WriteFile(sourcePath, []byte(name), 0644))
+		ops = append(ops, Operation{Kind: OpLink, Source: sourcePath, Target: filepath.Join(targetDir, name)})
+	}
+	// Links fail when something already occupies the target
+	for _, name := range []string{"b", "d"} {
+		require.NoError(t, os.WriteFile(filepath.Join(targetDir, name), []byte("user"), 0644))
+	}
+
+	executor := &Executor{TargetDir: targetDir, Concurrency: 4}
+	result, err := executor.Apply(&Plan{Operations: ops})
+	require.Error(t, err)
+
+	// Independent operations still run, and failures are reported in plan order
+	assert.Equal(t, []string{filepath.Join(targetDir, "a"), filepath.Join(targetDir, "c"), filepath.Join(targetDir, "e")}, result.Linked)
+	assert.Equal(t, []string{filepath.Join(targetDir, "b"), filepath.Join(targetDir, "d")}, result.Failed)
+	assert.Less(t, strings.Index(err.Error(), filepath.Join(targetDir, "b")), strings.Index(err.Error(), filepath.Join(targetDir, "d")))
+}
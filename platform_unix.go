@@ -0,0 +1,119 @@
+//go:build !windows
+
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// symlinkSupported reports whether symbolic links can be created in dir.
+// Unix-like systems always allow unprivileged symlinks.
+func symlinkSupported(dir string) bool {
+	return true
+}
+
+// normalizeLinkTarget returns the link target as reported by os.Readlink.
+func normalizeLinkTarget(linkTarget string) string {
+	return linkTarget
+}
+
+// isJunction reports whether fi, from Lstat, describes a Windows junction;
+// there are none on Unix-like systems.
+func isJunction(fi os.FileInfo) bool {
+	return false
+}
+
+// pathsEqual reports whether two cleaned absolute paths name the same location.
+func pathsEqual(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// isWritable reports whether the current user may create entries in dir.
+func isWritable(dir string) bool {
+	return syscall.Access(dir, 0x2) == nil // W_OK
+}
+
+// shellCommand returns the command running script with the system shell.
+func shellCommand(script string) *exec.Cmd {
+	return exec.Command("sh", "-c", script)
+}
+
+// isRoot reports whether gslk runs with root privileges.
+func isRoot() bool {
+	return os.Geteuid() == 0
+}
+
+// checkOwner returns an error if the file at path belongs to another user.
+// With allowRoot, root may manage files of any user.
+func checkOwner(fsys FS, path string, allowRoot bool) error {
+	if allowRoot && isRoot() {
+		return nil
+	}
+	fi, err := orOS(fsys).Lstat(path)
+	if err != nil {
+		return nil // Missing files need no check; other errors surface in the operation itself
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || int(st.Uid) == os.Geteuid() {
+		return nil
+	}
+
+	owner := "uid " + strconv.Itoa(int(st.Uid))
+	if u, err := user.LookupId(strconv.Itoa(int(st.Uid))); err == nil {
+		owner = u.Username
+	}
+	return fmt.Errorf("refusing to modify %s: it is owned by %s, not the current user (run as that user, or as root with --allow-root)", path, owner)
+}
+
+// userStateDir returns the directory for persistent application state,
+// $XDG_STATE_HOME or ~/.local/state.
+func userStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state"), nil
+}
+
+// lockFile opens the lock file at path, creating it if needed, and takes an
+// exclusive advisory lock on it without waiting. locked is false, and f nil,
+// if another process holds the lock. The lock is released when f is closed.
+func lockFile(path string) (f *os.File, locked bool, err error) {
+	f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return f, true, nil
+}
+
+// isTransient reports whether err is one network filesystems such as NFS and
+// SMB mounts report for conditions that clear up by themselves, such as a
+// stale file handle or a busy file.
+func isTransient(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case syscall.ESTALE, syscall.EBUSY, syscall.EAGAIN, syscall.ETIMEDOUT:
+		return true
+	}
+	return false
+}
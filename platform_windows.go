@@ -0,0 +1,133 @@
+//go:build windows
+
+package gslk
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// symlinkProbes caches symlink support per directory for the lifetime of the process.
+var symlinkProbes sync.Map
+
+// symlinkSupported reports whether symbolic links can be created in dir.
+// Windows only allows unprivileged symlinks when Developer Mode is enabled,
+// so this probes by creating (and removing) a throwaway link.
+func symlinkSupported(dir string) bool {
+	if supported, ok := symlinkProbes.Load(dir); ok {
+		return supported.(bool)
+	}
+
+	// The target may not exist yet, probe its closest existing ancestor
+	probeDir := existingAncestor(dir)
+
+	supported := false
+	if probe, err := os.CreateTemp(probeDir, ".gslk-probe-*"); err == nil {
+		probe.Close()
+		linkPath := probe.Name() + ".lnk"
+		if err := os.Symlink(probe.Name(), linkPath); err == nil {
+			supported = true
+			os.Remove(linkPath)
+		}
+		os.Remove(probe.Name())
+	}
+
+	symlinkProbes.Store(dir, supported)
+	return supported
+}
+
+// normalizeLinkTarget strips the extended-length prefix Windows may report
+// for symlink targets, so they compare equal to ordinary absolute paths.
+func normalizeLinkTarget(linkTarget string) string {
+	if strings.HasPrefix(linkTarget, `\\?\UNC\`) {
+		return `\\` + linkTarget[len(`\\?\UNC\`):]
+	}
+	return strings.TrimPrefix(linkTarget, `\\?\`)
+}
+
+// isJunction reports whether fi, from Lstat, may describe a junction (a
+// mount point reparse point), which os reports as irregular rather than as a
+// directory or a symbolic link. Other reparse points are irregular too; only
+// os.Readlink tells them apart.
+func isJunction(fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeIrregular != 0
+}
+
+// pathsEqual reports whether two cleaned absolute paths name the same location.
+// NTFS paths are case-insensitive.
+func pathsEqual(a, b string) bool {
+	return strings.EqualFold(filepath.Clean(a), filepath.Clean(b))
+}
+
+// isWritable reports whether the current user may create entries in dir.
+// Windows ACLs are not inspected; only the read-only attribute is honoured.
+func isWritable(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.Mode().Perm()&0200 != 0
+}
+
+// shellCommand returns the command running script with the system shell.
+func shellCommand(script string) *exec.Cmd {
+	return exec.Command("cmd", "/C", script)
+}
+
+// isRoot reports whether gslk runs with root privileges. Elevated Windows
+// sessions are not detected.
+func isRoot() bool {
+	return false
+}
+
+// checkOwner returns an error if the file at path belongs to another user.
+// Windows ACLs are not inspected, so every file passes.
+func checkOwner(fsys FS, path string, allowRoot bool) error {
+	return nil
+}
+
+// userStateDir returns the directory for persistent application state,
+// %LocalAppData%.
+func userStateDir() (string, error) {
+	return os.UserCacheDir()
+}
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION, returned when opening a
+// file another process opened without sharing it.
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens the lock file at path, creating it if needed, for exclusive
+// writing: other processes may only read it while it is open. locked is false,
+// and f nil, if another process holds it. The lock is released when f is
+// closed.
+func lockFile(path string) (f *os.File, locked bool, err error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, false, err
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, syscall.FILE_SHARE_READ, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		if err == errorSharingViolation {
+			return nil, false, nil
+		}
+		return nil, false, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(handle), path), true, nil
+}
+
+// isTransient reports whether err is one SMB shares report for conditions
+// that clear up by themselves, such as a file another process has open or a
+// dropped network connection.
+func isTransient(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case errorSharingViolation, 33, 59, 64: // ERROR_LOCK_VIOLATION, ERROR_UNEXP_NET_ERR, ERROR_NETNAME_DELETED
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package gslk
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Profile is a named group of packages, with variable values, that are linked
+// together, such as "work", "home" or "server".
+type Profile struct {
+	Name     string
+	Packages []string
+	Vars     map[string]string
+}
+
+// ActiveProfile records which profile was linked last and with which packages,
+// so switching profiles can undo the previous one.
+type ActiveProfile struct {
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+}
+
+// LinkProfile links the packages of profile. Packages linked by the previously
+// active profile that profile does not include are unlinked first, so switching
+// from one profile to another leaves only the new profile's packages linked.
+// The result covers both the unlinked and the linked packages.
+func (l *Linker) LinkProfile(profile Profile) (*Result, error) {
+	result := &Result{}
+
+	state, err := l.loadState()
+	if err != nil {
+		return result, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	// Groups are recorded by their members, so changing a group's members
+	// unlinks the packages it lost
+	packages := profile.Packages
+	if len(l.Groups) > 0 {
+		byName, err := l.packagesByName()
+		if err != nil {
+			return result, err
+		}
+		if packages, err = l.expandGroups(packages, byName); err != nil {
+			return result, err
+		}
+	}
+
+	if previous := state.Profile; previous != nil {
+		var stale []string
+		for _, name := range previous.Packages {
+			if !slices.Contains(packages, name) {
+				stale = append(stale, name)
+			}
+		}
+
+		stale, err = l.existingPackages(stale)
+		if err != nil {
+			return result, err
+		}
+		if len(stale) > 0 {
+			l.printf("Unlinking packages %v of previous profile %s\n", stale, previous.Name)
+			unlinked, err := l.Unlink(stale)
+			result.Merge(unlinked)
+			if err != nil {
+				return result, fmt.Errorf("failed to unlink packages of profile %s: %w", previous.Name, err)
+			}
+		}
+	}
+
+	linked, err := l.Link(packages)
+	result.Merge(linked)
+	if err != nil {
+		return result, err
+	}
+
+	return result, l.setActiveProfile(&ActiveProfile{Name: profile.Name, Packages: packages})
+}
+
+// UnlinkProfile unlinks the packages of profile and, if it is the active
+// profile, forgets it.
+func (l *Linker) UnlinkProfile(profile Profile) (*Result, error) {
+	result, err := l.Unlink(profile.Packages)
+	if err != nil {
+		return result, err
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return result, fmt.Errorf("failed to load state: %w", err)
+	}
+	if state.Profile == nil || state.Profile.Name != profile.Name {
+		return result, nil
+	}
+	return result, l.setActiveProfile(nil)
+}
+
+// ActiveProfile returns the profile linked last, or nil if none is.
+func (l *Linker) ActiveProfile() (*ActiveProfile, error) {
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return state.Profile, nil
+}
+
+// setActiveProfile records profile as the active profile in the state manifest.
+func (l *Linker) setActiveProfile(profile *ActiveProfile) error {
+	if l.DryRun {
+		return nil
+	}
+
+	defer l.lockState()()
+	state, err := l.loadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	state.Profile = profile
+	state.dirty = true
+	if err := state.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// existingPackages filters names down to packages present in the source
+// directories. Packages removed from the source can no longer be unlinked.
+func (l *Linker) existingPackages(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	var existing []string
+	for _, name := range names {
+		if slices.ContainsFunc(packages, func(pkg Package) bool { return pkg.Name == name }) {
+			existing = append(existing, name)
+		} else {
+			l.warnf("Warning: package %s no longer exists and cannot be unlinked\n", name)
+		}
+	}
+	return existing, nil
+}
@@ -0,0 +1,70 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkProfileSwitch(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	for _, name := range []string{"git", "work_vpn", "games"} {
+		createDummyPackage(t, filepath.Join(sourceDir, name), map[string]string{name + ".conf": name})
+	}
+	target := func(name string) string { return filepath.Join(targetDir, name+".conf") }
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	work := Profile{Name: "work", Packages: []string{"git", "work_vpn"}}
+	home := Profile{Name: "home", Packages: []string{"git", "games"}}
+
+	_, err := linker.LinkProfile(work)
+
+	require.NoError(t, err)
+	active, err := linker.ActiveProfile()
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, "work", active.Name)
+
+	// Switching unlinks packages only the previous profile had
+	_, err = linker.LinkProfile(home)
+	require.NoError(t, err)
+	for name, linked := range map[string]bool{"git": true, "games": true, "work_vpn": false} {
+		_, err := os.Lstat(target(name))
+		assert.Equal(t, linked, err == nil, "%s linked", name)
+	}
+
+	active, err = linker.ActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, &ActiveProfile{Name: "home", Packages: []string{"git", "games"}}, active)
+
+	// Unlinking the active profile forgets it
+	_, err = linker.UnlinkProfile(home)
+	require.NoError(t, err)
+	active, err = linker.ActiveProfile()
+	require.NoError(t, err)
+	assert.Nil(t, active)
+	_, err = os.Stat(linker.statePath())
+	assert.True(t, os.IsNotExist(err), "State should be empty once nothing is linked")
+}
+
+func TestLinkProfileSkipsRemovedPackages(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "kept"), map[string]string{"kept.conf": "k"})
+	createDummyPackage(t, filepath.Join(sourceDir, "removed"), map[string]string{"removed.conf": "r"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.LinkProfile(Profile{Name: "old", Packages: []string{"kept", "removed"}})
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(filepath.Join(sourceDir, "removed")))
+
+	_, err = linker.LinkProfile(Profile{Name: "new", Packages: []string{"kept"}})
+
+	require.NoError(t, err)
+}
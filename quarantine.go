@@ -0,0 +1,115 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QuarantineDirName is the directory in the target where conflicting files are saved.
+const QuarantineDirName = ".gslk-quarantine"
+
+// ConflictPolicy controls what Link does when a target path is already occupied.
+type ConflictPolicy string
+
+const (
+	ConflictFail   ConflictPolicy = "fail"   // Abort with a conflict error (default)
+	ConflictBackup ConflictPolicy = "backup" // Move the existing file to the quarantine directory and proceed
+
+	// ConflictAdoptIdentical replaces files that are not deployed by gslk but
+	// hold exactly what would be deployed, such as configuration copied by
+	// hand earlier; other conflicts fail as with ConflictFail
+	ConflictAdoptIdentical ConflictPolicy = "adopt-identical"
+)
+
+// ErrConflict is wrapped by the errors reporting a target path occupied by
+// something gslk may not replace, or provided by two packages.
+var ErrConflict = errors.New("conflict")
+
+// QuarantineEntry records a conflicting target file that was moved aside during Link.
+type QuarantineEntry struct {
+	Package string    `json:"package"`
+	Source  string    `json:"source"` // Package file that replaced the saved file
+	Target  string    `json:"target"` // Original location of the saved file
+	Saved   string    `json:"saved"`  // Current location of the saved file
+	Time    time.Time `json:"time"`
+}
+
+// Quarantined returns the saved conflict files for the given packages,
+// or for all packages if packageNames is empty.
+func (l *Linker) Quarantined(packageNames []string) ([]QuarantineEntry, error) {
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(packageNames))
+	for _, name := range packageNames {
+		wanted[name] = true
+	}
+
+	var entries []QuarantineEntry
+	for _, entry := range state.Quarantine {
+		if len(wanted) == 0 || wanted[entry.Package] {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// MergeQuarantined writes content into the package file the quarantined entry
+// conflicted with, then discards the saved copy.
+func (l *Linker) MergeQuarantined(entry QuarantineEntry, content []byte) error {
+	fi, err := os.Stat(entry.Source)
+	if err != nil {
+		return fmt.Errorf("failed to stat package file %s: %w", entry.Source, err)
+	}
+
+	l.printf("Merging: %s -> %s\n", entry.Saved, entry.Source)
+	if !l.DryRun {
+		if err := checkWritable(l.fs(), "write", entry.Source); err != nil {
+			return err
+		}
+		if err := os.WriteFile(entry.Source, content, fi.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write package file %s: %w", entry.Source, err)
+		}
+	}
+
+	return l.DiscardQuarantined(entry)
+}
+
+// DiscardQuarantined deletes the saved copy of a quarantined file and forgets it.
+func (l *Linker) DiscardQuarantined(entry QuarantineEntry) error {
+	l.printf("Discarding saved file: %s\n", entry.Saved)
+	if l.DryRun {
+		return nil
+	}
+	if err := checkWritable(l.fs(), "remove", entry.Saved); err != nil {
+		return err
+	}
+
+	defer l.lockState()()
+	state, err := l.loadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if err := os.Remove(entry.Saved); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove saved file %s: %w", entry.Saved, err)
+	}
+	quarantineDir := filepath.Join(l.TargetDir, QuarantineDirName)
+	removeParents(l.Logger, entry.Saved, quarantineDir)
+	os.Remove(quarantineDir) // Only succeeds once nothing is left in quarantine
+
+	for i, saved := range state.Quarantine {
+		if saved.Saved == entry.Saved {
+			state.Quarantine = append(state.Quarantine[:i], state.Quarantine[i+1:]...)
+			state.dirty = true
+			break
+		}
+	}
+
+	return state.Save()
+}
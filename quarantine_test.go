@@ -0,0 +1,186 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkBackupConflicts(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "backup_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"file.txt":        "package content",
+		"sub/nested.conf": "nested package content",
+	})
+
+	// Pre-existing user files in the way
+	conflictPath := filepath.Join(targetDir, "file.txt")
+	require.NoError(t, os.WriteFile(conflictPath, []byte("user content"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(targetDir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "sub/nested.conf"), []byte("user nested"), 0644))
+
+	linker := &Linker{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		ConflictPolicy: ConflictBackup,
+	}
+
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err, "Link with backup policy should resolve conflicts")
+
+	fi, err := os.Lstat(conflictPath)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode()&os.ModeSymlink != 0, "Conflicting file should be replaced by a link")
+
+	entries, err := linker.Quarantined(nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	saved := make(map[string]string)
+	for _, entry := range entries {
+		assert.Equal(t, pkgName, entry.Package)
+		content, err := os.ReadFile(entry.Saved)
+		require.NoError(t, err)
+		saved[entry.Target] = string(content)
+	}
+	assert.Equal(t, "user content", saved[conflictPath])
+	assert.Equal(t, "user nested", saved[filepath.Join(targetDir, "sub/nested.conf")])
+
+	// Filtering by an unrelated package yields nothing
+	entries, err = linker.Quarantined([]string{"other"})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestLinkAdoptIdentical(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"same.conf":      "same",
+		"copied.conf":    "copied",
+		"rendered.tmpl":  "{{ .Package }}",
+		"different.conf": "package",
+	})
+	for name, content := range map[string]string{"same.conf": "same", "copied.conf": "copied", "rendered": "pkg", "different.conf": "user"} {
+		require.NoError(t, os.WriteFile(filepath.Join(targetDir, name), []byte(content), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "pkg", ManifestFileName), []byte(`copy = ["copied.conf"]`), 0644))
+
+	linker := &Linker{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		Mapper:         Mapper{TemplateSuffix: DefaultTemplateSuffix},
+		ConflictPolicy: ConflictAdoptIdentical,
+	}
+	_, err := linker.PlanLink([]string{"pkg"})
+	assert.ErrorIs(t, err, ErrConflict, "Files with other content still conflict")
+
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "different.conf"), []byte("package"), 0644))
+	var conflicts int
+	linker.OnEvent = func(e Event) {
+		if e.Type == EventConflict {
+			conflicts++
+		}
+	}
+	_, err = linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+	assert.Zero(t, conflicts)
+
+	fi, err := os.Lstat(filepath.Join(targetDir, "same.conf"))
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Mode()&os.ModeSymlink, "Identical files are replaced by links")
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	for _, name := range []string{"copied.conf", "rendered"} {
+		_, ok := state.Lookup(filepath.Join(targetDir, name))
+		assert.True(t, ok, "%s is adopted as a deployed file", name)
+	}
+	entries, err := linker.Quarantined(nil)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestLinkBackupKeepsDirectories(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "dir_conflict_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"thing": "a file in the package",
+	})
+
+	// A directory in the target where the package has a file is never moved aside
+	require.NoError(t, os.MkdirAll(filepath.Join(targetDir, "thing", "inner"), 0755))
+
+	linker := &Linker{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		ConflictPolicy: ConflictBackup,
+	}
+
+	_, err := linker.Link([]string{pkgName})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflict: target")
+	_, err = os.Stat(filepath.Join(targetDir, "thing", "inner"))
+	assert.NoError(t, err)
+}
+
+func TestResolveQuarantined(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "review_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"keep.txt":  "package keep",
+		"merge.txt": "package merge",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "keep.txt"), []byte("user keep"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "merge.txt"), []byte("user merge"), 0644))
+
+	linker := &Linker{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		ConflictPolicy: ConflictBackup,
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	entries, err := linker.Quarantined([]string{pkgName})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for _, entry := range entries {
+		if filepath.Base(entry.Target) == "keep.txt" {
+			require.NoError(t, linker.DiscardQuarantined(entry))
+		} else {
+			require.NoError(t, linker.MergeQuarantined(entry, []byte("merged content")))
+		}
+		_, err := os.Stat(entry.Saved)
+		assert.True(t, os.IsNotExist(err), "Saved file %s should be removed after resolution", entry.Saved)
+	}
+
+	content, err := os.ReadFile(filepath.Join(pkgPath, "keep.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "package keep", string(content))
+
+	content, err = os.ReadFile(filepath.Join(pkgPath, "merge.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "merged content", string(content))
+
+	entries, err = linker.Quarantined(nil)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = os.Stat(filepath.Join(targetDir, QuarantineDirName))
+	assert.True(t, os.IsNotExist(err), "Empty quarantine directory should be removed")
+}
@@ -0,0 +1,178 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Relink brings the given packages in line with the source directories
+// without unlinking them first: missing links and copies are created, links
+// pointing to the wrong file of the package are replaced, outdated copies are
+// updated, and links, copies and directories the packages no longer provide
+// are removed. Correct links are left untouched, so configurations never
+// disappear while the packages are relinked.
+//
+// Package hooks run as for Link. The returned Result is never nil.
+func (l *Linker) Relink(packageNames []string) (result *Result, err error) {
+	span := l.startSpan("gslk.Relink", Attr{"gslk.packages", packageNames})
+	defer func() { span.End(err) }()
+	defer l.lockState()()
+
+	state, err := l.loadState()
+	if err != nil {
+		return &Result{}, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if err := l.checkRoot(); err != nil {
+		return &Result{}, err
+	}
+
+	plan, err := l.planRelink(span, packageNames, state)
+	if err != nil {
+		return &Result{}, err
+	}
+
+	if err := l.runHooks(plan.Packages, HookPreLink); err != nil {
+		return &Result{}, err
+	}
+
+	result, applyErr := l.executor(state, span).Apply(plan)
+
+	if !l.DryRun {
+		if err := state.Save(); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+	if applyErr != nil {
+		return result, applyErr
+	}
+
+	return result, l.runHooks(plan.Packages, HookPostLink)
+}
+
+// PlanRelink computes the operations Relink would perform.
+func (l *Linker) PlanRelink(packageNames []string) (*Plan, error) {
+	span := l.startSpan("gslk.PlanRelink", Attr{"gslk.packages", packageNames})
+	state, err := l.loadState()
+	if err != nil {
+		err = fmt.Errorf("failed to load state: %w", err)
+		span.End(err)
+		return nil, err
+	}
+	plan, err := l.planRelink(span, packageNames, state)
+	span.End(err)
+	return plan, err
+}
+
+// planRelink computes the relink plan for packageNames against state: the link
+// plan, preceded by the removal of what the packages deployed earlier but no
+// longer provide and followed by the release of directories they no longer
+// need. Stale files are only looked for when whole packages are relinked.
+// Packages are timed in spans nested in span.
+func (l *Linker) planRelink(span Span, packageNames []string, state *State) (*Plan, error) {
+	linkPlan, err := l.planLink(span, packageNames, state)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(linkPlan.Operations))
+	for _, op := range linkPlan.Operations {
+		wanted[op.Target] = true
+	}
+
+	plan := &Plan{Ignored: linkPlan.Ignored, Packages: linkPlan.Packages}
+	var release []Operation
+	for _, pkg := range linkPlan.Packages {
+		if pkg.Subpath != "" || len(l.Only) > 0 {
+			continue
+		}
+		stale, err := l.planStale(pkg, state, wanted)
+		if err != nil {
+			return nil, err
+		}
+		plan.Operations = append(plan.Operations, stale...)
+
+		releasePlan := &Plan{}
+		planReleaseDirs(releasePlan, pkg, state, l.TargetDir)
+		for _, op := range releasePlan.Operations {
+			if !wanted[op.Target] {
+				release = append(release, op)
+			}
+		}
+	}
+
+	plan.Operations = append(plan.Operations, linkPlan.Operations...)
+	plan.Operations = append(plan.Operations, release...)
+	return plan, nil
+}
+
+// planStale returns the operations removing the links, copies and bind mounts
+// of pkg at target paths not in wanted. Copies and bind mounts are found in
+// the state manifest; links
+// are looked for in the target directory and in the directories pkg uses.
+func (l *Linker) planStale(pkg Package, state *State, wanted map[string]bool) ([]Operation, error) {
+	var ops []Operation
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	for target, entry := range state.Entries {
+		if entry.Package != pkg.Name || wanted[target] || entry.Mode == ModeLink {
+			continue
+		}
+		c, err := classifier.Classify(pkg.Name, entry.Source, target)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Mode == ModeBind {
+			ops = append(ops, Operation{Kind: OpUnmount, Package: pkg.Name, Source: entry.Source, Target: target, Current: c.State, Mode: ModeBind})
+			continue
+		}
+		op := Operation{Kind: OpRemove, Package: pkg.Name, Source: entry.Source, Target: target, Current: c.State, Mode: entry.Mode}
+		switch c.State {
+		case TargetDeployed:
+		case TargetModified:
+			if !l.ForceRemove {
+				op.Kind = OpSkip
+				op.Reason = "no longer in the package, but modified since it was deployed"
+			}
+		default:
+			continue
+		}
+		ops = append(ops, op)
+	}
+
+	dirs := map[string]bool{l.TargetDir: true}
+	for target := range wanted {
+		dirs[filepath.Dir(target)] = true
+	}
+	for dir, owners := range state.Directories {
+		if slices.Contains(owners, pkg.Name) {
+			dirs[dir] = true
+		}
+	}
+	for dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Missing directories hold no stale links
+		}
+		for _, e := range entries {
+			target := filepath.Join(dir, e.Name())
+			if e.Type()&os.ModeSymlink == 0 || wanted[target] {
+				continue
+			}
+			owner, err := l.linkOwner(target)
+			if err != nil || owner.Package != pkg.Name {
+				continue
+			}
+			ops = append(ops, Operation{Kind: OpUnlink, Package: pkg.Name, RelPath: owner.RelPath, Source: owner.Source, Target: target, Current: TargetLinked})
+		}
+	}
+
+	// Map iteration order is random; keep plans reproducible
+	slices.SortFunc(ops, func(a, b Operation) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+	return ops, nil
+}
@@ -0,0 +1,117 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelink(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "shell")
+	createDummyPackage(t, pkgPath, map[string]string{
+		".bashrc":        "bash",
+		".inputrc":       "input",
+		".old":           "old",
+		"sub/nested.txt": "nested",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"shell"})
+	require.NoError(t, err)
+	before, err := os.Lstat(filepath.Join(targetDir, ".bashrc"))
+	require.NoError(t, err)
+
+	// The package changes: a file is added, two are removed, and a link ends up
+	// pointing to the wrong file of the package
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ".profile"), []byte("profile"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(pkgPath, ".old")))
+	require.NoError(t, os.RemoveAll(filepath.Join(pkgPath, "sub")))
+	require.NoError(t, os.Remove(filepath.Join(targetDir, ".inputrc")))
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, ".bashrc"), filepath.Join(targetDir, ".inputrc")))
+
+	result, err := linker.Relink([]string{"shell"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(targetDir, ".profile"), filepath.Join(targetDir, ".inputrc")}, result.Linked)
+
+	// The correct link was never touched
+	after, err := os.Lstat(filepath.Join(targetDir, ".bashrc"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+
+	dest, err := os.Readlink(filepath.Join(targetDir, ".inputrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(pkgPath, ".inputrc"), dest)
+
+	for _, gone := range []string{".old", "sub/nested.txt", "sub"} {
+		_, err := os.Lstat(filepath.Join(targetDir, gone))
+		assert.True(t, os.IsNotExist(err), "%s should be removed", gone)
+	}
+}
+
+func TestRelinkRemovesStaleCopies(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "conf")
+	createDummyPackage(t, pkgPath, map[string]string{"kept.conf": "kept", "dropped.conf": "dropped", "edited.conf": "edited"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Mode: ModeCopy}
+	_, err := linker.Link([]string{"conf"})
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(pkgPath, "dropped.conf")))
+	require.NoError(t, os.Remove(filepath.Join(pkgPath, "edited.conf")))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "edited.conf"), []byte("mine"), 0644))
+
+	plan, err := linker.PlanRelink([]string{"conf"})
+	require.NoError(t, err)
+	ops := opsByTarget(t, plan, targetDir)
+	assert.Equal(t, []OpKind{OpRemove}, ops["dropped.conf"])
+	assert.Equal(t, []OpKind{OpSkip}, ops["edited.conf"], "Modified copies are kept")
+	assert.Equal(t, []OpKind{OpSkip}, ops["kept.conf"])
+
+	_, err = linker.Relink([]string{"conf"})
+	require.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(targetDir, "dropped.conf"))
+	assert.True(t, os.IsNotExist(err))
+	state, err := LoadState(filepath.Join(targetDir, StateFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, state.Entries, filepath.Join(targetDir, "dropped.conf"))
+	assert.Contains(t, state.Entries, filepath.Join(targetDir, "edited.conf"))
+}
+
+func TestRelinkReplacesLinksInPlace(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "zsh")
+	createDummyPackage(t, pkgPath, map[string]string{".zshrc": "new", ".zshrc.old": "old"})
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, ".zshrc.old"), filepath.Join(targetDir, ".zshrc")))
+
+	// The wrong link is replaced by a single operation rather than unlinked first
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	plan, err := linker.PlanRelink([]string{"zsh"})
+	require.NoError(t, err)
+	assert.Equal(t, []OpKind{OpLink}, opsByTarget(t, plan, targetDir)[".zshrc"])
+
+	_, err = linker.Relink([]string{"zsh"})
+	require.NoError(t, err)
+	dest, err := os.Readlink(filepath.Join(targetDir, ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(pkgPath, ".zshrc"), dest)
+
+	// No temporary link is left behind
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{".zshrc", ".zshrc.old"}, names)
+}
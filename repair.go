@@ -0,0 +1,125 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repair points the links of packageNames, or of every package if none is
+// given, back into the source directories after the source directory was moved
+// or renamed. Links are repaired in place when they point to the same package
+// file below one of oldSources, or, without oldSources, when they are broken
+// and their destination ends in the package name and path of the file. Copies
+// recorded in the state manifest with a source below oldSources are recorded
+// with their new source. Nothing is unlinked first and nothing else changes.
+//
+// The returned Result lists the repaired links as linked. It is never nil.
+func (l *Linker) Repair(packageNames []string, oldSources ...string) (*Result, error) {
+	defer l.lockState()()
+
+	state, err := l.loadState()
+	if err != nil {
+		return &Result{}, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if err := l.checkRoot(); err != nil {
+		return &Result{}, err
+	}
+
+	for i, old := range oldSources {
+		if oldSources[i], err = filepath.Abs(old); err != nil {
+			return &Result{}, fmt.Errorf("failed to get absolute path for %s: %w", old, err)
+		}
+	}
+
+	var packages []Package
+	if len(packageNames) > 0 {
+		packages, err = l.lookupPackages(packageNames)
+	} else if packages, err = l.FindPackages(); err != nil {
+		err = fmt.Errorf("failed to find packages: %w", err)
+	}
+	if err != nil {
+		return &Result{}, err
+	}
+
+	classifier := &Classifier{State: state, FS: l.FS}
+	plan := &Plan{Packages: packages}
+	moved := 0
+	for _, pkg := range packages {
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+
+			switch c.State {
+			case TargetForeignLink:
+				dest, err := resolveLinkTarget(l.FS, path.targetPath)
+				if err != nil || !movedFrom(dest, filepath.Join(pkg.Name, path.relPath), oldSources) {
+					return nil
+				}
+				l.logVerbose("Repairing %s: %s moved to %s\n", path.targetPath, dest, path.sourcePath)
+				plan.add(Operation{
+					Kind:    OpLink,
+					Package: pkg.Name,
+					RelPath: path.relPath,
+					Source:  path.sourcePath,
+					Target:  path.targetPath,
+					Current: TargetForeignLink,
+				})
+
+			case TargetDeployed, TargetModified:
+				// The state entry of a copy still names its old package file
+				entry := c.Entry
+				if entry.Source == path.sourcePath || !movedFrom(entry.Source, filepath.Join(pkg.Name, path.relPath), oldSources) {
+					return nil
+				}
+				l.printf("Repairing state of %s: %s moved to %s\n", path.targetPath, entry.Source, path.sourcePath)
+				if !l.DryRun {
+					entry.Source = path.sourcePath
+					state.Record(entry)
+				}
+				moved++
+			}
+			return nil
+		})
+		if err != nil {
+			return &Result{}, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+	}
+
+	result, applyErr := l.executor(state, nil).Apply(plan)
+	if !l.DryRun {
+		if err := state.Save(); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+	if len(plan.Operations) == 0 && moved == 0 {
+		l.printf("Nothing to repair\n")
+	}
+	return result, applyErr
+}
+
+// movedFrom reports whether path is the old location of the package file at
+// pkgRelPath (the package name joined with the path inside the package): a
+// path below one of oldSources, or, without oldSources, a path that no longer
+// exists and ends in pkgRelPath.
+func movedFrom(path, pkgRelPath string, oldSources []string) bool {
+	if len(oldSources) == 0 {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			return false
+		}
+		return strings.HasSuffix(path, string(filepath.Separator)+pkgRelPath)
+	}
+	for _, old := range oldSources {
+		if rel, err := filepath.Rel(old, path); err == nil && rel == pkgRelPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{".vimrc": "vim", ".vim/colors/x.vim": "x"})
+	createDummyPackage(t, filepath.Join(sourceDir, "conf"), map[string]string{"app.conf": "conf"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"vim"})
+	require.NoError(t, err)
+	linker.Mode = ModeCopy
+	_, err = linker.Link([]string{"conf"})
+	require.NoError(t, err)
+	linker.Mode = ModeLink
+
+	// The dotfiles repository is renamed
+	newSource := sourceDir + "-moved"
+	require.NoError(t, os.Rename(sourceDir, newSource))
+	defer os.RemoveAll(newSource)
+	linker.SourceDir = newSource
+
+	result, err := linker.Repair(nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(targetDir, ".vimrc"), filepath.Join(targetDir, ".vim", "colors", "x.vim")}, result.Linked)
+
+	dest, err := os.Readlink(filepath.Join(targetDir, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(newSource, "vim", ".vimrc"), dest)
+
+	// Copies are recorded with their new package file
+	state, err := LoadState(filepath.Join(targetDir, StateFileName))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(newSource, "conf", "app.conf"), state.Entries[filepath.Join(targetDir, "app.conf")].Source)
+}
+
+func TestRepairOldSource(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	// The old checkout still exists, so its links are not broken
+	oldSource := t.TempDir()
+	createDummyPackage(t, filepath.Join(oldSource, "git"), map[string]string{".gitconfig": "old"})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": "new"})
+	require.NoError(t, os.Symlink(filepath.Join(oldSource, "git", ".gitconfig"), filepath.Join(targetDir, ".gitconfig")))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	result, err := linker.Repair(nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Linked)
+
+	result, err = linker.Repair([]string{"git"}, oldSource)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".gitconfig")}, result.Linked)
+	dest, err := os.Readlink(filepath.Join(targetDir, ".gitconfig"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sourceDir, "git", ".gitconfig"), dest)
+}
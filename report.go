@@ -0,0 +1,226 @@
+package gslk
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ReportFormat is the format Report.Write renders a report in.
+type ReportFormat string
+
+const (
+	ReportMarkdown ReportFormat = "md"   // GitHub-flavoured Markdown
+	ReportHTML     ReportFormat = "html" // A standalone HTML page
+)
+
+// ParseReportFormat checks that s names a report format.
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch format := ReportFormat(s); format {
+	case ReportMarkdown, ReportHTML:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown report format %q: must be md or html", s)
+}
+
+// Report documents the configuration state of a machine: the packages in the
+// source directories, where their files are deployed and in what state, and
+// the recent runs that changed the target directory.
+type Report struct {
+	Time       time.Time
+	Host       string
+	SourceDirs []string
+	TargetDir  string
+	Packages   []PackageReport
+	Changes    []HistoryEntry // Recent runs that changed files in TargetDir, oldest first
+}
+
+// PackageReport is a package in a Report, with its files.
+type PackageReport struct {
+	PackageStatus
+	Managed []ManagedFile // As listed by Linker.Files
+}
+
+// Report collects the state of every package in the source directories and
+// the last recent runs of history that changed files in the target directory;
+// a negative recent keeps them all. Nothing is modified.
+func (l *Linker) Report(history []HistoryEntry, recent int) (*Report, error) {
+	statuses, err := l.Status()
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	report := &Report{Time: time.Now(), Host: host, SourceDirs: l.sourceDirs(), TargetDir: l.TargetDir}
+	for _, status := range statuses {
+		files, err := l.Files(status.Package.Name)
+		if err != nil {
+			return nil, err
+		}
+		report.Packages = append(report.Packages, PackageReport{PackageStatus: status, Managed: files})
+	}
+
+	for _, entry := range history {
+		for _, op := range entry.Operations {
+			if isSubPath(l.TargetDir, op.Target) {
+				report.Changes = append(report.Changes, entry)
+				break
+			}
+		}
+	}
+	if recent >= 0 && len(report.Changes) > recent {
+		report.Changes = report.Changes[len(report.Changes)-recent:]
+	}
+	return report, nil
+}
+
+// Write renders the report to w in format.
+func (r *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportMarkdown:
+		return markdownReport.Execute(w, r)
+	case ReportHTML:
+		return htmlReport.Execute(w, r)
+	}
+	return fmt.Errorf("unknown report format %q: must be md or html", format)
+}
+
+// reportFuncs are the functions of both report templates.
+var reportFuncs = map[string]any{
+	"time": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"target": func(r *Report, path string) string {
+		if rel, err := filepath.Rel(r.TargetDir, path); err == nil && path != "" {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	},
+	"source": func(pkg PackageReport, path string) string {
+		for _, layer := range pkg.Package.Layers {
+			if rel, err := filepath.Rel(layer, path); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+		return path
+	},
+	"changes": func(entry HistoryEntry) string {
+		counts := make(map[OpKind]int)
+		var kinds []string
+		for _, op := range entry.Operations {
+			if counts[op.Op] == 0 {
+				kinds = append(kinds, string(op.Op))
+			}
+			counts[op.Op]++
+		}
+		for i, kind := range kinds {
+			kinds[i] = fmt.Sprintf("%d %s", counts[OpKind(kind)], kind)
+		}
+		return strings.Join(kinds, ", ")
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	// cell escapes the characters that would end a Markdown table cell
+	"cell": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	},
+}
+
+var markdownReport = template.Must(template.New("report.md").Funcs(reportFuncs).Parse(`# Configuration of {{ .Host }}
+
+Generated by gslk on {{ time .Time }} from {{ range $i, $dir := .SourceDirs }}{{ if $i }}, {{ end }}` + "`{{ $dir }}`" + `{{ end }} into ` + "`{{ .TargetDir }}`" + `.
+
+## Packages
+
+{{ if .Packages -}}
+| Package | Status | Deployed | Description |
+| --- | --- | --- | --- |
+{{ range .Packages -}}
+| [{{ cell .Package.Name }}](#package-{{ lower .Package.Name }}) | {{ .Status }} | {{ .Deployed }}/{{ .Files }} | {{ cell .Package.Manifest.Description }} |
+{{ end -}}
+{{ else -}}
+No packages.
+{{ end -}}
+{{ $report := . }}{{ range .Packages }}
+### Package {{ .Package.Name }}
+{{ with .Package.Manifest.Description }}
+{{ . }}
+{{ end }}
+| File | Target | State |
+| --- | --- | --- |
+{{ $pkg := . }}{{ range .Managed -}}
+| {{ cell (source $pkg .Source) }} | {{ with .Target }}{{ cell (target $report .) }}{{ else }}-{{ end }} | {{ .Status }} |
+{{ end -}}
+{{ end }}
+## Recent Changes
+
+{{ if .Changes -}}
+| Time | Action | Packages | Changes | Outcome |
+| --- | --- | --- | --- | --- |
+{{ range .Changes -}}
+| {{ time .Time }} | {{ .Action }} | {{ cell (join .Packages " ") }} | {{ changes . }} | {{ .Outcome }}{{ with .Error }}: {{ cell . }}{{ end }} |
+{{ end -}}
+{{ else -}}
+No changes recorded.
+{{ end -}}
+`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report.html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Configuration of {{ .Host }}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 60em; padding: 0 1em; }
+table { border-collapse: collapse; margin: 1em 0; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f4f4f4; }
+code, td.path { font-family: ui-monospace, monospace; }
+.linked { color: #1a7f37; }
+.partial, .modified { color: #9a6700; }
+.unlinked, .missing, .conflict { color: #cf222e; }
+.ignored { color: #6e7781; }
+</style>
+</head>
+<body>
+<h1>Configuration of {{ .Host }}</h1>
+<p>Generated by gslk on {{ time .Time }} from {{ range $i, $dir := .SourceDirs }}{{ if $i }}, {{ end }}<code>{{ $dir }}</code>{{ end }} into <code>{{ .TargetDir }}</code>.</p>
+
+<h2>Packages</h2>
+{{ if .Packages -}}
+<table>
+<tr><th>Package</th><th>Status</th><th>Deployed</th><th>Description</th></tr>
+{{ range .Packages -}}
+<tr><td><a href="#package-{{ .Package.Name }}">{{ .Package.Name }}</a></td><td class="{{ .Status }}">{{ .Status }}</td><td>{{ .Deployed }}/{{ .Files }}</td><td>{{ .Package.Manifest.Description }}</td></tr>
+{{ end -}}
+</table>
+{{ else -}}
+<p>No packages.</p>
+{{ end -}}
+{{ $report := . }}{{ range .Packages }}
+<h3 id="package-{{ .Package.Name }}">Package {{ .Package.Name }}</h3>
+{{ with .Package.Manifest.Description }}<p>{{ . }}</p>
+{{ end -}}
+<table>
+<tr><th>File</th><th>Target</th><th>State</th></tr>
+{{ $pkg := . }}{{ range .Managed -}}
+<tr><td class="path">{{ source $pkg .Source }}</td><td class="path">{{ with .Target }}{{ target $report . }}{{ else }}-{{ end }}</td><td class="{{ .Status }}">{{ .Status }}</td></tr>
+{{ end -}}
+</table>
+{{ end }}
+<h2>Recent Changes</h2>
+{{ if .Changes -}}
+<table>
+<tr><th>Time</th><th>Action</th><th>Packages</th><th>Changes</th><th>Outcome</th></tr>
+{{ range .Changes -}}
+<tr><td>{{ time .Time }}</td><td>{{ .Action }}</td><td>{{ join .Packages " " }}</td><td>{{ changes . }}</td><td>{{ .Outcome }}{{ with .Error }}: {{ . }}{{ end }}</td></tr>
+{{ end -}}
+</table>
+{{ else -}}
+<p>No changes recorded.</p>
+{{ end -}}
+</body>
+</html>
+`))
@@ -0,0 +1,63 @@
+package gslk
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{".vimrc": "set nu"})
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "vim", ManifestFileName), []byte(`description = "Editor <b>|</b> settings"`), 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": "[user]"})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"vim"})
+	require.NoError(t, err)
+
+	history := []HistoryEntry{
+		{Time: time.Now(), Action: "link", Packages: []string{"old"}, Operations: []HistoryOperation{{Op: OpLink, Target: filepath.Join(targetDir, ".old")}}},
+		{Time: time.Now(), Action: "link", Packages: []string{"elsewhere"}, Operations: []HistoryOperation{{Op: OpLink, Target: filepath.Join(sourceDir, ".x")}}},
+		{Time: time.Now(), Action: "link", Packages: []string{"vim"}, Outcome: "success", Operations: []HistoryOperation{
+			{Op: OpMkdir, Target: targetDir},
+			{Op: OpLink, Target: filepath.Join(targetDir, ".vimrc")},
+		}},
+	}
+	report, err := linker.Report(history, 1)
+	require.NoError(t, err)
+	require.Len(t, report.Packages, 2)
+	assert.Equal(t, "git", report.Packages[0].Package.Name)
+	assert.Equal(t, StatusUnlinked, report.Packages[0].Status)
+	assert.Equal(t, StatusLinked, report.Packages[1].Status)
+	require.Len(t, report.Packages[1].Managed, 1)
+	assert.Equal(t, FileLinked, report.Packages[1].Managed[0].Status)
+	require.Len(t, report.Changes, 1, "only the last run changing the target is kept")
+	assert.Equal(t, []string{"vim"}, report.Changes[0].Packages)
+
+	var md bytes.Buffer
+	require.NoError(t, report.Write(&md, ReportMarkdown))
+	assert.Contains(t, md.String(), "| [git](#package-git) | unlinked | 0/1 |  |\n")
+	assert.Contains(t, md.String(), `| [vim](#package-vim) | linked | 1/1 | Editor <b>\|</b> settings |`)
+	assert.Contains(t, md.String(), "| .vimrc | .vimrc | linked |\n")
+	assert.Contains(t, md.String(), "| link | vim | 1 mkdir, 1 link | success |\n")
+
+	var html bytes.Buffer
+	require.NoError(t, report.Write(&html, ReportHTML))
+	assert.Contains(t, html.String(), `<td>Editor &lt;b&gt;|&lt;/b&gt; settings</td>`)
+	assert.Contains(t, html.String(), `<h3 id="package-vim">Package vim</h3>`)
+	assert.Contains(t, html.String(), `<td class="missing">missing</td>`)
+
+	_, err = ParseReportFormat("pdf")
+	assert.Error(t, err)
+	assert.Error(t, report.Write(io.Discard, "pdf"))
+}
@@ -0,0 +1,114 @@
+package gslk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ConflictAction is the decision of a ConflictResolver about a conflict.
+type ConflictAction string
+
+const (
+	ResolveSkip      ConflictAction = "skip"      // Leave the target as it is and go on
+	ResolveOverwrite ConflictAction = "overwrite" // Replace the target in place, losing what it holds
+	ResolveBackup    ConflictAction = "backup"    // Move the target to the quarantine directory and proceed, as ConflictBackup
+	ResolveAdopt     ConflictAction = "adopt"     // Replace the target if it is identical to what is deployed, fail otherwise
+	ResolveAbort     ConflictAction = "abort"     // Fail with the conflict error, as ConflictFail
+)
+
+// Conflict describes a target path occupied by something Link may not
+// replace on its own.
+type Conflict struct {
+	// Operation is the operation the conflict stops: its Current field tells
+	// what occupies the target
+	Operation Operation
+
+	Target    fs.FileInfo // Lstat of the target; nil if it could not be read
+	Source    fs.FileInfo // Stat of the package file; nil if it could not be read
+	Owner     string      // Package the symlink in the way belongs to, if any
+	Identical bool        // The target is a regular file holding exactly what would be deployed
+	Err       error       // The conflict error, wrapping ErrConflict
+}
+
+// ConflictResolver decides what to do with each conflict met while planning
+// a link, in place of the conflict policy.
+type ConflictResolver interface {
+	Resolve(c Conflict) (ConflictAction, error)
+}
+
+// ConflictResolverFunc adapts a function to the ConflictResolver interface.
+type ConflictResolverFunc func(c Conflict) (ConflictAction, error)
+
+// Resolve calls f(c).
+func (f ConflictResolverFunc) Resolve(c Conflict) (ConflictAction, error) {
+	return f(c)
+}
+
+// newConflict gathers the details of the conflict at op.Target.
+func (l *Linker) newConflict(op Operation, err error) Conflict {
+	c := Conflict{Operation: op, Err: err}
+	c.Target, _ = orOS(l.FS).Lstat(op.Target)
+	c.Source, _ = os.Stat(op.Source)
+	if op.Current == TargetForeignLink {
+		if owner, err := l.linkOwner(op.Target); err == nil {
+			c.Owner = owner.Package
+		}
+	}
+	c.Identical = op.Current == TargetFile && l.isIdentical(op)
+	return c
+}
+
+// resolveConflict adds the operations carrying out the ConflictResolver's
+// decision about the conflict at op.Target.
+func (l *Linker) resolveConflict(plan *Plan, op Operation, conflict error) error {
+	c := l.newConflict(op, conflict)
+	action, err := l.ConflictResolver.Resolve(c)
+	if err != nil {
+		return fmt.Errorf("failed to resolve conflict at %s: %w", op.Target, err)
+	}
+
+	switch action {
+	case ResolveSkip:
+		op.Kind = OpSkip
+		op.Reason = "left in place by the conflict resolver"
+		plan.add(op)
+		return nil
+
+	case ResolveOverwrite:
+		if op.Current == TargetDirectory {
+			return fmt.Errorf("%w (directories are never overwritten)", conflict)
+		}
+		op.Current = TargetDeployed
+		op.Overwrite = true
+		plan.add(op)
+		return nil
+
+	case ResolveBackup:
+		if op.Current == TargetDirectory {
+			return fmt.Errorf("%w (directories are never moved aside)", conflict)
+		}
+		quarantine := op
+		quarantine.Kind = OpQuarantine
+		plan.add(quarantine)
+
+		op.Current = TargetMissing
+		plan.add(op)
+		return nil
+
+	case ResolveAdopt:
+		if !c.Identical {
+			return fmt.Errorf("%w (not identical to what would be deployed, cannot adopt it)", conflict)
+		}
+		op.Current = TargetDeployed
+		op.Adopt = true
+		plan.add(op)
+		return nil
+
+	case ResolveAbort:
+		return conflict
+
+	default:
+		return fmt.Errorf("invalid conflict resolution '%s' for %s", action, op.Target)
+	}
+}
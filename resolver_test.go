@@ -0,0 +1,137 @@
+package gslk
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkWithConflictResolver(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"skip.txt":      "package skip",
+		"overwrite.txt": "package overwrite",
+		"backup.txt":    "package backup",
+		"adopt.txt":     "package adopt",
+		"dir/file.txt":  "package dir",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "other"), map[string]string{
+		"overwrite.txt": "other overwrite",
+	})
+
+	write := func(rel, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(targetDir, rel)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(targetDir, rel), []byte(content), 0644))
+	}
+	write("skip.txt", "user skip")
+	write("backup.txt", "user backup")
+	write("adopt.txt", "package adopt")
+	require.NoError(t, os.Symlink(filepath.Join(sourceDir, "other", "overwrite.txt"), filepath.Join(targetDir, "overwrite.txt")))
+
+	actions := map[string]ConflictAction{
+		"skip.txt":      ResolveSkip,
+		"overwrite.txt": ResolveOverwrite,
+		"backup.txt":    ResolveBackup,
+		"adopt.txt":     ResolveAdopt,
+	}
+	conflicts := make(map[string]Conflict)
+	resolver := ConflictResolverFunc(func(c Conflict) (ConflictAction, error) {
+		conflicts[c.Operation.RelPath] = c
+		return actions[c.Operation.RelPath], nil
+	})
+	linker := New(sourceDir, targetDir, WithConflictResolver(resolver), WithLogger(log.New(io.Discard, "", 0)))
+
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	// The resolver gets the details of each conflict
+	require.Len(t, conflicts, 4)
+	assert.Equal(t, "other", conflicts["overwrite.txt"].Owner)
+	assert.True(t, conflicts["overwrite.txt"].Target.Mode()&os.ModeSymlink != 0)
+	assert.True(t, conflicts["adopt.txt"].Identical)
+	assert.False(t, conflicts["skip.txt"].Identical)
+	assert.Equal(t, int64(len("package skip")), conflicts["skip.txt"].Source.Size())
+	assert.ErrorIs(t, conflicts["skip.txt"].Err, ErrConflict)
+
+	content, err := os.ReadFile(filepath.Join(targetDir, "skip.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "user skip", string(content), "Skipped file should be left in place")
+
+	for _, rel := range []string{"overwrite.txt", "backup.txt", "adopt.txt", "dir/file.txt"} {
+		dest, err := os.Readlink(filepath.Join(targetDir, rel))
+		require.NoError(t, err, rel)
+		assert.Equal(t, filepath.Join(sourceDir, "pkg", rel), dest)
+	}
+
+	entries, err := linker.Quarantined(nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, filepath.Join(targetDir, "backup.txt"), entries[0].Target)
+}
+
+func TestConflictResolverRefusals(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"file.txt": "package content",
+	})
+	target := filepath.Join(targetDir, "file.txt")
+	require.NoError(t, os.WriteFile(target, []byte("user content"), 0644))
+
+	link := func(action ConflictAction, err error) error {
+		resolver := ConflictResolverFunc(func(Conflict) (ConflictAction, error) { return action, err })
+		linker := New(sourceDir, targetDir, WithConflictResolver(resolver), WithLogger(log.New(io.Discard, "", 0)))
+		_, linkErr := linker.Link([]string{"pkg"})
+		return linkErr
+	}
+
+	assert.ErrorIs(t, link(ResolveAbort, nil), ErrConflict)
+	assert.ErrorIs(t, link(ResolveAdopt, nil), ErrConflict, "Adopting a different file should fail")
+	assert.Error(t, link("merge", nil), "Unknown actions should fail")
+	errCancelled := errors.New("cancelled")
+	assert.ErrorIs(t, link("", errCancelled), errCancelled)
+
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "user content", string(content))
+
+	// Directories are never overwritten
+	require.NoError(t, os.Remove(target))
+	require.NoError(t, os.MkdirAll(filepath.Join(target, "sub"), 0755))
+	assert.ErrorIs(t, link(ResolveOverwrite, nil), ErrConflict)
+	assert.DirExists(t, filepath.Join(target, "sub"))
+}
+
+func TestConflictResolverOverwritesCopies(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"file.txt": "package content",
+	})
+	elsewhere := filepath.Join(t.TempDir(), "elsewhere.txt")
+	require.NoError(t, os.WriteFile(elsewhere, []byte("elsewhere"), 0644))
+	target := filepath.Join(targetDir, "file.txt")
+	require.NoError(t, os.Symlink(elsewhere, target))
+
+	resolver := ConflictResolverFunc(func(Conflict) (ConflictAction, error) { return ResolveOverwrite, nil })
+	linker := New(sourceDir, targetDir, WithMode(ModeCopy), WithConflictResolver(resolver), WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	fi, err := os.Lstat(target)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular(), "The symlink in the way should be replaced by a copy")
+	content, err := os.ReadFile(elsewhere)
+	require.NoError(t, err)
+	assert.Equal(t, "elsewhere", string(content), "The file the symlink pointed to should be untouched")
+}
@@ -0,0 +1,72 @@
+package gslk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result summarises what applying a plan did. Paths are target paths, except
+// for Ignored which lists source paths.
+type Result struct {
+	Linked    []string // Files linked, copied, rendered or decrypted into the target, and directories bind-mounted there
+	Unlinked  []string // Links, deployed files and bind mounts removed from the target
+	Skipped   []string // Files left as they were, e.g. because they are up to date
+	Ignored   []string // Package paths excluded by ignore rules
+	Conflicts []string // Files in the way that were quarantined
+	Failed    []string // Files whose operation failed
+}
+
+// record adds the target of a successfully applied operation to the result.
+func (r *Result) record(op Operation) {
+	switch op.Kind {
+	case OpLink, OpCopy, OpRender, OpMount:
+		r.Linked = append(r.Linked, op.Target)
+	case OpUnlink, OpRemove, OpUnmount:
+		r.Unlinked = append(r.Unlinked, op.Target)
+	case OpSkip:
+		r.Skipped = append(r.Skipped, op.Target)
+	case OpQuarantine:
+		r.Conflicts = append(r.Conflicts, op.Target)
+	}
+}
+
+// Merge appends the paths of other to r.
+func (r *Result) Merge(other *Result) {
+	if other == nil {
+		return
+	}
+	r.Linked = append(r.Linked, other.Linked...)
+	r.Unlinked = append(r.Unlinked, other.Unlinked...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Ignored = append(r.Ignored, other.Ignored...)
+	r.Conflicts = append(r.Conflicts, other.Conflicts...)
+	r.Failed = append(r.Failed, other.Failed...)
+}
+
+// Empty reports whether r records no path at all, as for runs failing while
+// planning, before anything was applied.
+func (r *Result) Empty() bool {
+	return len(r.Linked)+len(r.Unlinked)+len(r.Skipped)+len(r.Ignored)+len(r.Conflicts)+len(r.Failed) == 0
+}
+
+// String returns a one-line summary such as "12 linked, 3 skipped, 0 conflicts".
+// Categories without paths are left out, except conflicts.
+func (r *Result) String() string {
+	var parts []string
+	for _, c := range []struct {
+		label string
+		paths []string
+	}{
+		{"linked", r.Linked},
+		{"unlinked", r.Unlinked},
+		{"skipped", r.Skipped},
+		{"ignored", r.Ignored},
+		{"failed", r.Failed},
+	} {
+		if len(c.paths) > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", len(c.paths), c.label))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%d conflicts", len(r.Conflicts)))
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,51 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultString(t *testing.T) {
+	assert.Equal(t, "0 conflicts", (&Result{}).String())
+	assert.True(t, (&Result{}).Empty())
+
+	result := &Result{
+		Linked:    []string{"a", "b"},
+		Skipped:   []string{"c"},
+		Conflicts: []string{"d"},
+	}
+	assert.Equal(t, "2 linked, 1 skipped, 1 conflicts", result.String())
+	assert.False(t, result.Empty())
+
+	result.Merge(&Result{Unlinked: []string{"e"}, Failed: []string{"f"}})
+	assert.Equal(t, "2 linked, 1 unlinked, 1 skipped, 1 failed, 1 conflicts", result.String())
+}
+
+func TestLinkResult(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "result_pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"one.txt":      "1",
+		"two.txt":      "2",
+		"notes.md":     "ignored",
+		".gslk-ignore": "*.md\n",
+	})
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, "two.txt"), filepath.Join(targetDir, "two.txt")))
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	result, err := linker.Link([]string{"result_pkg"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, "one.txt")}, result.Linked)
+	assert.Equal(t, []string{filepath.Join(targetDir, "two.txt")}, result.Skipped)
+	assert.Contains(t, result.Ignored, filepath.Join(pkgPath, "notes.md"))
+
+	result, err = linker.Unlink([]string{"result_pkg"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(targetDir, "one.txt"), filepath.Join(targetDir, "two.txt")}, result.Unlinked)
+}
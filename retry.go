@@ -0,0 +1,37 @@
+package gslk
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRetryBackoff is how long the first retry of a filesystem call that
+// failed transiently waits; every further retry waits twice as long as the
+// one before.
+const DefaultRetryBackoff = 100 * time.Millisecond
+
+// retry calls fn, which does what to path, until it succeeds, fails with an
+// error that does not look transient (see isTransient), or Retries retries
+// were made. Every retry is logged in verbose mode; an error returned after
+// retrying says how many attempts were made.
+func (e *Executor) retry(what, path string, fn func() error) error {
+	delay := e.RetryBackoff
+	if delay <= 0 {
+		delay = DefaultRetryBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isTransient(err) || attempt > e.Retries {
+			if err != nil && attempt > 1 {
+				return fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+			if err == nil && attempt > 1 {
+				e.logVerbose("Attempt %d to %s %s succeeded\n", attempt, what, path)
+			}
+			return err
+		}
+		e.logVerbose("Failed to %s %s (attempt %d of %d), retrying in %s: %v\n", what, path, attempt, e.Retries+1, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,68 @@
+package gslk
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyFS fails the first failures calls to Symlink with err, as a network
+// filesystem might.
+type flakyFS struct {
+	OSFS
+	mu       sync.Mutex
+	failures int
+	err      error
+}
+
+func (f *flakyFS) Symlink(oldname, newname string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: f.err}
+	}
+	return f.OSFS.Symlink(oldname, newname)
+}
+
+func TestLinkRetriesTransientErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ESTALE is not a transient error on Windows")
+	}
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{"file": "content"})
+
+	var out strings.Builder
+	fsys := &flakyFS{failures: 2, err: syscall.ESTALE}
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, FS: fsys, Retries: 2, RetryBackoff: time.Millisecond, Verbose: true, Logger: log.New(&out, "", 0)}
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(targetDir, "file"))
+	assert.Contains(t, out.String(), "(attempt 2 of 3), retrying in 2ms")
+	assert.Contains(t, out.String(), "Attempt 3 to create symlink "+filepath.Join(targetDir, "file")+" succeeded")
+
+	// Running out of retries reports the attempts made
+	_, err = linker.Unlink([]string{"pkg"})
+	require.NoError(t, err)
+	fsys.failures = 3
+	_, err = linker.Link([]string{"pkg"})
+	assert.ErrorContains(t, err, "gave up after 3 attempts")
+
+	// Other errors are not retried
+	fsys.failures, fsys.err = 2, syscall.EACCES
+	_, err = linker.Link([]string{"pkg"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "attempts")
+	assert.Equal(t, 1, fsys.failures)
+}
@@ -0,0 +1,76 @@
+package gslk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Sandbox returns a copy of the Linker deploying into dir, a scratch
+// directory standing in for the target, to try packages out: manifests and
+// templates still see TargetDir, so a package target given as an absolute
+// path in it lands at the same place in dir. The copy works on the local
+// filesystem and changes dir even if the Linker is read-only, a dry run or
+// manages a remote target, keeps its state manifest in dir and never runs
+// hooks.
+func (l *Linker) Sandbox(dir string) *Linker {
+	linker := *l
+	linker.TargetDir = dir
+	linker.FS = nil
+	linker.DryRun = false
+	linker.ReadOnly = false
+	linker.sandboxFor = l.declaredTarget()
+	return &linker
+}
+
+// declaredTarget returns the target directory package manifests and
+// templates refer to: the one a sandbox stands in for, or TargetDir.
+func (l *Linker) declaredTarget() string {
+	if l.sandboxFor != "" {
+		return l.sandboxFor
+	}
+	return l.TargetDir
+}
+
+// TreeEntry is a file or directory listed by ListTree.
+type TreeEntry struct {
+	Path string      // Relative to the listed directory
+	Mode fs.FileMode // Type and permissions
+	Link string      // Destination of a symlink
+	Size int64       // Size of a regular file
+}
+
+// ListTree lists what is below dir, sorted by path, without the state
+// manifest; for instance what a sandbox Linker deployed.
+func ListTree(dir string) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." || rel == StateFileName {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := TreeEntry{Path: rel, Mode: fi.Mode()}
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			if entry.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case fi.Mode().IsRegular():
+			entry.Size = fi.Size()
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	return entries, nil
+}
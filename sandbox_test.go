@@ -0,0 +1,53 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandbox(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	hookLog := filepath.Join(t.TempDir(), "hooks.log")
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{
+		"init.lua": "-- nvim",
+		ManifestFileName: "target = \"" + filepath.ToSlash(filepath.Join(targetDir, ".config", "nvim")) + "\"\n" +
+			"[[hooks]]\nwhen = \"post-link\"\nrun = \"echo ran > " + filepath.ToSlash(hookLog) + "\"\n",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{
+		".gitconfig.tmpl": "[user]\n\tname = {{ .Vars.name }}\n",
+	})
+
+	linker := New(sourceDir, targetDir, WithReadOnly(), WithDryRun(), WithLogger(log.New(io.Discard, "", 0)),
+		WithMapper(Mapper{TemplateSuffix: DefaultTemplateSuffix}), WithVars(map[string]string{"name": "Ada"}))
+	sandboxDir := t.TempDir()
+	sandbox := linker.Sandbox(sandboxDir)
+	_, err := sandbox.Link([]string{"nvim", "git"})
+	require.NoError(t, err)
+
+	// The absolute target of the manifest is placed in the sandbox
+	tree, err := ListTree(sandboxDir)
+	require.NoError(t, err)
+	var paths []string
+	for _, entry := range tree {
+		paths = append(paths, filepath.ToSlash(entry.Path))
+	}
+	assert.Equal(t, []string{".config", ".config/nvim", ".config/nvim/init.lua", ".gitconfig"}, paths)
+	assert.Equal(t, filepath.Join(sourceDir, "nvim", "init.lua"), tree[2].Link)
+	assert.Equal(t, int64(len("[user]\n\tname = Ada\n")), tree[3].Size)
+
+	// Neither the target nor the original Linker are touched, and hooks do not run
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoFileExists(t, hookLog)
+	assert.Equal(t, targetDir, linker.TargetDir)
+	assert.True(t, linker.DryRun)
+}
@@ -0,0 +1,141 @@
+package gslk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repoConfigTemplate is the gslk.toml written by InitRepo.
+const repoConfigTemplate = `# gslk configuration of this repository, read by gslk clone.
+# Relative paths are resolved against the repository.
+
+# Source directories holding the packages; the repository itself by default
+# sources = ["."]
+# target = "~"
+
+# Packages gslk clone and gslk apply link
+# [apply]
+# packages = ["zsh", "git"]
+
+# Variables available to templates as {{ .Vars.name }}
+# [vars]
+# email = "me@example.com"
+
+# Named sets of packages
+# [groups]
+# shell = ["zsh", "tmux"]
+`
+
+// repoReadmeTemplate is the README.md written by InitRepo.
+const repoReadmeTemplate = `# Dotfiles
+
+Each directory is a package, linked into the home directory by gslk:
+
+    gslk new zsh             # create a package
+    gslk -v zsh              # link it
+    gslk clone <this repo>   # set up another machine
+`
+
+// packageManifestTemplate is the manifest written by ScaffoldPackage; %s is
+// the quoted description.
+const packageManifestTemplate = `description = %s
+# target = ".config/name"   # Link the package below this directory of the target
+# os = ["linux", "darwin"]  # Skip the package on other systems
+# depends = []              # Packages this one needs
+
+# [rename]                  # Place a package file under another name
+# "config" = ".config/name/config"
+
+# [vars]                    # Template variables the package needs, asked for when not set
+# email = "Email address for commits"
+
+# [[hooks]]                 # Run with the shell in the package directory
+# when = "post-link"        # pre-link, post-link, pre-unlink or post-unlink
+# run = "echo linked $GSLK_PACKAGE into $GSLK_TARGET"
+`
+
+// packageIgnoreTemplate is the ignore file written by ScaffoldPackage.
+const packageIgnoreTemplate = `# Package paths that are never linked, one pattern per line
+README.md
+`
+
+// InitRepo creates the layout of a new dotfiles repository in dir: a
+// gslk.toml with the available settings commented out and a README.md. Files
+// that already exist are left alone, except that an existing gslk.toml is an
+// error. It returns the files created.
+func InitRepo(dir string) ([]string, error) {
+	config := filepath.Join(dir, RepoConfigFileName)
+	if _, err := os.Lstat(config); err == nil {
+		return nil, fmt.Errorf("%s already exists", config)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var created []string
+	for _, file := range []struct{ name, content string }{
+		{RepoConfigFileName, repoConfigTemplate},
+		{"README.md", repoReadmeTemplate},
+	} {
+		ok, err := createFile(filepath.Join(dir, file.name), file.content)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created = append(created, filepath.Join(dir, file.name))
+		}
+	}
+	return created, nil
+}
+
+// ScaffoldPackage creates package name in sourceDir with a manifest holding
+// description and the other keys commented out, including a hook skeleton,
+// and an ignore file. The package must not exist yet. It returns the files
+// created.
+func ScaffoldPackage(sourceDir, name, description string) ([]string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid package name %q", name)
+	}
+	pkgDir := filepath.Join(sourceDir, name)
+	if _, err := os.Lstat(pkgDir); err == nil {
+		return nil, fmt.Errorf("package %s already exists in %s", name, sourceDir)
+	}
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create package %s: %w", name, err)
+	}
+	if description == "" {
+		description = name + " configuration"
+	}
+
+	var created []string
+	for _, file := range []struct{ name, content string }{
+		{ManifestFileName, fmt.Sprintf(packageManifestTemplate, tomlQuote(description))},
+		{IgnoreFileName, packageIgnoreTemplate},
+	} {
+		if _, err := createFile(filepath.Join(pkgDir, file.name), file.content); err != nil {
+			return created, err
+		}
+		created = append(created, filepath.Join(pkgDir, file.name))
+	}
+	return created, nil
+}
+
+// createFile writes content to a new file at path, returning false if the
+// file already exists.
+func createFile(path, content string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, f.Close()
+}
@@ -0,0 +1,46 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitRepo(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dotfiles")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("mine"), 0644))
+
+	created, err := InitRepo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, RepoConfigFileName)}, created)
+	assert.Equal(t, "mine", readMigrated(t, dir, "README.md"))
+
+	_, err = LoadConfig(filepath.Join(dir, RepoConfigFileName))
+	assert.NoError(t, err)
+
+	_, err = InitRepo(dir)
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestScaffoldPackage(t *testing.T) {
+	dir := t.TempDir()
+
+	created, err := ScaffoldPackage(dir, "zsh", `Z "shell"`)
+	require.NoError(t, err)
+	assert.Len(t, created, 2)
+	manifest, err := LoadManifest(filepath.Join(dir, "zsh"))
+	require.NoError(t, err)
+	assert.Equal(t, `Z "shell"`, manifest.Description)
+	assert.FileExists(t, filepath.Join(dir, "zsh", IgnoreFileName))
+
+	_, err = ScaffoldPackage(dir, "zsh", "")
+	assert.ErrorContains(t, err, "already exists")
+	for _, name := range []string{"", "a/b", ".hidden"} {
+		_, err = ScaffoldPackage(dir, name, "")
+		assert.ErrorContains(t, err, "invalid package name", name)
+	}
+}
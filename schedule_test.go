@@ -0,0 +1,52 @@
+package gslk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 17, 30, 0, time.UTC)
+
+	schedule, err := ParseSchedule("30m")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(30*time.Minute), schedule.Next(start))
+
+	schedule, err = ParseSchedule("0 */6 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), schedule.Next(start))
+
+	for _, invalid := range []string{"", "-5m", "hourly", "* * * *", "61 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *"} {
+		_, err := ParseSchedule(invalid)
+		assert.Error(t, err, "schedule %q", invalid)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 17, 30, 0, time.UTC) // A Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 1, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+		{"30 2 * * 7", time.Date(2024, 3, 3, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0,30 8-9 * 12 *", time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches
+		{"0 0 15 * 6", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, cron.Next(start), tt.expr)
+	}
+}
@@ -0,0 +1,79 @@
+package gslk
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SearchResult is a package file matching a search.
+type SearchResult struct {
+	Package string
+	Source  string // Absolute path of the file in the package
+	RelPath string // Path of the file relative to the package root
+	Target  string // Path the file is deployed at; empty for ignored files
+	Ignored bool   // Excluded by the package's ignore rules, so never deployed
+}
+
+// Search finds the files of every package matching one of patterns, with the
+// package providing them and where they are deployed. Patterns use the syntax
+// of ignore patterns and are matched against the path of each file relative
+// to its package and to the target directory: a pattern without a separator,
+// such as "kitty.conf" or "*.lua", matches a base name at any depth, and a
+// file also matches when a directory containing it does. Files the ignore
+// rules exclude are reported as ignored; ignored directories are matched
+// without their contents. Nothing is modified.
+func (l *Linker) Search(patterns []string) ([]SearchResult, error) {
+	for _, pattern := range patterns {
+		if err := CheckPattern(pattern); err != nil {
+			return nil, &PatternError{Pattern: pattern, Err: err}
+		}
+	}
+	compiled := compilePatterns(patterns)
+	matches := func(relPath string) bool {
+		for p := filepath.ToSlash(relPath); p != "."; p = path.Dir(p) {
+			if matchPatterns(p, compiled) {
+				return true
+			}
+		}
+		return false
+	}
+
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	var results []SearchResult
+	for _, pkg := range packages {
+		ignored, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			rel, err := filepath.Rel(l.TargetDir, path.targetPath)
+			if err != nil {
+				return err
+			}
+			if matches(path.relPath) || matches(rel) {
+				results = append(results, SearchResult{Package: pkg.Name, Source: path.sourcePath, RelPath: path.relPath, Target: path.targetPath})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+
+		for _, sourcePath := range ignored {
+			for _, layer := range pkg.Layers {
+				if rel, err := filepath.Rel(layer, sourcePath); err == nil && !strings.HasPrefix(rel, "..") {
+					if matches(rel) {
+						results = append(results, SearchResult{Package: pkg.Name, Source: sourcePath, RelPath: rel, Ignored: true})
+					}
+					break
+				}
+			}
+		}
+	}
+	return results, nil
+}
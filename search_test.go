@@ -0,0 +1,64 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "kitty"), map[string]string{
+		".config/kitty/kitty.conf":  "font_size 12",
+		".config/kitty/theme.conf":  "theme",
+		".config/kitty/kitty.conf~": "backup",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "kitty", IgnoreFileName), []byte("*~\n"), 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "nvim"), map[string]string{"init.lua": "init", "lua/opts.lua": "opts"})
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "nvim", ManifestFileName), []byte(`target = ".config/nvim"`), 0644))
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	results, err := linker.Search([]string{"kitty.conf"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, SearchResult{
+		Package: "kitty",
+		Source:  filepath.Join(sourceDir, "kitty", ".config", "kitty", "kitty.conf"),
+		RelPath: filepath.Join(".config", "kitty", "kitty.conf"),
+		Target:  filepath.Join(targetDir, ".config", "kitty", "kitty.conf"),
+	}, results[0])
+
+	results, err = linker.Search([]string{"kitty.conf*"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[1].Ignored)
+	assert.Empty(t, results[1].Target)
+
+	results, err = linker.Search([]string{".config/nvim", "*.conf"})
+	require.NoError(t, err)
+	var found []string
+	for _, result := range results {
+		found = append(found, result.Package+":"+filepath.ToSlash(result.RelPath))
+	}
+	assert.Equal(t, []string{
+		"kitty:.config/kitty/kitty.conf",
+		"kitty:.config/kitty/theme.conf",
+		"nvim:init.lua",
+		"nvim:lua/opts.lua",
+	}, found, "directories match everything below them, in the target too")
+
+	results, err = linker.Search([]string{"missing"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	_, err = linker.Search([]string{"[invalid"})
+	var patternErr *PatternError
+	assert.ErrorAs(t, err, &patternErr)
+}
@@ -0,0 +1,125 @@
+package gslk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSecretSuffix is the file name suffix marking age-encrypted package files.
+const DefaultSecretSuffix = ".age"
+
+// Age encrypts and decrypts secrets by running the age command-line tool
+// (https://age-encryption.org).
+type Age struct {
+	Command    string   // Path or name of the age binary; defaults to "age"
+	Identity   string   // Identity (private key) file used for decryption
+	Recipients []string // Public keys to encrypt to; if empty, the identity's own key is used
+}
+
+// command returns the age binary to run.
+func (a *Age) command() string {
+	if a.Command == "" {
+		return "age"
+	}
+	return a.Command
+}
+
+// Decrypt returns the plaintext of the age-encrypted file at path.
+func (a *Age) Decrypt(path string) ([]byte, error) {
+	if a.Identity == "" {
+		return nil, fmt.Errorf("cannot decrypt %s: no age identity configured", path)
+	}
+	return a.run(nil, "--decrypt", "-i", a.Identity, path)
+}
+
+// Encrypt returns plaintext encrypted to the configured recipients.
+func (a *Age) Encrypt(plaintext []byte) ([]byte, error) {
+	args := []string{"--encrypt"}
+	switch {
+	case len(a.Recipients) > 0:
+		for _, recipient := range a.Recipients {
+			args = append(args, "-r", recipient)
+		}
+	case a.Identity != "":
+		args = append(args, "-i", a.Identity)
+	default:
+		return nil, fmt.Errorf("cannot encrypt: no age recipients or identity configured")
+	}
+	return a.run(plaintext, args...)
+}
+
+// run executes age with args, feeding it stdin and returning its output.
+func (a *Age) run(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(a.command(), args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("age is not installed (looked for %q in PATH)", a.command())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", a.command(), msg)
+		}
+		return nil, fmt.Errorf("%s: %w", a.command(), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// EncryptFile encrypts plaintext to the configured recipients and writes the
+// result to path, creating parent directories as needed.
+func (a *Age) EncryptFile(path string, plaintext []byte) error {
+	ciphertext, err := a.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := writeFile(path, bytes.NewReader(ciphertext), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// SecretPath returns the path of the encrypted file for relPath in package
+// pkgName; relPath may include the secret suffix or not. An existing file in
+// the highest-precedence layer providing it is preferred. Otherwise the path
+// is in the package's highest-precedence layer, or in a new package directory
+// in the last source directory if the package does not exist yet.
+func (l *Linker) SecretPath(pkgName, relPath string) string {
+	suffix := l.Mapper.SecretSuffix
+	if suffix == "" {
+		suffix = DefaultSecretSuffix
+	}
+	if !strings.HasSuffix(relPath, suffix) {
+		relPath += suffix
+	}
+
+	sources := l.sourceDirs()
+	layers := []string{filepath.Join(sources[len(sources)-1], pkgName)}
+	if packages, err := l.FindPackages(); err == nil {
+		for _, pkg := range packages {
+			if pkg.Name == pkgName {
+				layers = pkg.Layers
+			}
+		}
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		path := filepath.Join(layers[i], relPath)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(layers[len(layers)-1], relPath)
+}
@@ -0,0 +1,119 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAge writes a stand-in for the age binary that "encrypts" by prepending a
+// header line and "decrypts" by dropping it.
+func fakeAge(t *testing.T) Age {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake age binary is a shell script")
+	}
+
+	dir := t.TempDir()
+	command := filepath.Join(dir, "age")
+	script := `#!/bin/sh
+mode=
+file=
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--decrypt) mode=d ;;
+	--encrypt) mode=e ;;
+	-i|-r) shift ;;
+	*) file=$1 ;;
+	esac
+	shift
+done
+if [ "$mode" = d ]; then
+	head -n 1 "$file" | grep -q '^fake-age$' || { echo "not an age file" >&2; exit 1; }
+	tail -n +2 "$file"
+else
+	echo fake-age
+	cat
+fi
+`
+	require.NoError(t, os.WriteFile(command, []byte(script), 0755))
+
+	identity := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(identity, []byte("AGE-SECRET-KEY-FAKE"), 0600))
+	return Age{Command: command, Identity: identity}
+}
+
+func TestLinkDecryptsSecrets(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	age := fakeAge(t)
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mapper:    Mapper{SecretSuffix: DefaultSecretSuffix},
+		Age:       age,
+	}
+
+	secretPath := linker.SecretPath("ssh", ".ssh/config")
+	assert.Equal(t, filepath.Join(sourceDir, "ssh", ".ssh", "config.age"), secretPath)
+	require.NoError(t, age.EncryptFile(secretPath, []byte("Host *\n")))
+
+	ciphertext, err := os.ReadFile(secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, "fake-age\nHost *\n", string(ciphertext))
+
+	_, err = linker.Link([]string{"ssh"})
+
+	require.NoError(t, err)
+
+	decryptedPath := filepath.Join(targetDir, ".ssh", "config")
+	fi, err := os.Lstat(decryptedPath)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular())
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "Decrypted secrets are only readable by the owner")
+	content, err := os.ReadFile(decryptedPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Host *\n", string(content))
+
+	// Linking again leaves the up-to-date secret alone
+	plan, err := linker.PlanLink([]string{"ssh"})
+	require.NoError(t, err)
+	assert.Equal(t, []OpKind{OpSkip}, opsByTarget(t, plan, targetDir)[".ssh/config"])
+
+	_, err = linker.Unlink([]string{"ssh"})
+
+	require.NoError(t, err)
+	_, err = os.Lstat(decryptedPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSecretWithoutIdentity(t *testing.T) {
+	age := fakeAge(t)
+	age.Identity = ""
+
+	_, err := age.Decrypt("/nonexistent.age")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no age identity configured")
+
+	_, err = age.Encrypt([]byte("x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no age recipients or identity configured")
+}
+
+func TestSecretPathPrefersExistingLayer(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	overlayDir := filepath.Join(filepath.Dir(sourceDir), "overlay")
+	createDummyPackage(t, filepath.Join(sourceDir, "ssh"), map[string]string{"token.age": "base"})
+	createDummyPackage(t, filepath.Join(overlayDir, "ssh"), map[string]string{"other": "x"})
+
+	linker := &Linker{SourceDir: sourceDir, ExtraSources: []string{overlayDir}, TargetDir: targetDir}
+	assert.Equal(t, filepath.Join(sourceDir, "ssh", "token.age"), linker.SecretPath("ssh", "token"))
+	assert.Equal(t, filepath.Join(overlayDir, "ssh", "new.age"), linker.SecretPath("ssh", "new.age"))
+	assert.Equal(t, filepath.Join(overlayDir, "fresh", "a.age"), linker.SecretPath("fresh", "a"))
+}
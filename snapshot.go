@@ -0,0 +1,221 @@
+package gslk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SnapshotFileName is the file in the target directory where gslk snapshot
+// saves the snapshot of the target by default.
+const SnapshotFileName = ".gslk-snapshot.json"
+
+// Snapshot records the managed portion of the target directory: the files
+// deployed from the packages, as they are, and the directories gslk created.
+// Compare reports how the target has changed since.
+type Snapshot struct {
+	Time   time.Time      `json:"time"`
+	Target string         `json:"target"`
+	Files  []SnapshotFile `json:"files"`
+	Dirs   []string       `json:"dirs,omitempty"` // Directories gslk created; anything else in them is foreign
+}
+
+// SnapshotFile is a deployed file as recorded in a Snapshot.
+type SnapshotFile struct {
+	Target  string      `json:"target"`
+	Package string      `json:"package"`
+	Mode    DeployMode  `json:"mode"`
+	Link    string      `json:"link,omitempty"` // Destination of a symlink (ModeLink)
+	Hash    string      `json:"hash,omitempty"` // Checksum of the content of any other file
+	Perm    os.FileMode `json:"perm,omitempty"` // Permissions of any other file
+}
+
+// SnapshotChangeKind categorises a change of the target since a Snapshot.
+type SnapshotChangeKind string
+
+const (
+	SnapshotModified SnapshotChangeKind = "modified" // A recorded file has other content, permissions or link destination
+	SnapshotDeleted  SnapshotChangeKind = "deleted"  // A recorded file is gone
+	SnapshotForeign  SnapshotChangeKind = "foreign"  // A path in a directory gslk created was not there
+)
+
+// SnapshotChange is a difference between the target and a Snapshot.
+type SnapshotChange struct {
+	Kind    SnapshotChangeKind
+	Target  string
+	Package string // Package the file was deployed from; empty for foreign paths
+	Detail  string // What changed (SnapshotModified)
+}
+
+// TakeSnapshot records the files currently deployed from the packages, with
+// the destination of links and the checksum and permissions of other files,
+// and the directories gslk created. Nothing is written; see Linker.SaveSnapshot.
+func (l *Linker) TakeSnapshot() (*Snapshot, error) {
+	if remote, ok := l.FS.(RemoteFS); ok {
+		return nil, fmt.Errorf("snapshots are not supported on remote target %s", remote.Location())
+	}
+	entries, err := l.deployedEntries()
+	if err != nil {
+		return nil, err
+	}
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	snapshot := &Snapshot{Time: time.Now(), Target: l.TargetDir, Files: []SnapshotFile{}}
+	for _, entry := range entries {
+		file := SnapshotFile{Target: entry.Target, Package: entry.Package, Mode: entry.Mode}
+		if file.Mode == ModeLink {
+			if file.Link, err = os.Readlink(entry.Target); err != nil {
+				return nil, fmt.Errorf("failed to read link %s: %w", entry.Target, err)
+			}
+		} else if file.Hash, file.Perm, err = fileChecksum(entry.Target); err != nil {
+			return nil, err
+		}
+		snapshot.Files = append(snapshot.Files, file)
+	}
+	snapshot.Dirs = slices.Sorted(maps.Keys(state.Directories))
+	return snapshot, nil
+}
+
+// fileChecksum returns the checksum and permissions of the regular file at path.
+func fileChecksum(path string) (string, os.FileMode, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hash, fi.Mode().Perm(), nil
+}
+
+// Save writes the snapshot to path as JSON.
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// SaveSnapshot saves snapshot to path like Snapshot.Save, refusing to write
+// it into the target in read-only mode.
+func (l *Linker) SaveSnapshot(snapshot *Snapshot, path string) error {
+	target, err := filepath.Abs(l.TargetDir)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if err := checkWritable(l.fs(), "write", abs); err != nil && isSubPath(target, abs) {
+		return err
+	}
+	return snapshot.Save(path)
+}
+
+// LoadSnapshot reads a snapshot saved by Snapshot.Save.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+// Compare reports how the target differs from s, sorted by path:
+// recorded files that were edited, had their permissions changed, were
+// replaced or point elsewhere, recorded files that are gone, and paths in the
+// directories gslk created that s does not know about. Nothing is modified.
+func (s *Snapshot) Compare() ([]SnapshotChange, error) {
+	known := make(map[string]bool, len(s.Files)+len(s.Dirs))
+	var changes []SnapshotChange
+	for _, file := range s.Files {
+		known[file.Target] = true
+		detail, err := snapshotFileChange(file)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			changes = append(changes, SnapshotChange{Kind: SnapshotDeleted, Target: file.Target, Package: file.Package})
+		case err != nil:
+			return nil, err
+		case detail != "":
+			changes = append(changes, SnapshotChange{Kind: SnapshotModified, Target: file.Target, Package: file.Package, Detail: detail})
+		}
+	}
+
+	for _, dir := range s.Dirs {
+		known[dir] = true
+	}
+	for _, dir := range s.Dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Its files are reported as deleted
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if !known[path] {
+				changes = append(changes, SnapshotChange{Kind: SnapshotForeign, Target: path})
+			}
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b SnapshotChange) int { return strings.Compare(a.Target, b.Target) })
+	return changes, nil
+}
+
+// snapshotFileChange describes how the file at file.Target differs from
+// file, or returns an empty string if it does not.
+func snapshotFileChange(file SnapshotFile) (string, error) {
+	fi, err := os.Lstat(file.Target)
+	if err != nil {
+		return "", err
+	}
+	isLink := fi.Mode()&os.ModeSymlink != 0
+	switch {
+	case file.Mode == ModeLink && !isLink:
+		return "the symlink was replaced", nil
+	case file.Mode == ModeLink:
+		dest, err := os.Readlink(file.Target)
+		if err != nil {
+			return "", err
+		}
+		if dest != file.Link {
+			return fmt.Sprintf("links to %s instead of %s", dest, file.Link), nil
+		}
+		return "", nil
+	case !fi.Mode().IsRegular():
+		return fmt.Sprintf("the %s is no longer a regular file", file.Mode), nil
+	}
+
+	hash, perm, err := fileChecksum(file.Target)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case hash != file.Hash:
+		return "content changed", nil
+	case perm != file.Perm:
+		return fmt.Sprintf("permissions changed from %04o to %04o", file.Perm, perm), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,90 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotCompare(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{
+		".vimrc":             "set nocompatible",
+		".vim/colors/a.vim":  "colors",
+		".vim/syntax/go.vim": "syntax",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "zsh"), map[string]string{
+		".zshrc": "setopt autocd",
+	})
+	discard := WithLogger(log.New(io.Discard, "", 0))
+	_, err := New(sourceDir, targetDir, discard).Link([]string{"vim"})
+	require.NoError(t, err)
+	linker := New(sourceDir, targetDir, discard, WithMode(ModeCopy))
+	_, err = linker.Link([]string{"zsh"})
+	require.NoError(t, err)
+
+	snapshot, err := linker.TakeSnapshot()
+	require.NoError(t, err)
+	require.Len(t, snapshot.Files, 4)
+	assert.Contains(t, snapshot.Dirs, filepath.Join(targetDir, ".vim", "colors"))
+
+	// Read-only mode keeps the snapshot out of the target, not out of others
+	path := filepath.Join(targetDir, SnapshotFileName)
+	readOnly := New(sourceDir, targetDir, WithReadOnly())
+	assert.ErrorIs(t, readOnly.SaveSnapshot(snapshot, path), ErrReadOnly)
+	assert.NoFileExists(t, path)
+	require.NoError(t, readOnly.SaveSnapshot(snapshot, filepath.Join(sourceDir, SnapshotFileName)))
+
+	require.NoError(t, linker.SaveSnapshot(snapshot, path))
+	snapshot, err = LoadSnapshot(path)
+	require.NoError(t, err)
+
+	changes, err := snapshot.Compare()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Edit, delete, relink and add files
+	zshrc := filepath.Join(targetDir, ".zshrc")
+	require.NoError(t, os.WriteFile(zshrc, []byte("edited"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(targetDir, ".vimrc")))
+	syntax := filepath.Join(targetDir, ".vim", "syntax", "go.vim")
+	require.NoError(t, os.Remove(syntax))
+	require.NoError(t, os.Symlink("/etc/hostname", syntax))
+	foreign := filepath.Join(targetDir, ".vim", "colors", "b.vim")
+	require.NoError(t, os.WriteFile(foreign, []byte("foreign"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "unmanaged"), nil, 0644))
+
+	changes, err = snapshot.Compare()
+	require.NoError(t, err)
+	assert.Equal(t, []SnapshotChange{
+		{Kind: SnapshotForeign, Target: foreign},
+		{Kind: SnapshotModified, Target: syntax, Package: "vim", Detail: "links to /etc/hostname instead of " + filepath.Join(sourceDir, "vim", ".vim", "syntax", "go.vim")},
+		{Kind: SnapshotDeleted, Target: filepath.Join(targetDir, ".vimrc"), Package: "vim"},
+		{Kind: SnapshotModified, Target: zshrc, Package: "zsh", Detail: "content changed"},
+	}, changes)
+
+	// Foreign files stay foreign in a new snapshot
+	snapshot, err = linker.TakeSnapshot()
+	require.NoError(t, err)
+	changes, err = snapshot.Compare()
+	require.NoError(t, err)
+	assert.Equal(t, []SnapshotChange{{Kind: SnapshotForeign, Target: foreign}, {Kind: SnapshotForeign, Target: syntax}}, changes)
+
+	// Permissions are recorded for copies
+	require.NoError(t, os.Remove(foreign))
+	require.NoError(t, os.Remove(syntax))
+	snapshot, err = linker.TakeSnapshot()
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(zshrc, 0600))
+	changes, err = snapshot.Compare()
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "permissions changed from 0644 to 0600", changes[0].Detail)
+}
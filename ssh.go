@@ -0,0 +1,318 @@
+package gslk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errRemoteNotExist is the exit status of the commands SSHFS runs for a
+// missing path, told apart from failures of ssh (255) and of the command.
+const errRemoteNotExist = 3
+
+// SSHFS is an experimental FS managing links in a target tree on another
+// machine. Paths below Root are inspected and changed by running stat, ln,
+// mkdir, rm and similar commands on Host with the ssh command-line tool, one
+// command per operation; other paths, such as the source directories, are
+// local. The state manifest is read and written on the host as well.
+//
+// Links point at the same paths on the host as on the local machine, so the
+// source directories must exist there at the same location, for instance by
+// cloning the dotfiles repository on both. The host needs a POSIX shell and
+// GNU coreutils and findutils. Connection sharing (ControlMaster in
+// ssh_config) avoids connecting anew for every command.
+type SSHFS struct {
+	Host    string   // Destination given to ssh, such as "me@server"
+	Root    string   // Absolute directory on Host managed through ssh, typically the target directory
+	Options []string // Further arguments to ssh, such as "-p", "2222"
+	Command string   // Path or name of the ssh binary; "ssh" if empty
+}
+
+// ParseSSHTarget parses a target given as ssh://[user@]host[:port]/path into
+// an SSHFS whose Root is path.
+func ParseSSHTarget(target string) (*SSHFS, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid SSH target %q: must be ssh://[user@]host[:port]/path", target)
+	}
+	if u.Path == "" || u.Path == "/" {
+		return nil, fmt.Errorf("invalid SSH target %q: the path of the target directory on the host is missing", target)
+	}
+
+	s := &SSHFS{Host: u.Hostname(), Root: path.Clean(u.Path)}
+	if u.User != nil {
+		s.Host = u.User.Username() + "@" + s.Host
+	}
+	if port := u.Port(); port != "" {
+		s.Options = []string{"-p", port}
+	}
+	return s, nil
+}
+
+// Location returns the target tree as an ssh:// URL.
+func (s *SSHFS) Location() string {
+	return "ssh://" + s.Host + s.Root
+}
+
+// remote reports whether name is on the host.
+func (s *SSHFS) remote(name string) bool {
+	return name == s.Root || isSubPath(s.Root, name)
+}
+
+func (s *SSHFS) Lstat(name string) (fs.FileInfo, error) {
+	if !s.remote(name) {
+		return os.Lstat(name)
+	}
+	return s.stat("lstat", name, `[ -e "$1" ] || [ -L "$1" ] || exit 3; stat -c '%f %s %Y' -- "$1"`)
+}
+
+func (s *SSHFS) Stat(name string) (fs.FileInfo, error) {
+	if !s.remote(name) {
+		return os.Stat(name)
+	}
+	return s.stat("stat", name, `[ -e "$1" ] || exit 3; stat -L -c '%f %s %Y' -- "$1"`)
+}
+
+func (s *SSHFS) Readlink(name string) (string, error) {
+	if !s.remote(name) {
+		return os.Readlink(name)
+	}
+	out, err := s.script("readlink", name, nil, `[ -e "$1" ] || [ -L "$1" ] || exit 3; readlink -- "$1"`)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (s *SSHFS) Symlink(oldname, newname string) error {
+	if err := s.checkRemote(newname); err != nil {
+		return err
+	}
+	_, err := s.run(nil, "ln", "-s", "--", oldname, newname)
+	return err
+}
+
+func (s *SSHFS) MkdirAll(path string, perm fs.FileMode) error {
+	if err := s.checkRemote(path); err != nil {
+		return err
+	}
+	_, err := s.run(nil, "mkdir", "-p", "-m", fmt.Sprintf("%04o", perm.Perm()), "--", path)
+	return err
+}
+
+// Remove removes a file or an empty directory.
+func (s *SSHFS) Remove(name string) error {
+	if err := s.checkRemote(name); err != nil {
+		return err
+	}
+	_, err := s.script("remove", name, nil, `[ -e "$1" ] || [ -L "$1" ] || exit 3; rm -d -- "$1"`)
+	return err
+}
+
+// Rename moves oldpath to newpath, replacing newpath like os.Rename does even
+// when it is a link to a directory, which mv without -T would move oldpath
+// into.
+func (s *SSHFS) Rename(oldpath, newpath string) error {
+	if err := s.checkRemote(oldpath); err != nil {
+		return err
+	}
+	if err := s.checkRemote(newpath); err != nil {
+		return err
+	}
+	_, err := s.run(nil, "mv", "-fT", "--", oldpath, newpath)
+	return err
+}
+
+// ReadFile returns the content of the file at name.
+func (s *SSHFS) ReadFile(name string) ([]byte, error) {
+	if !s.remote(name) {
+		return os.ReadFile(name)
+	}
+	return s.script("open", name, nil, `[ -e "$1" ] || exit 3; cat -- "$1"`)
+}
+
+// WriteFile writes data to a temporary file next to name and renames it into
+// place.
+func (s *SSHFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := s.checkRemote(name); err != nil {
+		return err
+	}
+	_, err := s.script("write", name, data, fmt.Sprintf(`cat > "$1.tmp" && chmod %04o -- "$1.tmp" && mv -fT -- "$1.tmp" "$1"`, perm.Perm()))
+	return err
+}
+
+// WalkDir walks the tree at root in lexical order, as filepath.WalkDir does,
+// listing a remote tree with a single find command.
+func (s *SSHFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	if !s.remote(root) {
+		return filepath.WalkDir(root, fn)
+	}
+
+	out, err := s.script("lstat", root, nil, `[ -e "$1" ] || [ -L "$1" ] || exit 3; find "$1" -printf '%y %P\0'`)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	entries := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	slices.SortFunc(entries, func(a, b string) int {
+		return strings.Compare(a[2:], b[2:])
+	})
+
+	var skipped []string // Directories whose contents are skipped
+	for _, entry := range entries {
+		kind, rel := entry[0], entry[2:]
+		name := root
+		if rel != "" {
+			name = path.Join(root, rel)
+		}
+		if slices.ContainsFunc(skipped, func(dir string) bool { return isSubPath(dir, name) }) {
+			continue
+		}
+
+		err := fn(name, remoteDirEntry{name: path.Base(name), kind: kind, fsys: s, path: name}, nil)
+		switch {
+		case errors.Is(err, fs.SkipAll):
+			return nil
+		case errors.Is(err, fs.SkipDir) && kind == 'd':
+			if name == root {
+				return nil
+			}
+			skipped = append(skipped, name)
+		case errors.Is(err, fs.SkipDir):
+			// Skip the remaining files of the parent directory
+			skipped = append(skipped, path.Dir(name))
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRemote refuses to change name unless it is on the host.
+func (s *SSHFS) checkRemote(name string) error {
+	if !s.remote(name) {
+		return fmt.Errorf("refusing to change %s: it is outside %s", name, s.Location())
+	}
+	return nil
+}
+
+// stat returns the file info of name printed by script as "%f %s %Y".
+func (s *SSHFS) stat(op, name, script string) (fs.FileInfo, error) {
+	out, err := s.script(op, name, nil, script)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 3 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("unexpected output of stat on %s: %q", s.Host, out)}
+	}
+	raw, err1 := strconv.ParseUint(fields[0], 16, 32)
+	size, err2 := strconv.ParseInt(fields[1], 10, 64)
+	mtime, err3 := strconv.ParseInt(fields[2], 10, 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("unexpected output of stat on %s: %w", s.Host, err)}
+	}
+	return remoteFileInfo{name: path.Base(name), size: size, mode: unixMode(uint32(raw)), modTime: time.Unix(mtime, 0)}, nil
+}
+
+// script runs a shell script on the host with name as $1, feeding it stdin.
+// Exit status 3 reports that name does not exist.
+func (s *SSHFS) script(op, name string, stdin []byte, script string) ([]byte, error) {
+	out, err := s.run(stdin, "sh", "-c", script, "sh", name)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errRemoteNotExist {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return out, err
+}
+
+// run runs args on the host, with stdin as standard input, and returns their output.
+func (s *SSHFS) run(stdin []byte, args ...string) ([]byte, error) {
+	command := s.Command
+	if command == "" {
+		command = "ssh"
+	}
+
+	sshArgs := append(slices.Clone(s.Options), "--", s.Host, shellQuote(args))
+	cmd := exec.Command(command, sshArgs...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("ssh is not installed (looked for %q in PATH)", command)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s on %s: %s: %w", args[0], s.Host, msg, err)
+		}
+		return nil, fmt.Errorf("%s on %s: %w", args[0], s.Host, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// unixMode converts a raw Unix st_mode to a FileMode.
+func unixMode(raw uint32) fs.FileMode {
+	mode := fs.FileMode(raw & 0777)
+	switch raw & 0170000 {
+	case 0040000:
+		mode |= fs.ModeDir
+	case 0120000:
+		mode |= fs.ModeSymlink
+	case 0100000:
+	default:
+		mode |= fs.ModeIrregular
+	}
+	return mode
+}
+
+// remoteFileInfo is the FileInfo of a file on an SSHFS host.
+type remoteFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi remoteFileInfo) Name() string       { return fi.name }
+func (fi remoteFileInfo) Size() int64        { return fi.size }
+func (fi remoteFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi remoteFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi remoteFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi remoteFileInfo) Sys() any           { return nil }
+
+// remoteDirEntry is a DirEntry listed by find on an SSHFS host.
+type remoteDirEntry struct {
+	name string
+	kind byte // Type letter of find -printf %y
+	fsys *SSHFS
+	path string
+}
+
+func (e remoteDirEntry) Name() string { return e.name }
+func (e remoteDirEntry) IsDir() bool  { return e.kind == 'd' }
+
+func (e remoteDirEntry) Type() fs.FileMode {
+	switch e.kind {
+	case 'd':
+		return fs.ModeDir
+	case 'l':
+		return fs.ModeSymlink
+	case 'f':
+		return 0
+	default:
+		return fs.ModeIrregular
+	}
+}
+
+func (e remoteDirEntry) Info() (fs.FileInfo, error) { return e.fsys.Lstat(e.path) }
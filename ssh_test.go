@@ -0,0 +1,130 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSSHTarget(t *testing.T) {
+	s, err := ParseSSHTarget("ssh://me@server:2222/home/me/")
+	require.NoError(t, err)
+	assert.Equal(t, &SSHFS{Host: "me@server", Root: "/home/me", Options: []string{"-p", "2222"}}, s)
+	assert.Equal(t, "ssh://me@server/home/me", s.Location())
+
+	s, err = ParseSSHTarget("ssh://server/srv/dotfiles")
+	require.NoError(t, err)
+	assert.Equal(t, &SSHFS{Host: "server", Root: "/srv/dotfiles"}, s)
+
+	for _, target := range []string{"ssh://server", "ssh://server/", "ssh:///home/me", "https://server/home/me"} {
+		_, err := ParseSSHTarget(target)
+		assert.Error(t, err, target)
+	}
+}
+
+// fakeSSH returns an SSHFS running its commands locally through a script
+// standing in for ssh.
+func fakeSSH(t *testing.T, root string) *SSHFS {
+	if runtime.GOOS != "linux" {
+		t.Skip("SSHFS runs GNU coreutils commands")
+	}
+	script := filepath.Join(t.TempDir(), "ssh")
+	// Drop the options and the host, then run the command line as sshd would
+	err := os.WriteFile(script, []byte("#!/bin/sh\nwhile [ \"$1\" != -- ]; do shift; done\nexec sh -c \"$3\"\n"), 0755)
+	require.NoError(t, err)
+	return &SSHFS{Host: "server", Root: root, Options: []string{"-p", "2222"}, Command: script}
+}
+
+func TestSSHFS(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+	s := fakeSSH(t, targetDir)
+
+	require.NoError(t, s.MkdirAll(filepath.Join(targetDir, "a b", "c"), 0755))
+	require.NoError(t, s.WriteFile(filepath.Join(targetDir, "a b", "it's"), []byte("data"), 0600))
+	require.NoError(t, s.Symlink("/no/where", filepath.Join(targetDir, "link")))
+
+	fi, err := s.Lstat(filepath.Join(targetDir, "a b", "it's"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode())
+	assert.Equal(t, int64(4), fi.Size())
+	fi, err = s.Lstat(filepath.Join(targetDir, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, fi.Mode().Type())
+	_, err = s.Stat(filepath.Join(targetDir, "link"))
+	assert.True(t, os.IsNotExist(err), "dangling link")
+	dest, err := s.Readlink(filepath.Join(targetDir, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "/no/where", dest)
+	data, err := s.ReadFile(filepath.Join(targetDir, "a b", "it's"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	var walked []string
+	err = s.WalkDir(targetDir, func(path string, d os.DirEntry, err error) error {
+		require.NoError(t, err)
+		rel, _ := filepath.Rel(targetDir, path)
+		walked = append(walked, rel)
+		if d.Name() == "a b" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".", "a b", "link"}, walked)
+
+	require.NoError(t, s.Remove(filepath.Join(targetDir, "link")))
+	assert.True(t, os.IsNotExist(s.Remove(filepath.Join(targetDir, "link"))))
+	assert.Error(t, s.Remove(filepath.Join(targetDir, "a b")), "not empty")
+
+	// A link to a directory is replaced, not renamed into
+	require.NoError(t, s.Symlink("/no/where", filepath.Join(targetDir, "new")))
+	require.NoError(t, s.Symlink(sourceDir, filepath.Join(targetDir, "dir")))
+	require.NoError(t, s.Rename(filepath.Join(targetDir, "new"), filepath.Join(targetDir, "dir")))
+	dest, err = s.Readlink(filepath.Join(targetDir, "dir"))
+	require.NoError(t, err)
+	assert.Equal(t, "/no/where", dest)
+	assert.NoFileExists(t, filepath.Join(sourceDir, "new"))
+
+	// Paths outside the root are local and read-only
+	_, err = s.Lstat(sourceDir)
+	require.NoError(t, err)
+	assert.Error(t, s.Symlink("/no/where", filepath.Join(sourceDir, "link")))
+}
+
+func TestSSHFSLink(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+	pkgPath := filepath.Join(sourceDir, "vim")
+	createDummyPackage(t, pkgPath, map[string]string{".vimrc": "set nu", ".vim/colors/dark.vim": "hi"})
+
+	linker := New(sourceDir, targetDir, WithFS(fakeSSH(t, targetDir)))
+	_, err := linker.Link([]string{"vim"})
+	require.NoError(t, err)
+	dest, err := os.Readlink(filepath.Join(targetDir, ".vim", "colors", "dark.vim"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(pkgPath, ".vim", "colors", "dark.vim"), dest)
+
+	statuses, err := linker.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, StatusLinked, statuses[0].Status)
+
+	// Only links are managed remotely
+	_, err = linker.Generations()
+	assert.Error(t, err)
+	copier := linker.ForTarget(targetDir)
+	copier.Mode = ModeCopy
+	_, err = copier.Relink([]string{"vim"})
+	assert.ErrorContains(t, err, "only links are managed on remote targets")
+
+	_, err = linker.Unlink([]string{"vim"})
+	require.NoError(t, err)
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,304 @@
+package gslk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// StateFileName is the name of the state manifest gslk keeps in the target directory.
+const StateFileName = ".gslk-state.json"
+
+// stateVersion is the current on-disk format version of the state manifest.
+const stateVersion = 1
+
+// DeployMode describes how a file was placed into the target directory.
+type DeployMode string
+
+const (
+	ModeLink     DeployMode = "link"     // Symbolic link pointing back into the package
+	ModeCopy     DeployMode = "copy"     // Plain copy of the package file
+	ModeTemplate DeployMode = "template" // Rendered output of a package template
+	ModeSecret   DeployMode = "secret"   // Decrypted copy of an encrypted package file, readable only by the owner
+	ModeBind     DeployMode = "bind"     // Bind mount of a package directory (see Manifest.Bind)
+)
+
+// StateEntry records a single file gslk deployed into the target directory.
+type StateEntry struct {
+	Package string     `json:"package"`
+	Source  string     `json:"source"`
+	Target  string     `json:"target"`
+	Mode    DeployMode `json:"mode"`
+	Hash    string     `json:"hash,omitempty"` // SHA-256 of the deployed content (copy/template only)
+}
+
+// State is the manifest of files gslk has deployed into a target directory.
+// Entries are keyed by absolute target path. Directories maps each directory
+// gslk created to the packages that still need it; a directory without owners
+// was left behind because it could not be removed.
+//
+// The methods an Executor uses to update the state are safe for concurrent use.
+type State struct {
+	Version     int                   `json:"version"`
+	Entries     map[string]StateEntry `json:"entries"`
+	Directories map[string][]string   `json:"directories,omitempty"`
+	Quarantine  []QuarantineEntry     `json:"quarantine,omitempty"`
+	Trash       []TrashEntry          `json:"trash,omitempty"`
+	Profile     *ActiveProfile        `json:"profile,omitempty"` // Profile linked last, if any
+
+	path   string
+	fsys   FS // Filesystem the manifest is saved through if it is a FileWriter
+	dirty  bool
+	exists bool // The manifest was read from disk
+	mu     sync.Mutex
+}
+
+// statePath returns the location of the state manifest for this Linker.
+func (l *Linker) statePath() string {
+	return filepath.Join(l.TargetDir, StateFileName)
+}
+
+// stateLocks holds a *sync.Mutex per state manifest path, shared by every
+// Linker of the process.
+var stateLocks sync.Map
+
+// lockState waits until no other goroutine of the process is updating the
+// state manifest of the target and returns the function releasing it. Methods
+// loading the manifest to save it again hold it from load to save, so that
+// concurrent calls on one target cannot lose each other's entries.
+func (l *Linker) lockState() (unlock func()) {
+	path, err := filepath.Abs(l.statePath())
+	if err != nil {
+		path = filepath.Clean(l.statePath())
+	}
+	mu, _ := stateLocks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// newState returns an empty state to be saved at path.
+func newState(path string) *State {
+	return &State{
+		Version:     stateVersion,
+		Entries:     make(map[string]StateEntry),
+		Directories: make(map[string][]string),
+		path:        path,
+	}
+}
+
+// LoadState reads the state manifest at path. A missing file yields an empty state.
+func LoadState(path string) (*State, error) {
+	return readState(path, os.ReadFile)
+}
+
+// loadState reads the state manifest of the target, through FS if it is a
+// FileReader. The state is saved through FS too if it is a FileWriter.
+func (l *Linker) loadState() (*State, error) {
+	readFile := os.ReadFile
+	if r, ok := l.FS.(FileReader); ok {
+		readFile = r.ReadFile
+	}
+	state, err := readState(l.statePath(), readFile)
+	if err != nil {
+		return nil, err
+	}
+	state.fsys = l.fs()
+	return state, nil
+}
+
+// readState reads the state manifest at path with readFile.
+func readState(path string, readFile func(string) ([]byte, error)) (*State, error) {
+	state := newState(path)
+
+	data, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Entries == nil {
+		state.Entries = make(map[string]StateEntry)
+	}
+	if state.Directories == nil {
+		state.Directories = make(map[string][]string)
+	}
+	if state.Version > stateVersion {
+		return nil, fmt.Errorf("state file %s has unsupported version %d", path, state.Version)
+	}
+	state.exists = true
+
+	return state, nil
+}
+
+// Lookup returns the entry recorded for targetPath, if any.
+func (s *State) Lookup(targetPath string) (StateEntry, bool) {
+	entry, ok := s.Entries[targetPath]
+	return entry, ok
+}
+
+// Record adds or replaces the entry for entry.Target.
+func (s *State) Record(entry StateEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Entries[entry.Target] = entry
+	s.dirty = true
+}
+
+// Forget removes the entry recorded for targetPath.
+func (s *State) Forget(targetPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Entries[targetPath]; ok {
+		delete(s.Entries, targetPath)
+		s.dirty = true
+	}
+}
+
+// IsCreatedDir reports whether dir was created by gslk.
+func (s *State) IsCreatedDir(dir string) bool {
+	_, ok := s.Directories[dir]
+	return ok
+}
+
+// DirOwners returns the packages that still need the gslk-created directory dir.
+func (s *State) DirOwners(dir string) []string {
+	return s.Directories[dir]
+}
+
+// claimDir records pkgName as needing dir. If created is false the directory
+// is only claimed when gslk created it earlier, since pre-existing directories
+// are never gslk's to remove.
+func (s *State) claimDir(dir, pkgName string, created bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owners, ok := s.Directories[dir]
+	if !ok && !created {
+		return
+	}
+	if slices.Contains(owners, pkgName) {
+		return
+	}
+	s.Directories[dir] = append(owners, pkgName)
+	s.dirty = true
+}
+
+// releaseDir drops pkgName's claim on dir and returns the remaining owners.
+func (s *State) releaseDir(dir, pkgName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owners, ok := s.Directories[dir]
+	if !ok {
+		return nil
+	}
+	if i := slices.Index(owners, pkgName); i >= 0 {
+		owners = slices.Delete(slices.Clone(owners), i, i+1)
+		s.Directories[dir] = owners
+		s.dirty = true
+	}
+	return owners
+}
+
+// forgetDir removes dir from the created directories.
+func (s *State) forgetDir(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Directories[dir]; ok {
+		delete(s.Directories, dir)
+		s.dirty = true
+	}
+}
+
+// addQuarantine records a file moved into the quarantine directory.
+func (s *State) addQuarantine(entry QuarantineEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Quarantine = append(s.Quarantine, entry)
+	s.dirty = true
+}
+
+// addTrash records a file or directory moved to the trash.
+func (s *State) addTrash(entry TrashEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Trash = append(s.Trash, entry)
+	s.dirty = true
+}
+
+// Save writes the state manifest back to disk if it has changed.
+// A state without entries, directories, quarantined or trashed files or profile removes the manifest file
+// instead of leaving an empty one behind.
+func (s *State) Save() error {
+	if !s.dirty {
+		return nil
+	}
+
+	if len(s.Entries) == 0 && len(s.Directories) == 0 && len(s.Quarantine) == 0 && len(s.Trash) == 0 && s.Profile == nil {
+		if _, err := orOS(s.fsys).Lstat(s.path); os.IsNotExist(err) {
+			s.dirty = false
+			return nil
+		}
+		if err := orOS(s.fsys).Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove state file %s: %w", s.path, err)
+		}
+		s.dirty = false
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if w, ok := s.fsys.(FileWriter); ok {
+		if err := w.WriteFile(s.path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write state file %s: %w", s.path, err)
+		}
+		s.dirty = false
+		return nil
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated manifest
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace state file %s: %w", s.path, err)
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// hashFile returns the hex-encoded SHA-256 checksum of the file at path.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// hashBytes returns the hex-encoded SHA-256 checksum of content.
+func hashBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
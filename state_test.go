@@ -0,0 +1,255 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deployCopy writes a copy of a package file into the target and records it in the state manifest,
+// mimicking what a copy-mode link would leave behind.
+func deployCopy(t *testing.T, linker *Linker, pkgName, relPath string, mode DeployMode) string {
+	sourcePath := filepath.Join(linker.SourceDir, pkgName, relPath)
+	targetPath := filepath.Join(linker.TargetDir, relPath)
+
+	content, err := os.ReadFile(sourcePath)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(targetPath), 0755))
+	require.NoError(t, os.WriteFile(targetPath, content, 0644))
+
+	hash, err := hashFile(targetPath)
+	require.NoError(t, err)
+
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	state.Record(StateEntry{Package: pkgName, Source: sourcePath, Target: targetPath, Mode: mode, Hash: hash})
+	require.NoError(t, state.Save())
+
+	return targetPath
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	_, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	statePath := filepath.Join(targetDir, StateFileName)
+	state, err := LoadState(statePath)
+	require.NoError(t, err)
+	assert.Empty(t, state.Entries)
+
+	entry := StateEntry{Package: "pkg", Source: "/src/pkg/a", Target: "/tgt/a", Mode: ModeCopy, Hash: "abc"}
+	state.Record(entry)
+	require.NoError(t, state.Save())
+
+	reloaded, err := LoadState(statePath)
+	require.NoError(t, err)
+	got, ok := reloaded.Lookup("/tgt/a")
+	require.True(t, ok)
+	assert.Equal(t, entry, got)
+
+	// Forgetting the last entry removes the manifest entirely
+	reloaded.Forget("/tgt/a")
+	require.NoError(t, reloaded.Save())
+	_, err = os.Stat(statePath)
+	assert.True(t, os.IsNotExist(err), "State file should be removed once empty")
+}
+
+func TestUnlinkDeployedCopies(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "copied_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"config.conf":      "copied config",
+		"sub/rendered.ini": "rendered template",
+		"linked.txt":       "linked file",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+
+	copyPath := deployCopy(t, linker, pkgName, "config.conf", ModeCopy)
+	templatePath := deployCopy(t, linker, pkgName, "sub/rendered.ini", ModeTemplate)
+	linkPath := filepath.Join(targetDir, "linked.txt")
+	require.NoError(t, os.Symlink(filepath.Join(pkgPath, "linked.txt"), linkPath))
+
+	_, err := linker.Unlink([]string{pkgName})
+	require.NoError(t, err, "Unlink of deployed copies failed")
+
+	for _, path := range []string{copyPath, templatePath, linkPath} {
+		_, err := os.Lstat(path)
+		assert.True(t, os.IsNotExist(err), "Deployed file %s should be removed (stat err: %v)", path, err)
+	}
+
+	_, err = os.Stat(filepath.Join(targetDir, StateFileName))
+	assert.True(t, os.IsNotExist(err), "State file should be removed once all entries are gone")
+}
+
+func TestUnlinkRefusesModifiedCopy(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "modified_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"settings.json": `{"theme": "dark"}`,
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+
+	copyPath := deployCopy(t, linker, pkgName, "settings.json", ModeCopy)
+	require.NoError(t, os.WriteFile(copyPath, []byte(`{"theme": "light"}`), 0644))
+
+	// Without force the locally modified copy must survive
+	_, err := linker.Unlink([]string{pkgName})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Contains(t, err.Error(), "refusing to remove")
+	content, readErr := os.ReadFile(copyPath)
+	require.NoError(t, readErr)
+	assert.Equal(t, `{"theme": "light"}`, string(content))
+
+	// With force it is removed
+	linker.ForceRemove = true
+	_, err = linker.Unlink([]string{pkgName})
+	require.NoError(t, err)
+	_, err = os.Lstat(copyPath)
+	assert.True(t, os.IsNotExist(err), "Modified copy should be removed with ForceRemove")
+}
+
+func TestUnlinkLeavesUnrecordedFiles(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "unrecorded_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"notes.txt": "same content",
+	})
+
+	// A regular file with identical content that gslk never deployed
+	userFile := filepath.Join(targetDir, "notes.txt")
+	require.NoError(t, os.WriteFile(userFile, []byte("same content"), 0644))
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+
+	_, err := linker.Unlink([]string{pkgName})
+	require.NoError(t, err)
+	_, err = os.Stat(userFile)
+	assert.NoError(t, err, "Files not recorded in the state manifest must not be removed")
+}
+
+func TestUnlinkRemovesOnlyCreatedDirectories(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "dirs_pkg"
+	pkgPath := filepath.Join(sourceDir, pkgName)
+	createDummyPackage(t, pkgPath, map[string]string{
+		"existing/created/file.txt": "f",
+		"fresh/deep/file.txt":       "g",
+		"fresh/empty":               "DIR",
+	})
+
+	// A pre-existing (and otherwise empty) user directory
+	existingDir := filepath.Join(targetDir, "existing")
+	require.NoError(t, os.Mkdir(existingDir, 0755))
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	assert.False(t, state.IsCreatedDir(existingDir), "Pre-existing directory must not be recorded")
+	for _, dir := range []string{"existing/created", "fresh", "fresh/deep", "fresh/empty"} {
+		assert.Equal(t, []string{pkgName}, state.DirOwners(filepath.Join(targetDir, dir)), "Directory %s should be recorded", dir)
+	}
+
+	_, err = linker.Unlink([]string{pkgName})
+
+	require.NoError(t, err)
+
+	for _, dir := range []string{"existing/created", "fresh"} {
+		_, err := os.Stat(filepath.Join(targetDir, dir))
+		assert.True(t, os.IsNotExist(err), "Created directory %s should be removed", dir)
+	}
+	_, err = os.Stat(existingDir)
+	assert.NoError(t, err, "Pre-existing directory must survive unlink even when empty")
+
+	_, err = os.Stat(linker.statePath())
+	assert.True(t, os.IsNotExist(err), "State should be empty after unlinking everything")
+}
+
+func TestUnlinkKeepsSharedDirectories(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "first"), map[string]string{"config/first.conf": "1"})
+	createDummyPackage(t, filepath.Join(sourceDir, "second"), map[string]string{"config/second.conf": "2"})
+
+	linker := &Linker{
+		SourceDir:   sourceDir,
+		TargetDir:   targetDir,
+		ForceRemove: true, // Even forced removal must not touch directories other packages need
+	}
+	_, err := linker.Link([]string{"first", "second"})
+	require.NoError(t, err)
+
+	configDir := filepath.Join(targetDir, "config")
+	_, err = linker.Unlink([]string{"first"})
+	require.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(configDir, "second.conf"))
+	assert.NoError(t, err, "Shared directory must be kept while another package uses it")
+
+	_, err = linker.Unlink([]string{"second"})
+
+	require.NoError(t, err)
+	_, err = os.Stat(configDir)
+	assert.True(t, os.IsNotExist(err), "Shared directory should be removed once no package needs it")
+}
+
+func TestUnlinkKeepsCreatedDirectoryWithUserFiles(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "user_files_pkg"
+	createDummyPackage(t, filepath.Join(sourceDir, pkgName), map[string]string{"app/app.conf": "a"})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	appDir := filepath.Join(targetDir, "app")
+	userFile := filepath.Join(appDir, "cache.db")
+	require.NoError(t, os.WriteFile(userFile, []byte("user"), 0644))
+
+	_, err = linker.Unlink([]string{pkgName})
+
+	require.NoError(t, err)
+	_, err = os.Stat(userFile)
+	assert.NoError(t, err, "Files added by the user must survive unlink")
+
+	// The directory stays recorded without owners so it can be reported later
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	assert.True(t, state.IsCreatedDir(appDir))
+	assert.Empty(t, state.DirOwners(appDir))
+}
@@ -0,0 +1,82 @@
+package gslk
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// PackageStats sums up the files of a package and their deployment.
+type PackageStats struct {
+	Package  Package
+	Files    int       // Files the package manages, after ignore rules
+	Size     int64     // Bytes in those files
+	Links    int       // Files linked in the target directory
+	Copies   int       // Files deployed as copies or rendered templates, modified or not
+	Broken   int       // Links into the package whose source file no longer exists
+	Modified time.Time // Latest modification time of its files; zero without files
+}
+
+// Stats sums up every package in the source directories: the number and size
+// of its files, when they were last modified, how many are linked or copied
+// into the target directory, and how many links into it are broken. As with
+// Doctor, broken links are only looked for in the directories the packages
+// populate. Nothing is modified.
+func (l *Linker) Stats() ([]PackageStats, error) {
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	stats := make([]PackageStats, 0, len(packages))
+	index := make(map[string]int, len(packages))
+	scanDirs := map[string]bool{l.TargetDir: true}
+	for _, pkg := range packages {
+		s := PackageStats{Package: pkg}
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			scanDirs[filepath.Dir(path.targetPath)] = true
+			fi, err := orOS(l.FS).Stat(path.sourcePath)
+			if err != nil {
+				return err
+			}
+			s.Files++
+			s.Size += fi.Size()
+			if fi.ModTime().After(s.Modified) {
+				s.Modified = fi.ModTime()
+			}
+
+			c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+			switch c.State {
+			case TargetLinked:
+				s.Links++
+			case TargetDeployed, TargetModified:
+				s.Copies++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+		index[pkg.Name] = len(stats)
+		stats = append(stats, s)
+	}
+
+	for _, finding := range l.checkLinks(scanDirs, packages) {
+		if finding.Kind == FindingBrokenLink {
+			stats[index[finding.Packages[0]]].Broken++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,45 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "shell")
+	createDummyPackage(t, pkgPath, map[string]string{".zshrc": "zsh", ".bashrc": "bash!", ".profile": "sh", ".inputrc": "keys"})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`copy = [".profile"]`), 0644))
+	modified := time.Now().Add(time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(pkgPath, ".bashrc"), modified, modified))
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{".gitconfig": "[user]"})
+
+	linker := New(sourceDir, targetDir, WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"shell"})
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(pkgPath, ".inputrc")))
+
+	stats, err := linker.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "git", stats[0].Package.Name)
+	assert.Equal(t, 1, stats[0].Files)
+	assert.Zero(t, stats[0].Links+stats[0].Copies+stats[0].Broken)
+
+	shell := stats[1]
+	assert.Equal(t, 3, shell.Files)
+	assert.Equal(t, int64(len("zsh")+len("bash!")+len("sh")), shell.Size)
+	assert.Equal(t, 2, shell.Links)
+	assert.Equal(t, 1, shell.Copies)
+	assert.Equal(t, 1, shell.Broken, "the link to the deleted .inputrc is broken")
+	assert.True(t, shell.Modified.Equal(modified))
+}
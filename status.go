@@ -0,0 +1,158 @@
+package gslk
+
+import (
+	"fmt"
+)
+
+// LinkStatus tells how much of a package is deployed in the target directory.
+type LinkStatus string
+
+const (
+	StatusLinked   LinkStatus = "linked"   // Every file of the package is deployed
+	StatusPartial  LinkStatus = "partial"  // Some files of the package are deployed
+	StatusUnlinked LinkStatus = "unlinked" // No file of the package is deployed
+)
+
+// PackageStatus describes a package and how much of it is deployed.
+type PackageStatus struct {
+	Package  Package
+	Files    int // Files the package manages, after ignore rules
+	Deployed int // Files currently linked, or deployed as copies, from the package
+	Status   LinkStatus
+}
+
+// Coverage returns the percentage of the package's files that are deployed;
+// like its status, a package without files is unlinked.
+func (s PackageStatus) Coverage() float64 {
+	if s.Files == 0 {
+		return 0
+	}
+	return 100 * float64(s.Deployed) / float64(s.Files)
+}
+
+// linkStatus tells how much of a package is deployed from the number of its
+// files that are.
+func linkStatus(deployed, files int) LinkStatus {
+	switch {
+	case deployed == 0:
+		return StatusUnlinked
+	case deployed < files:
+		return StatusPartial
+	}
+	return StatusLinked
+}
+
+// Status reports every package in the source directories with the number of
+// files it manages and how many of them are deployed in the target directory.
+// Locally modified copies count as deployed. Nothing is modified.
+func (l *Linker) Status() ([]PackageStatus, error) {
+	packages, err := l.FindPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packages: %w", err)
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	statuses := make([]PackageStatus, 0, len(packages))
+	for _, pkg := range packages {
+		status := PackageStatus{Package: pkg}
+		_, err := l.walkPackage(pkg, func(path pathInfo) error {
+			if path.isDir {
+				return nil
+			}
+			status.Files++
+			c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+			if err != nil {
+				return err
+			}
+			switch c.State {
+			case TargetLinked, TargetDeployed, TargetModified:
+				status.Deployed++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+		}
+
+		status.Status = linkStatus(status.Deployed, status.Files)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// FileStatus tells whether a package file is deployed at its target path.
+type FileStatus string
+
+const (
+	FileLinked   FileStatus = "linked"   // Linked, or deployed as an unmodified copy
+	FileModified FileStatus = "modified" // Deployed as a copy, modified since
+	FileConflict FileStatus = "conflict" // Something else occupies the target path
+	FileMissing  FileStatus = "missing"  // Nothing exists at the target path
+	FileIgnored  FileStatus = "ignored"  // Excluded by the package's ignore rules
+)
+
+// ManagedFile is a file of a package and where gslk places it.
+type ManagedFile struct {
+	Source string // Absolute path of the file in the package
+	Target string // Path the file is deployed at; empty for ignored files
+	Status FileStatus
+	State  TargetState // What occupies Target; TargetMissing for ignored files
+}
+
+// Files lists the files of package pkgName with their target paths and
+// whether they are deployed there, followed by the paths its ignore rules
+// exclude. Ignored directories are listed without their contents. Nothing is
+// modified.
+func (l *Linker) Files(pkgName string) ([]ManagedFile, error) {
+	packages, err := l.packagesByName()
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := l.findPackage(pkgName, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	classifier := &Classifier{State: state, FS: l.FS}
+
+	var files []ManagedFile
+	ignored, err := l.walkPackage(pkg, func(path pathInfo) error {
+		if path.isDir {
+			return nil
+		}
+		c, err := classifier.Classify(pkg.Name, path.sourcePath, path.targetPath)
+		if err != nil {
+			return err
+		}
+		file := ManagedFile{Source: path.sourcePath, Target: path.targetPath, State: c.State}
+		switch c.State {
+		case TargetLinked, TargetDeployed:
+			file.Status = FileLinked
+		case TargetModified:
+			file.Status = FileModified
+		case TargetMissing:
+			file.Status = FileMissing
+		default:
+			file.Status = FileConflict
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect package %s: %w", pkg.Name, err)
+	}
+
+	for _, sourcePath := range ignored {
+		files = append(files, ManagedFile{Source: sourcePath, Status: FileIgnored})
+	}
+	return files, nil
+}
@@ -0,0 +1,85 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{".vimrc": "set nu", ".vim/ftplugin/go.vim": "go"})
+	createDummyPackage(t, filepath.Join(sourceDir, "zsh"), map[string]string{".zshrc": "zsh", ".zshenv": "env"})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{
+		".gitconfig":     "git",
+		ManifestFileName: `description = "Git configuration"`,
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"vim", "zsh"})
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(targetDir, ".zshenv")))
+
+	statuses, err := linker.Status()
+	require.NoError(t, err)
+	byName := make(map[string]PackageStatus)
+	for _, s := range statuses {
+		byName[s.Package.Name] = s
+	}
+	require.Len(t, byName, 3)
+
+	assert.Equal(t, PackageStatus{Package: byName["vim"].Package, Files: 2, Deployed: 2, Status: StatusLinked}, byName["vim"])
+	assert.Equal(t, StatusPartial, byName["zsh"].Status)
+	assert.Equal(t, 1, byName["zsh"].Deployed)
+	assert.Equal(t, StatusUnlinked, byName["git"].Status)
+	assert.Equal(t, 1, byName["git"].Files, "The manifest is not a package file")
+	assert.Equal(t, "Git configuration", byName["git"].Package.Manifest.Description)
+}
+
+func TestFiles(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "shell")
+	createDummyPackage(t, pkgPath, map[string]string{
+		".bashrc":      "bash",
+		".profile":     "profile",
+		".inputrc":     "input",
+		"notes.md":     "notes",
+		IgnoreFileName: "notes.md",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"shell"})
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(targetDir, ".profile")))
+	require.NoError(t, os.Remove(filepath.Join(targetDir, ".inputrc")))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, ".inputrc"), []byte("mine"), 0644))
+
+	files, err := linker.Files("shell")
+	require.NoError(t, err)
+	bySource := make(map[string]ManagedFile)
+	for _, file := range files {
+		bySource[filepath.Base(file.Source)] = file
+	}
+	require.Len(t, bySource, 4)
+
+	assert.Equal(t, ManagedFile{
+		Source: filepath.Join(pkgPath, ".bashrc"),
+		Target: filepath.Join(targetDir, ".bashrc"),
+		Status: FileLinked,
+		State:  TargetLinked,
+	}, bySource[".bashrc"])
+	assert.Equal(t, FileMissing, bySource[".profile"].Status)
+	assert.Equal(t, FileConflict, bySource[".inputrc"].Status)
+	assert.Equal(t, TargetFile, bySource[".inputrc"].State)
+	assert.Equal(t, ManagedFile{Source: filepath.Join(pkgPath, "notes.md"), Status: FileIgnored}, bySource["notes.md"])
+
+	_, err = linker.Files("nope")
+	assert.ErrorContains(t, err, "package 'nope' not found")
+}
@@ -0,0 +1,135 @@
+package gslk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SudoFS is an FS for system packages targeting root-owned locations such as
+// /etc or /usr/local. Paths are read as the current user, while links and
+// directories are created and removed by running ln, mkdir and rm through
+// sudo, one command per file. The state manifest is written the same way, and
+// bind mounts are made with mount and umount.
+//
+// With Script set, the commands are written to it as a shell script instead of
+// being run, for review or to run elsewhere. Nothing changes on disk then, so
+// checks after the fact, such as the verification after unlinking, fail.
+//
+// Copies, rendered templates, secrets and quarantined files are still written
+// with the privileges of the current user.
+type SudoFS struct {
+	Command string    // Program used to gain privileges; "sudo" if empty
+	Script  io.Writer // If set, receives the commands instead of running them
+
+	mu sync.Mutex // Keeps parallel commands and script lines apart
+}
+
+func (s *SudoFS) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (s *SudoFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (s *SudoFS) Readlink(name string) (string, error)         { return os.Readlink(name) }
+func (s *SudoFS) WalkDir(root string, fn fs.WalkDirFunc) error { return filepath.WalkDir(root, fn) }
+
+func (s *SudoFS) Symlink(oldname, newname string) error {
+	return s.run(nil, "ln", "-s", "--", oldname, newname)
+}
+
+func (s *SudoFS) MkdirAll(path string, perm fs.FileMode) error {
+	return s.run(nil, "mkdir", "-p", "-m", fmt.Sprintf("%04o", perm.Perm()), "--", path)
+}
+
+// Remove removes a file or an empty directory.
+func (s *SudoFS) Remove(name string) error {
+	return s.run(nil, "rm", "-d", "--", name)
+}
+
+// Rename moves oldpath to newpath, replacing newpath like os.Rename does even
+// when it is a link to a directory, which plain mv would move oldpath into.
+// It relies on the -T option of GNU mv.
+func (s *SudoFS) Rename(oldpath, newpath string) error {
+	return s.run(nil, "mv", "-fT", "--", oldpath, newpath)
+}
+
+// BindMount bind-mounts the directory source onto target.
+func (s *SudoFS) BindMount(source, target string) error {
+	return s.run(nil, "mount", "--bind", "--", source, target)
+}
+
+// Unmount undoes the mount at target.
+func (s *SudoFS) Unmount(target string) error {
+	return s.run(nil, "umount", "--", target)
+}
+
+// WriteFile writes data to a temporary file next to name and renames it into
+// place.
+func (s *SudoFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	tmp := name + ".tmp"
+	if err := s.run(data, "tee", "--", tmp); err != nil {
+		return err
+	}
+	if err := s.run(nil, "chmod", fmt.Sprintf("%04o", perm.Perm()), "--", tmp); err != nil {
+		return err
+	}
+	return s.Rename(tmp, name)
+}
+
+// run runs args through Command, with stdin as standard input if not nil, or
+// writes the command to Script.
+func (s *SudoFS) run(stdin []byte, args ...string) error {
+	command := s.Command
+	if command == "" {
+		command = "sudo"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Script != nil {
+		line := shellQuote(append([]string{command}, args...))
+		if stdin != nil {
+			if !bytes.HasSuffix(stdin, []byte("\n")) {
+				stdin = append(slices.Clip(stdin), '\n')
+			}
+			line = fmt.Sprintf("%s >/dev/null <<'GSLK_EOF'\n%sGSLK_EOF", line, stdin)
+		}
+		_, err := fmt.Fprintln(s.Script, line)
+		return err
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = os.Stdin
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s %s: %s", command, strings.Join(args, " "), msg)
+		}
+		return fmt.Errorf("%s %s: %w", command, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// shellQuote joins args into a POSIX shell command line.
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+			return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@%+,", r)
+		}) < 0 {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,84 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSudoFSScript(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "etc")
+	createDummyPackage(t, pkgPath, map[string]string{"hosts": "127.0.0.1", "ssh/sshd_config": "Port 22"})
+
+	var script strings.Builder
+	linker := New(sourceDir, targetDir, WithFS(&SudoFS{Script: &script}))
+	_, err := linker.Link([]string{"etc"})
+	require.NoError(t, err)
+
+	lines := script.String()
+	assert.Contains(t, lines, shellQuote([]string{"sudo", "mkdir", "-p", "-m", "0755", "--", filepath.Join(targetDir, "ssh")}))
+	assert.Contains(t, lines, shellQuote([]string{"sudo", "ln", "-s", "--", filepath.Join(pkgPath, "hosts"), filepath.Join(targetDir, "hosts")}))
+	assert.Contains(t, lines, shellQuote([]string{"sudo", "tee", "--", filepath.Join(targetDir, StateFileName+".tmp")})+" >/dev/null <<'GSLK_EOF'\n{")
+
+	// Nothing changes until the script runs
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSudoFSRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SudoFS runs POSIX commands")
+	}
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "etc")
+	createDummyPackage(t, pkgPath, map[string]string{"ssh/sshd_config": "Port 22"})
+
+	// env runs the commands unprivileged, standing in for sudo
+	linker := New(sourceDir, targetDir, WithFS(&SudoFS{Command: "env"}))
+	_, err := linker.Link([]string{"etc"})
+	require.NoError(t, err)
+
+	linkPath := filepath.Join(targetDir, "ssh", "sshd_config")
+	dest, err := os.Readlink(linkPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(pkgPath, "ssh", "sshd_config"), dest)
+	state, err := LoadState(filepath.Join(targetDir, StateFileName))
+	require.NoError(t, err)
+	assert.Contains(t, state.Directories, filepath.Join(targetDir, "ssh"))
+
+	// A link to a directory is replaced, not renamed into
+	fsys := &SudoFS{Command: "env"}
+	tmp := filepath.Join(targetDir, "ssh.tmp")
+	require.NoError(t, os.Symlink(pkgPath, tmp))
+	dirLink := filepath.Join(targetDir, "dir")
+	require.NoError(t, os.Symlink(sourceDir, dirLink))
+	require.NoError(t, fsys.Rename(tmp, dirLink))
+	dest, err = os.Readlink(dirLink)
+	require.NoError(t, err)
+	assert.Equal(t, pkgPath, dest)
+	assert.NoFileExists(t, filepath.Join(sourceDir, "ssh.tmp"))
+	require.NoError(t, os.Remove(dirLink))
+
+	_, err = linker.Unlink([]string{"etc"})
+	require.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(targetDir, "ssh"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Lstat(filepath.Join(targetDir, StateFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "sudo ln -s -- /a/b.conf /etc/b.conf", shellQuote([]string{"sudo", "ln", "-s", "--", "/a/b.conf", "/etc/b.conf"}))
+	assert.Equal(t, `rm 'my file' 'it'\''s' ''`, shellQuote([]string{"rm", "my file", "it's", ""}))
+}
@@ -0,0 +1,108 @@
+package gslk
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is the number of close package names offered for a typo.
+const maxSuggestions = 3
+
+// findPackage returns the package called name. With IgnoreCase, a name that
+// differs from exactly one package in case only names that package. For an
+// unknown name, the error suggests packages and groups with a similar name.
+func (l *Linker) findPackage(name string, packagesByName map[string]Package) (Package, error) {
+	if pkg, ok := packagesByName[name]; ok {
+		return pkg, nil
+	}
+
+	if l.IgnoreCase {
+		var matches []string
+		for candidate := range packagesByName {
+			if strings.EqualFold(candidate, name) {
+				matches = append(matches, candidate)
+			}
+		}
+		switch len(matches) {
+		case 1:
+			l.logVerbose("Using package %s for %s\n", matches[0], name)
+			return packagesByName[matches[0]], nil
+		case 0:
+		default:
+			slices.Sort(matches)
+			return Package{}, fmt.Errorf("package '%s' is ambiguous: it matches %s", name, quotedList(matches, "and"))
+		}
+	}
+
+	err := fmt.Sprintf("package '%s' not found in source directory %s", name, l.SourceDir)
+	candidates := slices.Concat(slices.Collect(maps.Keys(packagesByName)), slices.Collect(maps.Keys(l.Groups)))
+	if suggestions := suggestNames(name, candidates); len(suggestions) > 0 {
+		err += "; did you mean " + quotedList(suggestions, "or") + "?"
+	}
+	return Package{}, fmt.Errorf("%s", err)
+}
+
+// suggestNames returns up to maxSuggestions of candidates close to name,
+// closest first: those equal to it but for case, and those within an edit
+// distance of a third of its length (at least 1).
+func suggestNames(name string, candidates []string) []string {
+	maxDistance := max(1, len([]rune(name))/3)
+	distances := make(map[string]int)
+	for _, candidate := range candidates {
+		d := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if d <= maxDistance {
+			distances[candidate] = d
+		}
+	}
+
+	suggestions := slices.Collect(maps.Keys(distances))
+	slices.SortFunc(suggestions, func(a, b string) int {
+		if distances[a] != distances[b] {
+			return distances[a] - distances[b]
+		}
+		return strings.Compare(a, b)
+	})
+	return suggestions[:min(len(suggestions), maxSuggestions)]
+}
+
+// editDistance returns the Levenshtein distance between a and b, counting a
+// swap of two adjacent characters as a single edit.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between s[:i] and t[:j]
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// quotedList formats names as 'a', 'b' or 'c', joining the last with conj.
+func quotedList(names []string, conj string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " " + conj + " " + quoted[len(quoted)-1]
+}
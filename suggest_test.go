@@ -0,0 +1,45 @@
+package gslk
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("vim", "vim"))
+	assert.Equal(t, 1, editDistance("vmi", "vim"), "Adjacent swaps count once")
+	assert.Equal(t, 1, editDistance("nvim", "vim"))
+	assert.Equal(t, 3, editDistance("", "zsh"))
+	assert.Equal(t, 2, editDistance("tmux", "tuxx"))
+}
+
+func TestSuggestNames(t *testing.T) {
+	candidates := []string{"vim", "nvim", "zsh", "bash", "git"}
+	assert.Equal(t, []string{"vim"}, suggestNames("vmi", candidates))
+	assert.Equal(t, []string{"nvim", "vim"}, suggestNames("nim", candidates), "Equally close names are sorted")
+	assert.Equal(t, []string{"zsh"}, suggestNames("ZSH", candidates))
+	assert.Empty(t, suggestNames("emacs", candidates))
+}
+
+func TestPackageNotFoundSuggestions(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "vim"), map[string]string{".vimrc": "vim"})
+	createDummyPackage(t, filepath.Join(sourceDir, "Zsh"), map[string]string{".zshrc": "zsh"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"vmi"})
+	assert.ErrorContains(t, err, "package 'vmi' not found in source directory "+sourceDir+"; did you mean 'vim'?")
+
+	_, err = linker.Link([]string{"zsh"})
+	assert.ErrorContains(t, err, "did you mean 'Zsh'?")
+
+	linker.IgnoreCase = true
+	result, err := linker.Link([]string{"zsh"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".zshrc")}, result.Linked)
+}
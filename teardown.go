@@ -0,0 +1,208 @@
+package gslk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TeardownFileName is the teardown manifest ephemeral runs keep in the target
+// directory (see Linker.Ephemeral).
+const TeardownFileName = ".gslk-teardown.json"
+
+// TeardownManifest lists what ephemeral runs deployed into a target: the
+// files, with the checksum of their content, and the directories created for
+// them. It is enough on its own to remove them, so a container can be torn
+// down after its dotfiles repository is gone.
+type TeardownManifest struct {
+	Target string       `json:"target"`
+	Files  []StateEntry `json:"files"`
+	Dirs   []string     `json:"dirs,omitempty"` // Directories created for the files; removed once empty
+}
+
+// teardownPath returns the location of the teardown manifest of the target.
+func (l *Linker) teardownPath() string {
+	return filepath.Join(l.TargetDir, TeardownFileName)
+}
+
+// LoadTeardownManifest reads the teardown manifest at path.
+func LoadTeardownManifest(path string) (*TeardownManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read teardown manifest: %w", err)
+	}
+	var manifest TeardownManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse teardown manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Save writes the manifest to path as JSON, through a temporary file so a
+// crash never leaves a truncated one.
+func (m *TeardownManifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode teardown manifest: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write teardown manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace teardown manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// recordTeardown adds the files result deployed and the directories created
+// since existingDirs were recorded in state to the teardown manifest.
+func (l *Linker) recordTeardown(state *State, result *Result, existingDirs map[string]bool) error {
+	path := l.teardownPath()
+	manifest, err := LoadTeardownManifest(path)
+	if errors.Is(err, os.ErrNotExist) {
+		manifest, err = &TeardownManifest{Target: l.TargetDir}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]StateEntry, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		files[entry.Target] = entry
+	}
+	for _, target := range result.Linked {
+		if entry, ok := state.Lookup(target); ok {
+			files[target] = entry
+		}
+	}
+	manifest.Files = manifest.Files[:0]
+	for _, entry := range files {
+		manifest.Files = append(manifest.Files, entry)
+	}
+	slices.SortFunc(manifest.Files, func(a, b StateEntry) int { return strings.Compare(a.Target, b.Target) })
+
+	for dir := range state.Directories {
+		if !existingDirs[dir] && !slices.Contains(manifest.Dirs, dir) {
+			manifest.Dirs = append(manifest.Dirs, dir)
+		}
+	}
+	slices.Sort(manifest.Dirs)
+
+	if err := manifest.Save(path); err != nil {
+		return err
+	}
+	l.logVerbose("Recorded %d files and %d directories in %s\n", len(manifest.Files), len(manifest.Dirs), path)
+	return nil
+}
+
+// Teardown removes exactly what ephemeral runs deployed into the target, as
+// listed in its teardown manifest, then the manifest itself. The packages are
+// not needed and no hooks run. Files edited since they were deployed stop the
+// teardown before anything is removed, unless ForceRemove is set, in which
+// case they are moved to the trash; files that are gone or were replaced by
+// something else are left alone. Created directories are removed once empty.
+//
+// The returned Result is never nil.
+func (l *Linker) Teardown() (result *Result, err error) {
+	span := l.startSpan("gslk.Teardown")
+	defer func() { span.End(err) }()
+	if remote, ok := l.FS.(RemoteFS); ok {
+		return &Result{}, fmt.Errorf("teardown is not supported on remote target %s", remote.Location())
+	}
+	defer l.lockState()()
+
+	path := l.teardownPath()
+	manifest, err := LoadTeardownManifest(path)
+	if err != nil {
+		return &Result{}, err
+	}
+	state, err := l.loadState()
+	if err != nil {
+		return &Result{}, fmt.Errorf("failed to load state: %w", err)
+	}
+	plan, err := l.planTeardown(manifest, state)
+	if err != nil {
+		return &Result{}, err
+	}
+
+	result, applyErr := l.executor(state, span).Apply(plan)
+	if !l.DryRun {
+		if err := state.Save(); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+	if applyErr != nil || l.DryRun {
+		return result, applyErr
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return result, fmt.Errorf("failed to remove teardown manifest %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// planTeardown computes the operations removing the files and directories of
+// manifest, files first and directories deepest first.
+func (l *Linker) planTeardown(manifest *TeardownManifest, state *State) (*Plan, error) {
+	plan := &Plan{}
+	for _, entry := range manifest.Files {
+		op := Operation{Kind: OpRemove, Package: entry.Package, Source: entry.Source, Target: entry.Target, Mode: entry.Mode}
+		fi, err := os.Lstat(entry.Target)
+		if os.IsNotExist(err) {
+			continue // Nothing to remove
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat target path %s: %w", entry.Target, err)
+		}
+		if !fi.Mode().IsRegular() {
+			op.Kind = OpSkip
+			op.Reason = fmt.Sprintf("no longer the deployed %s", entry.Mode)
+			plan.add(op)
+			continue
+		}
+
+		hash, err := hashFile(entry.Target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash target file %s: %w", entry.Target, err)
+		}
+		op.Current = TargetDeployed
+		if hash != entry.Hash {
+			if !l.ForceRemove {
+				return nil, fmt.Errorf("%w: refusing to remove %s: file was modified since it was deployed (use -f to remove anyway)", ErrConflict, entry.Target)
+			}
+			op.Current = TargetModified
+		}
+		plan.add(op)
+	}
+
+	dirs := slices.Clone(manifest.Dirs)
+	slices.SortFunc(dirs, func(a, b string) int {
+		if depthA, depthB := strings.Count(a, string(filepath.Separator)), strings.Count(b, string(filepath.Separator)); depthA != depthB {
+			return depthB - depthA
+		}
+		return strings.Compare(a, b)
+	})
+	packages := make(map[string]bool)
+	for _, entry := range manifest.Files {
+		packages[entry.Package] = true
+	}
+	for _, dir := range dirs {
+		// The claims of the packages torn down are released; a directory
+		// another package still needs is kept, and one without claims removed
+		owners := state.DirOwners(dir)
+		if len(owners) == 0 {
+			plan.add(Operation{Kind: OpRmdir, Target: dir})
+		}
+		for _, owner := range owners {
+			if packages[owner] {
+				plan.add(Operation{Kind: OpRmdir, Package: owner, Target: dir})
+			}
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,64 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEphemeralTeardown(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "zsh"), map[string]string{
+		".zshrc":             "setopt autocd",
+		".config/zsh/prompt": "PROMPT='%~ '",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{
+		".config/git/config": "[core]",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(targetDir, ".config", "fish"), 0755))
+
+	discard := WithLogger(log.New(io.Discard, "", 0))
+	linker := New(sourceDir, targetDir, discard, WithEphemeral())
+	_, err := linker.Link([]string{"zsh"})
+	require.NoError(t, err)
+	_, err = linker.Link([]string{"git"})
+	require.NoError(t, err)
+
+	fi, err := os.Lstat(filepath.Join(targetDir, ".zshrc"))
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular(), "ephemeral runs copy files")
+	manifest, err := LoadTeardownManifest(filepath.Join(targetDir, TeardownFileName))
+	require.NoError(t, err)
+	assert.Len(t, manifest.Files, 3)
+	assert.Equal(t, []string{filepath.Join(targetDir, ".config", "git"), filepath.Join(targetDir, ".config", "zsh")}, manifest.Dirs)
+
+	// Edited files stop the teardown
+	prompt := filepath.Join(targetDir, ".config", "zsh", "prompt")
+	require.NoError(t, os.WriteFile(prompt, []byte("edited"), 0644))
+	_, err = linker.Teardown()
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorContains(t, err, "was modified since it was deployed")
+	require.NoError(t, os.WriteFile(prompt, []byte("PROMPT='%~ '"), 0644))
+
+	// The packages are not needed, and only what was deployed is removed
+	require.NoError(t, os.RemoveAll(sourceDir))
+	result, err := New(sourceDir, targetDir, discard).Teardown()
+	require.NoError(t, err)
+	assert.Len(t, result.Unlinked, 3)
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ".config", entries[0].Name())
+	assert.DirExists(t, filepath.Join(targetDir, ".config", "fish"))
+	assert.NoDirExists(t, filepath.Join(targetDir, ".config", "zsh"))
+
+	_, err = linker.Teardown()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,238 @@
+package gslk
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplateSuffix is the file name suffix marking package templates.
+const DefaultTemplateSuffix = ".tmpl"
+
+// TemplateData is the data package templates are executed with:
+//
+//	{{ .Vars.email }}  {{ .Env.HOME }}  {{ .Hostname }}  {{ if eq .OS "darwin" }}...{{ end }}
+//
+// Referring to a variable that is not defined is an error. Templates may also
+// call these functions:
+//
+//	hostname, os, arch     The host name, runtime.GOOS and runtime.GOARCH
+//	env "EDITOR"           The environment variable, empty if it is not set
+//	lookPath "nvim"        The path of the command in PATH, empty if there is none
+//	fileExists "~/.work"   Whether the path exists; relative paths are in the target directory
+type TemplateData struct {
+	Vars     map[string]string // Variables from the configuration, profile and vars file
+	Env      map[string]string // Environment variables
+	Hostname string
+	OS       string // runtime.GOOS
+	Arch     string // runtime.GOARCH
+	User     string // Name of the current user
+	Home     string // Home directory of the current user
+	Package  string // Name of the package the template belongs to
+}
+
+// LoadVars reads template variables from a TOML file of `name = value` pairs,
+// or from a YAML file of `name: value` pairs if its name ends in .yaml or .yml.
+func LoadVars(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vars file %s: %w", path, err)
+	}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		vars, err := parseYAMLVars(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vars file %s: %w", path, err)
+		}
+		return vars, nil
+	}
+
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vars file %s: %w", path, err)
+	}
+
+	vars, err := tomlVars(map[string]any{"vars": doc}, "vars")
+	if err != nil {
+		return nil, fmt.Errorf("vars file %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// parseYAMLVars parses a YAML mapping of names to scalar values, the subset of
+// YAML vars files use. Nested mappings, lists and multi-line values are errors.
+func parseYAMLVars(text string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if line != trimmed || strings.HasPrefix(line, "- ") {
+			return nil, fmt.Errorf("line %d: only a mapping of names to values is supported", i+1)
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || (value != "" && value[0] != ' ' && value[0] != '\t') {
+			return nil, fmt.Errorf("line %d: expected name: value", i+1)
+		}
+		name, err := yamlScalar(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if vars[name], err = yamlScalar(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return vars, nil
+}
+
+// yamlScalar returns the value of a plain, single-quoted or double-quoted
+// YAML scalar, without a trailing comment.
+func yamlScalar(text string) (string, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		end := strings.LastIndex(text, `"`)
+		if end == 0 || !isYAMLComment(text[end+1:]) {
+			return "", fmt.Errorf("invalid quoted value %s", text)
+		}
+		value, err := strconv.Unquote(text[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		end := strings.LastIndex(text, "'")
+		if end == 0 || !isYAMLComment(text[end+1:]) {
+			return "", fmt.Errorf("invalid quoted value %s", text)
+		}
+		return strings.ReplaceAll(text[1:end], "''", "'"), nil
+	case text != "" && strings.ContainsRune("[{|>&*!", rune(text[0])):
+		return "", fmt.Errorf("unsupported value %s: only strings, numbers and booleans are", text)
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = strings.TrimSpace(text[:i])
+	}
+	return text, nil
+}
+
+// isYAMLComment reports whether text after a quoted scalar is empty or a comment.
+func isYAMLComment(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || strings.HasPrefix(text, "#")
+}
+
+// templateFuncs returns the functions available to templates; relative paths
+// given to fileExists are resolved against targetDir.
+func templateFuncs(targetDir string) template.FuncMap {
+	return template.FuncMap{
+		"hostname": func() string {
+			name, _ := os.Hostname()
+			return name
+		},
+		"os":   func() string { return runtime.GOOS },
+		"arch": func() string { return runtime.GOARCH },
+		"env":  os.Getenv,
+		"lookPath": func(name string) string {
+			path, _ := exec.LookPath(name)
+			return path
+		},
+		"fileExists": func(path string) bool {
+			_, err := os.Stat(expandPath(path, targetDir))
+			return err == nil
+		},
+	}
+}
+
+// MissingVar is a template variable a package declares in its manifest that
+// is not set.
+type MissingVar struct {
+	Name     string
+	Question string // What to ask for the value
+	Package  string // First package declaring the variable
+}
+
+// MissingVars returns the template variables the packages (and their
+// dependencies) declare that Vars does not set, sorted by name. Linking such
+// a package fails until they are set.
+func (l *Linker) MissingVars(packageNames []string) ([]MissingVar, error) {
+	packages, err := l.lookupPackages(packageNames)
+	if err != nil {
+		return nil, err
+	}
+	if packages, err = l.withDependencies(packages); err != nil {
+		return nil, err
+	}
+	return l.missingVars(packages), nil
+}
+
+// missingVars returns the template variables packages declare that Vars does
+// not set, sorted by name.
+func (l *Linker) missingVars(packages []Package) []MissingVar {
+	missing := make(map[string]MissingVar)
+	for _, pkg := range packages {
+		for name, question := range pkg.Manifest.Vars {
+			if _, ok := l.Vars[name]; ok {
+				continue
+			}
+			if _, ok := missing[name]; !ok {
+				missing[name] = MissingVar{Name: name, Question: question, Package: pkg.Name}
+			}
+		}
+	}
+	return slices.SortedFunc(maps.Values(missing), func(a, b MissingVar) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// templateData returns the data templates of pkgName are rendered with.
+func (l *Linker) templateData(pkgName string) TemplateData {
+	data := TemplateData{
+		Vars:    l.Vars,
+		Env:     make(map[string]string),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Package: pkgName,
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			data.Env[name] = value
+		}
+	}
+	data.Hostname, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		data.User = u.Username
+	}
+	data.Home, _ = os.UserHomeDir()
+	return data
+}
+
+// renderTemplate executes the template at sourcePath for package pkgName.
+func (l *Linker) renderTemplate(pkgName, sourcePath string) ([]byte, error) {
+	text, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(sourcePath).Option("missingkey=error").Funcs(templateFuncs(l.declaredTarget())).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, l.templateData(pkgName)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,192 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkRendersTemplates(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgName := "git"
+	createDummyPackage(t, filepath.Join(sourceDir, pkgName), map[string]string{
+		".gitconfig.tmpl": "[user]\n\temail = {{ .Vars.email }}\n# {{ .OS }} {{ .Package }}\n",
+		"plain.conf":      "plain",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mapper:    Mapper{TemplateSuffix: DefaultTemplateSuffix},
+		Vars:      map[string]string{"email": "me@example.com"},
+	}
+	_, err := linker.Link([]string{pkgName})
+	require.NoError(t, err)
+
+	renderedPath := filepath.Join(targetDir, ".gitconfig")
+	fi, err := os.Lstat(renderedPath)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular(), "Templates are rendered to regular files, not linked")
+	content, err := os.ReadFile(renderedPath)
+	require.NoError(t, err)
+	assert.Equal(t, "[user]\n\temail = me@example.com\n# "+runtime.GOOS+" git\n", string(content))
+
+	fi, err = os.Lstat(filepath.Join(targetDir, "plain.conf"))
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Mode()&os.ModeSymlink, "Other files are still linked")
+
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	entry, ok := state.Lookup(renderedPath)
+	require.True(t, ok)
+	assert.Equal(t, ModeTemplate, entry.Mode)
+
+	// Unchanged output is left alone, changed variables re-render
+	plan, err := linker.PlanLink([]string{pkgName})
+	require.NoError(t, err)
+	assert.Equal(t, []OpKind{OpSkip}, opsByTarget(t, plan, targetDir)[".gitconfig"])
+
+	linker.Vars["email"] = "new@example.com"
+	_, err = linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	content, err = os.ReadFile(renderedPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "new@example.com")
+
+	// Local edits are conflicts, and unlink removes the rendered file
+	require.NoError(t, os.WriteFile(renderedPath, []byte("edited"), 0644))
+	_, err = linker.PlanLink([]string{pkgName})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modified since it was deployed")
+
+	require.NoError(t, os.Remove(renderedPath))
+	_, err = linker.Link([]string{pkgName})
+	require.NoError(t, err)
+	_, err = linker.Unlink([]string{pkgName})
+	require.NoError(t, err)
+	_, err = os.Lstat(renderedPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLinkRendersListedTemplates(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "ssh"), map[string]string{
+		".ssh/config":    "User {{ .Vars.user }}\n",
+		".ssh/known":     "{{ literal }}",
+		ManifestFileName: "templates = [\".ssh/config\"]\n",
+	})
+
+	// No template suffix is needed for files the manifest lists
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Vars: map[string]string{"user": "me"}}
+	_, err := linker.Link([]string{"ssh"})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(targetDir, ".ssh", "config"))
+	require.NoError(t, err)
+	assert.Equal(t, "User me\n", string(content))
+	state, err := LoadState(linker.statePath())
+	require.NoError(t, err)
+	entry, ok := state.Lookup(filepath.Join(targetDir, ".ssh", "config"))
+	require.True(t, ok)
+	assert.Equal(t, ModeTemplate, entry.Mode)
+
+	fi, err := os.Lstat(filepath.Join(targetDir, ".ssh", "known"))
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Mode()&os.ModeSymlink, "Other files are still linked")
+}
+
+func TestTemplateMissingVariable(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"conf.tmpl": "{{ .Vars.undefined }}",
+	})
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mapper:    Mapper{TemplateSuffix: DefaultTemplateSuffix},
+	}
+	_, err := linker.PlanLink([]string{"pkg"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to render template")
+}
+
+func TestLoadVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.toml")
+	require.NoError(t, os.WriteFile(path, []byte("email = \"me@example.com\"\nport = 2222\n"), 0644))
+
+	vars, err := LoadVars(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"email": "me@example.com", "port": "2222"}, vars)
+}
+
+func TestLoadVarsYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("---\n# Mine\nemail: me@example.com  # work\nname: 'O''Brien'\n\"quoted\": \"a # b\\n\"\nport: 2222\nempty:\n"), 0644))
+
+	vars, err := LoadVars(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"email": "me@example.com", "name": "O'Brien", "quoted": "a # b\n", "port": "2222", "empty": ""}, vars)
+
+	for _, text := range []string{"git:\n  email: x\n", "- a\n", "list: [a, b]\n", "email:x\n", "s: \"open\n"} {
+		require.NoError(t, os.WriteFile(path, []byte(text), 0644))
+		_, err := LoadVars(path)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	t.Setenv("GSLK_TEST_VAR", "value")
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, ".work"), nil, 0644))
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{
+		"conf.tmpl": `{{ os }}/{{ arch }} {{ env "GSLK_TEST_VAR" }} {{ fileExists ".work" }} {{ fileExists "missing" }} {{ lookPath "gslk-no-such-command" }}{{ if eq hostname .Hostname }}.{{ end }}`,
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Mapper: Mapper{TemplateSuffix: DefaultTemplateSuffix}}
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(targetDir, "conf"))
+	require.NoError(t, err)
+	assert.Equal(t, runtime.GOOS+"/"+runtime.GOARCH+" value true false .", string(content))
+}
+
+func TestMissingVars(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "base"), map[string]string{
+		ManifestFileName: "[vars]\nname = \"Your name\"\n",
+	})
+	createDummyPackage(t, filepath.Join(sourceDir, "git"), map[string]string{
+		ManifestFileName: "depends = [\"base\"]\n[vars]\nemail = \"Email for commits\"\nname = \"Full name\"\n",
+		"conf.tmpl":      "{{ .Vars.email }}",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Mapper: Mapper{TemplateSuffix: DefaultTemplateSuffix}, Vars: map[string]string{"name": "Me"}}
+	missing, err := linker.MissingVars([]string{"git"})
+	require.NoError(t, err)
+	assert.Equal(t, []MissingVar{{Name: "email", Question: "Email for commits", Package: "git"}}, missing)
+
+	_, err = linker.PlanLink([]string{"git"})
+	assert.ErrorContains(t, err, "package git needs template variable email")
+
+	linker.Vars["email"] = "me@example.com"
+	missing, err = linker.MissingVars([]string{"git"})
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	_, err = linker.Link([]string{"git"})
+	assert.NoError(t, err)
+}
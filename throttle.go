@@ -0,0 +1,42 @@
+package gslk
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle limits the rate of filesystem operations, for running gslk on busy
+// shared servers or slow network homes without saturating the storage. Waits
+// are spaced evenly, with no bursts. A Throttle is safe for concurrent use and
+// shared by every Linker and Executor given it, so they stay within the rate
+// together; a nil Throttle never waits.
+type Throttle struct {
+	mu       sync.Mutex
+	interval time.Duration // Time between two operations
+	next     time.Time     // Earliest time of the next operation
+}
+
+// NewThrottle returns a Throttle allowing opsPerSecond operations a second,
+// or nil, which never waits, if opsPerSecond is not positive.
+func NewThrottle(opsPerSecond int) *Throttle {
+	if opsPerSecond <= 0 {
+		return nil
+	}
+	return &Throttle{interval: time.Second / time.Duration(opsPerSecond)}
+}
+
+// Wait blocks until the next operation may run.
+func (t *Throttle) Wait() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+	time.Sleep(delay)
+}
@@ -0,0 +1,54 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	assert.Nil(t, NewThrottle(0))
+	var unlimited *Throttle
+	unlimited.Wait()
+
+	// The first operation runs at once, and parallel callers share the rate
+	throttle := NewThrottle(100)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				throttle.Wait()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestLinkThrottled(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "shell"), map[string]string{
+		".bashrc":  "",
+		".profile": "",
+		".inputrc": "",
+	})
+
+	// Three paths planned and three links created
+	linker := New(sourceDir, targetDir, WithThrottle(50), WithLogger(log.New(io.Discard, "", 0)))
+	start := time.Now()
+	result, err := linker.Link([]string{"shell"})
+	require.NoError(t, err)
+	assert.Len(t, result.Linked, 3)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
@@ -0,0 +1,417 @@
+package gslk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML decodes the subset of TOML used by gslk configuration files:
+// tables, arrays of tables, dotted and quoted keys, basic and literal strings,
+// integers, floats, booleans, arrays (which may span lines) and inline tables.
+// Dates and multi-line strings are not supported.
+//
+// Tables decode to map[string]any, arrays to []any, and scalars to string,
+// int64, float64 or bool.
+func parseTOML(data string) (map[string]any, error) {
+	p := &tomlParser{src: data, line: 1}
+	root := make(map[string]any)
+	current := root
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		var err error
+		if p.peek() == '[' {
+			current, err = p.parseHeader(root)
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Only a comment may follow on the same line
+		p.skipSpace()
+		if p.peek() == '#' {
+			p.skipComment()
+		}
+		if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, p.errorf("unexpected %q after value", p.peek())
+		}
+	}
+}
+
+// tomlParser holds the position within the document being decoded.
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skipSpace skips spaces and tabs.
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// parseHeader parses a [table] or [[array.of.tables]] header and returns the
+// table subsequent key/value pairs belong to.
+func (p *tomlParser) parseHeader(root map[string]any) (map[string]any, error) {
+	p.pos++ // [
+	isArray := p.peek() == '['
+	if isArray {
+		p.pos++
+	}
+
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	p.skipSpace()
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+
+	if isArray {
+		table := make(map[string]any)
+		switch existing := parent[last].(type) {
+		case nil:
+			parent[last] = []any{table}
+		case []any:
+			parent[last] = append(existing, table)
+		default:
+			return nil, p.errorf("key %q is not an array of tables", last)
+		}
+		return table, nil
+	}
+
+	switch existing := parent[last].(type) {
+	case nil:
+		table := make(map[string]any)
+		parent[last] = table
+		return table, nil
+	case map[string]any:
+		return existing, nil
+	default:
+		return nil, p.errorf("key %q is already defined as a value", last)
+	}
+}
+
+// descend walks (and creates) the tables named by keys below table. The last
+// element of an array of tables is used for array keys.
+func (p *tomlParser) descend(table map[string]any, keys []string) (map[string]any, error) {
+	for _, key := range keys {
+		switch existing := table[key].(type) {
+		case nil:
+			next := make(map[string]any)
+			table[key] = next
+			table = next
+		case map[string]any:
+			table = existing
+		case []any:
+			if len(existing) == 0 {
+				return nil, p.errorf("key %q is not a table", key)
+			}
+			next, ok := existing[len(existing)-1].(map[string]any)
+			if !ok {
+				return nil, p.errorf("key %q is not a table", key)
+			}
+			table = next
+		default:
+			return nil, p.errorf("key %q is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// parseKeyValue parses `key = value` into table.
+func (p *tomlParser) parseKeyValue(table map[string]any) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey parses a possibly dotted key made of bare or quoted parts.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+
+		var key string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case c == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			key = p.src[start:p.pos]
+		}
+		keys = append(keys, key)
+
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseValue parses any value starting at the current position.
+func (p *tomlParser) parseValue() (any, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.parseBasicString()
+	case c == '\'':
+		if strings.HasPrefix(p.src[p.pos:], "'''") {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case strings.HasPrefix(p.src[p.pos:], "true"):
+		p.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(p.src[p.pos:], "false"):
+		p.pos += len("false")
+		return false, nil
+	default:
+		return p.parseNumber()
+	}
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++ // "
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.peek()
+			p.pos++
+			switch esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(esc)
+			case 'u', 'U':
+				size := 4
+				if esc == 'U' {
+					size = 8
+				}
+				if p.pos+size > len(p.src) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(code)) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				p.pos += size
+			default:
+				return "", p.errorf("invalid escape sequence \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++ // '
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.pos++ // [
+	values := []any{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return values, nil
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	p.pos++ // {
+	table := make(map[string]any)
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) parseNumber() (any, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-0123456789._eE", p.peek()) >= 0 {
+		p.pos++
+	}
+	text := strings.ReplaceAll(p.src[start:p.pos], "_", "")
+	if text == "" {
+		return nil, p.errorf("expected a value")
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q", text)
+}
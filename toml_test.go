@@ -0,0 +1,69 @@
+package gslk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc, err := parseTOML(`
+# Top-level settings
+sources = [
+  "~/team-dotfiles",   # shared base
+  '~/my-dotfiles',
+]
+target = "/home/user"
+jobs = 4
+ratio = 0.5
+dry = false
+"quoted key" = "a \"b\" é"
+
+[profiles.work]
+packages = ["git", "ssh"]
+vars = { email = "me@work.example", port = 22 }
+
+[[hooks]]
+run = "first"
+
+[[hooks]]
+run = "second"
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{"~/team-dotfiles", "~/my-dotfiles"}, doc["sources"])
+	assert.Equal(t, "/home/user", doc["target"])
+	assert.Equal(t, int64(4), doc["jobs"])
+	assert.Equal(t, 0.5, doc["ratio"])
+	assert.Equal(t, false, doc["dry"])
+	assert.Equal(t, `a "b" é`, doc["quoted key"])
+
+	profiles := doc["profiles"].(map[string]any)
+	work := profiles["work"].(map[string]any)
+	assert.Equal(t, []any{"git", "ssh"}, work["packages"])
+	assert.Equal(t, map[string]any{"email": "me@work.example", "port": int64(22)}, work["vars"])
+
+	hooks := doc["hooks"].([]any)
+	require.Len(t, hooks, 2)
+	assert.Equal(t, "second", hooks[1].(map[string]any)["run"])
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"missing equals":      "key \"value\"",
+		"unterminated string": `key = "value`,
+		"duplicate key":       "a = 1\na = 2",
+		"trailing garbage":    "a = 1 2",
+		"bad array":           "a = [1 2]",
+		"table over value":    "a = 1\n[a]",
+		"multi-line string":   `a = """x"""`,
+	} {
+		_, err := parseTOML(input)
+		assert.Error(t, err, name)
+	}
+
+	_, err := parseTOML("a = 1\n\nb = ")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+}
@@ -0,0 +1,50 @@
+package gslk
+
+// Tracer starts the spans timing what Link, Unlink, Relink and planning do,
+// so that services embedding gslk can see where the time goes on slow
+// filesystems. Each call gets a span named after it, holding a span per
+// package planned and one per batch of operations applied.
+//
+// gslk does not depend on OpenTelemetry; an OpenTelemetry trace.Tracer is
+// adapted with a few lines (see README.md). Spans may be started and ended
+// from several goroutines at once.
+type Tracer interface {
+	Start(name string, attrs ...Attr) Span
+}
+
+// Span is a timed part of a call started by a Tracer.
+type Span interface {
+	// Start starts a span nested in this one.
+	Start(name string, attrs ...Attr) Span
+	// End ends the span; err is why the part failed, or nil.
+	End(err error)
+}
+
+// Attr is an attribute of a span. Values are strings, ints or string slices.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// noSpan is the Span of a Linker without a Tracer.
+type noSpan struct{}
+
+func (noSpan) Start(string, ...Attr) Span { return noSpan{} }
+func (noSpan) End(error)                  {}
+
+// startSpan starts the span of a Linker call, or returns a span doing
+// nothing if the Linker has no Tracer.
+func (l *Linker) startSpan(name string, attrs ...Attr) Span {
+	if l.Tracer == nil {
+		return noSpan{}
+	}
+	return l.Tracer.Start(name, append(attrs, Attr{"gslk.target", l.TargetDir})...)
+}
+
+// orNoSpan returns span, or a span doing nothing if span is nil.
+func orNoSpan(span Span) Span {
+	if span == nil {
+		return noSpan{}
+	}
+	return span
+}
@@ -0,0 +1,103 @@
+package gslk
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTracer records the spans it starts by their path of names, such as
+// "gslk.Link/gslk.plan.package", and the attributes and errors they got.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer *recordingTracer
+	path   string
+	attrs  map[string]any
+	ended  bool
+	err    error
+}
+
+func (t *recordingTracer) Start(name string, attrs ...Attr) Span {
+	return t.start(name, attrs)
+}
+
+func (t *recordingTracer) start(path string, attrs []Attr) *recordedSpan {
+	span := &recordedSpan{tracer: t, path: path, attrs: make(map[string]any)}
+	for _, attr := range attrs {
+		span.attrs[attr.Key] = attr.Value
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return span
+}
+
+func (s *recordedSpan) Start(name string, attrs ...Attr) Span {
+	return s.tracer.start(s.path+"/"+name, attrs)
+}
+
+func (s *recordedSpan) End(err error) {
+	s.tracer.mu.Lock()
+	s.ended, s.err = true, err
+	s.tracer.mu.Unlock()
+}
+
+// paths returns the paths of the spans started, in order.
+func (t *recordingTracer) paths() []string {
+	var paths []string
+	for _, span := range t.spans {
+		paths = append(paths, span.path)
+	}
+	return paths
+}
+
+func TestTracing(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "one"), map[string]string{"a.txt": "a", "dir/b.txt": "b"})
+	createDummyPackage(t, filepath.Join(sourceDir, "two"), map[string]string{"c.txt": "c"})
+
+	tracer := &recordingTracer{}
+	linker := New(sourceDir, targetDir, WithTracer(tracer), WithLogger(log.New(io.Discard, "", 0)))
+	_, err := linker.Link([]string{"one", "two"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"gslk.Link",
+		"gslk.Link/gslk.plan.package",
+		"gslk.Link/gslk.plan.package",
+		"gslk.Link/gslk.apply.batch", // a.txt
+		"gslk.Link/gslk.apply.batch", // dir
+		"gslk.Link/gslk.apply.batch", // dir/b.txt and c.txt
+	}, tracer.paths())
+	root := tracer.spans[0]
+	assert.Equal(t, []string{"one", "two"}, root.attrs["gslk.packages"])
+	assert.Equal(t, targetDir, root.attrs["gslk.target"])
+	assert.Equal(t, "two", tracer.spans[2].attrs["gslk.package"])
+	for _, span := range tracer.spans {
+		assert.True(t, span.ended, "%s is ended", span.path)
+		assert.NoError(t, span.err)
+	}
+
+	tracer.spans = nil
+	_, err = linker.Unlink([]string{"one"})
+	require.NoError(t, err)
+	assert.Contains(t, tracer.paths(), "gslk.Unlink/gslk.verify")
+
+	// Failures end the spans they happen in with the error
+	tracer.spans = nil
+	_, err = linker.PlanLink([]string{"missing"})
+	require.Error(t, err)
+	assert.Equal(t, []string{"gslk.PlanLink"}, tracer.paths())
+	assert.Equal(t, err, tracer.spans[0].err)
+}
@@ -0,0 +1,166 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// TrashDirName is the directory in the target where files and directories
+// removed with ForceRemove are kept until they are restored or the trash is
+// emptied.
+const TrashDirName = ".gslk-trash"
+
+// TrashEntry records a file or directory moved to the trash.
+type TrashEntry struct {
+	Package string    `json:"package"`
+	Path    string    `json:"path"`  // Original location
+	Saved   string    `json:"saved"` // Current location in the trash
+	Time    time.Time `json:"time"`
+}
+
+// saveAsidePath returns where to keep targetPath below dir, in a directory
+// named after now. A path saved earlier within the same second is never reused.
+func saveAsidePath(dir, targetDir, targetPath string, now time.Time) (string, error) {
+	relTarget, err := filepath.Rel(targetDir, targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get relative path for %s: %w", targetPath, err)
+	}
+
+	savedPath := filepath.Join(dir, now.Format("20060102-150405"), relTarget)
+	basePath := savedPath
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(savedPath); os.IsNotExist(err) {
+			return savedPath, nil
+		}
+		savedPath = fmt.Sprintf("%s.%d", basePath, i)
+	}
+}
+
+// trash moves path into the trash directory and records it in state, so that
+// nothing force-removed on behalf of pkgName is lost for good.
+func (e *Executor) trash(path, pkgName string) error {
+	if remote, ok := e.FS.(RemoteFS); ok {
+		return fmt.Errorf("cannot move %s to the trash on %s: the trash is not supported on remote targets", path, remote.Location())
+	}
+	now := time.Now()
+	savedPath, err := saveAsidePath(filepath.Join(e.TargetDir, TrashDirName), e.TargetDir, path, now)
+	if err != nil {
+		return err
+	}
+
+	e.printf("Moving to trash: %s -> %s\n", path, savedPath)
+
+	if e.DryRun {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(savedPath), 0700); err != nil {
+		return fmt.Errorf("failed to create trash directory for %s: %w", savedPath, err)
+	}
+	if err := os.Rename(path, savedPath); err != nil {
+		return fmt.Errorf("failed to move %s to the trash: %w", path, err)
+	}
+
+	e.State.addTrash(TrashEntry{
+		Package: pkgName,
+		Path:    path,
+		Saved:   savedPath,
+		Time:    now,
+	})
+	return nil
+}
+
+// Trashed returns the files and directories in the trash, oldest first.
+func (l *Linker) Trashed() ([]TrashEntry, error) {
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return state.Trash, nil
+}
+
+// RestoreTrashed moves a trashed file or directory back to where it was
+// removed from. Nothing is overwritten: restoring fails if the original
+// location is occupied again.
+func (l *Linker) RestoreTrashed(entry TrashEntry) error {
+	l.printf("Restoring: %s -> %s\n", entry.Saved, entry.Path)
+	if l.DryRun {
+		return nil
+	}
+	if err := checkWritable(l.fs(), "restore", entry.Path); err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(entry.Path); err == nil {
+		return fmt.Errorf("cannot restore %s: the path is occupied", entry.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", entry.Path, err)
+	}
+	if err := os.Rename(entry.Saved, entry.Path); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", entry.Path, err)
+	}
+
+	return l.forgetTrashed(entry)
+}
+
+// EmptyTrash permanently deletes everything in the trash.
+func (l *Linker) EmptyTrash() error {
+	trashDir := filepath.Join(l.TargetDir, TrashDirName)
+	if err := checkWritable(l.fs(), "remove", trashDir); err != nil && !l.DryRun {
+		return err
+	}
+	if !l.DryRun && l.Confirm != nil {
+		ok, err := l.Confirm(fmt.Sprintf("Permanently delete everything in %s", trashDir))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	l.printf("Emptying trash: %s\n", trashDir)
+	if l.DryRun {
+		return nil
+	}
+
+	if err := os.RemoveAll(trashDir); err != nil {
+		return fmt.Errorf("failed to empty trash %s: %w", trashDir, err)
+	}
+
+	defer l.lockState()()
+	state, err := l.loadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	if len(state.Trash) > 0 {
+		state.Trash = nil
+		state.dirty = true
+	}
+	return state.Save()
+}
+
+// forgetTrashed removes entry from the state and prunes the trash directories
+// it leaves empty.
+func (l *Linker) forgetTrashed(entry TrashEntry) error {
+	defer l.lockState()()
+
+	state, err := l.loadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	trashDir := filepath.Join(l.TargetDir, TrashDirName)
+	removeParents(l.Logger, entry.Saved, trashDir)
+	os.Remove(trashDir) // Only succeeds once the trash is empty
+
+	state.Trash = slices.DeleteFunc(state.Trash, func(trashed TrashEntry) bool {
+		return trashed.Saved == entry.Saved
+	})
+	state.dirty = true
+	return state.Save()
+}
@@ -0,0 +1,148 @@
+package gslk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceRemoveMovesToTrash(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "app"), map[string]string{
+		".config/app/app.conf": "package",
+	})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	_, err := linker.Link([]string{"app"})
+	require.NoError(t, err)
+
+	// The user keeps their own file next to the linked one
+	userFile := filepath.Join(targetDir, ".config", "app", "history")
+	require.NoError(t, os.WriteFile(userFile, []byte("user data"), 0644))
+
+	linker.ForceRemove = true
+	_, err = linker.Unlink([]string{"app"})
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(targetDir, ".config", "app"))
+
+	trashed, err := linker.Trashed()
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, filepath.Join(targetDir, ".config", "app"), trashed[0].Path)
+	assert.Equal(t, "app", trashed[0].Package)
+	content, err := os.ReadFile(filepath.Join(trashed[0].Saved, "history"))
+	require.NoError(t, err)
+	assert.Equal(t, "user data", string(content))
+
+	// Restoring brings the directory back and empties the trash
+	require.NoError(t, linker.RestoreTrashed(trashed[0]))
+	content, err = os.ReadFile(userFile)
+	require.NoError(t, err)
+	assert.Equal(t, "user data", string(content))
+	assert.NoDirExists(t, filepath.Join(targetDir, TrashDirName))
+
+	trashed, err = linker.Trashed()
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestForceRemoveTrashesModifiedCopy(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "copied"), map[string]string{"file.txt": "package"})
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir, Mode: ModeCopy}
+	_, err := linker.Link([]string{"copied"})
+	require.NoError(t, err)
+
+	targetFile := filepath.Join(targetDir, "file.txt")
+	require.NoError(t, os.WriteFile(targetFile, []byte("edited"), 0644))
+
+	linker.ForceRemove = true
+	_, err = linker.Unlink([]string{"copied"})
+	require.NoError(t, err)
+	assert.NoFileExists(t, targetFile)
+
+	trashed, err := linker.Trashed()
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	content, err := os.ReadFile(trashed[0].Saved)
+	require.NoError(t, err)
+	assert.Equal(t, "edited", string(content))
+
+	// An occupied path is never overwritten by a restore
+	require.NoError(t, os.WriteFile(targetFile, []byte("new"), 0644))
+	assert.Error(t, linker.RestoreTrashed(trashed[0]))
+
+	require.NoError(t, linker.EmptyTrash())
+	assert.NoDirExists(t, filepath.Join(targetDir, TrashDirName))
+	trashed, err = linker.Trashed()
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestForceRemoveAsksForConfirmation(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "app"), map[string]string{".config/app/app.conf": "package"})
+
+	var questions []string
+	answer := false
+	linker := &Linker{
+		SourceDir:   sourceDir,
+		TargetDir:   targetDir,
+		ForceRemove: true,
+		Confirm: func(question string) (bool, error) {
+			questions = append(questions, question)
+			return answer, nil
+		},
+	}
+	_, err := linker.Link([]string{"app"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, ".config", "app", "history"), []byte("user data"), 0644))
+
+	// Declining keeps the directory as if -f had not been given
+	_, err = linker.Unlink([]string{"app"})
+	require.NoError(t, err)
+	require.Len(t, questions, 2, "Both directories gslk created are asked about")
+	assert.Contains(t, questions[0], filepath.Join(targetDir, ".config", "app"))
+	assert.FileExists(t, filepath.Join(targetDir, ".config", "app", "history"))
+
+	trashed, err := linker.Trashed()
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestConfirmErrorFailsQuarantine(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	createDummyPackage(t, filepath.Join(sourceDir, "pkg"), map[string]string{"file.txt": "package"})
+	userFile := filepath.Join(targetDir, "file.txt")
+	require.NoError(t, os.WriteFile(userFile, []byte("user"), 0644))
+
+	linker := &Linker{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		ConflictPolicy: ConflictBackup,
+		Confirm: func(question string) (bool, error) {
+			return false, errors.New("confirmation required")
+		},
+	}
+	result, err := linker.Link([]string{"pkg"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "confirmation required")
+	assert.Equal(t, []string{userFile}, result.Failed)
+
+	content, err := os.ReadFile(userFile)
+	require.NoError(t, err)
+	assert.Equal(t, "user", string(content), "The user's file stays in place")
+}
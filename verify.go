@@ -0,0 +1,110 @@
+package gslk
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+)
+
+// ChecksumStatus is the outcome of comparing one side of a deployed file with
+// the checksum recorded in the state manifest.
+type ChecksumStatus string
+
+const (
+	ChecksumOK        ChecksumStatus = "ok"        // The content matches the recorded checksum
+	ChecksumChanged   ChecksumStatus = "changed"   // The content differs from the recorded checksum
+	ChecksumMissing   ChecksumStatus = "missing"   // The file is gone, or something other than a regular file is in its place
+	ChecksumUnchecked ChecksumStatus = "unchecked" // The content could not be produced, such as a secret that is not decrypted
+)
+
+// Verification is the result of checking a copy, rendered template or
+// decrypted secret against the checksum the state manifest recorded when it
+// was deployed. TargetStatus says whether the deployed file was edited in the
+// target; SourceStatus whether the package would deploy something else now.
+type Verification struct {
+	Package      string
+	Source       string // Package file
+	Target       string // Deployed file
+	Mode         DeployMode
+	SourceStatus ChecksumStatus
+	TargetStatus ChecksumStatus
+	Err          error // Why SourceStatus is ChecksumUnchecked, unless the file is a secret
+}
+
+// OK reports whether both sides still match the recorded checksum, as far as
+// they could be checked.
+func (v Verification) OK() bool {
+	return v.TargetStatus == ChecksumOK && (v.SourceStatus == ChecksumOK || v.SourceStatus == ChecksumUnchecked && v.Err == nil)
+}
+
+// Verify checks the files of packageNames, or of every package if none is
+// given, that the state manifest records as copies, rendered templates or
+// decrypted secrets: the deployed file and what the package deploys now are
+// both compared with the checksum recorded at deployment, so edits made in
+// the target are told apart from updates of the package. Secrets are not
+// decrypted, so their source side is ChecksumUnchecked. Results are sorted
+// by target path; nothing is modified.
+func (l *Linker) Verify(packageNames []string) ([]Verification, error) {
+	state, err := l.loadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var results []Verification
+	for _, entry := range state.Entries {
+		if entry.Hash == "" || len(packageNames) > 0 && !slices.Contains(packageNames, entry.Package) {
+			continue
+		}
+		v := Verification{
+			Package:      entry.Package,
+			Source:       entry.Source,
+			Target:       entry.Target,
+			Mode:         entry.Mode,
+			TargetStatus: checksumStatus(entry.Target, entry.Hash),
+		}
+		switch entry.Mode {
+		case ModeSecret:
+			v.SourceStatus = ChecksumUnchecked
+			if _, err := os.Stat(entry.Source); err != nil {
+				v.SourceStatus = ChecksumMissing
+			}
+		case ModeTemplate:
+			v.SourceStatus = ChecksumMissing
+			if _, err := os.Stat(entry.Source); err == nil {
+				v.SourceStatus = ChecksumOK
+				content, err := l.renderTemplate(entry.Package, entry.Source)
+				switch {
+				case err != nil:
+					v.SourceStatus, v.Err = ChecksumUnchecked, err
+				case hashBytes(content) != entry.Hash:
+					v.SourceStatus = ChecksumChanged
+				}
+			}
+		default:
+			v.SourceStatus = checksumStatus(entry.Source, entry.Hash)
+		}
+		results = append(results, v)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Target < results[j].Target
+	})
+	return results, nil
+}
+
+// checksumStatus compares the regular file at path with hash.
+func checksumStatus(path, hash string) ChecksumStatus {
+	fi, err := os.Lstat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return ChecksumMissing
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return ChecksumMissing
+	}
+	if sum != hash {
+		return ChecksumChanged
+	}
+	return ChecksumOK
+}
@@ -0,0 +1,84 @@
+package gslk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{
+		"same":        "same",
+		"edited":      "edited",
+		"updated":     "updated",
+		"both":        "both",
+		"gone":        "gone",
+		"linked":      "linked",
+		"conf.tmpl":   "{{ .Vars.name }}",
+		"source.tmpl": "source",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, ManifestFileName), []byte(`copy = ["same", "edited", "updated", "both", "gone"]`), 0644))
+
+	linker := &Linker{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Mapper:    Mapper{TemplateSuffix: DefaultTemplateSuffix},
+		Vars:      map[string]string{"name": "old"},
+	}
+	_, err := linker.Link([]string{"pkg"})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "edited"), []byte("user"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "updated"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "both"), []byte("user"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "both"), []byte("new"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(targetDir, "gone")))
+	require.NoError(t, os.Remove(filepath.Join(pkgPath, "source.tmpl")))
+	linker.Vars["name"] = "new"
+
+	results, err := linker.Verify(nil)
+	require.NoError(t, err)
+	status := make(map[string][2]ChecksumStatus)
+	for _, v := range results {
+		status[filepath.Base(v.Target)] = [2]ChecksumStatus{v.SourceStatus, v.TargetStatus}
+	}
+	assert.Equal(t, map[string][2]ChecksumStatus{
+		"same":    {ChecksumOK, ChecksumOK},
+		"edited":  {ChecksumOK, ChecksumChanged},
+		"updated": {ChecksumChanged, ChecksumOK},
+		"both":    {ChecksumChanged, ChecksumChanged},
+		"gone":    {ChecksumOK, ChecksumMissing},
+		"conf":    {ChecksumChanged, ChecksumOK},
+		"source":  {ChecksumMissing, ChecksumOK},
+	}, status, "Links have no checksums and are not verified")
+	assert.True(t, verificationOf(results, "same").OK())
+
+	results, err = linker.Verify([]string{"other"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	delete(linker.Vars, "name")
+	results, err = linker.Verify([]string{"pkg"})
+	require.NoError(t, err)
+	v := verificationOf(results, "conf")
+	assert.Equal(t, ChecksumUnchecked, v.SourceStatus)
+	assert.Error(t, v.Err)
+	assert.False(t, v.OK())
+}
+
+// verificationOf returns the verification of the target named base.
+func verificationOf(results []Verification, base string) Verification {
+	for _, v := range results {
+		if filepath.Base(v.Target) == base {
+			return v
+		}
+	}
+	return Verification{}
+}
@@ -0,0 +1,154 @@
+package gslk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce is how long Watch waits for the packages to settle
+// before relinking them.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// WatchOptions configure Linker.Watch.
+type WatchOptions struct {
+	// Debounce is how long the packages must stay unchanged before they are
+	// relinked, so that a checkout or an editor saving several files causes a
+	// single relink; 0 means DefaultWatchDebounce
+	Debounce time.Duration
+
+	// Lock, if set, is called before every relink and the function it returns
+	// after it, e.g. to hold the target lock only while relinking. An error
+	// skips the relink and is passed to OnRelink
+	Lock func() (unlock func(), err error)
+
+	// OnRelink is called with the outcome of every relink, including the first
+	OnRelink func(*Result, error)
+}
+
+// Watch relinks packageNames, then keeps them in line with the source
+// directories until ctx is done: once files were added, removed, renamed or
+// changed in the packages and the packages stayed unchanged for the debounce
+// period, Relink links the new files, unlinks the removed ones and repairs
+// links to moved files. Changes are reported by the operating system through
+// fsnotify; every directory of the packages is watched, along with the source
+// directories holding them, so that a package directory replaced by a
+// checkout is watched again. Changes to permissions alone are ignored.
+//
+// A failed relink is reported to OnRelink and does not stop watching. Watch
+// returns nil once ctx is done, or an error if the packages cannot be found
+// or watched.
+func (l *Linker) Watch(ctx context.Context, packageNames []string, opts WatchOptions) error {
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	relink := func() (*Result, error) {
+		if opts.Lock != nil {
+			unlock, err := opts.Lock()
+			if err != nil {
+				return &Result{}, err
+			}
+			defer unlock()
+		}
+		return l.Relink(packageNames)
+	}
+	report := func(result *Result, err error) {
+		if opts.OnRelink != nil {
+			opts.OnRelink(result, err)
+		}
+	}
+
+	packages, err := l.lookupPackages(packageNames)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch packages: %w", err)
+	}
+	defer watcher.Close()
+
+	var layers []string
+	for _, pkg := range packages {
+		layers = append(layers, pkg.Layers...)
+	}
+	for _, layer := range layers {
+		if err := watcher.Add(filepath.Dir(layer)); err != nil {
+			return fmt.Errorf("failed to watch source directory %s: %w", filepath.Dir(layer), err)
+		}
+		if err := watchTree(watcher, layer); err != nil {
+			return err
+		}
+	}
+	inPackages := func(path string) bool {
+		return slices.ContainsFunc(layers, func(layer string) bool { return path == layer || isSubPath(layer, path) })
+	}
+	report(relink())
+
+	settled := time.NewTimer(debounce)
+	settled.Stop()
+	defer settled.Stop()
+	changed := false // The packages changed and were not relinked since
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !inPackages(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				// New directories are not watched by those holding them
+				if err := watchTree(watcher, event.Name); err != nil {
+					l.warnf("Warning: %v\n", err)
+				}
+			}
+			if !changed {
+				l.logVerbose("Packages changed, relinking once they settle\n")
+				changed = true
+			}
+			settled.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// Such as events lost because too many arrived at once
+			l.warnf("Warning: %v\n", err)
+		case <-settled.C:
+			changed = false
+			report(relink())
+		}
+	}
+}
+
+// watchTree adds root and every directory below it to watcher. A root that is
+// not a directory, or no longer exists, is left alone.
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // Removed again before it could be watched
+	}
+	return err
+}
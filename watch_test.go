@@ -0,0 +1,88 @@
+package gslk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	pkgPath := filepath.Join(sourceDir, "pkg")
+	createDummyPackage(t, pkgPath, map[string]string{"old.txt": "old"})
+
+	var mu sync.Mutex
+	var results []*Result
+	relinked := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	go func() {
+		done <- linker.Watch(ctx, []string{"pkg"}, WatchOptions{
+			Debounce: 30 * time.Millisecond,
+			OnRelink: func(result *Result, err error) {
+				assert.NoError(t, err)
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+				relinked <- struct{}{}
+			},
+		})
+	}()
+
+	waitRelink := func() {
+		select {
+		case <-relinked:
+		case <-time.After(5 * time.Second):
+			t.Fatal("packages were not relinked")
+		}
+	}
+
+	// The packages are relinked when watching starts
+	waitRelink()
+	_, err := os.Lstat(filepath.Join(targetDir, "old.txt"))
+	require.NoError(t, err)
+
+	// Renaming a file links the new name and removes the link to the old one
+	require.NoError(t, os.Rename(filepath.Join(pkgPath, "old.txt"), filepath.Join(pkgPath, "new.txt")))
+	waitRelink()
+	_, err = os.Lstat(filepath.Join(targetDir, "new.txt"))
+	assert.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(targetDir, "old.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// New directories are watched as well
+	require.NoError(t, os.MkdirAll(filepath.Join(pkgPath, "dir"), 0755))
+	waitRelink()
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "dir", "file.txt"), nil, 0644))
+	waitRelink()
+	_, err = os.Lstat(filepath.Join(targetDir, "dir", "file.txt"))
+	assert.NoError(t, err)
+
+	// Changes to permissions alone are not
+	require.NoError(t, os.Chmod(filepath.Join(pkgPath, "new.txt"), 0600))
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, results, 4, "nothing else changed, so no further relink was expected")
+}
+
+func TestWatchMissingPackage(t *testing.T) {
+	sourceDir, targetDir, cleanup := setupTestDirs(t)
+	defer cleanup()
+
+	linker := &Linker{SourceDir: sourceDir, TargetDir: targetDir}
+	err := linker.Watch(context.Background(), []string{"missing"}, WatchOptions{})
+	assert.Error(t, err)
+}